@@ -2,17 +2,25 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"errors"
+	"flag"
+	"log/slog"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	// Embedded zone data, the runtime image ships without /usr/share/zoneinfo
+	_ "time/tzdata"
 
+	"pwp-remastered/internal/config"
+	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/logging"
 	"pwp-remastered/internal/server"
+	"pwp-remastered/internal/tracing"
 )
 
-func gracefulShutdown(apiServer *http.Server, done chan bool) {
+func gracefulShutdown(apiServer *http.Server, stopBackground func(), db database.Service, timeout time.Duration, done chan bool) {
 	// Create context that listens for the interrupt signal from the OS.
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -20,39 +28,74 @@ func gracefulShutdown(apiServer *http.Server, done chan bool) {
 	// Listen for the interrupt signal.
 	<-ctx.Done()
 
-	log.Println("shutting down gracefully, press Ctrl+C again to force")
+	slog.Info("shutting down gracefully, press Ctrl+C again to force")
 
-	// The context is used to inform the server it has 5 seconds to finish
+	// The context is used to inform the server it has timeout to finish
 	// the request it is currently handling
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 	if err := apiServer.Shutdown(ctx); err != nil {
-		log.Printf("Server forced to shutdown with error: %v", err)
+		slog.Error("server forced to shutdown", "error", err)
+	}
+
+	// Requests and background work are done, the connections they used can be closed
+	stopBackground()
+	if err := db.Close(); err != nil {
+		slog.Error("close database", "error", err)
 	}
 
-	log.Println("Server exiting")
+	slog.Info("server exiting")
 
 	// Notify the main goroutine that the shutdown is complete
 	done <- true
 }
 
 func main() {
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		logging.New(os.Stderr, slog.LevelInfo).Error("configuration", "error", err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logging.New(os.Stdout, cfg.LogLevel))
+
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		slog.Error("tracing setup", "error", err)
+		os.Exit(1)
+	}
 
-	fmt.Println("dasdasd")
-	server := server.NewServer()
+	db := database.New(cfg.Database)
+	server, stopBackground, err := server.NewServer(cfg, db)
+	if err != nil {
+		slog.Error("server setup", "error", err)
+		db.Close()
+		os.Exit(1)
+	}
 
 	// Create a done channel to signal when the shutdown is complete
 	done := make(chan bool, 1)
 
 	// Run graceful shutdown in a separate goroutine
-	go gracefulShutdown(server, done)
+	go gracefulShutdown(server, stopBackground, db, cfg.HTTP.ShutdownTimeout, done)
 
-	err := server.ListenAndServe()
+	slog.Info("server listening", "addr", server.Addr)
+	err = server.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
-		panic(fmt.Sprintf("http server error: %s", err))
+		slog.Error("http server error", "error", err)
+		os.Exit(1)
 	}
 
 	// Wait for the graceful shutdown to complete
 	<-done
-	log.Println("Graceful shutdown complete.")
+
+	// Send the spans still buffered
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("tracing shutdown", "error", err)
+	}
+	slog.Info("graceful shutdown complete")
 }
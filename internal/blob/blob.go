@@ -0,0 +1,26 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when a blob does not exist in the store.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore abstracts the storage backend used for attachment contents.
+// Keys are slash separated paths such as "events/12/3f2a...".
+type BlobStore interface {
+	// Put stores the contents of r under key. size may be -1 when unknown.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+
+	// Get opens the blob stored under key. The caller must close the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the blob stored under key. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+
+	// Ping checks that blobs can be stored.
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,95 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go"
+	tcminio "github.com/testcontainers/testcontainers-go/modules/minio"
+)
+
+func testRoundTrip(t *testing.T, store BlobStore) {
+	ctx := context.Background()
+	key := "events/1/receipt.pdf"
+	content := []byte("%PDF-1.4 fuel receipt")
+
+	if err := store.Ping(ctx); err != nil {
+		t.Fatalf("Ping() returned error: %v", err)
+	}
+
+	if err := store.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+		t.Fatalf("Put() returned error: %v", err)
+	}
+
+	rc, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get() returned error: %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatalf("error reading blob: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatalf("expected blob content %q, got %q", content, got)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete() returned error: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("expected deleting a missing blob to succeed, got %v", err)
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore() returned error: %v", err)
+	}
+	testRoundTrip(t, store)
+
+	if err := store.Put(context.Background(), "../escape", bytes.NewReader(nil), 0, ""); err == nil {
+		t.Fatal("expected key with .. to be rejected")
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctx := context.Background()
+	container, err := tcminio.Run(ctx, "minio/minio:RELEASE.2024-01-16T16-07-38Z",
+		tcminio.WithUsername("minioadmin"),
+		tcminio.WithPassword("minioadmin"),
+	)
+	if err != nil {
+		t.Fatalf("could not start minio container: %v", err)
+	}
+	defer func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("could not teardown minio container: %v", err)
+		}
+	}()
+
+	endpoint, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("could not get minio endpoint: %v", err)
+	}
+
+	store, err := NewS3Store(ctx, S3Config{
+		Endpoint:  endpoint,
+		AccessKey: "minioadmin",
+		SecretKey: "minioadmin",
+		Bucket:    "attachments",
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store() returned error: %v", err)
+	}
+	testRoundTrip(t, store)
+}
@@ -0,0 +1,339 @@
+// Package config loads the settings of the API server. Every setting has a
+// default, and can be set in a YAML file, an environment variable and a
+// command line flag; later sources override earlier ones in that order. The
+// YAML file is read from -config or CONFIG_FILE.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	// A .env file in the working directory fills the environment
+	_ "github.com/joho/godotenv/autoload"
+	"gopkg.in/yaml.v3"
+
+	"pwp-remastered/internal/logging"
+)
+
+// Config holds every setting of the API server
+type Config struct {
+	Port        int        `yaml:"port"`
+	MetricsAddr string     `yaml:"metrics_addr"`
+	LogLevel    slog.Level `yaml:"log_level"`
+	HTTP        HTTP       `yaml:"http"`
+	CORS        CORS       `yaml:"cors"`
+	Database    Database   `yaml:"database"`
+	Auth        Auth       `yaml:"auth"`
+	Blob        Blob       `yaml:"blob"`
+	// LiveBroker is the live event stream backend, memory or postgres
+	LiveBroker string `yaml:"live_broker"`
+	// AttachmentMaxBytes is the largest attachment that can be uploaded
+	AttachmentMaxBytes int64 `yaml:"attachment_max_bytes"`
+}
+
+// HTTP holds the timeouts of the API listener
+type HTTP struct {
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout is how long requests in flight get to finish on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// CORS holds the cross-origin settings of the API
+type CORS struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// Database holds the PostgreSQL connection settings
+type Database struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Name     string `yaml:"name"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Schema   string `yaml:"schema"`
+	// SSLMode is a libpq sslmode: disable, allow, prefer, require,
+	// verify-ca or verify-full
+	SSLMode string `yaml:"sslmode"`
+	// SSLRootCert is the CA file the server certificate is verified
+	// against, the system roots are used when empty
+	SSLRootCert string `yaml:"sslrootcert"`
+	// MaxOpenConns limits the connections of the pool, 0 means unlimited
+	MaxOpenConns int `yaml:"max_open_conns"`
+	// MaxIdleConns is how many unused connections the pool keeps open
+	MaxIdleConns int `yaml:"max_idle_conns"`
+	// ConnMaxLifetime and ConnMaxIdleTime close connections older or idle
+	// longer than them, 0 keeps them open
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// StatementTimeout cancels statements running longer, 0 means no limit
+	StatementTimeout time.Duration `yaml:"statement_timeout"`
+	// ApplicationName identifies the connections in pg_stat_activity
+	ApplicationName string `yaml:"application_name"`
+}
+
+// Auth holds the settings of the issued tokens
+type Auth struct {
+	JWTSecret     string        `yaml:"jwt_secret"`
+	TokenLifetime time.Duration `yaml:"token_lifetime"`
+}
+
+// Blob holds the attachment storage settings
+type Blob struct {
+	// Backend is local or s3
+	Backend  string `yaml:"backend"`
+	LocalDir string `yaml:"local_dir"`
+	S3       S3     `yaml:"s3"`
+}
+
+// S3 holds the settings of an S3 compatible attachment bucket
+type S3 struct {
+	Endpoint  string `yaml:"endpoint"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	Bucket    string `yaml:"bucket"`
+	Region    string `yaml:"region"`
+	UseSSL    bool   `yaml:"use_ssl"`
+}
+
+// Default returns the settings used when no source sets them
+func Default() Config {
+	return Config{
+		Port:     8080,
+		LogLevel: slog.LevelInfo,
+		HTTP: HTTP{
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     time.Minute,
+			ShutdownTimeout: 5 * time.Second,
+		},
+		CORS: CORS{AllowedOrigins: []string{"https://*", "http://*"}},
+		Database: Database{
+			Host:             "localhost",
+			Port:             5432,
+			Schema:           "public",
+			SSLMode:          "disable",
+			MaxOpenConns:     25,
+			MaxIdleConns:     25,
+			ConnMaxLifetime:  30 * time.Minute,
+			ConnMaxIdleTime:  5 * time.Minute,
+			StatementTimeout: 30 * time.Second,
+			ApplicationName:  "pwp-api",
+		},
+		Auth:               Auth{TokenLifetime: 72 * time.Hour},
+		Blob:               Blob{Backend: "local", LocalDir: "data/attachments"},
+		LiveBroker:         "memory",
+		AttachmentMaxBytes: 10 << 20,
+	}
+}
+
+// setting binds a field of Config to its environment variable and flag
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	value any
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"PORT", "port", "port of the API listener", &c.Port},
+		{"METRICS_ADDR", "metrics-addr", "address of the metrics listener, empty to disable it", &c.MetricsAddr},
+		{"LOG_LEVEL", "log-level", "debug, info, warn or error", &c.LogLevel},
+		{"HTTP_READ_TIMEOUT", "http-read-timeout", "time to read a request", &c.HTTP.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", "http-write-timeout", "time to write a response", &c.HTTP.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", "http-idle-timeout", "time a keep-alive connection is kept idle", &c.HTTP.IdleTimeout},
+		{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "time requests in flight get to finish on shutdown", &c.HTTP.ShutdownTimeout},
+		{"CORS_ALLOWED_ORIGINS", "cors-allowed-origins", "comma separated origins allowed to call the API", &c.CORS.AllowedOrigins},
+		{"DB_HOST", "db-host", "database host", &c.Database.Host},
+		{"DB_PORT", "db-port", "database port", &c.Database.Port},
+		{"DB_DATABASE", "db-database", "database name", &c.Database.Name},
+		{"DB_USERNAME", "db-username", "database user", &c.Database.User},
+		{"DB_PASSWORD", "db-password", "database password", &c.Database.Password},
+		{"DB_SCHEMA", "db-schema", "database schema", &c.Database.Schema},
+		{"DB_MAX_OPEN_CONNS", "db-max-open-conns", "most open database connections, 0 for unlimited", &c.Database.MaxOpenConns},
+		{"DB_SSLMODE", "db-sslmode", "disable, allow, prefer, require, verify-ca or verify-full", &c.Database.SSLMode},
+		{"DB_SSLROOTCERT", "db-sslrootcert", "CA file verifying the database certificate", &c.Database.SSLRootCert},
+		{"DB_MAX_IDLE_CONNS", "db-max-idle-conns", "most idle database connections", &c.Database.MaxIdleConns},
+		{"DB_CONN_MAX_LIFETIME", "db-conn-max-lifetime", "age database connections are closed at, 0 to keep them", &c.Database.ConnMaxLifetime},
+		{"DB_CONN_MAX_IDLE_TIME", "db-conn-max-idle-time", "idle time database connections are closed after, 0 to keep them", &c.Database.ConnMaxIdleTime},
+		{"DB_STATEMENT_TIMEOUT", "db-statement-timeout", "longest a statement may run, 0 for no limit", &c.Database.StatementTimeout},
+		{"DB_APPLICATION_NAME", "db-application-name", "application_name of the database connections", &c.Database.ApplicationName},
+		{"JWT_SECRET", "jwt-secret", "key signing the access tokens", &c.Auth.JWTSecret},
+		{"JWT_LIFETIME", "jwt-lifetime", "lifetime of the access tokens", &c.Auth.TokenLifetime},
+		{"BLOB_BACKEND", "blob-backend", "attachment storage, local or s3", &c.Blob.Backend},
+		{"BLOB_LOCAL_DIR", "blob-local-dir", "directory of the local attachment storage", &c.Blob.LocalDir},
+		{"S3_ENDPOINT", "s3-endpoint", "S3 endpoint", &c.Blob.S3.Endpoint},
+		{"S3_ACCESS_KEY", "s3-access-key", "S3 access key", &c.Blob.S3.AccessKey},
+		{"S3_SECRET_KEY", "s3-secret-key", "S3 secret key", &c.Blob.S3.SecretKey},
+		{"S3_BUCKET", "s3-bucket", "S3 bucket of the attachments", &c.Blob.S3.Bucket},
+		{"S3_REGION", "s3-region", "S3 region", &c.Blob.S3.Region},
+		{"S3_USE_SSL", "s3-use-ssl", "connect to S3 over TLS", &c.Blob.S3.UseSSL},
+		{"LIVE_BROKER", "live-broker", "live event stream backend, memory or postgres", &c.LiveBroker},
+		{"ATTACHMENT_MAX_BYTES", "attachment-max-bytes", "largest attachment that can be uploaded", &c.AttachmentMaxBytes},
+	}
+}
+
+// Load reads the settings from the YAML file, getenv and the command line
+// arguments args, in increasing precedence, and validates them
+func Load(args []string, getenv func(string) string) (Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	// Flags are applied last but parsed first, they can name the file
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	file := fs.String("config", getenv("CONFIG_FILE"), "YAML configuration file")
+	flags := make(map[string]string)
+	for _, s := range settings {
+		fs.Func(s.flag, s.usage+" ($"+s.env+")", func(v string) error {
+			flags[s.flag] = v
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *file != "" {
+		if err := cfg.readFile(*file); err != nil {
+			return cfg, err
+		}
+	}
+	for _, s := range settings {
+		if v := getenv(s.env); v != "" {
+			if err := set(s.value, v); err != nil {
+				return cfg, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+	for _, s := range settings {
+		if v, ok := flags[s.flag]; ok {
+			if err := set(s.value, v); err != nil {
+				return cfg, fmt.Errorf("-%s: %w", s.flag, err)
+			}
+		}
+	}
+	return cfg, cfg.Validate()
+}
+
+func (c *Config) readFile(name string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// set parses v into the field value points to
+func set(value any, v string) error {
+	switch p := value.(type) {
+	case *string:
+		*p = v
+	case *int:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", v)
+		}
+		*p = n
+	case *int64:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", v)
+		}
+		*p = n
+	case *bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", v)
+		}
+		*p = b
+	case *time.Duration:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 5m", v)
+		}
+		*p = d
+	case *[]string:
+		*p = nil
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*p = append(*p, item)
+			}
+		}
+	case *slog.Level:
+		level, err := logging.ParseLevel(v)
+		if err != nil {
+			return fmt.Errorf("%q is not debug, info, warn or error", v)
+		}
+		*p = level
+	default:
+		panic(fmt.Sprintf("config: unsupported setting type %T", value))
+	}
+	return nil
+}
+
+// Validate reports every missing or invalid setting at once
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Port > 0 && c.Port < 65536, "PORT %d is not a valid port", c.Port)
+	check(c.HTTP.ReadTimeout > 0, "HTTP_READ_TIMEOUT must be positive")
+	check(c.HTTP.WriteTimeout > 0, "HTTP_WRITE_TIMEOUT must be positive")
+	check(c.HTTP.IdleTimeout > 0, "HTTP_IDLE_TIMEOUT must be positive")
+	check(c.HTTP.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive")
+	check(len(c.CORS.AllowedOrigins) > 0, "CORS_ALLOWED_ORIGINS is required")
+
+	check(c.Database.Host != "", "DB_HOST is required")
+	check(c.Database.Port > 0 && c.Database.Port < 65536, "DB_PORT %d is not a valid port", c.Database.Port)
+	check(c.Database.Name != "", "DB_DATABASE is required")
+	check(c.Database.User != "", "DB_USERNAME is required")
+	check(c.Database.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "DB_MAX_IDLE_CONNS must not be negative")
+	check(c.Database.ConnMaxLifetime >= 0, "DB_CONN_MAX_LIFETIME must not be negative")
+	check(c.Database.ConnMaxIdleTime >= 0, "DB_CONN_MAX_IDLE_TIME must not be negative")
+	check(c.Database.StatementTimeout >= 0, "DB_STATEMENT_TIMEOUT must not be negative")
+	switch c.Database.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		check(false, "DB_SSLMODE %q is not disable, allow, prefer, require, verify-ca or verify-full", c.Database.SSLMode)
+	}
+
+	check(c.Auth.JWTSecret != "", "JWT_SECRET is required")
+	check(c.Auth.TokenLifetime > 0, "JWT_LIFETIME must be positive")
+
+	switch c.Blob.Backend {
+	case "local":
+		check(c.Blob.LocalDir != "", "BLOB_LOCAL_DIR is required with the local blob backend")
+	case "s3":
+		check(c.Blob.S3.Endpoint != "", "S3_ENDPOINT is required with the s3 blob backend")
+		check(c.Blob.S3.Bucket != "", "S3_BUCKET is required with the s3 blob backend")
+	default:
+		check(false, "BLOB_BACKEND %q is not local or s3", c.Blob.Backend)
+	}
+	check(c.LiveBroker == "memory" || c.LiveBroker == "postgres", "LIVE_BROKER %q is not memory or postgres", c.LiveBroker)
+	check(c.AttachmentMaxBytes > 0, "ATTACHMENT_MAX_BYTES must be positive")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
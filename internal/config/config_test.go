@@ -0,0 +1,113 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// env returns a getenv reading from vars
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+// required holds the settings without a default
+var required = map[string]string{
+	"DB_DATABASE": "pwp",
+	"DB_USERNAME": "pwp",
+	"JWT_SECRET":  "secret",
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(nil, env(required))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 8080 || cfg.HTTP.ShutdownTimeout != 5*time.Second || cfg.Auth.TokenLifetime != 72*time.Hour {
+		t.Errorf("defaults = %+v", cfg)
+	}
+	if cfg.Database.Name != "pwp" || cfg.Auth.JWTSecret != "secret" {
+		t.Errorf("environment not applied: %+v", cfg)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(file, []byte(`
+port: 9000
+log_level: debug
+http:
+  write_timeout: 1m
+cors:
+  allowed_origins: [https://pwp.example.com]
+database:
+  host: db.internal
+  max_open_conns: 10
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vars := map[string]string{"CONFIG_FILE": file, "PORT": "9100", "DB_MAX_OPEN_CONNS": "20"}
+	for k, v := range required {
+		vars[k] = v
+	}
+	cfg, err := Load([]string{"-port", "9200"}, env(vars))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Port != 9200 {
+		t.Errorf("port = %d, want the flag to win", cfg.Port)
+	}
+	if cfg.Database.MaxOpenConns != 20 {
+		t.Errorf("max open conns = %d, want the environment to win over the file", cfg.Database.MaxOpenConns)
+	}
+	if cfg.Database.Host != "db.internal" || cfg.HTTP.WriteTimeout != time.Minute || cfg.LogLevel != slog.LevelDebug {
+		t.Errorf("file not applied: %+v", cfg)
+	}
+	if cfg.HTTP.ReadTimeout != 10*time.Second {
+		t.Errorf("read timeout = %s, want the default kept", cfg.HTTP.ReadTimeout)
+	}
+	if len(cfg.CORS.AllowedOrigins) != 1 || cfg.CORS.AllowedOrigins[0] != "https://pwp.example.com" {
+		t.Errorf("origins = %v", cfg.CORS.AllowedOrigins)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	unknown := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(unknown, []byte("databse:\n  host: x\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		vars map[string]string
+		want []string
+	}{
+		{"missing", nil, nil, []string{"DB_DATABASE is required", "DB_USERNAME is required", "JWT_SECRET is required"}},
+		{"bad number", nil, map[string]string{"DB_PORT": "five"}, []string{"DB_PORT", `"five" is not a number`}},
+		{"bad duration", []string{"-jwt-lifetime", "3"}, required, []string{"-jwt-lifetime", "not a duration"}},
+		{"bad level", nil, map[string]string{"LOG_LEVEL": "loud"}, []string{"LOG_LEVEL"}},
+		{"bad backend", []string{"-blob-backend", "ftp"}, required, []string{`BLOB_BACKEND "ftp"`}},
+		{"s3 bucket", []string{"-blob-backend", "s3", "-s3-endpoint", "minio:9000"}, required, []string{"S3_BUCKET is required"}},
+		{"bad sslmode", []string{"-db-sslmode", "on"}, required, []string{`DB_SSLMODE "on"`}},
+		{"unknown key", []string{"-config", unknown}, required, []string{"databse"}},
+	}
+	for _, tt := range tests {
+		_, err := Load(tt.args, env(tt.vars))
+		if err == nil {
+			t.Errorf("%s: no error", tt.name)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error %q does not mention %q", tt.name, err, want)
+			}
+		}
+	}
+}
@@ -3,111 +3,117 @@ package database
 import (
 	"context"
 	"database/sql"
-	"fmt"
-	"log"
+	"log/slog"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
-	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
-	_ "github.com/joho/godotenv/autoload"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.opentelemetry.io/otel/trace"
+
+	"pwp-remastered/internal/config"
 )
 
 // Service represents a service that interacts with a database.
 type Service interface {
-	// Health returns a map of health status information.
-	// The keys and values in the map are service-specific.
-	Health() map[string]string
+	// Ping checks that the database accepts connections
+	Ping(ctx context.Context) error
+
+	// SchemaVersion returns the version of the last applied migration and
+	// whether it failed halfway, 0 when none was applied
+	SchemaVersion(ctx context.Context) (version uint, dirty bool, err error)
+
+	// Stats returns the connection pool statistics
+	Stats() sql.DBStats
 
 	// Close terminates the database connection.
 	// It returns an error if the connection cannot be closed.
 	Close() error
 
 	// Database operations
-	QueryRow(query string, args ...interface{}) *sql.Row
-	Query(query string, args ...interface{}) (*sql.Rows, error)
-	Exec(query string, args ...interface{}) (sql.Result, error)
+	Querier
+
+	// Transact runs fn in a transaction. It commits when fn returns nil and
+	// rolls back when fn returns an error or panics.
+	Transact(ctx context.Context, fn func(tx Querier) error) error
+
+	// Listen runs LISTEN channel on a dedicated connection and calls fn with
+	// the payload of every notification until ctx is done or the connection fails.
+	Listen(ctx context.Context, channel string, fn func(payload string)) error
 }
 
-type service struct {
-	db *sql.DB
+// Querier runs statements, either directly on the pool or inside a
+// transaction. Statements are canceled with their context.
+type Querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 }
 
-var (
-	database   = os.Getenv("DB_DATABASE")
-	password   = os.Getenv("DB_PASSWORD")
-	username   = os.Getenv("DB_USERNAME")
-	port       = os.Getenv("DB_PORT")
-	host       = os.Getenv("DB_HOST")
-	schema     = os.Getenv("DB_SCHEMA")
-	dbInstance *service
-)
+type service struct {
+	db   *sql.DB
+	name string
+}
 
-func New() Service {
-	// Reuse Connection
-	if dbInstance != nil {
-		return dbInstance
-	}
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&search_path=%s", username, password, host, port, database, schema)
-	db, err := sql.Open("pgx", connStr)
+// New opens a connection pool to the database of cfg
+func New(cfg config.Database) Service {
+	db, err := sql.Open("pgx", dsn(cfg))
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("open database", "error", err)
+		os.Exit(1)
 	}
-	dbInstance = &service{
-		db: db,
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	return &service{
+		db:   db,
+		name: cfg.Name,
 	}
-	return dbInstance
 }
 
-// Health checks the health of the database connection by pinging the database.
-// It returns a map with keys indicating various health statistics.
-func (s *service) Health() map[string]string {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-	defer cancel()
-
-	stats := make(map[string]string)
-
-	// Ping the database
-	err := s.db.PingContext(ctx)
-	if err != nil {
-		stats["status"] = "down"
-		stats["error"] = fmt.Sprintf("db down: %v", err)
-		log.Fatalf("db down: %v", err) // Log the error and terminate the program
-		return stats
+// dsn builds the connection URL of cfg. Parameters pgx does not know, such as
+// statement_timeout, are set on every connection as session parameters.
+func dsn(cfg config.Database) string {
+	params := url.Values{
+		"sslmode":           {cfg.SSLMode},
+		"search_path":       {cfg.Schema},
+		"application_name":  {cfg.ApplicationName},
+		"statement_timeout": {strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)},
 	}
-
-	// Database is up, add more statistics
-	stats["status"] = "up"
-	stats["message"] = "It's healthy"
-
-	// Get database stats (like open connections, in use, idle, etc.)
-	dbStats := s.db.Stats()
-	stats["open_connections"] = strconv.Itoa(dbStats.OpenConnections)
-	stats["in_use"] = strconv.Itoa(dbStats.InUse)
-	stats["idle"] = strconv.Itoa(dbStats.Idle)
-	stats["wait_count"] = strconv.FormatInt(dbStats.WaitCount, 10)
-	stats["wait_duration"] = dbStats.WaitDuration.String()
-	stats["max_idle_closed"] = strconv.FormatInt(dbStats.MaxIdleClosed, 10)
-	stats["max_lifetime_closed"] = strconv.FormatInt(dbStats.MaxLifetimeClosed, 10)
-
-	// Evaluate stats to provide a health message
-	if dbStats.OpenConnections > 40 { // Assuming 50 is the max for this example
-		stats["message"] = "The database is experiencing heavy load."
+	if cfg.SSLRootCert != "" {
+		params.Set("sslrootcert", cfg.SSLRootCert)
 	}
-
-	if dbStats.WaitCount > 1000 {
-		stats["message"] = "The database has a high number of wait events, indicating potential bottlenecks."
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Path:     cfg.Name,
+		RawQuery: params.Encode(),
 	}
+	return u.String()
+}
 
-	if dbStats.MaxIdleClosed > int64(dbStats.OpenConnections)/2 {
-		stats["message"] = "Many idle connections are being closed, consider revising the connection pool settings."
-	}
+// Ping checks that a connection to the database can be used
+func (s *service) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
 
-	if dbStats.MaxLifetimeClosed > int64(dbStats.OpenConnections)/2 {
-		stats["message"] = "Many connections are being closed due to max lifetime, consider increasing max lifetime or revising the connection usage pattern."
+// SchemaVersion reads the version golang-migrate recorded in schema_migrations
+func (s *service) SchemaVersion(ctx context.Context) (uint, bool, error) {
+	var version uint
+	var dirty bool
+	err := s.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
 	}
+	return version, dirty, err
+}
 
-	return stats
+func (s *service) Stats() sql.DBStats {
+	return s.db.Stats()
 }
 
 // Close closes the database connection.
@@ -115,18 +121,62 @@ func (s *service) Health() map[string]string {
 // If the connection is successfully closed, it returns nil.
 // If an error occurs while closing the connection, it returns the error.
 func (s *service) Close() error {
-	log.Printf("Disconnected from database: %s", database)
+	slog.Info("disconnected from database", "database", s.name)
 	return s.db.Close()
 }
 
-func (s *service) QueryRow(query string, args ...interface{}) *sql.Row {
-	return s.db.QueryRow(query, args...)
+func (s *service) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tracedQuerier{s.db}.QueryRowContext(ctx, query, args...)
+}
+
+func (s *service) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return tracedQuerier{s.db}.QueryContext(ctx, query, args...)
 }
 
-func (s *service) Query(query string, args ...interface{}) (*sql.Rows, error) {
-	return s.db.Query(query, args...)
+func (s *service) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tracedQuerier{s.db}.ExecContext(ctx, query, args...)
 }
 
-func (s *service) Exec(query string, args ...interface{}) (sql.Result, error) {
-	return s.db.Exec(query, args...)
+func (s *service) Transact(ctx context.Context, fn func(tx Querier) error) (err error) {
+	ctx, span := tracer.Start(ctx, "transaction", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+	return fn(tracedQuerier{tx})
+}
+
+func (s *service) Listen(ctx context.Context, channel string, fn func(payload string)) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		pg := driverConn.(*stdlib.Conn).Conn()
+		if _, err := pg.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+		for {
+			notification, err := pg.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			fn(notification.Payload)
+		}
+	})
 }
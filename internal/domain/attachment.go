@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"time"
+)
+
+type Attachment struct {
+	ID          int       `json:"id"`
+	EventID     int       `json:"event_id"`
+	UserID      int       `json:"user_id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	StorageKey  string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type AttachmentList struct {
+	Attachments []Attachment `json:"attachments"`
+}
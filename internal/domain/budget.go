@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"time"
+)
+
+// Budget periods, windows follow the calendar in the tenant's time zone
+const (
+	BudgetMonth   = "month"
+	BudgetQuarter = "quarter"
+	BudgetYear    = "year"
+)
+
+// BudgetThresholds are the percentages of a limit that raise an alert once per period
+var BudgetThresholds = []int{80, 100}
+
+// Budget limits the road costs of exactly one of a user, a team or an event type
+type Budget struct {
+	ID        int       `json:"id"`
+	TenantID  int       `json:"tenant_id,omitempty"`
+	UserID    *int      `json:"user_id"`
+	TeamID    *int      `json:"team_id"`
+	TypeID    *int      `json:"type_id"`
+	Period    string    `json:"period"`
+	Limit     float64   `json:"limit"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+type BudgetList struct {
+	Budgets []Budget `json:"budgets"`
+}
+
+// BudgetStatus is the spend of a budget in the period window [PeriodStart, PeriodEnd).
+// Threshold is the highest threshold reached, 0 when none.
+type BudgetStatus struct {
+	Budget      Budget    `json:"budget"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Spent       float64   `json:"spent"`
+	EventCount  int       `json:"event_count"`
+	Ratio       float64   `json:"ratio"`
+	Threshold   int       `json:"threshold"`
+}
+
+type BudgetStatusList struct {
+	Budgets []BudgetStatus `json:"budgets"`
+}
+
+// BudgetAlert is the payload of a budget.threshold_crossed notification
+type BudgetAlert struct {
+	Threshold int `json:"threshold"`
+	BudgetStatus
+}
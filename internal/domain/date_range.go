@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"time"
+)
+
+// DateRangeMode selects which events a DateRange matches
+type DateRangeMode string
+
+const (
+	// RangeOverlap matches events that intersect the range, the default
+	RangeOverlap DateRangeMode = "overlap"
+	// RangeContained matches events that start and end inside the range
+	RangeContained DateRangeMode = "contained"
+	// RangeStartsWithin matches events that start inside the range
+	RangeStartsWithin DateRangeMode = "starts_within"
+)
+
+// DateRange is the half-open interval [From, To). A zero bound is open ended.
+type DateRange struct {
+	From time.Time
+	To   time.Time
+	Mode DateRangeMode
+}
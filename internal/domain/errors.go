@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"errors"
+)
+
+// ErrorKind classifies an Error so the transport layer can pick a status code
+type ErrorKind int
+
+const (
+	KindInternal ErrorKind = iota
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindTooLarge
+	KindUnsupportedMediaType
+)
+
+// Error is a failure that is safe to show to API clients. Code is stable and
+// machine readable, Message is the English default that can be localized by code.
+// Declare them as package variables and wrap them with fmt.Errorf("%w: ...") to
+// add context, errors.Is keeps working on the variable. Only Message and Details
+// are shown to clients, the wrapped context is logged.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	// Details are further facts about this occurrence the client may act on,
+	// sent as extension members of the problem body
+	Details map[string]any
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is matches errors with the same code, so a copy made by WithDetails is still
+// the declared variable for errors.Is
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithDetails returns a copy of e carrying details
+func (e *Error) WithDetails(details map[string]any) *Error {
+	detailed := *e
+	detailed.Details = details
+	return &detailed
+}
+
+func NewValidationError(code, message string) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message}
+}
+
+func NewUnauthorizedError(code, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+func NewForbiddenError(code, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+func NewNotFoundError(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func NewConflictError(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+func NewTooLargeError(code, message string) *Error {
+	return &Error{Kind: KindTooLarge, Code: code, Message: message}
+}
+
+func NewUnsupportedMediaTypeError(code, message string) *Error {
+	return &Error{Kind: KindUnsupportedMediaType, Code: code, Message: message}
+}
+
+// AsError returns the domain error in err's chain, if any
+func AsError(err error) (*Error, bool) {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr, true
+	}
+	return nil, false
+}
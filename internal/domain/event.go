@@ -9,26 +9,65 @@ type Event struct {
 	TypeID int `json:"type_id"`
 	UserID int `json:"user_id"`
 	// Username    string    `json:"username"`
-	Name        string     `json:"name"`
-	Title       string     `json:"title"`
-	Description *string    `json:"description"`
-	StartDate   time.Time  `json:"start_date"`
-	EndDate     time.Time  `json:"end_date"`
-	RoadPrice   float64    `json:"road_price"`
-	User        *EventUser `json:"user,omitempty"`
-	Type        *EventType `json:"type,omitempty"`
+	Name        string    `json:"name"`
+	Title       string    `json:"title"`
+	Description *string   `json:"description"`
+	StartDate   time.Time `json:"start_date"`
+	EndDate     time.Time `json:"end_date"`
+	RoadPrice   float64   `json:"road_price"`
+	// Optional trip geometry, when present RoadPrice is derived from the mileage rates
+	OriginLat      *float64 `json:"origin_lat,omitempty"`
+	OriginLng      *float64 `json:"origin_lng,omitempty"`
+	DestinationLat *float64 `json:"destination_lat,omitempty"`
+	DestinationLng *float64 `json:"destination_lng,omitempty"`
+	DistanceKm     *float64 `json:"distance_km,omitempty"`
+	// Saved locations, their coordinates are used when none are given
+	OriginLocationID      *int      `json:"origin_location_id,omitempty"`
+	DestinationLocationID *int      `json:"destination_location_id,omitempty"`
+	OriginLocation        *Location `json:"origin_location,omitempty"`
+	DestinationLocation   *Location `json:"destination_location,omitempty"`
+	// Vehicle used for the trip, odometer readings are checked against its history
+	VehicleID     *int `json:"vehicle_id,omitempty"`
+	OdometerStart *int `json:"odometer_start,omitempty"`
+	OdometerEnd   *int `json:"odometer_end,omitempty"`
+	// Approval state, set by the server
+	Status     string     `json:"status"`
+	ApprovedBy *int       `json:"approved_by"`
+	ApprovedAt *time.Time `json:"approved_at"`
+
+	User *EventUser `json:"user,omitempty"`
+	Type *EventType `json:"type,omitempty"`
+}
+
+// Event approval states, editing an approved event sends it back to pending
+const (
+	EventPending  = "pending"
+	EventApproved = "approved"
+)
+
+// EventTotals sums the events matching a filter
+type EventTotals struct {
+	EventCount int     `json:"event_count"`
+	RoadPrice  float64 `json:"road_price"`
 }
 
 type EventList struct {
 	Events []Event `json:"events"`
+	PageInfo
 }
 
 type EventType struct {
-	ID         int     `json:"id"`
-	Type       string  `json:"type"`
-	Language   string  `json:"language"`
-	Color      *string `json:"color"`
-	IsPricable bool    `json:"is_pricable"`
+	ID int `json:"id"`
+	// TenantID is the tenant owning the type, nil for the types shared by every tenant
+	TenantID *int `json:"tenant_id"`
+	// Type is the default label, used when no translation matches the caller's locale
+	Type         string            `json:"type"`
+	Label        string            `json:"label,omitempty"`
+	Translations map[string]string `json:"translations,omitempty"`
+	Color        *string           `json:"color"`
+	IsPricable   bool              `json:"is_pricable"`
+	IsArchived   bool              `json:"is_archived"`
+	SortOrder    int               `json:"sort_order"`
 }
 
 type EventTypeList struct {
@@ -40,4 +79,34 @@ type EventUser struct {
 	Username  string `json:"username"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
+	TenantID  int    `json:"tenant_id"`
+}
+
+// MileageRate is a per-km price that applies to a tenant's events from EffectiveFrom on.
+// A nil TypeID makes it the tenant default for every pricable event type.
+type MileageRate struct {
+	ID            int       `json:"id"`
+	TenantID      int       `json:"tenant_id"`
+	TypeID        *int      `json:"type_id"`
+	RatePerKm     float64   `json:"rate_per_km"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type MileageRateList struct {
+	Rates []MileageRate `json:"rates"`
+}
+
+// EventSearchResult is an event matched by full-text search. The highlights
+// wrap matched words in <mark> tags, the rest of the text is not escaped.
+type EventSearchResult struct {
+	Event
+	Rank                 float32 `json:"rank"`
+	TitleHighlight       string  `json:"title_highlight"`
+	DescriptionHighlight string  `json:"description_highlight,omitempty"`
+}
+
+type EventSearchList struct {
+	Results []EventSearchResult `json:"results"`
+	PageInfo
 }
@@ -0,0 +1,44 @@
+package domain
+
+// Event fields an import can fill. A mapping names the CSV column of each
+// field, fields left out of it are read from the column with their own name.
+const (
+	ImportUsername    = "username"
+	ImportType        = "type"
+	ImportName        = "name"
+	ImportTitle       = "title"
+	ImportDescription = "description"
+	ImportStartDate   = "start_date"
+	ImportEndDate     = "end_date"
+	ImportRoadPrice   = "road_price"
+)
+
+// ImportFields lists the fields in the order they are validated
+var ImportFields = []string{
+	ImportUsername, ImportType, ImportName, ImportTitle, ImportDescription,
+	ImportStartDate, ImportEndDate, ImportRoadPrice,
+}
+
+// EventImportMapping maps import fields to CSV column headers
+type EventImportMapping map[string]string
+
+// EventImportRowError is a problem with one CSV row. Row is the line number in
+// the file, the header being line 1. Err is the cause, the transport layer
+// fills in Code and Title from it.
+type EventImportRowError struct {
+	Row    int    `json:"row"`
+	Column string `json:"column,omitempty"`
+	Code   string `json:"code"`
+	Title  string `json:"title"`
+	Err    error  `json:"-"`
+}
+
+// EventImportResult reports an import. In a dry run, or when a row is invalid,
+// nothing is saved and Events previews the rows that were valid.
+type EventImportResult struct {
+	DryRun   bool                  `json:"dry_run"`
+	Rows     int                   `json:"rows"`
+	Imported int                   `json:"imported"`
+	Errors   []EventImportRowError `json:"errors"`
+	Events   []Event               `json:"events"`
+}
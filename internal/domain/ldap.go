@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"time"
+)
+
+// LDAPConfig makes a tenant's logins bind against an LDAP or Active Directory
+// server. Users are looked up under BaseDN with UserFilter, their attributes
+// are copied to the local account at every login. Groups are looked up under
+// GroupBaseDN with GroupFilter; membership of AdminGroup and AccountantGroup
+// sets the matching flags, an empty group leaves the flag to the admins.
+// BindPassword is write-only.
+type LDAPConfig struct {
+	TenantID           int       `json:"tenant_id"`
+	Enabled            bool      `json:"enabled"`
+	URL                string    `json:"url"`
+	StartTLS           bool      `json:"start_tls"`
+	BindDN             string    `json:"bind_dn"`
+	BindPassword       string    `json:"bind_password,omitempty"`
+	BaseDN             string    `json:"base_dn"`
+	UserFilter         string    `json:"user_filter"`
+	EmailAttribute     string    `json:"email_attribute"`
+	FirstNameAttribute string    `json:"first_name_attribute"`
+	LastNameAttribute  string    `json:"last_name_attribute"`
+	GroupBaseDN        string    `json:"group_base_dn"`
+	GroupFilter        string    `json:"group_filter"`
+	AdminGroup         string    `json:"admin_group"`
+	AccountantGroup    string    `json:"accountant_group"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
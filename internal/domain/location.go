@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"time"
+)
+
+// Location is a saved site such as a customer office, shared by a tenant
+type Location struct {
+	ID        int       `json:"id"`
+	TenantID  int       `json:"tenant_id,omitempty"`
+	Name      string    `json:"name"`
+	Address   *string   `json:"address"`
+	Latitude  *float64  `json:"latitude"`
+	Longitude *float64  `json:"longitude"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+type LocationList struct {
+	Locations []Location `json:"locations"`
+}
+
+// LocationRoute is a configured road distance between two saved locations
+type LocationRoute struct {
+	FromLocationID int     `json:"from_location_id"`
+	ToLocationID   int     `json:"to_location_id"`
+	DistanceKm     float64 `json:"distance_km"`
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"time"
+)
+
+// OIDCConfig signs a tenant's users in with an OpenID Connect provider using
+// the authorization code flow with PKCE. The claims are copied to the user at
+// every login; AdminRole and AccountantRole are matched against the values of
+// RolesClaim, an empty role leaves the flag to the admins. Users land on
+// RedirectURL with the PWP token in the fragment, without one the callback
+// answers with the token as JSON. ClientSecret is write-only.
+type OIDCConfig struct {
+	TenantID       int       `json:"tenant_id"`
+	Enabled        bool      `json:"enabled"`
+	Issuer         string    `json:"issuer"`
+	ClientID       string    `json:"client_id"`
+	ClientSecret   string    `json:"client_secret,omitempty"`
+	Scopes         string    `json:"scopes"`
+	UsernameClaim  string    `json:"username_claim"`
+	EmailClaim     string    `json:"email_claim"`
+	FirstNameClaim string    `json:"first_name_claim"`
+	LastNameClaim  string    `json:"last_name_claim"`
+	RolesClaim     string    `json:"roles_claim"`
+	AdminRole      string    `json:"admin_role"`
+	AccountantRole string    `json:"accountant_role"`
+	RedirectURL    string    `json:"redirect_url"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// OIDCLogin is an authorization request waiting for the provider's callback
+type OIDCLogin struct {
+	State        string
+	TenantID     int
+	CodeVerifier string
+	Nonce        string
+	RedirectURI  string
+}
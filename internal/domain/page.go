@@ -0,0 +1,59 @@
+package domain
+
+// PageRequest is the cursor pagination input shared by list endpoints.
+// Sort is a field name, prefixed with "-" for descending order.
+type PageRequest struct {
+	Cursor    string
+	Limit     int
+	Sort      string
+	WithTotal bool
+}
+
+// PageInfo is returned next to a page of results. NextCursor is empty on the
+// last page, Total is only set when it was requested.
+type PageInfo struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int   `json:"total,omitempty"`
+}
+
+// EventFilter narrows event listings, zero values do not filter
+type EventFilter struct {
+	PageRequest
+	TenantID int
+	Range    DateRange
+	UserIDs  []int
+	// ManagerID keeps the events of the manager's reports at any depth
+	ManagerID int
+	// VisibleTo keeps the user's own events and those of their reports at any depth
+	VisibleTo int
+	// TeamIDs keeps the events of members of any of the teams
+	TeamIDs  []int
+	TypeIDs  []int
+	MinPrice *float64
+	MaxPrice *float64
+	Query    string
+}
+
+// UserFilter narrows user listings, zero values do not filter
+type UserFilter struct {
+	PageRequest
+	TenantID int
+	IDs      []int
+	Statuses []int
+	// ManagerID keeps the manager's reports at any depth
+	ManagerID int
+	TeamIDs   []int
+	Query     string
+	// Username and Email match exactly, ignoring case
+	Username string
+	Email    string
+	// Offset skips rows for clients that page by index, it cannot be combined with Cursor
+	Offset int
+}
+
+// WebhookDeliveryFilter narrows the delivery log, zero values do not filter
+type WebhookDeliveryFilter struct {
+	PageRequest
+	SubscriptionID int
+	Statuses       []string
+}
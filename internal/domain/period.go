@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"time"
+)
+
+// Accounting period states, events overlapping a closed period are locked
+const (
+	PeriodOpen   = "open"
+	PeriodClosed = "closed"
+)
+
+// Period history actions
+const (
+	PeriodActionCreated  = "created"
+	PeriodActionClosed   = "closed"
+	PeriodActionReopened = "reopened"
+)
+
+// AccountingPeriod is an inclusive range of days in the tenant's time zone.
+// Dates are formatted as YYYY-MM-DD.
+type AccountingPeriod struct {
+	ID        int                 `json:"id"`
+	TenantID  int                 `json:"tenant_id,omitempty"`
+	StartDate string              `json:"start_date"`
+	EndDate   string              `json:"end_date"`
+	Status    string              `json:"status"`
+	ClosedBy  *int                `json:"closed_by"`
+	ClosedAt  *time.Time          `json:"closed_at"`
+	CreatedAt time.Time           `json:"created_at,omitzero"`
+	History   []PeriodHistoryItem `json:"history,omitempty"`
+}
+
+type AccountingPeriodList struct {
+	Periods []AccountingPeriod `json:"periods"`
+}
+
+// PeriodHistoryItem records who created, closed or reopened a period and when
+type PeriodHistoryItem struct {
+	Action   string    `json:"action"`
+	UserID   *int      `json:"user_id"`
+	Username *string   `json:"username"`
+	At       time.Time `json:"at"`
+}
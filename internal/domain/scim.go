@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"time"
+)
+
+// ScimToken authenticates a provisioning client of a tenant on the SCIM
+// endpoints. Token is only returned when the token is created.
+type ScimToken struct {
+	ID         int        `json:"id"`
+	TenantID   int        `json:"tenant_id,omitempty"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token,omitempty"`
+	CreatedAt  time.Time  `json:"created_at,omitzero"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type ScimTokenList struct {
+	Tokens []ScimToken `json:"tokens"`
+}
@@ -0,0 +1,13 @@
+package domain
+
+import (
+	"time"
+)
+
+// TenantSettings holds tenant wide defaults. TimeZone is an IANA name used for
+// users without a time zone of their own.
+type TenantSettings struct {
+	TenantID  int       `json:"tenant_id"`
+	TimeZone  string    `json:"time_zone"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"time"
+)
+
+// Team is a team or department of a tenant
+type Team struct {
+	ID          int          `json:"id"`
+	TenantID    int          `json:"tenant_id,omitempty"`
+	Name        string       `json:"name"`
+	Description *string      `json:"description"`
+	Members     []TeamMember `json:"members,omitempty"`
+	CreatedAt   time.Time    `json:"created_at,omitzero"`
+	UpdatedAt   time.Time    `json:"updated_at,omitzero"`
+}
+
+type TeamList struct {
+	Teams []Team `json:"teams"`
+}
+
+type TeamMember struct {
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	ManagerID *int      `json:"manager_id"`
+	AddedAt   time.Time `json:"added_at,omitzero"`
+}
@@ -1,5 +1,7 @@
 package domain
 
+import "log/slog"
+
 type User struct {
 	ID             int    `json:"id"`
 	Username       string `json:"username"`
@@ -8,7 +10,28 @@ type User struct {
 	FirstName      string `json:"first_name"`
 	LastName       string `json:"last_name"`
 	IsAdmin        bool   `json:"is_admin"`
-	IsUser         bool   `json:"is_user"`
-	TenantID       int    `json:"tenant_id"`
-	Status         int    `json:"status"`
+	// IsAccountant may close and reopen accounting periods
+	IsAccountant bool `json:"is_accountant"`
+	IsUser       bool `json:"is_user"`
+	TenantID     int  `json:"tenant_id"`
+	Status       int  `json:"status"`
+	// TimeZone is an IANA name, nil falls back to the tenant setting
+	TimeZone *string `json:"time_zone"`
+	// ManagerID is the user's direct manager in the same tenant
+	ManagerID *int `json:"manager_id"`
+}
+
+// LogValue identifies the user in logs without their password hash or
+// personal details
+func (u User) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", u.ID),
+		slog.String("username", u.Username),
+		slog.Int("tenant_id", u.TenantID),
+	)
+}
+
+type UserList struct {
+	Users []User `json:"users"`
+	PageInfo
 }
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"time"
+)
+
+// Vehicle is a company or personal car whose trips are reimbursed
+type Vehicle struct {
+	ID       int    `json:"id"`
+	TenantID int    `json:"tenant_id"`
+	Plate    string `json:"plate"`
+	// OwnerUserID is nil for pool cars
+	OwnerUserID *int   `json:"owner_user_id"`
+	FuelType    string `json:"fuel_type"`
+	// RatePerKm overrides the tenant mileage rates when set
+	RatePerKm         *float64  `json:"rate_per_km"`
+	InitialOdometerKm int       `json:"initial_odometer_km"`
+	LastOdometerKm    int       `json:"last_odometer_km"`
+	IsActive          bool      `json:"is_active"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type VehicleList struct {
+	Vehicles []Vehicle `json:"vehicles"`
+}
+
+type OdometerReading struct {
+	ID         int       `json:"id"`
+	VehicleID  int       `json:"vehicle_id"`
+	EventID    *int      `json:"event_id"`
+	UserID     int       `json:"user_id"`
+	ReadingKm  int       `json:"reading_km"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// VehicleRoadPrice is the road price total of one vehicle over a date range.
+// VehicleID is nil for events without a vehicle.
+type VehicleRoadPrice struct {
+	VehicleID  *int    `json:"vehicle_id"`
+	Plate      *string `json:"plate"`
+	EventCount int     `json:"event_count"`
+	DistanceKm float64 `json:"distance_km"`
+	RoadPrice  float64 `json:"road_price"`
+}
+
+type VehicleRoadPriceReport struct {
+	Vehicles []VehicleRoadPrice `json:"vehicles"`
+}
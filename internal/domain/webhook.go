@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook event types a subscription can listen to
+const (
+	WebhookEventCreated    = "event.created"
+	WebhookEventUpdated    = "event.updated"
+	WebhookEventApproved   = "event.approved"
+	WebhookEventDeleted    = "event.deleted"
+	WebhookUserDeactivated = "user.deactivated"
+	WebhookBudgetThreshold = "budget.threshold_crossed"
+)
+
+var WebhookEventTypes = []string{
+	WebhookEventCreated,
+	WebhookEventUpdated,
+	WebhookEventApproved,
+	WebhookEventDeleted,
+	WebhookUserDeactivated,
+	WebhookBudgetThreshold,
+}
+
+// Delivery states, a pending delivery is retried until it succeeds or runs out of attempts
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookSubscription sends the tenant's changes of the listed types to URL.
+// Secret signs the payloads, it is only returned when the subscription is created.
+type WebhookSubscription struct {
+	ID         int       `json:"id"`
+	TenantID   int       `json:"tenant_id,omitempty"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	IsActive   bool      `json:"is_active"`
+	CreatedAt  time.Time `json:"created_at,omitzero"`
+	UpdatedAt  time.Time `json:"updated_at,omitzero"`
+}
+
+type WebhookSubscriptionList struct {
+	Subscriptions []WebhookSubscription `json:"subscriptions"`
+}
+
+// WebhookPayload is the signed JSON body posted to subscribers. ID is stable
+// across retries and redeliveries so receivers can drop duplicates.
+type WebhookPayload struct {
+	ID         int             `json:"id"`
+	Type       string          `json:"type"`
+	TenantID   int             `json:"tenant_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// WebhookDelivery is one payload sent to one subscription
+type WebhookDelivery struct {
+	ID             int              `json:"id"`
+	SubscriptionID int              `json:"subscription_id"`
+	Status         string           `json:"status"`
+	Attempts       int              `json:"attempts"`
+	NextAttemptAt  *time.Time       `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time       `json:"last_attempt_at"`
+	CreatedAt      time.Time        `json:"created_at"`
+	Payload        WebhookPayload   `json:"payload"`
+	AttemptLog     []WebhookAttempt `json:"attempt_log,omitempty"`
+}
+
+type WebhookDeliveryList struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	PageInfo
+}
+
+// WebhookAttempt records one HTTP request of a delivery
+type WebhookAttempt struct {
+	ID             int       `json:"id"`
+	AttemptedAt    time.Time `json:"attempted_at"`
+	ResponseStatus *int      `json:"response_status"`
+	Error          *string   `json:"error"`
+	DurationMs     int       `json:"duration_ms"`
+}
+
+// WebhookJob is a due delivery with what is needed to send it
+type WebhookJob struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+}
@@ -0,0 +1,143 @@
+// Package health reports whether the API can serve requests. Readiness runs
+// a set of pluggable checkers, one per dependency, concurrently and with a
+// timeout each, so a slow dependency cannot hold up the probe.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pwp-remastered/internal/blob"
+	"pwp-remastered/internal/database"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// errTimeout is reported for checkers that did not finish in time
+var errTimeout = errors.New("check timed out")
+
+// Checker checks one dependency
+type Checker interface {
+	// Name identifies the dependency in the report
+	Name() string
+	// Check returns an error when the dependency cannot serve requests
+	Check(ctx context.Context) error
+}
+
+type checkerFunc struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (c checkerFunc) Name() string                    { return c.name }
+func (c checkerFunc) Check(ctx context.Context) error { return c.check(ctx) }
+
+// CheckerFunc returns a Checker named name that calls check
+func CheckerFunc(name string, check func(ctx context.Context) error) Checker {
+	return checkerFunc{name: name, check: check}
+}
+
+// Result is the outcome of one checker
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of every checker, up when all of them are
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks,omitempty"`
+}
+
+// Readiness runs the checkers of the dependencies
+type Readiness struct {
+	checkers []Checker
+	timeout  time.Duration
+}
+
+// NewReadiness creates a readiness check that gives each checker timeout
+func NewReadiness(timeout time.Duration, checkers ...Checker) *Readiness {
+	return &Readiness{checkers: checkers, timeout: timeout}
+}
+
+// Check runs every checker and reports them in the order they were given
+func (r *Readiness) Check(ctx context.Context) Report {
+	report := Report{Status: StatusUp, Checks: make([]Result, len(r.checkers))}
+	var wg sync.WaitGroup
+	for i, checker := range r.checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = r.run(ctx, checker)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func (r *Readiness) run(ctx context.Context, checker Checker) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	// A checker that ignores ctx is given up on, it finishes in the background
+	done := make(chan error, 1)
+	go func() { done <- checker.Check(ctx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = errTimeout
+	}
+	result := Result{
+		Name:      checker.Name(),
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Database checks that the database accepts connections
+func Database(db database.Service) Checker {
+	return CheckerFunc("database", db.Ping)
+}
+
+// Migrations checks that the database schema is at version latest, the
+// newest migration the binary was built with
+func Migrations(db database.Service, latest uint) Checker {
+	return CheckerFunc("migrations", func(ctx context.Context) error {
+		version, dirty, err := db.SchemaVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("migration %d failed halfway", version)
+		}
+		if version < latest {
+			return fmt.Errorf("schema is at version %d, %d is expected", version, latest)
+		}
+		return nil
+	})
+}
+
+// Blob checks that attachments can be stored
+func Blob(store blob.BlobStore) Checker {
+	return CheckerFunc("blob", store.Ping)
+}
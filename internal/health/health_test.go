@@ -0,0 +1,81 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"pwp-remastered/internal/database"
+	"testing"
+	"time"
+)
+
+func TestReadiness(t *testing.T) {
+	up := CheckerFunc("up", func(ctx context.Context) error { return nil })
+	down := CheckerFunc("down", func(ctx context.Context) error { return errors.New("refused") })
+
+	report := NewReadiness(time.Second, up).Check(context.Background())
+	if report.Status != StatusUp || len(report.Checks) != 1 || report.Checks[0].Status != StatusUp {
+		t.Errorf("report = %+v, want up", report)
+	}
+
+	report = NewReadiness(time.Second, up, down).Check(context.Background())
+	if report.Status != StatusDown {
+		t.Errorf("status = %s, want down when a checker fails", report.Status)
+	}
+	if report.Checks[0].Name != "up" || report.Checks[1].Name != "down" {
+		t.Errorf("checks = %+v, want them in the given order", report.Checks)
+	}
+	if report.Checks[1].Error != "refused" {
+		t.Errorf("error = %q", report.Checks[1].Error)
+	}
+}
+
+func TestReadinessTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	// stuck ignores its context, like a driver call without a deadline
+	stuck := CheckerFunc("stuck", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	start := time.Now()
+	report := NewReadiness(20*time.Millisecond, stuck).Check(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("check took %s, want the timeout", elapsed)
+	}
+	if report.Status != StatusDown || report.Checks[0].Error != errTimeout.Error() {
+		t.Errorf("report = %+v, want a timeout", report)
+	}
+}
+
+// fakeDB reports a fixed schema version
+type fakeDB struct {
+	database.Service
+	version uint
+	dirty   bool
+	err     error
+}
+
+func (db *fakeDB) SchemaVersion(ctx context.Context) (uint, bool, error) {
+	return db.version, db.dirty, db.err
+}
+
+func TestMigrations(t *testing.T) {
+	tests := []struct {
+		name string
+		db   *fakeDB
+		ok   bool
+	}{
+		{"latest", &fakeDB{version: 18}, true},
+		{"newer", &fakeDB{version: 19}, true},
+		{"behind", &fakeDB{version: 17}, false},
+		{"dirty", &fakeDB{version: 18, dirty: true}, false},
+		{"error", &fakeDB{err: errors.New("no table")}, false},
+	}
+	for _, tt := range tests {
+		err := Migrations(tt.db, 18).Check(context.Background())
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+// Package live fans out changes to the clients listening on the event stream
+package live
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Message is a change sent to the subscribers. TenantID and UserID tell who
+// owns the changed object, ObjectID identifies it and Data is its JSON.
+type Message struct {
+	ID       int64           `json:"id"`
+	Type     string          `json:"type"`
+	TenantID int             `json:"tenant_id"`
+	UserID   int             `json:"user_id"`
+	ObjectID int             `json:"object_id"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+// Broker delivers every published message to every subscriber. IDs grow in
+// publishing order, so a subscriber can resume after the last one it saw.
+type Broker interface {
+	// Publish sends msg to the subscribers, the broker assigns its ID
+	Publish(ctx context.Context, msg Message) error
+	// Subscribe returns the messages published after lastID, then the new ones.
+	// A lastID of 0 starts with the new ones. The channel is closed when ctx
+	// is done or when the subscriber falls too far behind.
+	Subscribe(ctx context.Context, lastID int64) <-chan Message
+}
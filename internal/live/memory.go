@@ -0,0 +1,104 @@
+package live
+
+import (
+	"context"
+	"sync"
+)
+
+// subscriberBuffer is how many messages a subscriber may fall behind before it
+// is dropped, it reconnects and resumes from the backlog
+const subscriberBuffer = 64
+
+// MemoryBroker fans out within the process and keeps the latest messages for
+// resuming subscribers. It only suits a single replica.
+type MemoryBroker struct {
+	mu          sync.Mutex
+	lastID      int64
+	backlog     []Message
+	size        int
+	subscribers map[chan Message]struct{}
+}
+
+// NewMemoryBroker creates a broker that keeps the latest backlog messages
+func NewMemoryBroker(backlog int) *MemoryBroker {
+	return &MemoryBroker{
+		size:        backlog,
+		subscribers: map[chan Message]struct{}{},
+	}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, msg Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	msg.ID = b.lastID
+	b.deliverLocked(msg)
+	return nil
+}
+
+// deliver sends a message that already has its ID, the Postgres broker uses it
+// for the messages of every replica
+func (b *MemoryBroker) deliver(msg Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.deliverLocked(msg)
+}
+
+func (b *MemoryBroker) deliverLocked(msg Message) {
+	if b.size > 0 {
+		if len(b.backlog) == b.size {
+			b.backlog = append(b.backlog[:0], b.backlog[1:]...)
+		}
+		b.backlog = append(b.backlog, msg)
+	}
+	for ch := range b.subscribers {
+		select {
+		case ch <- msg:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, lastID int64) <-chan Message {
+	b.mu.Lock()
+	replay := b.replayLocked(lastID)
+	ch := make(chan Message, subscriberBuffer+len(replay))
+	for _, msg := range replay {
+		ch <- msg
+	}
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}()
+	return ch
+}
+
+// replayLocked returns the backlog after lastID. Messages of other replicas may
+// arrive slightly out of ID order, so the position of lastID wins over the IDs.
+func (b *MemoryBroker) replayLocked(lastID int64) []Message {
+	if lastID == 0 {
+		return nil
+	}
+	for i, msg := range b.backlog {
+		if msg.ID == lastID {
+			return append([]Message(nil), b.backlog[i+1:]...)
+		}
+	}
+	var replay []Message
+	for _, msg := range b.backlog {
+		if msg.ID > lastID {
+			replay = append(replay, msg)
+		}
+	}
+	return replay
+}
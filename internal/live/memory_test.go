@@ -0,0 +1,92 @@
+package live
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func receiveIDs(ch <-chan Message, n int) []int64 {
+	var ids []int64
+	for range n {
+		msg, ok := <-ch
+		if !ok {
+			break
+		}
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
+
+func TestMemoryBrokerReplay(t *testing.T) {
+	b := NewMemoryBroker(3)
+	for range 5 {
+		b.Publish(t.Context(), Message{Type: "event.created"})
+	}
+
+	tests := []struct {
+		name   string
+		lastID int64
+		want   []int64
+	}{
+		{"new only", 0, nil},
+		{"within backlog", 3, []int64{4, 5}},
+		{"older than backlog", 1, []int64{3, 4, 5}},
+		{"up to date", 5, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ch := b.Subscribe(ctx, tt.lastID)
+			if got := receiveIDs(ch, len(ch)); !slices.Equal(got, tt.want) {
+				t.Errorf("replayed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryBrokerReplayFollowsArrivalOrder(t *testing.T) {
+	b := NewMemoryBroker(10)
+	for _, id := range []int64{1, 3, 2, 4} {
+		b.deliver(Message{ID: id})
+	}
+	ch := b.Subscribe(context.Background(), 3)
+	if got, want := receiveIDs(ch, len(ch)), []int64{2, 4}; !slices.Equal(got, want) {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+}
+
+func TestMemoryBrokerFanOut(t *testing.T) {
+	b := NewMemoryBroker(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	first := b.Subscribe(ctx, 0)
+	second := b.Subscribe(context.Background(), 0)
+
+	b.Publish(t.Context(), Message{})
+	b.Publish(t.Context(), Message{})
+	for name, ch := range map[string]<-chan Message{"first": first, "second": second} {
+		if got, want := receiveIDs(ch, 2), []int64{1, 2}; !slices.Equal(got, want) {
+			t.Errorf("%s received %v, want %v", name, got, want)
+		}
+	}
+
+	cancel()
+	if _, ok := <-first; ok {
+		t.Error("channel still open after the context is done")
+	}
+}
+
+func TestMemoryBrokerDropsSlowSubscriber(t *testing.T) {
+	b := NewMemoryBroker(0)
+	ch := b.Subscribe(context.Background(), 0)
+	for range subscriberBuffer + 1 {
+		b.Publish(t.Context(), Message{})
+	}
+	if got := receiveIDs(ch, subscriberBuffer+1); len(got) != subscriberBuffer {
+		t.Errorf("received %d messages, want %d before the channel closes", len(got), subscriberBuffer)
+	}
+	if _, ok := <-ch; ok {
+		t.Error("slow subscriber was not dropped")
+	}
+}
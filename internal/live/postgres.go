@@ -0,0 +1,78 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/logging"
+	"time"
+)
+
+const notifyChannel = "pwp_live"
+
+// maxNotifyPayload stays under the 8000 byte limit of a NOTIFY payload
+const maxNotifyPayload = 7900
+
+// PostgresBroker fans out across replicas with LISTEN/NOTIFY. Every replica
+// receives every message and hands it to its own subscribers, the IDs come from
+// a shared sequence so a client can resume on any replica.
+type PostgresBroker struct {
+	db    database.Service
+	local *MemoryBroker
+}
+
+// NewPostgresBroker creates a broker that keeps the latest backlog messages,
+// Run must be running for its subscribers to receive anything
+func NewPostgresBroker(db database.Service, backlog int) *PostgresBroker {
+	return &PostgresBroker{db: db, local: NewMemoryBroker(backlog)}
+}
+
+// Publish notifies every replica. Data is left out when the message does not
+// fit in a notification, subscribers then fetch the object by ObjectID.
+func (b *PostgresBroker) Publish(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayload {
+		msg.Data = nil
+		if payload, err = json.Marshal(msg); err != nil {
+			return err
+		}
+	}
+	_, err = b.db.ExecContext(ctx, `
+		SELECT pg_notify($1, jsonb_set($2::jsonb, '{id}', to_jsonb(nextval('live_message_id_seq')))::text)`,
+		notifyChannel, string(payload))
+	return err
+}
+
+func (b *PostgresBroker) Subscribe(ctx context.Context, lastID int64) <-chan Message {
+	return b.local.Subscribe(ctx, lastID)
+}
+
+// Run listens for the notifications of every replica until ctx is done. A lost
+// connection is reopened, messages published meanwhile are not received.
+func (b *PostgresBroker) Run(ctx context.Context) {
+	delay := time.Second
+	for {
+		err := b.db.Listen(ctx, notifyChannel, func(payload string) {
+			delay = time.Second
+			var msg Message
+			if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+				logging.FromContext(ctx).WarnContext(ctx, "live: invalid notification", "error", err)
+				return
+			}
+			b.local.deliver(msg)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		logging.FromContext(ctx).ErrorContext(ctx, "live: listen failed", "error", err, "retry_in", delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, 30*time.Second)
+	}
+}
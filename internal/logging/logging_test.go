@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"pwp-remastered/internal/domain"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+
+	user := domain.User{ID: 7, Username: "ayse", HashedPassword: "$argon2id$v=19$secret", TenantID: 2}
+	logger.Info("login",
+		"user", user,
+		"password", "hunter2",
+		slog.Group("request", "Authorization", "Bearer abc", "path", "/login"),
+		"refresh_token", "xyz",
+		"client_secret", "s3cr3t",
+	)
+
+	out := buf.String()
+	for _, secret := range []string{"hunter2", "abc", "xyz", "s3cr3t", "argon2id"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log line leaks %q: %s", secret, out)
+		}
+	}
+
+	var line struct {
+		User     map[string]interface{} `json:"user"`
+		Password string                 `json:"password"`
+		Request  map[string]string      `json:"request"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if line.Password != Redacted || line.Request["Authorization"] != Redacted {
+		t.Errorf("sensitive values = %q, %q; want %q", line.Password, line.Request["Authorization"], Redacted)
+	}
+	if line.Request["path"] != "/login" || line.User["username"] != "ayse" {
+		t.Errorf("plain values were changed: %s", out)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want slog.Level
+		err  bool
+	}{
+		{"", slog.LevelInfo, false},
+		{"debug", slog.LevelDebug, false},
+		{"WARN", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"loud", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.in)
+		if (err != nil) != tt.err || (!tt.err && got != tt.want) {
+			t.Errorf("ParseLevel(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("FromContext without a logger should return the default logger")
+	}
+
+	var buf bytes.Buffer
+	ctx := NewContext(context.Background(), New(&buf, slog.LevelInfo))
+	ctx = With(ctx, "request_id", "r1")
+	FromContext(ctx).Debug("hidden")
+	FromContext(ctx).Info("shown")
+
+	if strings.Contains(buf.String(), "hidden") {
+		t.Error("debug line written at info level")
+	}
+	if !strings.Contains(buf.String(), `"request_id":"r1"`) {
+		t.Errorf("context attributes missing: %s", buf.String())
+	}
+}
+
+func TestTraceIDs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+
+	span := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), span)
+	logger.With("request_id", "r1").InfoContext(ctx, "traced")
+	logger.Info("untraced")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7"`) {
+		t.Errorf("traced line = %s", lines[0])
+	}
+	if strings.Contains(lines[1], "trace_id") {
+		t.Errorf("untraced line = %s", lines[1])
+	}
+}
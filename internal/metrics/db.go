@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"pwp-remastered/internal/database"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type methodKey struct{}
+
+// WithMethod returns ctx labelling the statements run with it by method
+func WithMethod(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, methodKey{}, method)
+}
+
+// Tracer returns tracer labelling the statements run within a span by the
+// span's name, the services name their spans after their methods, such as
+// EventService.CreateEvent
+func Tracer(tracer trace.Tracer) trace.Tracer {
+	return methodTracer{tracer}
+}
+
+type methodTracer struct {
+	trace.Tracer
+}
+
+func (t methodTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return t.Tracer.Start(WithMethod(ctx, name), name, opts...)
+}
+
+// InstrumentDB returns db timing every statement by the method labelled on its
+// context, and reports the gauges of its connection pool
+func InstrumentDB(db database.Service) database.Service {
+	pool.set(db)
+	return &instrumentedDB{Service: db}
+}
+
+type instrumentedDB struct {
+	database.Service
+}
+
+func (db *instrumentedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer observeQuery(ctx, time.Now())
+	return db.Service.QueryRowContext(ctx, query, args...)
+}
+
+func (db *instrumentedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer observeQuery(ctx, time.Now())
+	return db.Service.QueryContext(ctx, query, args...)
+}
+
+func (db *instrumentedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer observeQuery(ctx, time.Now())
+	return db.Service.ExecContext(ctx, query, args...)
+}
+
+func (db *instrumentedDB) Transact(ctx context.Context, fn func(tx database.Querier) error) error {
+	return db.Service.Transact(ctx, func(tx database.Querier) error {
+		return fn(instrumentedTx{tx})
+	})
+}
+
+type instrumentedTx struct {
+	tx database.Querier
+}
+
+func (q instrumentedTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer observeQuery(ctx, time.Now())
+	return q.tx.QueryRowContext(ctx, query, args...)
+}
+
+func (q instrumentedTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer observeQuery(ctx, time.Now())
+	return q.tx.QueryContext(ctx, query, args...)
+}
+
+func (q instrumentedTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer observeQuery(ctx, time.Now())
+	return q.tx.ExecContext(ctx, query, args...)
+}
+
+// observeQuery records a statement, those run without a label count as other
+func observeQuery(ctx context.Context, start time.Time) {
+	method, _ := ctx.Value(methodKey{}).(string)
+	if method == "" {
+		method = "other"
+	}
+	dbQueries.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+var (
+	poolOpen = prometheus.NewDesc(namespace+"_db_open_connections",
+		"Established connections, in use and idle.", nil, nil)
+	poolInUse = prometheus.NewDesc(namespace+"_db_in_use_connections",
+		"Connections currently in use.", nil, nil)
+	poolIdle = prometheus.NewDesc(namespace+"_db_idle_connections",
+		"Idle connections.", nil, nil)
+	poolMaxOpen = prometheus.NewDesc(namespace+"_db_max_open_connections",
+		"Maximum number of open connections, 0 is unlimited.", nil, nil)
+	poolWaits = prometheus.NewDesc(namespace+"_db_wait_count_total",
+		"Connections waited for.", nil, nil)
+	poolWaitDuration = prometheus.NewDesc(namespace+"_db_wait_duration_seconds_total",
+		"Time spent waiting for a connection.", nil, nil)
+)
+
+// pool is registered with Registry once, InstrumentDB points it at the
+// database of the latest server
+var pool = &poolCollector{}
+
+// poolCollector reads the pool statistics of the database on every scrape
+type poolCollector struct {
+	mu sync.Mutex
+	db database.Service
+}
+
+func (c *poolCollector) set(db database.Service) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.db = db
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{poolOpen, poolInUse, poolIdle, poolMaxOpen, poolWaits, poolWaitDuration} {
+		ch <- desc
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	db := c.db
+	c.mu.Unlock()
+	if db == nil {
+		return
+	}
+	stats := db.Stats()
+	ch <- prometheus.MustNewConstMetric(poolOpen, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(poolInUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(poolIdle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(poolMaxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(poolWaits, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(poolWaitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+}
@@ -0,0 +1,94 @@
+// Package metrics exposes the Prometheus metrics of the HTTP server, the
+// database and the business counters on their own registry, served apart
+// from the API on METRICS_ADDR.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "pwp"
+
+// Registry holds every metric of the application
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests by method, route pattern and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	dbQueries = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Duration of database statements by the service method running them.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"method"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Logins by method (local, ldap or oidc) and result (success or failure).",
+	}, []string{"method", "result"})
+
+	eventsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_created_total",
+		Help:      "Events created by source (api or import).",
+	}, []string{"source"})
+
+	roadPrice = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_road_price_total",
+		Help:      "Sum of the road price of the created events by source.",
+	}, []string{"source"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, dbQueries, logins, eventsCreated, roadPrice, pool,
+	)
+}
+
+// Handler serves the metrics of Registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveRequest records a served HTTP request. Route is the matched route
+// pattern, which keeps IDs out of the labels.
+func ObserveRequest(method string, route string, status int, elapsed time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Observe(elapsed.Seconds())
+}
+
+// ObserveLogin counts a login attempt with method, failed when err is set
+func ObserveLogin(method string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	logins.WithLabelValues(method, result).Inc()
+}
+
+// EventCreated counts an event created from source with its road price
+func EventCreated(source string, price float64) {
+	eventsCreated.WithLabelValues(source).Inc()
+	if price > 0 {
+		roadPrice.WithLabelValues(source).Add(price)
+	}
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"pwp-remastered/internal/database"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// fakeDB counts statements, the returned values are never used
+type fakeDB struct {
+	database.Service
+	statements int
+}
+
+func (db *fakeDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	db.statements++
+	return nil, nil
+}
+
+func (db *fakeDB) Transact(ctx context.Context, fn func(tx database.Querier) error) error {
+	return fn(db)
+}
+
+func (db *fakeDB) Stats() sql.DBStats {
+	return sql.DBStats{OpenConnections: 3, InUse: 1, Idle: 2, WaitCount: 5}
+}
+
+func TestCounters(t *testing.T) {
+	ObserveRequest(http.MethodGet, "/events/{id}", http.StatusOK, 20*time.Millisecond)
+	ObserveRequest(http.MethodGet, "", http.StatusNotFound, time.Millisecond)
+	if n := testutil.CollectAndCount(httpRequests); n != 2 {
+		t.Errorf("request series = %d, want 2", n)
+	}
+
+	ObserveLogin("ldap", nil)
+	ObserveLogin("ldap", errors.New("invalid credentials"))
+	ObserveLogin("ldap", errors.New("invalid credentials"))
+	if got := testutil.ToFloat64(logins.WithLabelValues("ldap", "failure")); got != 2 {
+		t.Errorf("ldap failures = %v, want 2", got)
+	}
+
+	EventCreated("api", 120.5)
+	EventCreated("api", 0)
+	if got := testutil.ToFloat64(eventsCreated.WithLabelValues("api")); got != 2 {
+		t.Errorf("events created = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(roadPrice.WithLabelValues("api")); got != 120.5 {
+		t.Errorf("road price = %v, want 120.5", got)
+	}
+}
+
+func TestInstrumentDB(t *testing.T) {
+	InstrumentDB(&fakeDB{})
+	// A second server instruments its own database without registering again
+	fake := &fakeDB{}
+	db := InstrumentDB(fake)
+
+	ctx := context.Background()
+	db.ExecContext(ctx, `SELECT 1`)
+	tracer := Tracer(noop.NewTracerProvider().Tracer("test"))
+	spanCtx, span := tracer.Start(ctx, "EventService.CreateEvent")
+	db.Transact(spanCtx, func(tx database.Querier) error {
+		_, err := tx.ExecContext(spanCtx, `SELECT 2`)
+		return err
+	})
+	span.End()
+	db.ExecContext(WithMethod(ctx, "Dispatcher.Tick"), `SELECT 3`)
+	if fake.statements != 3 {
+		t.Fatalf("statements = %d, want 3", fake.statements)
+	}
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		"pwp_db_open_connections 3",
+		"pwp_db_wait_count_total 5",
+		`pwp_db_query_duration_seconds_count{method="other"} 1`,
+		`pwp_db_query_duration_seconds_count{method="EventService.CreateEvent"} 1`,
+		`pwp_db_query_duration_seconds_count{method="Dispatcher.Tick"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output has no %q", want)
+		}
+	}
+}
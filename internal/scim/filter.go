@@ -0,0 +1,77 @@
+package scim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Comparison is an "attribute eq value" filter expression. Attribute is lower
+// cased, as attribute names are case insensitive.
+type Comparison struct {
+	Attribute string
+	Value     string
+}
+
+// ParseFilter parses the filter subset provisioning clients send to look
+// resources up: eq comparisons of a string, boolean or number joined by and,
+// such as userName eq "ayse" and active eq true. An empty filter matches all.
+func ParseFilter(filter string) ([]Comparison, error) {
+	var comparisons []Comparison
+	rest := strings.TrimSpace(filter)
+	for rest != "" {
+		attribute, after, ok := strings.Cut(rest, " ")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q has no operator", ErrInvalidFilter, rest)
+		}
+		operator, after, ok := strings.Cut(strings.TrimLeft(after, " "), " ")
+		if !ok || !strings.EqualFold(operator, "eq") {
+			return nil, fmt.Errorf("%w: only eq is supported", ErrInvalidFilter)
+		}
+
+		value, after, err := readValue(strings.TrimLeft(after, " "))
+		if err != nil {
+			return nil, err
+		}
+		comparisons = append(comparisons, Comparison{Attribute: strings.ToLower(attribute), Value: value})
+
+		rest = strings.TrimLeft(after, " ")
+		if rest == "" {
+			break
+		}
+		conjunction, after, ok := strings.Cut(rest, " ")
+		if !ok || !strings.EqualFold(conjunction, "and") {
+			return nil, fmt.Errorf("%w: only and is supported", ErrInvalidFilter)
+		}
+		rest = strings.TrimLeft(after, " ")
+		if rest == "" {
+			return nil, fmt.Errorf("%w: and needs a second comparison", ErrInvalidFilter)
+		}
+	}
+	return comparisons, nil
+}
+
+// readValue reads a JSON string, boolean or number literal at the start of s
+func readValue(s string) (string, string, error) {
+	if strings.HasPrefix(s, `"`) {
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				value, err := strconv.Unquote(s[:i+1])
+				if err != nil {
+					return "", "", fmt.Errorf("%w: invalid string %s", ErrInvalidFilter, s[:i+1])
+				}
+				return value, s[i+1:], nil
+			}
+		}
+		return "", "", fmt.Errorf("%w: unterminated string", ErrInvalidFilter)
+	}
+
+	value, rest, _ := strings.Cut(s, " ")
+	if _, err := strconv.ParseFloat(value, 64); err != nil && value != "true" && value != "false" {
+		return "", "", fmt.Errorf("%w: invalid value %q", ErrInvalidFilter, value)
+	}
+	return value, rest, nil
+}
@@ -0,0 +1,250 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// PatchRequest is the body of a PATCH, RFC 7644 section 3.5.2
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation adds, replaces or removes the attribute at Path. Without a
+// path Value is an object of attribute paths to values.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+const (
+	opAdd     = "add"
+	opReplace = "replace"
+	opRemove  = "remove"
+)
+
+// ApplyUserPatch applies ops to user. Attributes a User does not hold, such as
+// phone numbers, are ignored the same way they are on create and replace.
+func ApplyUserPatch(user *User, ops []PatchOperation) error {
+	return applyPatch(ops, func(op string, path string, value json.RawMessage) error {
+		return patchUser(user, op, path, value)
+	})
+}
+
+// ApplyGroupPatch applies ops to group, members are added, replaced or
+// removed by their value
+func ApplyGroupPatch(group *Group, ops []PatchOperation) error {
+	return applyPatch(ops, func(op string, path string, value json.RawMessage) error {
+		return patchGroup(group, op, path, value)
+	})
+}
+
+func applyPatch(ops []PatchOperation, apply func(op string, path string, value json.RawMessage) error) error {
+	for _, operation := range ops {
+		op := strings.ToLower(operation.Op)
+		if op != opAdd && op != opReplace && op != opRemove {
+			return fmt.Errorf("%w: unknown op %q", ErrInvalidPath, operation.Op)
+		}
+		if operation.Path != "" {
+			if err := apply(op, operation.Path, operation.Value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if op == opRemove {
+			return fmt.Errorf("%w: remove needs a path", ErrInvalidPath)
+		}
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(operation.Value, &values); err != nil {
+			return fmt.Errorf("%w: an operation without a path needs an object value", ErrInvalidValue)
+		}
+		for path, value := range values {
+			if err := apply(op, path, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func patchUser(user *User, op string, path string, value json.RawMessage) error {
+	attribute := strings.TrimPrefix(strings.ToLower(path), strings.ToLower(SchemaUser)+":")
+	enterprise := strings.ToLower(SchemaEnterpriseUser)
+	if attribute == enterprise {
+		// The extension as a whole, {"manager": {"value": "12"}}
+		var extension EnterpriseUser
+		if op != opRemove {
+			if err := decode(value, &extension); err != nil {
+				return err
+			}
+		}
+		user.Enterprise = &extension
+		return nil
+	}
+	attribute = strings.TrimPrefix(attribute, enterprise+":")
+
+	switch attribute {
+	case "username":
+		return patchString(&user.UserName, op, value)
+	case "password":
+		return patchString(&user.Password, op, value)
+	case "active":
+		if op == opRemove {
+			return fmt.Errorf("%w: active cannot be removed", ErrInvalidPath)
+		}
+		active, err := decodeBool(value)
+		if err != nil {
+			return err
+		}
+		user.Active = &active
+	case "name":
+		name := Name{}
+		if op != opRemove {
+			if user.Name != nil && op == opAdd {
+				name = *user.Name
+			}
+			if err := decode(value, &name); err != nil {
+				return err
+			}
+		}
+		user.Name = &name
+	case "name.givenname", "name.familyname", "name.formatted":
+		if user.Name == nil {
+			user.Name = &Name{}
+		}
+		field := map[string]*string{
+			"name.givenname":  &user.Name.GivenName,
+			"name.familyname": &user.Name.FamilyName,
+			"name.formatted":  &user.Name.Formatted,
+		}[attribute]
+		return patchString(field, op, value)
+	case "emails":
+		var emails []Email
+		if op != opRemove {
+			if err := decode(value, &emails); err != nil {
+				return err
+			}
+		}
+		user.Emails = emails
+	case "emails.value", `emails[type eq "work"].value`, `emails[primary eq true].value`:
+		var email string
+		if err := patchString(&email, op, value); err != nil {
+			return err
+		}
+		user.Emails = nil
+		if email != "" {
+			user.Emails = []Email{{Value: email, Type: "work", Primary: true}}
+		}
+	case "manager", "manager.value":
+		manager, err := decodeManager(op, value)
+		if err != nil {
+			return err
+		}
+		user.Enterprise = &EnterpriseUser{Manager: manager}
+	}
+	return nil
+}
+
+func patchGroup(group *Group, op string, path string, value json.RawMessage) error {
+	attribute := strings.TrimPrefix(strings.ToLower(path), strings.ToLower(SchemaGroup)+":")
+
+	if inner, ok := strings.CutPrefix(attribute, "members["); ok {
+		// members[value eq "12"] selects a single member, ids are numeric so case does not matter
+		selector, closed := strings.CutSuffix(inner, "]")
+		comparisons, err := ParseFilter(selector)
+		if !closed || err != nil || len(comparisons) != 1 || comparisons[0].Attribute != "value" {
+			return fmt.Errorf("%w: select members by value", ErrInvalidPath)
+		}
+		if op != opRemove {
+			return fmt.Errorf("%w: selected members can only be removed", ErrInvalidPath)
+		}
+		group.Members = slices.DeleteFunc(group.Members, func(m Reference) bool { return m.Value == comparisons[0].Value })
+		return nil
+	}
+
+	switch attribute {
+	case "displayname":
+		return patchString(&group.DisplayName, op, value)
+	case "members":
+		var members []Reference
+		if len(value) > 0 {
+			if err := decode(value, &members); err != nil {
+				return err
+			}
+		}
+		switch {
+		case op == opReplace:
+			group.Members = nil
+			fallthrough
+		case op == opAdd:
+			for _, member := range members {
+				if !slices.ContainsFunc(group.Members, func(m Reference) bool { return m.Value == member.Value }) {
+					group.Members = append(group.Members, Reference{Value: member.Value})
+				}
+			}
+		case len(members) == 0:
+			group.Members = nil
+		default:
+			group.Members = slices.DeleteFunc(group.Members, func(m Reference) bool {
+				return slices.ContainsFunc(members, func(r Reference) bool { return r.Value == m.Value })
+			})
+		}
+	}
+	return nil
+}
+
+func patchString(field *string, op string, value json.RawMessage) error {
+	if op == opRemove {
+		*field = ""
+		return nil
+	}
+	return decode(value, field)
+}
+
+// decodeBool accepts JSON booleans and the "True" and "False" strings some
+// clients send
+func decodeBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		switch strings.ToLower(s) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("%w: %s is not a boolean", ErrInvalidValue, value)
+}
+
+// decodeManager accepts a manager as {"value": "12"} or as the bare id
+func decodeManager(op string, value json.RawMessage) (*Reference, error) {
+	if op == opRemove {
+		return nil, nil
+	}
+	var manager Reference
+	if err := json.Unmarshal(value, &manager); err != nil {
+		if err := decode(value, &manager.Value); err != nil {
+			return nil, err
+		}
+	}
+	if manager.Value == "" {
+		return nil, nil
+	}
+	return &manager, nil
+}
+
+func decode(value json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(value, v); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidValue, value)
+	}
+	return nil
+}
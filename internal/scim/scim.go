@@ -0,0 +1,177 @@
+// Package scim holds the SCIM 2.0 (RFC 7643, RFC 7644) resources served to
+// provisioning clients such as HR systems, and the filter and PATCH subsets
+// they use. Mapping the resources onto users and teams is up to the services.
+package scim
+
+import (
+	"pwp-remastered/internal/domain"
+	"time"
+)
+
+// Schema and message URNs
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaEnterpriseUser        = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// ContentType is the media type of SCIM requests and responses
+const ContentType = "application/scim+json"
+
+// MaxResults caps the resources of one list response
+const MaxResults = 200
+
+var (
+	ErrInvalidFilter = domain.NewValidationError("invalid_scim_filter", "filter must compare supported attributes with eq, joined by and")
+	ErrInvalidPath   = domain.NewValidationError("invalid_scim_path", "patch operation or path is not supported")
+	ErrInvalidValue  = domain.NewValidationError("invalid_scim_value", "value is missing or not valid for the attribute")
+)
+
+// ScimType returns the RFC 7644 scimType of a validation or conflict error
+// code, empty when there is none
+func ScimType(code string) string {
+	switch code {
+	case "invalid_body":
+		return "invalidSyntax"
+	case ErrInvalidFilter.Code:
+		return "invalidFilter"
+	case ErrInvalidPath.Code:
+		return "invalidPath"
+	case ErrInvalidValue.Code, "invalid_manager", "invalid_time_zone", "invalid_team":
+		return "invalidValue"
+	case "user_exists", "team_exists":
+		return "uniqueness"
+	}
+	return ""
+}
+
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Location     string     `json:"location,omitempty"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+}
+
+type Name struct {
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	Formatted  string `json:"formatted,omitempty"`
+}
+
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// Reference points at another resource by id, as a manager or group member
+type Reference struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+type EnterpriseUser struct {
+	Manager *Reference `json:"manager,omitempty"`
+}
+
+// User is the core User resource with the enterprise extension's manager.
+// Password is write-only.
+type User struct {
+	Schemas    []string        `json:"schemas"`
+	ID         string          `json:"id,omitempty"`
+	UserName   string          `json:"userName"`
+	Name       *Name           `json:"name,omitempty"`
+	Emails     []Email         `json:"emails,omitempty"`
+	Active     *bool           `json:"active,omitempty"`
+	Password   string          `json:"password,omitempty"`
+	Enterprise *EnterpriseUser `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Meta       *Meta           `json:"meta,omitempty"`
+}
+
+// PrimaryEmail returns the primary email, else the first one
+func (u *User) PrimaryEmail() string {
+	for _, email := range u.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+type Group struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	DisplayName string      `json:"displayName"`
+	Members     []Reference `json:"members"`
+	Meta        *Meta       `json:"meta,omitempty"`
+}
+
+// ListResponse is a page of resources, StartIndex is 1-based
+type ListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// Error is the body of SCIM error responses, Status is the HTTP status as a string
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+type supported struct {
+	Supported bool `json:"supported"`
+}
+
+type bulk struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type filterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type authenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ServiceProviderConfig describes the supported subset of the protocol
+type ServiceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	Patch                 supported              `json:"patch"`
+	Bulk                  bulk                   `json:"bulk"`
+	Filter                filterSupport          `json:"filter"`
+	ChangePassword        supported              `json:"changePassword"`
+	Sort                  supported              `json:"sort"`
+	ETag                  supported              `json:"etag"`
+	AuthenticationSchemes []authenticationScheme `json:"authenticationSchemes"`
+}
+
+// Config is the ServiceProviderConfig of this implementation
+var Config = ServiceProviderConfig{
+	Schemas:        []string{SchemaServiceProviderConfig},
+	Patch:          supported{Supported: true},
+	Filter:         filterSupport{Supported: true, MaxResults: MaxResults},
+	ChangePassword: supported{Supported: true},
+	AuthenticationSchemes: []authenticationScheme{{
+		Type:        "oauthbearertoken",
+		Name:        "Bearer token",
+		Description: "A SCIM token created by a tenant administrator",
+	}},
+}
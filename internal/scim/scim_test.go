@@ -0,0 +1,118 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   []Comparison
+		err    error
+	}{
+		{"", nil, nil},
+		{`userName eq "ayse"`, []Comparison{{"username", "ayse"}}, nil},
+		{`userName  EQ "a \"b\"" and active eq true`, []Comparison{{"username", `a "b"`}, {"active", "true"}}, nil},
+		{`id eq 12`, []Comparison{{"id", "12"}}, nil},
+		{`userName sw "a"`, nil, ErrInvalidFilter},
+		{`userName eq "a" or active eq true`, nil, ErrInvalidFilter},
+		{`userName eq "a" and`, nil, ErrInvalidFilter},
+		{`userName eq "a`, nil, ErrInvalidFilter},
+		{`userName eq ayse`, nil, ErrInvalidFilter},
+		{`userName`, nil, ErrInvalidFilter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			got, err := ParseFilter(tt.filter)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParseFilter() error = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyUserPatch(t *testing.T) {
+	active := true
+	user := User{UserName: "ayse", Active: &active, Emails: []Email{{Value: "ayse@example.com", Primary: true}}}
+	ops := []PatchOperation{
+		{Op: "replace", Path: "active", Value: json.RawMessage(`"False"`)},
+		{Op: "add", Value: json.RawMessage(`{"name.familyName": "Yılmaz", "emails[type eq \"work\"].value": "ayse.yilmaz@example.com"}`)},
+		{Op: "replace", Path: SchemaEnterpriseUser + ":manager", Value: json.RawMessage(`{"value": "7"}`)},
+		{Op: "add", Path: "phoneNumbers", Value: json.RawMessage(`[{"value": "555"}]`)},
+	}
+	if err := ApplyUserPatch(&user, ops); err != nil {
+		t.Fatalf("ApplyUserPatch() error = %v", err)
+	}
+	if *user.Active {
+		t.Error("active = true, want false")
+	}
+	if user.Name == nil || user.Name.FamilyName != "Yılmaz" {
+		t.Errorf("name = %+v, want family name Yılmaz", user.Name)
+	}
+	if user.PrimaryEmail() != "ayse.yilmaz@example.com" {
+		t.Errorf("PrimaryEmail() = %q", user.PrimaryEmail())
+	}
+	if user.Enterprise == nil || user.Enterprise.Manager == nil || user.Enterprise.Manager.Value != "7" {
+		t.Errorf("manager = %+v, want 7", user.Enterprise)
+	}
+
+	if err := ApplyUserPatch(&user, []PatchOperation{{Op: "remove", Path: "manager"}}); err != nil {
+		t.Fatalf("ApplyUserPatch() error = %v", err)
+	}
+	if user.Enterprise == nil || user.Enterprise.Manager != nil {
+		t.Errorf("manager = %+v, want an extension without a manager", user.Enterprise)
+	}
+
+	invalid := []struct {
+		op  PatchOperation
+		err error
+	}{
+		{PatchOperation{Op: "move", Path: "active"}, ErrInvalidPath},
+		{PatchOperation{Op: "remove", Path: "active"}, ErrInvalidPath},
+		{PatchOperation{Op: "remove"}, ErrInvalidPath},
+		{PatchOperation{Op: "replace", Path: "active", Value: json.RawMessage(`"yes"`)}, ErrInvalidValue},
+		{PatchOperation{Op: "replace", Value: json.RawMessage(`[]`)}, ErrInvalidValue},
+	}
+	for _, tt := range invalid {
+		if err := ApplyUserPatch(&user, []PatchOperation{tt.op}); !errors.Is(err, tt.err) {
+			t.Errorf("ApplyUserPatch(%+v) error = %v, want %v", tt.op, err, tt.err)
+		}
+	}
+}
+
+func TestApplyGroupPatch(t *testing.T) {
+	group := Group{DisplayName: "Saha", Members: []Reference{{Value: "1"}, {Value: "2"}}}
+
+	tests := []struct {
+		op   PatchOperation
+		want []string
+	}{
+		{PatchOperation{Op: "add", Path: "members", Value: json.RawMessage(`[{"value": "2"}, {"value": "3"}]`)}, []string{"1", "2", "3"}},
+		{PatchOperation{Op: "remove", Path: `members[value eq "2"]`}, []string{"1", "3"}},
+		{PatchOperation{Op: "remove", Path: "members", Value: json.RawMessage(`[{"value": "1"}]`)}, []string{"3"}},
+		{PatchOperation{Op: "replace", Path: "members", Value: json.RawMessage(`[{"value": "4"}]`)}, []string{"4"}},
+		{PatchOperation{Op: "remove", Path: "members"}, nil},
+	}
+	for _, tt := range tests {
+		if err := ApplyGroupPatch(&group, []PatchOperation{tt.op}); err != nil {
+			t.Fatalf("ApplyGroupPatch(%+v) error = %v", tt.op, err)
+		}
+		var got []string
+		for _, m := range group.Members {
+			got = append(got, m.Value)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ApplyGroupPatch(%+v) members = %v, want %v", tt.op, got, tt.want)
+		}
+	}
+
+	if err := ApplyGroupPatch(&group, []PatchOperation{{Op: "add", Path: `members[value eq "4"]`}}); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("adding a selected member error = %v, want %v", err, ErrInvalidPath)
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"pwp-remastered/internal/services"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type AttachmentHandlers struct {
+	attachmentService *services.AttachmentService
+}
+
+// NewAttachmentHandlers creates a new attachment handlers
+func NewAttachmentHandlers(attachmentService *services.AttachmentService) *AttachmentHandlers {
+	return &AttachmentHandlers{
+		attachmentService: attachmentService,
+	}
+}
+
+func (h *AttachmentHandlers) RegisterRoutes(r chi.Router) {
+	r.Route("/events/{id}/attachments", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", h.ListAttachments)
+		r.Post("/", h.UploadAttachment)
+		r.Get("/{attachmentID}", h.DownloadAttachment)
+		r.Delete("/{attachmentID}", h.DeleteAttachment)
+	})
+}
+
+// ListAttachments returns the attachments of an event
+func (h *AttachmentHandlers) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	eventID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	attachments, err := h.attachmentService.ListAttachments(r.Context(), &caller, eventID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"attachments": attachments})
+}
+
+// UploadAttachment stores the "file" part of a multipart form as a new attachment
+func (h *AttachmentHandlers) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	eventID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	// Leave some room for the multipart envelope on top of the file itself
+	maxSize := h.attachmentService.MaxSize()
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeProblem(w, r, fmt.Errorf("%w: limit is %d bytes", services.ErrAttachmentTooLarge, maxSize))
+			return
+		}
+		writeProblem(w, r, fmt.Errorf("%w: invalid multipart form", errInvalidBody))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("%w: missing file", errInvalidBody))
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.attachmentService.Upload(r.Context(), &caller, eventID, header.Filename, header.Size, file)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(attachment)
+}
+
+// DownloadAttachment streams the attachment contents
+func (h *AttachmentHandlers) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	eventID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+	attachmentID, err := strconv.Atoi(chi.URLParam(r, "attachmentID"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	attachment, contents, err := h.attachmentService.OpenAttachment(r.Context(), &caller, eventID, attachmentID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	defer contents.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	// Non-ASCII names such as Turkish receipts are sent as filename*=utf-8''...
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, contents)
+}
+
+// DeleteAttachment removes an attachment
+func (h *AttachmentHandlers) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	eventID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+	attachmentID, err := strconv.Atoi(chi.URLParam(r, "attachmentID"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	if err := h.attachmentService.DeleteAttachment(r.Context(), &caller, eventID, attachmentID); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
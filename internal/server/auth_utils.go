@@ -1,36 +1,34 @@
 package server
 
 import (
+	"context"
 	"net/http"
 
-	"github.com/golang-jwt/jwt/v5"
 	"pwp-remastered/internal/domain"
 )
 
-// ExtractUserFromRequest extracts and validates JWT from Authorization header and returns the user info
+type callerKey struct{}
+
+func withCaller(ctx context.Context, caller domain.User) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// ExtractUserFromRequest returns the caller whose token TokenSigner.Authenticate
+// verified for the request
 func ExtractUserFromRequest(r *http.Request) (domain.User, error) {
-	var caller domain.User
-	tokenString := r.Header.Get("Authorization")
-	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-		tokenString = tokenString[7:]
-	}
-	if tokenString == "" {
-		return caller, http.ErrNoCookie // Use as unauthorized error
-	}
-	token, err := ParseJWT(tokenString)
-	if err != nil || !token.Valid {
-		return caller, http.ErrNoCookie // Use as unauthorized error
-	}
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		if idVal, ok := claims["user_id"].(float64); ok {
-			caller.ID = int(idVal)
-		}
-		if username, ok := claims["username"].(string); ok {
-			caller.Username = username
-		}
-		if isAdmin, ok := claims["is_admin"].(bool); ok {
-			caller.IsAdmin = isAdmin
-		}
+	caller, ok := r.Context().Value(callerKey{}).(domain.User)
+	if !ok {
+		return caller, errUnauthorized
 	}
 	return caller, nil
 }
+
+// baseURL is the scheme and host the client reached the server at, honouring
+// X-Forwarded-Proto from a TLS terminating proxy
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
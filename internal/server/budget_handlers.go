@@ -0,0 +1,183 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/services"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type BudgetHandlers struct {
+	budgetService   *services.BudgetService
+	settingsService *services.SettingsService
+}
+
+// NewBudgetHandlers creates a new budget handlers
+func NewBudgetHandlers(budgetService *services.BudgetService, settingsService *services.SettingsService) *BudgetHandlers {
+	return &BudgetHandlers{
+		budgetService:   budgetService,
+		settingsService: settingsService,
+	}
+}
+
+func (h *BudgetHandlers) RegisterRoutes(r chi.Router) {
+	r.Route("/budgets", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/status", h.GetStatus)
+		r.Group(func(r chi.Router) {
+			r.Use(AdminMiddleware)
+			r.Get("/", h.ListBudgets)
+			r.Post("/", h.CreateBudget)
+			r.Get("/{id}", h.GetBudget)
+			r.Put("/{id}", h.UpdateBudget)
+			r.Delete("/{id}", h.DeleteBudget)
+		})
+	})
+}
+
+// GetStatus reports spend against limit in the periods containing the optional
+// at parameter, a YYYY-MM-DD date or an RFC 3339 timestamp, now by default
+func (h *BudgetHandlers) GetStatus(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	loc, err := h.settingsService.TimeZone(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	at, err := parseRangeBound(r.URL.Query().Get("at"), loc, false)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("%w: invalid at, %v", errInvalidListParam, err))
+		return
+	}
+
+	statuses, err := h.budgetService.GetStatus(r.Context(), &caller, at)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domain.BudgetStatusList{Budgets: statuses})
+}
+
+func (h *BudgetHandlers) ListBudgets(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	budgets, err := h.budgetService.ListBudgets(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domain.BudgetList{Budgets: budgets})
+}
+
+func (h *BudgetHandlers) GetBudget(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	budget, err := h.budgetService.GetBudget(r.Context(), &caller, id)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budget)
+}
+
+func (h *BudgetHandlers) CreateBudget(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	var budget domain.Budget
+	if err := json.NewDecoder(r.Body).Decode(&budget); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+
+	if err := h.budgetService.CreateBudget(r.Context(), &caller, &budget); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(budget)
+}
+
+func (h *BudgetHandlers) UpdateBudget(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	var budget domain.Budget
+	if err := json.NewDecoder(r.Body).Decode(&budget); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+	budget.ID = id
+
+	if err := h.budgetService.UpdateBudget(r.Context(), &caller, &budget); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budget)
+}
+
+func (h *BudgetHandlers) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	if err := h.budgetService.DeleteBudget(r.Context(), &caller, id); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
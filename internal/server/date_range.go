@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"pwp-remastered/internal/domain"
+	"time"
+)
+
+// parseDateRange reads startdate, enddate and range from q. A YYYY-MM-DD date
+// covers the whole day in loc, so enddate=2025-03-31 includes March 31st. An
+// RFC 3339 timestamp is an exact instant and is an exclusive end bound.
+func parseDateRange(q url.Values, loc *time.Location) (domain.DateRange, error) {
+	r := domain.DateRange{Mode: domain.RangeOverlap}
+	if v := q.Get("range"); v != "" {
+		r.Mode = domain.DateRangeMode(v)
+		switch r.Mode {
+		case domain.RangeOverlap, domain.RangeContained, domain.RangeStartsWithin:
+		default:
+			return r, fmt.Errorf("%w: range must be overlap, contained or starts_within", errInvalidListParam)
+		}
+	}
+
+	var err error
+	if r.From, err = parseRangeBound(q.Get("startdate"), loc, false); err != nil {
+		return r, fmt.Errorf("%w: invalid startdate, %v", errInvalidListParam, err)
+	}
+	if r.To, err = parseRangeBound(q.Get("enddate"), loc, true); err != nil {
+		return r, fmt.Errorf("%w: invalid enddate, %v", errInvalidListParam, err)
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && !r.From.Before(r.To) {
+		return r, fmt.Errorf("%w: startdate must be before enddate", errInvalidListParam)
+	}
+	return r, nil
+}
+
+// parseRangeBound parses one bound, an end date moves to the start of the next day
+func parseRangeBound(v string, loc *time.Location, end bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if day, err := time.ParseInLocation("2006-01-02", v, loc); err == nil {
+		if end {
+			day = day.AddDate(0, 0, 1)
+		}
+		return day, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("use YYYY-MM-DD or an RFC 3339 timestamp")
+	}
+	return t, nil
+}
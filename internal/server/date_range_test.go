@@ -0,0 +1,65 @@
+package server
+
+import (
+	"errors"
+	"net/url"
+	"pwp-remastered/internal/domain"
+	"testing"
+	"time"
+)
+
+func TestParseDateRangeDaysInTimeZone(t *testing.T) {
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := url.Values{"startdate": {"2025-03-01"}, "enddate": {"2025-03-31"}}
+
+	r, err := parseDateRange(q, istanbul)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Mode != domain.RangeOverlap {
+		t.Errorf("default mode = %q, want overlap", r.Mode)
+	}
+	if want := time.Date(2025, 2, 28, 21, 0, 0, 0, time.UTC); !r.From.Equal(want) {
+		t.Errorf("From = %v, want %v", r.From.UTC(), want)
+	}
+	// The last day is included: the range ends at the next local midnight
+	if want := time.Date(2025, 3, 31, 21, 0, 0, 0, time.UTC); !r.To.Equal(want) {
+		t.Errorf("To = %v, want %v", r.To.UTC(), want)
+	}
+}
+
+func TestParseDateRangeTimestamps(t *testing.T) {
+	q := url.Values{
+		"startdate": {"2025-03-01T08:00:00+03:00"},
+		"enddate":   {"2025-03-01T18:30:00Z"},
+		"range":     {"contained"},
+	}
+	r, err := parseDateRange(q, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Mode != domain.RangeContained {
+		t.Errorf("mode = %q, want contained", r.Mode)
+	}
+	if want := time.Date(2025, 3, 1, 5, 0, 0, 0, time.UTC); !r.From.Equal(want) {
+		t.Errorf("From = %v, want %v", r.From.UTC(), want)
+	}
+	if want := time.Date(2025, 3, 1, 18, 30, 0, 0, time.UTC); !r.To.Equal(want) {
+		t.Errorf("To = %v, want %v", r.To.UTC(), want)
+	}
+}
+
+func TestParseDateRangeRejectsInvalidInput(t *testing.T) {
+	for _, q := range []url.Values{
+		{"startdate": {"01/03/2025"}},
+		{"range": {"inside"}},
+		{"startdate": {"2025-03-02"}, "enddate": {"2025-03-01"}},
+	} {
+		if _, err := parseDateRange(q, time.UTC); !errors.Is(err, errInvalidListParam) {
+			t.Errorf("parseDateRange(%v) error = %v, want errInvalidListParam", q, err)
+		}
+	}
+}
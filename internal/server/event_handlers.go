@@ -1,28 +1,36 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"pwp-remastered/internal/domain"
 	"pwp-remastered/internal/services"
-	"pwp-remastered/internal/store"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type EventHandlers struct {
-	eventService services.EventService
-	eventStore   store.EventStore
+	eventService    services.EventService
+	settingsService *services.SettingsService
+	importService   *services.EventImportService
+	// shutdown is done once the server shuts down, it ends the event streams
+	// that http.Server.Shutdown would otherwise wait for
+	shutdown context.Context
 }
 
-// NewEventHandlers creates a new event handlers
-func NewEventHandlers(eventService services.EventService, eventStore store.EventStore) *EventHandlers {
+// NewEventHandlers creates a new event handlers, their event streams end when
+// shutdown is done
+func NewEventHandlers(eventService services.EventService, settingsService *services.SettingsService, importService *services.EventImportService, shutdown context.Context) *EventHandlers {
 	return &EventHandlers{
-		eventService: eventService,
-		eventStore:   eventStore,
+		eventService:    eventService,
+		settingsService: settingsService,
+		importService:   importService,
+		shutdown:        shutdown,
 	}
 }
 
@@ -32,25 +40,44 @@ func (h *EventHandlers) RegisterRoutes(r chi.Router) {
 		r.Get("/dated/{id}", h.GetDatedUserEvents)
 		r.Get("/dated", h.GetAllDatedEvents)
 		r.Get("/dated/me", h.GetSelfDatedEvents)
+		r.Get("/dated/team", h.GetTeamDatedEvents)
+		r.Get("/search", h.SearchEvents)
+		r.Get("/stream", h.StreamEvents)
 		r.Get("/{id}", h.GetEvent)
 		r.Post("/", h.CreateEvent)
 		r.Put("/{id}", h.UpdateEvent)
 		r.Delete("/{id}", h.DeleteEvent)
+		r.Post("/{id}/approve", h.ApproveEvent)
 		r.Get("/types", h.GetEventTypes)
+		r.Group(func(r chi.Router) {
+			r.Use(AdminMiddleware)
+			r.Post("/import", h.ImportEvents)
+			r.Post("/types", h.CreateEventType)
+			r.Put("/types/order", h.ReorderEventTypes)
+			r.Put("/types/{typeID}", h.UpdateEventType)
+			r.Post("/types/{typeID}/archive", h.ArchiveEventType)
+			r.Post("/types/{typeID}/restore", h.RestoreEventType)
+		})
 	})
 }
 
 // GetEvent returns a single event by ID
 func (h *EventHandlers) GetEvent(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
 	eventID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		writeProblem(w, r, errInvalidID)
 		return
 	}
 
-	event, err := h.eventStore.GetEvent(eventID)
+	event, err := h.eventService.GetEvent(r.Context(), &caller, eventID)
 	if err != nil {
-		http.Error(w, "Event not found", http.StatusNotFound)
+		writeProblem(w, r, err)
 		return
 	}
 
@@ -60,33 +87,20 @@ func (h *EventHandlers) GetEvent(w http.ResponseWriter, r *http.Request) {
 
 // CreateEvent creates a new event
 func (h *EventHandlers) CreateEvent(w http.ResponseWriter, r *http.Request) {
-	var caller domain.User
-	tokenString := r.Header.Get("Authorization")
-	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-		tokenString = tokenString[7:]
-	}
-	if tokenString != "" {
-		token, err := ParseJWT(tokenString)
-		if err == nil && token.Valid {
-			if claims, ok := token.Claims.(jwt.MapClaims); ok {
-				if idVal, ok := claims["user_id"].(float64); ok {
-					caller.ID = int(idVal)
-				}
-				if isAdmin, ok := claims["is_admin"].(bool); ok {
-					caller.IsAdmin = isAdmin
-				}
-			}
-		}
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
 	}
 
 	var event domain.Event
 	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeProblem(w, r, errInvalidBody)
 		return
 	}
 
-	if err := h.eventService.CreateEvent(&event, &caller); err != nil {
-		http.Error(w, "Failed to create event", http.StatusInternalServerError)
+	if err := h.eventService.CreateEvent(r.Context(), &event, &caller); err != nil {
+		writeProblem(w, r, err)
 		return
 	}
 
@@ -97,40 +111,27 @@ func (h *EventHandlers) CreateEvent(w http.ResponseWriter, r *http.Request) {
 
 // UpdateEvent updates an existing event
 func (h *EventHandlers) UpdateEvent(w http.ResponseWriter, r *http.Request) {
-	var caller domain.User
-	tokenString := r.Header.Get("Authorization")
-	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-		tokenString = tokenString[7:]
-	}
-	if tokenString != "" {
-		token, err := ParseJWT(tokenString)
-		if err == nil && token.Valid {
-			if claims, ok := token.Claims.(jwt.MapClaims); ok {
-				if idVal, ok := claims["user_id"].(float64); ok {
-					caller.ID = int(idVal)
-				}
-				if isAdmin, ok := claims["is_admin"].(bool); ok {
-					caller.IsAdmin = isAdmin
-				}
-			}
-		}
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
 	}
 
 	eventID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		writeProblem(w, r, errInvalidID)
 		return
 	}
 
 	var event domain.Event
 	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeProblem(w, r, errInvalidBody)
 		return
 	}
 
 	event.ID = eventID
-	if err := h.eventService.UpdateEvent(&event, &caller); err != nil {
-		http.Error(w, "Failed to update event", http.StatusInternalServerError)
+	if err := h.eventService.UpdateEvent(r.Context(), &event, &caller); err != nil {
+		writeProblem(w, r, err)
 		return
 	}
 
@@ -140,106 +141,258 @@ func (h *EventHandlers) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 
 // DeleteEvent deletes an event by ID
 func (h *EventHandlers) DeleteEvent(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
 	eventID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		writeProblem(w, r, errInvalidID)
 		return
 	}
 
-	if err := h.eventService.DeleteEvent(eventID); err != nil {
-		http.Error(w, "Failed to delete event", http.StatusInternalServerError)
+	if err := h.eventService.DeleteEvent(r.Context(), &caller, eventID); err != nil {
+		writeProblem(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *EventHandlers) GetDatedUserEvents(w http.ResponseWriter, r *http.Request) {
-	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+// streamHeartbeat keeps idle streams from being closed by proxies
+const streamHeartbeat = 25 * time.Second
+
+// StreamEvents streams the created, updated and deleted events the caller may
+// read as Server-Sent Events. A reconnecting client sends Last-Event-ID and
+// receives what it missed first, as far as the broker's backlog reaches.
+func (h *EventHandlers) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		writeProblem(w, r, errUnauthorized)
 		return
 	}
 
-	startDateStr := r.URL.Query().Get("startdate")
-	endDateStr := r.URL.Query().Get("enddate")
+	var lastEventID int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		if lastEventID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			writeProblem(w, r, fmt.Errorf("%w: Last-Event-ID must be an integer", errInvalidListParam))
+			return
+		}
+	}
 
-	if startDateStr == "" || endDateStr == "" {
-		http.Error(w, "Missing startdate or enddate", http.StatusBadRequest)
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		writeProblem(w, r, err)
 		return
 	}
 
-	// ISO 8601 formatını parse et (örnek: "2025-03-01")
-	startDate, err := time.Parse("2006-01-02", startDateStr)
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	defer context.AfterFunc(h.shutdown, cancel)()
+	stream := h.eventService.StreamEvents(ctx, &caller, lastEventID)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	rc.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case msg, ok := <-stream:
+			if !ok {
+				// Dropped for falling behind, the client reconnects and resumes
+				return
+			}
+			data := []byte(msg.Data)
+			if len(data) == 0 {
+				data = fmt.Appendf(nil, `{"id":%d}`, msg.ObjectID)
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.ID, msg.Type, data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// ApproveEvent approves an event of one of the caller's reports, or of anyone
+// in the tenant for admins
+func (h *EventHandlers) ApproveEvent(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
 	if err != nil {
-		http.Error(w, "Invalid startdate format. Use YYYY-MM-DD", http.StatusBadRequest)
+		writeProblem(w, r, errUnauthorized)
 		return
 	}
-	endDate, err := time.Parse("2006-01-02", endDateStr)
+
+	eventID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid enddate format. Use YYYY-MM-DD", http.StatusBadRequest)
+		writeProblem(w, r, errInvalidID)
 		return
 	}
 
-	var caller domain.User
-	tokenString := r.Header.Get("Authorization")
-	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-		tokenString = tokenString[7:]
-	}
-	if tokenString != "" {
-		token, err := ParseJWT(tokenString)
-		if err == nil && token.Valid {
-			if claims, ok := token.Claims.(jwt.MapClaims); ok {
-				if idVal, ok := claims["user_id"].(float64); ok {
-					caller.ID = int(idVal)
-				}
-				if isAdmin, ok := claims["is_admin"].(bool); ok {
-					caller.IsAdmin = isAdmin
-				}
-			}
+	event, err := h.eventService.ApproveEvent(r.Context(), &caller, eventID)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(event)
+}
+
+// ImportEvents creates events from the CSV "file" part of a multipart form.
+// The optional "mapping" part is a JSON object naming the column of each field,
+// dry_run=true only validates. The result is 200 for a dry run, 201 once the
+// events are saved and 422 when a row is invalid and nothing was saved.
+func (h *EventHandlers) ImportEvents(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			writeProblem(w, r, fmt.Errorf("%w: invalid dry_run, use true or false", errInvalidListParam))
+			return
 		}
 	}
 
-	events, err := h.eventService.GetDatedUserEvents(&caller, userID, startDate, endDate)
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxImportSize)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeProblem(w, r, fmt.Errorf("%w: limit is %d bytes", services.ErrImportTooLarge, services.MaxImportSize))
+			return
+		}
+		writeProblem(w, r, fmt.Errorf("%w: invalid multipart form", errInvalidBody))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, _, err := r.FormFile("file")
 	if err != nil {
-		http.Error(w, "Failed to retrieve events", http.StatusInternalServerError)
+		writeProblem(w, r, fmt.Errorf("%w: missing file", errInvalidBody))
+		return
+	}
+	defer file.Close()
+
+	var mapping domain.EventImportMapping
+	if v := r.FormValue("mapping"); v != "" {
+		if err := json.Unmarshal([]byte(v), &mapping); err != nil {
+			writeProblem(w, r, fmt.Errorf("%w: mapping must be a JSON object of field to column", errInvalidBody))
+			return
+		}
+	}
+
+	loc, err := h.settingsService.TimeZone(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	result, err := h.importService.ImportEvents(r.Context(), &caller, file, mapping, loc, dryRun)
+	if err != nil {
+		writeProblem(w, r, err)
 		return
 	}
+	localizeRowErrors(r, result.Errors)
 
+	status := http.StatusOK
+	if !dryRun {
+		status = http.StatusCreated
+		if len(result.Errors) > 0 {
+			status = http.StatusUnprocessableEntity
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(events)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
 }
 
-func (h *EventHandlers) GetAllDatedEvents(w http.ResponseWriter, r *http.Request) {
+// localizeRowErrors fills in the code and title of import row errors the way
+// writeProblem does for a whole response, the context the errors were wrapped
+// with is logged
+func localizeRowErrors(r *http.Request, rowErrors []domain.EventImportRowError) {
+	locales := acceptLanguages(r)
+	for i := range rowErrors {
+		domainErr := clientError(r, rowErrors[i].Err)
+		rowErrors[i].Code = domainErr.Code
+		rowErrors[i].Title = localizedMessage(domainErr, locales)
+	}
+}
+
+// GetDatedUserEvents lists one user's events, see parseEventFilter for the query parameters
+func (h *EventHandlers) GetDatedUserEvents(w http.ResponseWriter, r *http.Request) {
 	caller, err := ExtractUserFromRequest(r)
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeProblem(w, r, errUnauthorized)
 		return
 	}
 
-	startDateStr := r.URL.Query().Get("startdate")
-	endDateStr := r.URL.Query().Get("enddate")
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
 
-	if startDateStr == "" || endDateStr == "" {
-		http.Error(w, "Missing startdate or enddate", http.StatusBadRequest)
+	loc, err := h.settingsService.TimeZone(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
 		return
 	}
 
-	// ISO 8601 formatını parse et (örnek: "2025-03-01")
-	startDate, err := time.Parse("2006-01-02", startDateStr)
+	filter, err := parseEventFilter(r, loc)
 	if err != nil {
-		http.Error(w, "Invalid startdate format. Use YYYY-MM-DD", http.StatusBadRequest)
+		writeProblem(w, r, err)
 		return
 	}
-	endDate, err := time.Parse("2006-01-02", endDateStr)
+
+	events, err := h.eventService.GetDatedUserEvents(r.Context(), &caller, userID, filter)
 	if err != nil {
-		http.Error(w, "Invalid enddate format. Use YYYY-MM-DD", http.StatusBadRequest)
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// GetAllDatedEvents lists every user's events, see parseEventFilter for the query parameters
+func (h *EventHandlers) GetAllDatedEvents(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	loc, err := h.settingsService.TimeZone(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
 		return
 	}
 
-	events, err := h.eventService.GetAllDatedEvents(&caller, startDate, endDate)
+	filter, err := parseEventFilter(r, loc)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProblem(w, r, err)
+		return
+	}
+
+	events, err := h.eventService.GetAllDatedEvents(r.Context(), &caller, filter)
+	if err != nil {
+		writeProblem(w, r, err)
 		return
 	}
 
@@ -247,49 +400,60 @@ func (h *EventHandlers) GetAllDatedEvents(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(events)
 }
 
+// GetSelfDatedEvents lists the caller's events, see parseEventFilter for the query parameters
 func (h *EventHandlers) GetSelfDatedEvents(w http.ResponseWriter, r *http.Request) {
-	startDateStr := r.URL.Query().Get("startdate")
-	endDateStr := r.URL.Query().Get("enddate")
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
 
-	if startDateStr == "" || endDateStr == "" {
-		http.Error(w, "Missing startdate or enddate", http.StatusBadRequest)
+	loc, err := h.settingsService.TimeZone(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
 		return
 	}
 
-	// ISO 8601 formatını parse et (örnek: "2025-03-01")
-	startDate, err := time.Parse("2006-01-02", startDateStr)
+	filter, err := parseEventFilter(r, loc)
 	if err != nil {
-		http.Error(w, "Invalid startdate format. Use YYYY-MM-DD", http.StatusBadRequest)
+		writeProblem(w, r, err)
 		return
 	}
-	endDate, err := time.Parse("2006-01-02", endDateStr)
+
+	events, err := h.eventService.GetSelfDatedEvents(r.Context(), &caller, filter)
 	if err != nil {
-		http.Error(w, "Invalid enddate format. Use YYYY-MM-DD", http.StatusBadRequest)
+		writeProblem(w, r, err)
 		return
 	}
 
-	var caller domain.User
-	tokenString := r.Header.Get("Authorization")
-	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-		tokenString = tokenString[7:]
-	}
-	if tokenString != "" {
-		token, err := ParseJWT(tokenString)
-		if err == nil && token.Valid {
-			if claims, ok := token.Claims.(jwt.MapClaims); ok {
-				if idVal, ok := claims["user_id"].(float64); ok {
-					caller.ID = int(idVal)
-				}
-				if isAdmin, ok := claims["is_admin"].(bool); ok {
-					caller.IsAdmin = isAdmin
-				}
-			}
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// GetTeamDatedEvents lists the events of everybody reporting to the caller,
+// see parseEventFilter for the query parameters
+func (h *EventHandlers) GetTeamDatedEvents(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
 	}
 
-	events, err := h.eventService.GetSelfDatedEvents(&caller, startDate, endDate)
+	loc, err := h.settingsService.TimeZone(r.Context(), &caller)
 	if err != nil {
-		http.Error(w, "Failed to retrieve events", http.StatusInternalServerError)
+		writeProblem(w, r, err)
+		return
+	}
+
+	filter, err := parseEventFilter(r, loc)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	events, err := h.eventService.GetTeamDatedEvents(r.Context(), &caller, filter)
+	if err != nil {
+		writeProblem(w, r, err)
 		return
 	}
 
@@ -297,10 +461,50 @@ func (h *EventHandlers) GetSelfDatedEvents(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(events)
 }
 
+// SearchEvents runs a full-text search, q is required and the listing filters
+// apply with an optional date range. Snippets follow the Accept-Language header.
+func (h *EventHandlers) SearchEvents(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	loc, err := h.settingsService.TimeZone(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	filter, err := parseEventSearchFilter(r, loc)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	results, err := h.eventService.SearchEvents(r.Context(), &caller, filter, acceptLanguages(r))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// GetEventTypes lists event types labelled for the Accept-Language header.
+// Admins can pass include_archived=true to see archived types as well.
 func (h *EventHandlers) GetEventTypes(w http.ResponseWriter, r *http.Request) {
-	types, err := h.eventService.GetEventTypes()
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	includeArchived, _ := strconv.ParseBool(r.URL.Query().Get("include_archived"))
+	types, err := h.eventService.GetEventTypes(r.Context(), &caller, acceptLanguages(r), includeArchived)
 	if err != nil {
-		http.Error(w, "Failed to retrieve event types", http.StatusInternalServerError)
+		writeProblem(w, r, err)
 		return
 	}
 
@@ -308,6 +512,113 @@ func (h *EventHandlers) GetEventTypes(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(types)
 }
 
+// CreateEventType adds a new event type
+func (h *EventHandlers) CreateEventType(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	var eventType domain.EventType
+	if err := json.NewDecoder(r.Body).Decode(&eventType); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+
+	if err := h.eventService.CreateEventType(r.Context(), &caller, &eventType); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(eventType)
+}
+
+// UpdateEventType replaces an event type and its translations
+func (h *EventHandlers) UpdateEventType(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	typeID, err := strconv.Atoi(chi.URLParam(r, "typeID"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	var eventType domain.EventType
+	if err := json.NewDecoder(r.Body).Decode(&eventType); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+	eventType.ID = typeID
+
+	if err := h.eventService.UpdateEventType(r.Context(), &caller, &eventType); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(eventType)
+}
+
+func (h *EventHandlers) ArchiveEventType(w http.ResponseWriter, r *http.Request) {
+	h.setEventTypeArchived(w, r, true)
+}
+
+func (h *EventHandlers) RestoreEventType(w http.ResponseWriter, r *http.Request) {
+	h.setEventTypeArchived(w, r, false)
+}
+
+func (h *EventHandlers) setEventTypeArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	typeID, err := strconv.Atoi(chi.URLParam(r, "typeID"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	if err := h.eventService.SetEventTypeArchived(r.Context(), &caller, typeID, archived); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReorderEventTypes sets the display order from a list of event type IDs
+func (h *EventHandlers) ReorderEventTypes(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	var req struct {
+		IDs []int `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+
+	if err := h.eventService.ReorderEventTypes(r.Context(), &caller, req.IDs); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 /*func (h *EventHandlers) GetSelfDatedEvents(w http.ResponseWriter, r *http.Request) {
 	startDateStr := r.URL.Query().Get("startdate")
 	endDateStr := r.URL.Query().Get("enddate")
@@ -353,7 +664,7 @@ func (h *EventHandlers) GetEventTypes(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	events, err := h.eventService.GetSelfDatedEvents(&caller, startDate, endDate)
+	events, err := h.eventService.GetSelfDatedEvents(r.Context(), &caller, startDate, endDate)
 	if err != nil {
 		http.Error(w, "Failed to retrieve events", http.StatusInternalServerError)
 		return
@@ -0,0 +1,737 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"pwp-remastered/internal/blob"
+	"pwp-remastered/internal/config"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/live"
+	"pwp-remastered/internal/services"
+	"pwp-remastered/internal/store"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Users of the test tenant 1: the owner of event 10, the owner's manager, a
+// colleague and an admin. User 5 is an admin of tenant 2.
+var (
+	testOwner      = domain.User{ID: 1, Username: "owner", TenantID: 1, ManagerID: intPtr(2)}
+	testManager    = domain.User{ID: 2, Username: "manager", TenantID: 1}
+	testColleague  = domain.User{ID: 3, Username: "colleague", TenantID: 1}
+	testAdmin      = domain.User{ID: 4, Username: "admin", TenantID: 1, IsAdmin: true}
+	testOtherAdmin = domain.User{ID: 5, Username: "other", TenantID: 2, IsAdmin: true}
+)
+
+// testTokens signs the tokens doRequest sends, the test routers verify them
+var testTokens = NewTokenSigner(config.Auth{JWTSecret: "test-secret", TokenLifetime: time.Hour})
+
+// lockedDate falls in the closed period of fakePeriodStore
+var lockedDate = time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+func intPtr(v int) *int { return &v }
+
+var (
+	firstTenant  = 1
+	secondTenant = 2
+	// eventTypes holds a type of each tenant, a shared type and an archived one
+	eventTypes = map[int]domain.EventType{
+		1: {ID: 1, TenantID: &firstTenant, Type: "Yol"},
+		2: {ID: 2, Type: "Otopark"},
+		3: {ID: 3, TenantID: &secondTenant, Type: "Konaklama"},
+		5: {ID: 5, TenantID: &firstTenant, Type: "Taksi", IsArchived: true},
+	}
+)
+
+type fakeEventStore struct {
+	store.EventStore
+	events   map[int]domain.Event
+	filter   domain.EventFilter
+	imported []domain.Event
+	// listed is the page ListEvents answers with
+	listed []domain.Event
+}
+
+func (s *fakeEventStore) GetEvent(ctx context.Context, id int) (*domain.Event, error) {
+	event, ok := s.events[id]
+	if !ok {
+		return nil, store.ErrEventNotFound
+	}
+	return &event, nil
+}
+
+func (s *fakeEventStore) CreateEvent(ctx context.Context, event *domain.Event, caller *domain.User) error {
+	if err := checkJanuaryOpen(event); err != nil {
+		return err
+	}
+	event.ID = 11
+	return nil
+}
+
+func (s *fakeEventStore) ImportEvents(ctx context.Context, events []domain.Event) error {
+	for i := range events {
+		if err := checkJanuaryOpen(&events[i]); err != nil {
+			return err
+		}
+		events[i].ID = 20 + i
+	}
+	s.imported = events
+	return nil
+}
+
+func (s *fakeEventStore) UpdateEvent(ctx context.Context, event *domain.Event, caller *domain.User) error {
+	existing := s.events[event.ID]
+	if err := checkJanuaryOpen(&existing); err != nil {
+		return err
+	}
+	return checkJanuaryOpen(event)
+}
+
+func (s *fakeEventStore) DeleteEvent(ctx context.Context, id int) error {
+	event := s.events[id]
+	if err := checkJanuaryOpen(&event); err != nil {
+		return err
+	}
+	delete(s.events, id)
+	return nil
+}
+
+func (s *fakeEventStore) ApproveEvent(ctx context.Context, id int, approverID int) (*domain.Event, error) {
+	event := s.events[id]
+	if err := checkJanuaryOpen(&event); err != nil {
+		return nil, err
+	}
+	event.Status = domain.EventApproved
+	event.ApprovedBy = &approverID
+	s.events[id] = event
+	return &event, nil
+}
+
+func (s *fakeEventStore) ListEvents(ctx context.Context, filter domain.EventFilter) ([]domain.Event, *domain.PageInfo, error) {
+	s.filter = filter
+	if s.listed == nil {
+		return []domain.Event{}, &domain.PageInfo{}, nil
+	}
+	return s.listed, &domain.PageInfo{}, nil
+}
+
+func (s *fakeEventStore) SearchEvents(ctx context.Context, filter domain.EventFilter, config string) ([]domain.EventSearchResult, *domain.PageInfo, error) {
+	s.filter = filter
+	return []domain.EventSearchResult{}, &domain.PageInfo{}, nil
+}
+
+func (s *fakeEventStore) GetEventType(ctx context.Context, tenantID int, id int) (*domain.EventType, error) {
+	eventType, ok := eventTypes[id]
+	if !ok || (eventType.TenantID != nil && *eventType.TenantID != tenantID) {
+		return nil, store.ErrEventTypeNotFound
+	}
+	return &eventType, nil
+}
+
+func (s *fakeEventStore) GetEventTypes(ctx context.Context, tenantID int, includeArchived bool) ([]domain.EventType, error) {
+	return []domain.EventType{eventTypes[1]}, nil
+}
+
+func (s *fakeEventStore) CreateEventType(ctx context.Context, eventType *domain.EventType) error {
+	return nil
+}
+func (s *fakeEventStore) UpdateEventType(ctx context.Context, eventType *domain.EventType) error {
+	return nil
+}
+func (s *fakeEventStore) SetEventTypeArchived(ctx context.Context, tenantID int, id int, archived bool) error {
+	return nil
+}
+
+// ReorderEventTypes checks ids against the tenant's own types like the database store
+func (s *fakeEventStore) ReorderEventTypes(ctx context.Context, tenantID int, ids []int) error {
+	var own []int
+	for id, eventType := range eventTypes {
+		if eventType.TenantID != nil && *eventType.TenantID == tenantID {
+			own = append(own, id)
+		}
+	}
+	slices.Sort(own)
+	if !slices.Equal(slices.Sorted(slices.Values(ids)), own) {
+		return store.ErrInvalidEventTypeOrder
+	}
+	return nil
+}
+
+type fakeUserStore struct {
+	store.UserStore
+	users map[int]domain.User
+}
+
+func (s *fakeUserStore) GetUser(ctx context.Context, id int) (*domain.User, error) {
+	user, ok := s.users[id]
+	if !ok {
+		return nil, store.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (s *fakeUserStore) GetUserByUsername(ctx context.Context, tenantID int, username string) (*domain.User, error) {
+	for _, user := range s.users {
+		if user.TenantID == tenantID && user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeUserStore) IsManagerOf(ctx context.Context, managerID int, userID int) (bool, error) {
+	user, ok := s.users[userID]
+	return ok && user.ManagerID != nil && *user.ManagerID == managerID, nil
+}
+
+type fakeSettingsStore struct {
+	store.SettingsStore
+}
+
+func (s *fakeSettingsStore) EffectiveTimeZone(ctx context.Context, userID int) (string, error) {
+	return "Europe/Istanbul", nil
+}
+
+// fakePeriodStore has January 2024 closed
+type fakePeriodStore struct {
+	store.PeriodStore
+}
+
+func (s *fakePeriodStore) ClosedPeriodOverlapping(ctx context.Context, tenantID int, start time.Time, end time.Time) (*domain.AccountingPeriod, error) {
+	closed := domain.AccountingPeriod{ID: 1, StartDate: "2024-01-01", EndDate: "2024-01-31", Status: domain.PeriodClosed}
+	if start.Before(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) && !end.Before(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		return &closed, nil
+	}
+	return nil, nil
+}
+
+// checkJanuaryOpen refuses to write an event in the closed period of
+// fakePeriodStore, as the event store does
+func checkJanuaryOpen(event *domain.Event) error {
+	period, _ := (&fakePeriodStore{}).ClosedPeriodOverlapping(context.Background(), 1, event.StartDate, event.EndDate)
+	if period != nil {
+		return store.PeriodClosed(period)
+	}
+	return nil
+}
+
+// fakeBudgetStore has no budgets
+type fakeBudgetStore struct {
+	store.BudgetStore
+}
+
+func (s *fakeBudgetStore) ListBudgetsFor(ctx context.Context, tenantID int, userID int, typeID int) ([]domain.Budget, error) {
+	return nil, nil
+}
+
+type fakeVehicleStore struct {
+	store.VehicleStore
+}
+
+type fakeAttachmentStore struct {
+	store.AttachmentStore
+	events *fakeEventStore
+}
+
+func (s *fakeAttachmentStore) ListEventAttachments(ctx context.Context, eventID int) ([]domain.Attachment, error) {
+	return []domain.Attachment{}, nil
+}
+
+// receipt is attachment 2 of event 10, the only stored one. lockedReceipt is
+// recorded on event 14 of the closed period, its contents are not stored.
+var (
+	receipt       = domain.Attachment{ID: 2, EventID: 10, FileName: "Öğle yemeği fişi.pdf", ContentType: "application/pdf", Size: 7, StorageKey: "events/10/receipt"}
+	lockedReceipt = domain.Attachment{ID: 3, EventID: 14, FileName: "Taksi.pdf", ContentType: "application/pdf", Size: 7, StorageKey: "events/14/receipt"}
+)
+
+func (s *fakeAttachmentStore) GetAttachment(ctx context.Context, eventID int, id int) (*domain.Attachment, error) {
+	for _, attachment := range []*domain.Attachment{&receipt, &lockedReceipt} {
+		if eventID == attachment.EventID && id == attachment.ID {
+			return attachment, nil
+		}
+	}
+	return nil, store.ErrAttachmentNotFound
+}
+
+// CreateAttachment and DeleteAttachment refuse events of the closed period,
+// as the attachment store does
+func (s *fakeAttachmentStore) CreateAttachment(ctx context.Context, attachment *domain.Attachment) error {
+	event := s.events.events[attachment.EventID]
+	if err := checkJanuaryOpen(&event); err != nil {
+		return err
+	}
+	attachment.ID = 4
+	return nil
+}
+
+func (s *fakeAttachmentStore) DeleteAttachment(ctx context.Context, eventID int, id int) error {
+	event := s.events.events[eventID]
+	return checkJanuaryOpen(&event)
+}
+
+func newEventTestRouter(t *testing.T) (http.Handler, *fakeEventStore, *live.MemoryBroker) {
+	t.Helper()
+	return newShutdownEventTestRouter(t, t.Context())
+}
+
+// newShutdownEventTestRouter is newEventTestRouter with its event streams
+// ending when shutdown is done
+func newShutdownEventTestRouter(t *testing.T, shutdown context.Context) (http.Handler, *fakeEventStore, *live.MemoryBroker) {
+	t.Helper()
+	events := &fakeEventStore{events: map[int]domain.Event{
+		10: {ID: 10, TypeID: 1, UserID: testOwner.ID, Status: domain.EventPending, User: &domain.EventUser{ID: testOwner.ID, TenantID: testOwner.TenantID}},
+		14: {ID: 14, TypeID: 1, UserID: testOwner.ID, StartDate: lockedDate, EndDate: lockedDate, User: &domain.EventUser{ID: testOwner.ID, TenantID: testOwner.TenantID}},
+		12: {ID: 12, TypeID: 1, UserID: testOwner.ID, Status: domain.EventApproved, User: &domain.EventUser{ID: testOwner.ID, TenantID: testOwner.TenantID}},
+	}}
+	users := &fakeUserStore{users: map[int]domain.User{}}
+	for _, user := range []domain.User{testOwner, testManager, testColleague, testAdmin, testOtherAdmin} {
+		users.users[user.ID] = user
+	}
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blobs.Put(context.Background(), receipt.StorageKey, strings.NewReader("receipt"), receipt.Size, receipt.ContentType); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := services.NewEventPolicy(users)
+	broker := live.NewMemoryBroker(10)
+	budgets := services.NewBudgetService(&fakeBudgetStore{}, events, &fakeSettingsStore{}, users, nil, policy)
+	eventService := services.NewEventService(events, nil, nil, &fakeVehicleStore{}, &fakePeriodStore{}, policy, broker, budgets)
+	settingsService := services.NewSettingsService(&fakeSettingsStore{})
+	attachmentService := services.NewAttachmentService(&fakeAttachmentStore{events: events}, events, blobs, services.DefaultAttachmentLimits, policy)
+
+	r := chi.NewRouter()
+	r.Use(testTokens.Authenticate)
+	NewEventHandlers(*eventService, settingsService, services.NewEventImportService(eventService, users), shutdown).RegisterRoutes(r)
+	NewAttachmentHandlers(attachmentService).RegisterRoutes(r)
+	return r, events, broker
+}
+
+func doRequest(t *testing.T, h http.Handler, caller *domain.User, method string, target string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if caller != nil {
+		token, err := testTokens.Sign(caller)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestEventRoutes(t *testing.T) {
+	const dates = "startdate=2025-01-01&enddate=2025-01-31"
+	const eventBody = `{"type_id":1,"user_id":3,"title":"Ziyaret","start_date":"2025-01-02T09:00:00Z","end_date":"2025-01-02T10:00:00Z"}`
+	const lockedBody = `{"type_id":1,"title":"Ziyaret","start_date":"2024-01-10T09:00:00Z","end_date":"2024-01-10T10:00:00Z"}`
+	const typeBody = `{"type":"Otopark","translations":{"en":"Parking"}}`
+
+	tests := []struct {
+		name       string
+		caller     *domain.User
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{"no token", nil, http.MethodGet, "/events/10", "", http.StatusUnauthorized},
+
+		{"owner reads", &testOwner, http.MethodGet, "/events/10", "", http.StatusOK},
+		{"manager reads", &testManager, http.MethodGet, "/events/10", "", http.StatusOK},
+		{"tenant admin reads", &testAdmin, http.MethodGet, "/events/10", "", http.StatusOK},
+		{"colleague cannot read", &testColleague, http.MethodGet, "/events/10", "", http.StatusForbidden},
+		{"other tenant admin cannot read", &testOtherAdmin, http.MethodGet, "/events/10", "", http.StatusForbidden},
+		{"missing event", &testOwner, http.MethodGet, "/events/404", "", http.StatusNotFound},
+		{"invalid id", &testOwner, http.MethodGet, "/events/abc", "", http.StatusBadRequest},
+
+		{"create", &testColleague, http.MethodPost, "/events/", eventBody, http.StatusCreated},
+		{"create invalid body", &testColleague, http.MethodPost, "/events/", "{", http.StatusBadRequest},
+
+		{"owner updates", &testOwner, http.MethodPut, "/events/10", eventBody, http.StatusOK},
+		{"tenant admin updates", &testAdmin, http.MethodPut, "/events/10", eventBody, http.StatusOK},
+		{"manager updates", &testManager, http.MethodPut, "/events/10", eventBody, http.StatusOK},
+		{"colleague cannot update", &testColleague, http.MethodPut, "/events/10", eventBody, http.StatusForbidden},
+		{"other tenant admin cannot update", &testOtherAdmin, http.MethodPut, "/events/10", eventBody, http.StatusForbidden},
+		{"update missing event", &testOwner, http.MethodPut, "/events/404", eventBody, http.StatusNotFound},
+
+		{"manager deletes", &testManager, http.MethodDelete, "/events/10", "", http.StatusNoContent},
+		{"colleague cannot delete", &testColleague, http.MethodDelete, "/events/10", "", http.StatusForbidden},
+		{"other tenant admin cannot delete", &testOtherAdmin, http.MethodDelete, "/events/10", "", http.StatusForbidden},
+		{"owner deletes", &testOwner, http.MethodDelete, "/events/10", "", http.StatusNoContent},
+		{"tenant admin deletes", &testAdmin, http.MethodDelete, "/events/10", "", http.StatusNoContent},
+		{"delete missing event", &testOwner, http.MethodDelete, "/events/404", "", http.StatusNotFound},
+
+		{"owner cannot approve", &testOwner, http.MethodPost, "/events/10/approve", "", http.StatusForbidden},
+		{"manager approves", &testManager, http.MethodPost, "/events/10/approve", "", http.StatusOK},
+		{"tenant admin approves", &testAdmin, http.MethodPost, "/events/10/approve", "", http.StatusOK},
+		{"colleague cannot approve", &testColleague, http.MethodPost, "/events/10/approve", "", http.StatusForbidden},
+		{"other tenant admin cannot approve", &testOtherAdmin, http.MethodPost, "/events/10/approve", "", http.StatusForbidden},
+		{"approve approved event", &testManager, http.MethodPost, "/events/12/approve", "", http.StatusConflict},
+		{"approve missing event", &testManager, http.MethodPost, "/events/404/approve", "", http.StatusNotFound},
+
+		{"create in closed period", &testOwner, http.MethodPost, "/events/", lockedBody, http.StatusConflict},
+		{"update in closed period", &testOwner, http.MethodPut, "/events/14", eventBody, http.StatusConflict},
+		{"move onto archived type", &testOwner, http.MethodPut, "/events/10", `{"type_id":5,"title":"Ziyaret"}`, http.StatusConflict},
+		{"move onto other tenant's type", &testOwner, http.MethodPut, "/events/10", `{"type_id":3,"title":"Ziyaret"}`, http.StatusNotFound},
+		{"create with shared type", &testOwner, http.MethodPost, "/events/", `{"type_id":2,"title":"Ziyaret","start_date":"2025-01-02T09:00:00Z","end_date":"2025-01-02T10:00:00Z"}`, http.StatusCreated},
+		{"move into closed period", &testOwner, http.MethodPut, "/events/10", lockedBody, http.StatusConflict},
+		{"delete in closed period", &testAdmin, http.MethodDelete, "/events/14", "", http.StatusConflict},
+		{"approve in closed period", &testManager, http.MethodPost, "/events/14/approve", "", http.StatusConflict},
+		{"read in closed period", &testOwner, http.MethodGet, "/events/14", "", http.StatusOK},
+		{"delete attachment in closed period", &testOwner, http.MethodDelete, "/events/14/attachments/3", "", http.StatusConflict},
+
+		{"own dated events", &testOwner, http.MethodGet, "/events/dated/me?" + dates, "", http.StatusOK},
+		{"dated events without range", &testOwner, http.MethodGet, "/events/dated/me", "", http.StatusBadRequest},
+		{"user lists own events", &testOwner, http.MethodGet, "/events/dated/1?" + dates, "", http.StatusOK},
+		{"manager lists report's events", &testManager, http.MethodGet, "/events/dated/1?" + dates, "", http.StatusOK},
+		{"tenant admin lists user's events", &testAdmin, http.MethodGet, "/events/dated/1?" + dates, "", http.StatusOK},
+		{"colleague cannot list user's events", &testColleague, http.MethodGet, "/events/dated/1?" + dates, "", http.StatusForbidden},
+		{"other tenant admin cannot list user's events", &testOtherAdmin, http.MethodGet, "/events/dated/1?" + dates, "", http.StatusForbidden},
+		{"admin lists all events", &testAdmin, http.MethodGet, "/events/dated?" + dates, "", http.StatusOK},
+		{"user cannot list all events", &testOwner, http.MethodGet, "/events/dated?" + dates, "", http.StatusForbidden},
+		{"team events", &testManager, http.MethodGet, "/events/dated/team?" + dates, "", http.StatusOK},
+		{"team events with invalid team", &testManager, http.MethodGet, "/events/dated/team?team_id=x&" + dates, "", http.StatusBadRequest},
+
+		{"search", &testOwner, http.MethodGet, "/events/search?q=ziyaret", "", http.StatusOK},
+		{"search without query", &testOwner, http.MethodGet, "/events/search", "", http.StatusBadRequest},
+
+		{"list types", &testOwner, http.MethodGet, "/events/types", "", http.StatusOK},
+		{"admin creates type", &testAdmin, http.MethodPost, "/events/types", typeBody, http.StatusCreated},
+		{"user cannot create type", &testOwner, http.MethodPost, "/events/types", typeBody, http.StatusForbidden},
+		{"admin updates type", &testAdmin, http.MethodPut, "/events/types/1", typeBody, http.StatusOK},
+		{"user cannot update type", &testOwner, http.MethodPut, "/events/types/1", typeBody, http.StatusForbidden},
+		{"admin cannot update shared type", &testAdmin, http.MethodPut, "/events/types/2", typeBody, http.StatusForbidden},
+		{"admin cannot update other tenant's type", &testAdmin, http.MethodPut, "/events/types/3", typeBody, http.StatusNotFound},
+		{"admin reorders types", &testAdmin, http.MethodPut, "/events/types/order", `{"ids":[5,1]}`, http.StatusNoContent},
+		{"reorder missing a type", &testAdmin, http.MethodPut, "/events/types/order", `{"ids":[1]}`, http.StatusBadRequest},
+		{"reorder repeating a type", &testAdmin, http.MethodPut, "/events/types/order", `{"ids":[1,1,5]}`, http.StatusBadRequest},
+		{"reorder with shared type", &testAdmin, http.MethodPut, "/events/types/order", `{"ids":[1,2,5]}`, http.StatusBadRequest},
+		{"reorder with other tenant's type", &testAdmin, http.MethodPut, "/events/types/order", `{"ids":[1,3,5]}`, http.StatusBadRequest},
+		{"user cannot reorder types", &testOwner, http.MethodPut, "/events/types/order", `{"ids":[1]}`, http.StatusForbidden},
+		{"admin archives type", &testAdmin, http.MethodPost, "/events/types/1/archive", "", http.StatusNoContent},
+		{"user cannot archive type", &testOwner, http.MethodPost, "/events/types/1/archive", "", http.StatusForbidden},
+		{"admin restores type", &testAdmin, http.MethodPost, "/events/types/1/restore", "", http.StatusNoContent},
+		{"admin cannot archive shared type", &testAdmin, http.MethodPost, "/events/types/2/archive", "", http.StatusForbidden},
+		{"user cannot restore type", &testOwner, http.MethodPost, "/events/types/1/restore", "", http.StatusForbidden},
+
+		{"owner lists attachments", &testOwner, http.MethodGet, "/events/10/attachments/", "", http.StatusOK},
+		{"manager lists attachments", &testManager, http.MethodGet, "/events/10/attachments/", "", http.StatusOK},
+		{"colleague cannot list attachments", &testColleague, http.MethodGet, "/events/10/attachments/", "", http.StatusForbidden},
+		{"manager reads missing attachment", &testManager, http.MethodGet, "/events/10/attachments/1", "", http.StatusNotFound},
+		{"manager deletes missing attachment", &testManager, http.MethodDelete, "/events/10/attachments/1", "", http.StatusNotFound},
+		{"colleague cannot delete attachment", &testColleague, http.MethodDelete, "/events/10/attachments/1", "", http.StatusForbidden},
+		{"tenant admin deletes missing attachment", &testAdmin, http.MethodDelete, "/events/10/attachments/1", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _, _ := newEventTestRouter(t)
+			w := doRequest(t, h, tt.caller, tt.method, tt.target, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Errorf("%s %s = %d, want %d: %s", tt.method, tt.target, w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
+
+func TestAttachmentUploadPeriods(t *testing.T) {
+	h, _, _ := newEventTestRouter(t)
+	upload := func(eventID int) *httptest.ResponseRecorder {
+		t.Helper()
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", "fiş.pdf")
+		part.Write([]byte("%PDF-1.7\n"))
+		form.Close()
+
+		r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/events/%d/attachments/", eventID), &body)
+		r.Header.Set("Content-Type", form.FormDataContentType())
+		token, err := testTokens.Sign(&testOwner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := upload(10); w.Code != http.StatusCreated {
+		t.Errorf("open period: status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	w := upload(14)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("closed period: status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `"period_id":1`) || !strings.Contains(body, `"period_start":"2024-01-01"`) {
+		t.Errorf("closed period not described: %s", body)
+	}
+}
+
+func TestUpdateEventKeepsOwner(t *testing.T) {
+	for _, caller := range []*domain.User{&testAdmin, &testManager} {
+		t.Run(caller.Username, func(t *testing.T) {
+			h, _, _ := newEventTestRouter(t)
+			w := doRequest(t, h, caller, http.MethodPut, "/events/10", `{"type_id":1,"user_id":4,"title":"Ziyaret"}`)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), `"user_id":1`) {
+				t.Errorf("owner changed: %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestDownloadAttachmentFileName(t *testing.T) {
+	h, _, _ := newEventTestRouter(t)
+	w := doRequest(t, h, &testOwner, http.MethodGet, "/events/10/attachments/2", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	disposition := w.Header().Get("Content-Disposition")
+	for _, c := range disposition {
+		if c > unicode.MaxASCII {
+			t.Fatalf("Content-Disposition is not ASCII: %s", disposition)
+		}
+	}
+	_, params, err := mime.ParseMediaType(disposition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params["filename"] != receipt.FileName {
+		t.Errorf("filename = %q, want %q", params["filename"], receipt.FileName)
+	}
+}
+
+func TestEventListingsAreScopedToCaller(t *testing.T) {
+	const dates = "startdate=2025-01-01&enddate=2025-01-31"
+	tests := []struct {
+		name        string
+		caller      *domain.User
+		target      string
+		wantTenant  int
+		wantUsers   []int
+		wantManager int
+		wantVisible int
+	}{
+		{"admin listing", &testAdmin, "/events/dated?" + dates, 1, nil, 0, 0},
+		{"admin search", &testAdmin, "/events/search?q=yol", 1, nil, 0, 0},
+		{"user search", &testOwner, "/events/search?q=yol", 0, nil, 0, 1},
+		{"manager search", &testManager, "/events/search?q=yol", 0, nil, 0, 2},
+		{"own listing", &testOwner, "/events/dated/me?" + dates, 0, []int{1}, 0, 0},
+		{"manager listing", &testManager, "/events/dated/1?" + dates, 0, []int{1}, 0, 0},
+		{"team listing", &testManager, "/events/dated/team?team_id=3&" + dates, 0, nil, 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, events, _ := newEventTestRouter(t)
+			w := doRequest(t, h, tt.caller, http.MethodGet, tt.target, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			if events.filter.TenantID != tt.wantTenant {
+				t.Errorf("TenantID = %d, want %d", events.filter.TenantID, tt.wantTenant)
+			}
+			if len(events.filter.UserIDs) != len(tt.wantUsers) || (len(tt.wantUsers) > 0 && events.filter.UserIDs[0] != tt.wantUsers[0]) {
+				t.Errorf("UserIDs = %v, want %v", events.filter.UserIDs, tt.wantUsers)
+			}
+			if events.filter.ManagerID != tt.wantManager {
+				t.Errorf("ManagerID = %d, want %d", events.filter.ManagerID, tt.wantManager)
+			}
+			if events.filter.VisibleTo != tt.wantVisible {
+				t.Errorf("VisibleTo = %d, want %d", events.filter.VisibleTo, tt.wantVisible)
+			}
+		})
+	}
+}
+
+// readStreamEvent returns the id, event and data fields of the next message of
+// an SSE stream, skipping comments and the retry hint
+func readStreamEvent(t *testing.T, r *bufio.Reader) (string, string, string) {
+	t.Helper()
+	fields := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if fields["data"] != "" {
+				return fields["id"], fields["event"], fields["data"]
+			}
+			fields = map[string]string{}
+			continue
+		}
+		if name, value, ok := strings.Cut(line, ": "); ok {
+			fields[name] = value
+		}
+	}
+}
+
+func TestImportEvents(t *testing.T) {
+	h, events, _ := newEventTestRouter(t)
+
+	upload := func(caller *domain.User, query string, csv string, mapping string) *httptest.ResponseRecorder {
+		t.Helper()
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", "events.csv")
+		part.Write([]byte(csv))
+		if mapping != "" {
+			form.WriteField("mapping", mapping)
+		}
+		form.Close()
+
+		r := httptest.NewRequest(http.MethodPost, "/events/import"+query, &body)
+		r.Header.Set("Content-Type", form.FormDataContentType())
+		r.Header.Set("Accept-Language", "tr")
+		token, err := testTokens.Sign(caller)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	const valid = "Kullanıcı,Tür,Başlangıç,Bitiş\nowner,Yol,2025-01-02 09:00,2025-01-02 10:00\n"
+	const mapping = `{"username":"Kullanıcı","type":"Tür","start_date":"Başlangıç","end_date":"Bitiş"}`
+	const invalid = "username,type,start_date,end_date\nowner,Yol,2025-01-02,2025-01-02\nother,Yol,2025-01-02,2025-01-02\n"
+
+	if w := upload(&testOwner, "?dry_run=true", valid, mapping); w.Code != http.StatusForbidden {
+		t.Errorf("owner: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := upload(&testAdmin, "?dry_run=maybe", valid, mapping); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid dry_run: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := upload(&testAdmin, "?dry_run=true", valid, `{"vehicle":"Araç"}`); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_import") {
+		t.Errorf("unknown field: status = %d: %s", w.Code, w.Body)
+	}
+
+	w := upload(&testAdmin, "?dry_run=true", valid, mapping)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"dry_run":true`) || events.imported != nil {
+		t.Errorf("dry run: status = %d: %s", w.Code, w.Body)
+	}
+
+	// user 5 belongs to another tenant, so nothing is saved
+	w = upload(&testAdmin, "", invalid, "")
+	if w.Code != http.StatusUnprocessableEntity || events.imported != nil {
+		t.Errorf("invalid rows: status = %d: %s", w.Code, w.Body)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `"row":3`) || !strings.Contains(body, `"code":"user_not_found"`) || !strings.Contains(body, "Kullanıcı bulunamadı") {
+		t.Errorf("invalid rows: body = %s", body)
+	}
+
+	w = upload(&testAdmin, "", valid, mapping)
+	if w.Code != http.StatusCreated || len(events.imported) != 1 || events.imported[0].UserID != testOwner.ID {
+		t.Errorf("commit: status = %d, imported %+v: %s", w.Code, events.imported, w.Body)
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	h, _, broker := newEventTestRouter(t)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	publish := func(tenantID int, userID int) {
+		broker.Publish(t.Context(), live.Message{Type: domain.WebhookEventCreated, TenantID: tenantID, UserID: userID, ObjectID: 20, Data: []byte(`{"id":20}`)})
+	}
+	publish(testOwner.TenantID, testOwner.ID)           // 1, seen before disconnecting
+	publish(testColleague.TenantID, testColleague.ID)   // 2, not managed by the manager
+	publish(testOtherAdmin.TenantID, testOtherAdmin.ID) // 3, other tenant
+	publish(testOwner.TenantID, testOwner.ID)           // 4
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream := func(caller *domain.User, lastEventID string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events/stream", nil)
+		token, err := testTokens.Sign(caller)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Last-Event-ID", lastEventID)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := stream(&testManager, "abc"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid Last-Event-ID: status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	resp := stream(&testManager, "1")
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || ct != "text/event-stream" {
+		t.Fatalf("status = %d, content type = %q", resp.StatusCode, ct)
+	}
+	r := bufio.NewReader(resp.Body)
+
+	if id, event, _ := readStreamEvent(t, r); id != "4" || event != domain.WebhookEventCreated {
+		t.Errorf("replayed %s %s, want 4 %s", id, event, domain.WebhookEventCreated)
+	}
+
+	if w := doRequest(t, h, &testOwner, http.MethodPut, "/events/10", `{"type_id":1,"title":"Ziyaret"}`); w.Code != http.StatusOK {
+		t.Fatalf("update: status = %d: %s", w.Code, w.Body)
+	}
+	id, event, data := readStreamEvent(t, r)
+	if id != "5" || event != domain.WebhookEventUpdated || !strings.Contains(data, `"id":10`) {
+		t.Errorf("streamed %s %s %s, want event 10 updated as 5", id, event, data)
+	}
+}
+
+func TestStreamEventsEndOnShutdown(t *testing.T) {
+	shutdown, endStreams := context.WithCancel(t.Context())
+	h, _, _ := newShutdownEventTestRouter(t, shutdown)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	srv.Config.RegisterOnShutdown(endStreams)
+
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL+"/events/stream", nil)
+	token, err := testTokens.Sign(&testOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Config.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v, want the open stream ended", err)
+	}
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Errorf("stream ended with %v, want a clean end", err)
+	}
+}
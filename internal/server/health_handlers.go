@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"pwp-remastered/internal/health"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type HealthHandlers struct {
+	readiness *health.Readiness
+}
+
+// NewHealthHandlers creates new liveness and readiness handlers
+func NewHealthHandlers(readiness *health.Readiness) *HealthHandlers {
+	return &HealthHandlers{
+		readiness: readiness,
+	}
+}
+
+func (h *HealthHandlers) RegisterRoutes(r chi.Router) {
+	r.Get("/livez", h.Livez)
+	r.Get("/readyz", h.Readyz)
+	// Kept for probes configured before /readyz existed
+	r.Get("/health", h.Readyz)
+}
+
+// Livez reports that the process is serving requests, it checks no dependency
+// so a failing database does not get the process restarted
+func (h *HealthHandlers) Livez(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(health.Report{Status: health.StatusUp})
+}
+
+// Readyz reports whether every dependency is up, with 503 when one is down.
+// The result of each checker is only shown to admins.
+func (h *HealthHandlers) Readyz(w http.ResponseWriter, r *http.Request) {
+	report := h.readiness.Check(r.Context())
+	if caller, err := ExtractUserFromRequest(r); err != nil || !caller.IsAdmin {
+		report.Checks = nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != health.StatusUp {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
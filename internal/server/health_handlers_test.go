@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/health"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHealthRoutes(t *testing.T) {
+	var dbErr error
+	readiness := health.NewReadiness(time.Second,
+		health.CheckerFunc("database", func(ctx context.Context) error { return dbErr }),
+		health.CheckerFunc("blob", func(ctx context.Context) error { return nil }),
+	)
+	r := chi.NewRouter()
+	r.Use(testTokens.Authenticate)
+	NewHealthHandlers(readiness).RegisterRoutes(r)
+
+	tests := []struct {
+		name       string
+		caller     *domain.User
+		target     string
+		dbErr      error
+		wantStatus int
+		wantChecks int
+	}{
+		{"live", nil, "/livez", errors.New("refused"), http.StatusOK, 0},
+		{"ready", nil, "/readyz", nil, http.StatusOK, 0},
+		{"not ready", &testOwner, "/readyz", errors.New("refused"), http.StatusServiceUnavailable, 0},
+		{"admin details", &testAdmin, "/readyz", errors.New("refused"), http.StatusServiceUnavailable, 2},
+		{"health alias", &testAdmin, "/health", nil, http.StatusOK, 2},
+	}
+	for _, tt := range tests {
+		dbErr = tt.dbErr
+		w := doRequest(t, r, tt.caller, http.MethodGet, tt.target, "")
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.wantStatus)
+			continue
+		}
+		var report health.Report
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(report.Checks) != tt.wantChecks {
+			t.Errorf("%s: checks = %+v, want %d", tt.name, report.Checks, tt.wantChecks)
+		}
+	}
+}
@@ -1,31 +1,83 @@
 package server
 
 import (
-	"os"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	_ "github.com/joho/godotenv/autoload"
+
+	"pwp-remastered/internal/config"
+	"pwp-remastered/internal/domain"
 )
 
-var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
+// TokenSigner signs the access tokens PWP issues and verifies those sent back,
+// with the key and lifetime of config.Auth
+type TokenSigner struct {
+	secret   []byte
+	lifetime time.Duration
+}
+
+// NewTokenSigner creates a token signer from cfg, which must be valid
+func NewTokenSigner(cfg config.Auth) *TokenSigner {
+	return &TokenSigner{secret: []byte(cfg.JWTSecret), lifetime: cfg.TokenLifetime}
+}
 
-func GenerateJWT(userID int, username string, isAdmin bool) (string, error) {
+// Sign issues an access token for user
+func (s *TokenSigner) Sign(user *domain.User) (string, error) {
 	claims := jwt.MapClaims{
-		"user_id":  userID,
-		"username": username,
-		"is_admin": isAdmin,
-		"exp":      time.Now().Add(time.Hour * 72).Unix(),
+		"user_id":   user.ID,
+		"username":  user.Username,
+		"is_admin":  user.IsAdmin,
+		"tenant_id": user.TenantID,
+		"exp":       time.Now().Add(s.lifetime).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return token.SignedString(s.secret)
 }
 
-func ParseJWT(tokenString string) (*jwt.Token, error) {
-	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+// Verify checks the signature and expiry of tokenString and returns the
+// caller it was issued to
+func (s *TokenSigner) Verify(tokenString string) (domain.User, error) {
+	var caller domain.User
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, jwt.ErrSignatureInvalid
 		}
-		return jwtSecret, nil
+		return s.secret, nil
+	})
+	if err != nil || !token.Valid {
+		return caller, errInvalidToken
+	}
+	if claims, ok := token.Claims.(jwt.MapClaims); ok {
+		if idVal, ok := claims["user_id"].(float64); ok {
+			caller.ID = int(idVal)
+		}
+		if username, ok := claims["username"].(string); ok {
+			caller.Username = username
+		}
+		if isAdmin, ok := claims["is_admin"].(bool); ok {
+			caller.IsAdmin = isAdmin
+		}
+		if tenantVal, ok := claims["tenant_id"].(float64); ok {
+			caller.TenantID = int(tenantVal)
+		}
+	}
+	return caller, nil
+}
+
+// Authenticate verifies the bearer token of every request and puts its caller
+// in the request context for ExtractUserFromRequest. Requests without a valid
+// token pass on without a caller, AuthMiddleware refuses them where a caller
+// is required.
+func (s *TokenSigner) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok && tokenString != "" {
+			if caller, err := s.Verify(tokenString); err == nil {
+				r = r.WithContext(withCaller(r.Context(), caller))
+			}
+		}
+		next.ServeHTTP(w, r)
 	})
 }
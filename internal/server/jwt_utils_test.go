@@ -0,0 +1,52 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"pwp-remastered/internal/config"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestTokenSigner(t *testing.T) {
+	token, err := testTokens.Sign(&testAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caller, err := testTokens.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if caller.ID != testAdmin.ID || caller.Username != testAdmin.Username || !caller.IsAdmin || caller.TenantID != testAdmin.TenantID {
+		t.Errorf("Verify() = %+v, want the claims of %+v", caller, testAdmin)
+	}
+
+	otherKey := NewTokenSigner(config.Auth{JWTSecret: "other-secret", TokenLifetime: time.Hour})
+	expired := NewTokenSigner(config.Auth{JWTSecret: "test-secret", TokenLifetime: -time.Minute})
+	for name, signer := range map[string]*TokenSigner{"other key": otherKey, "expired": expired} {
+		token, err := signer.Sign(&testAdmin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := testTokens.Verify(token); !errors.Is(err, errInvalidToken) {
+			t.Errorf("%s: Verify() error = %v, want %v", name, err, errInvalidToken)
+		}
+
+		// The router does not take the caller of a token it cannot verify
+		r := chi.NewRouter()
+		r.Use(testTokens.Authenticate)
+		r.With(AuthMiddleware).Get("/me", func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("%s: handler reached", name)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want %d", name, w.Code, http.StatusUnauthorized)
+		}
+	}
+}
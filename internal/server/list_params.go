@@ -0,0 +1,128 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"pwp-remastered/internal/domain"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errInvalidListParam is wrapped with the offending query parameter
+var errInvalidListParam = domain.NewValidationError("invalid_parameter", "invalid query parameter")
+
+// parsePageRequest reads cursor, limit, sort and include_total
+func parsePageRequest(q url.Values) (domain.PageRequest, error) {
+	page := domain.PageRequest{
+		Cursor:    q.Get("cursor"),
+		Sort:      q.Get("sort"),
+		WithTotal: q.Get("include_total") == "true",
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return page, fmt.Errorf("%w: limit must be a positive integer", errInvalidListParam)
+		}
+		page.Limit = limit
+	}
+	return page, nil
+}
+
+// parseIntList reads a repeatable or comma separated integer parameter,
+// e.g. type_id=1&type_id=2 or type_id=1,2
+func parseIntList(q url.Values, key string) ([]int, error) {
+	var ids []int
+	for _, v := range q[key] {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			id, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s must be a list of integers", errInvalidListParam, key)
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func parseOptionalFloat(q url.Values, key string) (*float64, error) {
+	v := q.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be a number", errInvalidListParam, key)
+	}
+	return &f, nil
+}
+
+// parseEventFilter reads the date range, filters and paging of an event
+// listing, dates are interpreted in loc
+func parseEventFilter(r *http.Request, loc *time.Location) (domain.EventFilter, error) {
+	q := r.URL.Query()
+	if q.Get("startdate") == "" || q.Get("enddate") == "" {
+		return domain.EventFilter{}, fmt.Errorf("%w: missing startdate or enddate", errInvalidListParam)
+	}
+	return parseEventConditions(q, loc)
+}
+
+// parseEventSearchFilter reads an event search, the date range is optional
+func parseEventSearchFilter(r *http.Request, loc *time.Location) (domain.EventFilter, error) {
+	return parseEventConditions(r.URL.Query(), loc)
+}
+
+func parseEventConditions(q url.Values, loc *time.Location) (domain.EventFilter, error) {
+	var filter domain.EventFilter
+	var err error
+
+	if filter.Range, err = parseDateRange(q, loc); err != nil {
+		return filter, err
+	}
+	if filter.PageRequest, err = parsePageRequest(q); err != nil {
+		return filter, err
+	}
+	if filter.TypeIDs, err = parseIntList(q, "type_id"); err != nil {
+		return filter, err
+	}
+	if filter.UserIDs, err = parseIntList(q, "user_id"); err != nil {
+		return filter, err
+	}
+	if filter.TeamIDs, err = parseIntList(q, "team_id"); err != nil {
+		return filter, err
+	}
+	if filter.MinPrice, err = parseOptionalFloat(q, "min_price"); err != nil {
+		return filter, err
+	}
+	if filter.MaxPrice, err = parseOptionalFloat(q, "max_price"); err != nil {
+		return filter, err
+	}
+	filter.Query = strings.TrimSpace(q.Get("q"))
+	return filter, nil
+}
+
+// parseUserFilter reads the filters and paging of a user listing
+func parseUserFilter(r *http.Request) (domain.UserFilter, error) {
+	q := r.URL.Query()
+	var filter domain.UserFilter
+	var err error
+
+	if filter.PageRequest, err = parsePageRequest(q); err != nil {
+		return filter, err
+	}
+	if filter.IDs, err = parseIntList(q, "id"); err != nil {
+		return filter, err
+	}
+	if filter.Statuses, err = parseIntList(q, "status"); err != nil {
+		return filter, err
+	}
+	if filter.TeamIDs, err = parseIntList(q, "team_id"); err != nil {
+		return filter, err
+	}
+	filter.Query = strings.TrimSpace(q.Get("q"))
+	return filter, nil
+}
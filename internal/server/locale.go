@@ -0,0 +1,20 @@
+package server
+
+import (
+	"net/http"
+
+	"golang.org/x/text/language"
+)
+
+// acceptLanguages returns the locales of the Accept-Language header ordered by preference
+func acceptLanguages(r *http.Request) []string {
+	tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil {
+		return nil
+	}
+	locales := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		locales = append(locales, tag.String())
+	}
+	return locales
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/services"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type LocationHandlers struct {
+	locationService *services.LocationService
+}
+
+// NewLocationHandlers creates a new location handlers
+func NewLocationHandlers(locationService *services.LocationService) *LocationHandlers {
+	return &LocationHandlers{
+		locationService: locationService,
+	}
+}
+
+func (h *LocationHandlers) RegisterRoutes(r chi.Router) {
+	r.Route("/locations", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", h.SearchLocations)
+		r.Post("/", h.CreateLocation)
+		r.Get("/{id}", h.GetLocation)
+		r.Put("/{id}", h.UpdateLocation)
+		r.Delete("/{id}", h.DeleteLocation)
+		r.Put("/{id}/routes/{toID}", h.SetRouteDistance)
+	})
+}
+
+// SearchLocations lists locations, filtered by the optional q and tag query parameters
+func (h *LocationHandlers) SearchLocations(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	locations, err := h.locationService.SearchLocations(r.Context(), &caller, r.URL.Query().Get("q"), r.URL.Query().Get("tag"))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domain.LocationList{Locations: locations})
+}
+
+func (h *LocationHandlers) GetLocation(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	location, err := h.locationService.GetLocation(r.Context(), &caller, id)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(location)
+}
+
+func (h *LocationHandlers) CreateLocation(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	var location domain.Location
+	if err := json.NewDecoder(r.Body).Decode(&location); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+
+	if err := h.locationService.CreateLocation(r.Context(), &caller, &location); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(location)
+}
+
+func (h *LocationHandlers) UpdateLocation(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	var location domain.Location
+	if err := json.NewDecoder(r.Body).Decode(&location); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+	location.ID = id
+
+	if err := h.locationService.UpdateLocation(r.Context(), &caller, &location); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(location)
+}
+
+func (h *LocationHandlers) DeleteLocation(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	if err := h.locationService.DeleteLocation(r.Context(), &caller, id); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetRouteDistance configures the road distance used to price trips between two locations
+func (h *LocationHandlers) SetRouteDistance(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	fromID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+	toID, err := strconv.Atoi(chi.URLParam(r, "toID"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	route := domain.LocationRoute{FromLocationID: fromID, ToLocationID: toID}
+	if err := json.NewDecoder(r.Body).Decode(&route); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+	route.FromLocationID, route.ToLocationID = fromID, toID
+
+	if err := h.locationService.SetRouteDistance(r.Context(), &caller, &route); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(route)
+}
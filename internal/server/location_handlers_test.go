@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/services"
+	"pwp-remastered/internal/store"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeLocationStore keeps locations of several tenants in memory
+type fakeLocationStore struct {
+	store.LocationStore
+	locations map[int]domain.Location
+	routes    []domain.LocationRoute
+}
+
+func (s *fakeLocationStore) GetLocation(ctx context.Context, tenantID int, id int) (*domain.Location, error) {
+	location, ok := s.locations[id]
+	if !ok || location.TenantID != tenantID {
+		return nil, store.ErrLocationNotFound
+	}
+	return &location, nil
+}
+
+func (s *fakeLocationStore) SearchLocations(ctx context.Context, tenantID int, query string, tag string) ([]domain.Location, error) {
+	locations := []domain.Location{}
+	for _, location := range s.locations {
+		if location.TenantID != tenantID {
+			continue
+		}
+		address := ""
+		if location.Address != nil {
+			address = *location.Address
+		}
+		if query != "" && !strings.Contains(strings.ToLower(location.Name+" "+address), strings.ToLower(query)) {
+			continue
+		}
+		if tag != "" && !slices.Contains(location.Tags, tag) {
+			continue
+		}
+		locations = append(locations, location)
+	}
+	slices.SortFunc(locations, func(a, b domain.Location) int { return strings.Compare(a.Name, b.Name) })
+	return locations, nil
+}
+
+func (s *fakeLocationStore) CreateLocation(ctx context.Context, location *domain.Location) error {
+	location.ID = len(s.locations) + 1
+	s.locations[location.ID] = *location
+	return nil
+}
+
+func (s *fakeLocationStore) UpdateLocation(ctx context.Context, location *domain.Location) error {
+	if _, err := s.GetLocation(ctx, location.TenantID, location.ID); err != nil {
+		return err
+	}
+	s.locations[location.ID] = *location
+	return nil
+}
+
+func (s *fakeLocationStore) DeleteLocation(ctx context.Context, tenantID int, id int) error {
+	if _, err := s.GetLocation(ctx, tenantID, id); err != nil {
+		return err
+	}
+	delete(s.locations, id)
+	return nil
+}
+
+func (s *fakeLocationStore) SetRouteDistance(ctx context.Context, route *domain.LocationRoute) error {
+	s.routes = append(s.routes, *route)
+	return nil
+}
+
+func newLocationTestRouter(t *testing.T) (http.Handler, *fakeLocationStore) {
+	t.Helper()
+	levent := "Büyükdere Cd. No:1, Levent"
+	locations := &fakeLocationStore{locations: map[int]domain.Location{
+		1: {ID: 1, TenantID: 1, Name: "Merkez Ofis", Address: &levent, Tags: []string{"ofis"}},
+		2: {ID: 2, TenantID: 1, Name: "Müşteri Deposu", Tags: []string{"müşteri"}},
+		3: {ID: 3, TenantID: 2, Name: "Ankara Şube", Tags: []string{"ofis"}},
+	}}
+	r := chi.NewRouter()
+	r.Use(testTokens.Authenticate)
+	NewLocationHandlers(services.NewLocationService(locations)).RegisterRoutes(r)
+	return r, locations
+}
+
+func TestLocationRoutes(t *testing.T) {
+	const locationBody = `{"name":"Liman","latitude":41.02,"longitude":28.97,"tags":[" liman ",""]}`
+
+	tests := []struct {
+		name       string
+		caller     *domain.User
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{"list without token", nil, http.MethodGet, "/locations", "", http.StatusUnauthorized},
+		{"list", &testOwner, http.MethodGet, "/locations", "", http.StatusOK},
+		{"create", &testOwner, http.MethodPost, "/locations", locationBody, http.StatusCreated},
+		{"create without name", &testOwner, http.MethodPost, "/locations", `{"name":" "}`, http.StatusBadRequest},
+		{"create with half coordinates", &testOwner, http.MethodPost, "/locations", `{"name":"Liman","latitude":41.02}`, http.StatusBadRequest},
+		{"create out of range", &testOwner, http.MethodPost, "/locations", `{"name":"Liman","latitude":91,"longitude":28.97}`, http.StatusBadRequest},
+		{"get", &testOwner, http.MethodGet, "/locations/1", "", http.StatusOK},
+		{"get other tenant's", &testOwner, http.MethodGet, "/locations/3", "", http.StatusNotFound},
+		{"other tenant's admin get", &testOtherAdmin, http.MethodGet, "/locations/1", "", http.StatusNotFound},
+		{"update", &testOwner, http.MethodPut, "/locations/2", locationBody, http.StatusOK},
+		{"update other tenant's", &testAdmin, http.MethodPut, "/locations/3", locationBody, http.StatusNotFound},
+		{"delete as non-admin", &testOwner, http.MethodDelete, "/locations/2", "", http.StatusForbidden},
+		{"delete", &testAdmin, http.MethodDelete, "/locations/2", "", http.StatusNoContent},
+		{"delete other tenant's", &testOtherAdmin, http.MethodDelete, "/locations/2", "", http.StatusNotFound},
+		{"route", &testOwner, http.MethodPut, "/locations/1/routes/2", `{"distance_km":12.5}`, http.StatusOK},
+		{"route to other tenant's", &testOwner, http.MethodPut, "/locations/1/routes/3", `{"distance_km":450}`, http.StatusNotFound},
+		{"route to itself", &testOwner, http.MethodPut, "/locations/1/routes/1", `{"distance_km":0}`, http.StatusBadRequest},
+		{"negative route", &testOwner, http.MethodPut, "/locations/1/routes/2", `{"distance_km":-1}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newLocationTestRouter(t)
+			w := doRequest(t, h, tt.caller, tt.method, tt.target, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
+
+func TestCreateLocationKeepsCallerTenant(t *testing.T) {
+	h, locations := newLocationTestRouter(t)
+	w := doRequest(t, h, &testOtherAdmin, http.MethodPost, "/locations", `{"name":"Liman","tenant_id":1,"tags":[" liman ",""]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var created domain.Location
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	stored := locations.locations[created.ID]
+	if stored.TenantID != testOtherAdmin.TenantID {
+		t.Errorf("tenant = %d, want the caller's %d", stored.TenantID, testOtherAdmin.TenantID)
+	}
+	if !slices.Equal(stored.Tags, []string{"liman"}) {
+		t.Errorf("tags = %q, want them trimmed without blanks", stored.Tags)
+	}
+}
+
+func TestSearchLocations(t *testing.T) {
+	tests := []struct {
+		name   string
+		caller *domain.User
+		query  string
+		want   []int
+	}{
+		{"all of the tenant", &testOwner, "", []int{1, 2}},
+		{"by name", &testOwner, "?q=depo", []int{2}},
+		{"by address", &testOwner, "?q=levent", []int{1}},
+		{"by tag", &testOwner, "?tag=ofis", []int{1}},
+		{"other tenant", &testOtherAdmin, "?tag=ofis", []int{3}},
+		{"no match", &testOwner, "?q=ofis&tag=müşteri", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newLocationTestRouter(t)
+			w := doRequest(t, h, tt.caller, http.MethodGet, "/locations"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			var list domain.LocationList
+			if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for _, location := range list.Locations {
+				got = append(got, location.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("found %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDatedEventsEmbedLocations(t *testing.T) {
+	h, events, _ := newEventTestRouter(t)
+	office, depot := 1, 2
+	events.listed = []domain.Event{{
+		ID: 10, UserID: testOwner.ID, OriginLocationID: &office, DestinationLocationID: &depot,
+		OriginLocation:      &domain.Location{ID: office, Name: "Merkez Ofis", Tags: []string{"ofis"}},
+		DestinationLocation: &domain.Location{ID: depot, Name: "Müşteri Deposu", Tags: []string{}},
+	}}
+
+	w := doRequest(t, h, &testOwner, http.MethodGet, "/events/dated/me?startdate=2025-01-01&enddate=2025-01-31", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var list domain.EventList
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Events) != 1 {
+		t.Fatalf("got %d events", len(list.Events))
+	}
+	event := list.Events[0]
+	if event.OriginLocation == nil || event.OriginLocation.Name != "Merkez Ofis" || !slices.Equal(event.OriginLocation.Tags, []string{"ofis"}) {
+		t.Errorf("origin_location = %+v", event.OriginLocation)
+	}
+	if event.DestinationLocation == nil || event.DestinationLocation.ID != depot {
+		t.Errorf("destination_location = %+v", event.DestinationLocation)
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"pwp-remastered/internal/domain"
+	"strings"
+)
+
+// messages holds the translations of domain error messages by code and locale.
+// Codes without an entry fall back to the English message of the error.
+var messages = map[string]map[string]string{
+	"unauthorized":                   {"tr": "Kimlik doğrulaması gerekli."},
+	"invalid_token":                  {"tr": "Oturum anahtarı eksik, süresi dolmuş veya geçersiz."},
+	"invalid_credentials":            {"tr": "Giriş bilgileri hatalı."},
+	"account_inactive":               {"tr": "Kullanıcı hesabı inaktif."},
+	"tenant_required":                {"tr": "Giriş için tenant_id gereklidir."},
+	"admin_required":                 {"tr": "Bu işlem için yönetici yetkisi gerekir."},
+	"forbidden":                      {"tr": "Bu işlemi yapmaya yetkiniz yok."},
+	"invalid_body":                   {"tr": "İstek gövdesi geçersiz."},
+	"invalid_id":                     {"tr": "Yol parametresi bir tam sayı olmalıdır."},
+	"invalid_parameter":              {"tr": "Sorgu parametresi geçersiz."},
+	"invalid_cursor":                 {"tr": "Sayfalama imleci geçersiz."},
+	"invalid_sort":                   {"tr": "Sıralama alanı geçersiz."},
+	"invalid_search":                 {"tr": "Arama ifadesi gerekli."},
+	"invalid_time_zone":              {"tr": "Saat dilimi geçersiz, Europe/Istanbul gibi bir IANA adı kullanın."},
+	"internal_error":                 {"tr": "Beklenmeyen bir hata oluştu."},
+	"user_not_found":                 {"tr": "Kullanıcı bulunamadı."},
+	"user_exists":                    {"tr": "Kullanıcı adı veya e-posta zaten kullanılıyor."},
+	"invalid_manager":                {"tr": "Yönetici, aynı kiracıda kullanıcıya bağlı olmayan başka bir kullanıcı olmalıdır."},
+	"event_not_found":                {"tr": "Etkinlik bulunamadı."},
+	"invalid_import":                 {"tr": "İçe aktarma için başlık satırı olan ve username, type, start_date ile end_date sütunlarını içeren bir CSV dosyası gerekli."},
+	"invalid_import_value":           {"tr": "Değer eksik veya sütunu için geçersiz."},
+	"import_too_large":               {"tr": "İçe aktarılan dosya izin verilen en büyük boyutu aşıyor."},
+	"invalid_scim_token":             {"tr": "SCIM belirteci eksik veya tanınmıyor."},
+	"invalid_scim_token_name":        {"tr": "SCIM belirteci için bir ad gerekli."},
+	"scim_token_not_found":           {"tr": "SCIM belirteci bulunamadı."},
+	"invalid_scim_filter":            {"tr": "Filtre, desteklenen öznitelikleri eq ile karşılaştırmalı ve and ile birleştirmelidir."},
+	"invalid_scim_path":              {"tr": "PATCH işlemi veya yolu desteklenmiyor."},
+	"invalid_scim_value":             {"tr": "Değer eksik veya öznitelik için geçersiz."},
+	"ldap_config_not_found":          {"tr": "Kiracının LDAP yapılandırması yok."},
+	"invalid_ldap_config":            {"tr": "LDAP yapılandırması için ldap:// veya ldaps:// adresi, base_dn ve %s içeren filtreler gerekli."},
+	"ldap_account_incomplete":        {"tr": "Dizindeki kayıtta e-posta adresi yok."},
+	"oidc_config_not_found":          {"tr": "Kiracı OpenID Connect ile giriş kullanmıyor."},
+	"invalid_oidc_config":            {"tr": "OpenID Connect yapılandırması için https issuer adresi, client_id ve openid kapsamı gerekli."},
+	"invalid_oidc_state":             {"tr": "Giriş isteği bilinmiyor veya süresi dolmuş, girişi yeniden başlatın."},
+	"oidc_login_failed":              {"tr": "Kimlik sağlayıcısıyla giriş başarısız oldu."},
+	"oidc_account_incomplete":        {"tr": "Kimlik belirtecinde kullanıcı adı veya doğrulanmış e-posta yok."},
+	"budget_not_found":               {"tr": "Bütçe bulunamadı."},
+	"invalid_budget":                 {"tr": "Bütçe için kiracıdaki bir user_id, team_id veya type_id'den yalnızca biri, month, quarter ya da year dönemi ve pozitif bir limit gerekli."},
+	"period_not_found":               {"tr": "Muhasebe dönemi bulunamadı."},
+	"period_overlaps":                {"tr": "Dönem başka bir muhasebe dönemiyle çakışıyor."},
+	"period_already_closed":          {"tr": "Muhasebe dönemi zaten kapalı."},
+	"period_not_closed":              {"tr": "Muhasebe dönemi kapalı değil."},
+	"period_closed":                  {"tr": "Etkinlik kapalı bir muhasebe dönemine denk geliyor."},
+	"invalid_period":                 {"tr": "Dönem için YYYY-AA-GG biçiminde start_date ve end_date gerekli, başlangıç bitişten sonra olamaz."},
+	"event_already_approved":         {"tr": "Etkinlik zaten onaylanmış."},
+	"event_type_not_found":           {"tr": "Etkinlik türü bulunamadı."},
+	"event_type_archived":            {"tr": "Etkinlik türü arşivlenmiş."},
+	"event_type_shared":              {"tr": "Tüm kiracıların ortak etkinlik türleri kiracı yöneticisi tarafından değiştirilemez."},
+	"invalid_event_type_order":       {"tr": "Sıralama kiracının her etkinlik türünü tam bir kez içermelidir."},
+	"invalid_event_type":             {"tr": "Etkinlik türü için ad, #1a2b3c biçiminde renk ve boş olmayan çeviriler gerekir."},
+	"attachment_not_found":           {"tr": "Ek bulunamadı."},
+	"attachment_too_large":           {"tr": "Ek izin verilen boyutu aşıyor."},
+	"attachment_type_denied":         {"tr": "Bu dosya türüne izin verilmiyor."},
+	"rate_not_found":                 {"tr": "Kilometre ücreti bulunamadı."},
+	"rate_exists":                    {"tr": "Bu tür ve tarih için kilometre ücreti zaten tanımlı."},
+	"invalid_rate":                   {"tr": "Kilometre ücreti negatif olmamalı ve geçerlilik tarihi gerekli."},
+	"invalid_trip":                   {"tr": "Yolculuk için geçerli başlangıç ve varış koordinatları ya da negatif olmayan bir mesafe gerekli."},
+	"team_not_found":                 {"tr": "Ekip bulunamadı."},
+	"team_exists":                    {"tr": "Bu adla bir ekip zaten var."},
+	"team_member_not_found":          {"tr": "Kullanıcı bu ekibin üyesi değil."},
+	"invalid_team":                   {"tr": "Ekip için ad gerekli."},
+	"webhook_subscription_not_found": {"tr": "Webhook aboneliği bulunamadı."},
+	"webhook_delivery_not_found":     {"tr": "Webhook gönderimi bulunamadı."},
+	"invalid_webhook":                {"tr": "Webhook için herkese açık bir sunucuya giden mutlak bir https adresi ve en az bir bilinen olay türü gerekli."},
+	"location_not_found":             {"tr": "Konum bulunamadı."},
+	"location_exists":                {"tr": "Bu adla bir konum zaten var."},
+	"route_not_found":                {"tr": "Güzergah bulunamadı."},
+	"invalid_location":               {"tr": "Konum için ad ve geçerli koordinatlar gerekli."},
+	"vehicle_not_found":              {"tr": "Araç bulunamadı."},
+	"vehicle_exists":                 {"tr": "Bu plakaya sahip bir araç zaten var."},
+	"invalid_vehicle":                {"tr": "Araç için plaka, bilinen bir yakıt türü ve negatif olmayan ücret ve kilometre gerekli."},
+	"invalid_odometer":               {"tr": "Kilometre sayacı aracın son okumasının altına düşemez ve bitiş başlangıçtan büyük olmalıdır."},
+	"vehicle_not_usable":             {"tr": "Araç pasif veya başka bir çalışana ait."},
+}
+
+// localizedMessage returns the message of err in the first supported locale
+func localizedMessage(err *domain.Error, locales []string) string {
+	for _, locale := range locales {
+		base, _, _ := strings.Cut(strings.ToLower(locale), "-")
+		if base == "en" {
+			return err.Message
+		}
+		if message, ok := messages[err.Code][base]; ok {
+			return message
+		}
+	}
+	return err.Message
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"pwp-remastered/internal/logging"
+	"pwp-remastered/internal/services"
+	"pwp-remastered/internal/store"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// stateCookie binds a login to the browser that started it, it holds the hash
+// of the state so a callback link sent to someone else is refused
+const stateCookie = "pwp_oidc_state"
+
+type OIDCHandlers struct {
+	oidcService *services.OIDCService
+	tokens      *TokenSigner
+}
+
+// NewOIDCHandlers creates a new OpenID Connect login handlers
+func NewOIDCHandlers(oidcService *services.OIDCService, tokens *TokenSigner) *OIDCHandlers {
+	return &OIDCHandlers{oidcService: oidcService, tokens: tokens}
+}
+
+// RegisterRoutes registers the single sign-on routes, both are reached by
+// browser redirects and take no JWT
+func (h *OIDCHandlers) RegisterRoutes(r chi.Router) {
+	r.Route("/auth/oidc", func(r chi.Router) {
+		r.Get("/login", h.Login)
+		r.Get("/callback", h.Callback)
+	})
+}
+
+// Login redirects to the identity provider of the tenant_id tenant
+func (h *OIDCHandlers) Login(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := strconv.Atoi(r.URL.Query().Get("tenant_id"))
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("%w: tenant_id must be an integer", errInvalidListParam))
+		return
+	}
+
+	authURL, state, err := h.oidcService.BeginLogin(r.Context(), tenantID, baseURL(r)+"/auth/oidc/callback")
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	// Lax, the callback is a top-level redirect from the provider's site
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    stateHash(state),
+		Path:     "/auth/oidc",
+		MaxAge:   int(store.OIDCLoginTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(baseURL(r), "https:"),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback completes the login started by Login in the same browser and
+// issues a PWP token. It redirects to the
+// tenant's redirect URL with the token in the fragment, which browsers do not
+// send to servers, or answers like /login when there is none.
+func (h *OIDCHandlers) Callback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if providerErr := q.Get("error"); providerErr != "" {
+		logging.FromContext(r.Context()).WarnContext(r.Context(), "oidc login failed",
+			"reason", "provider error", "error", providerErr, "error_description", q.Get("error_description"))
+		writeProblem(w, r, services.ErrOIDCLoginFailed)
+		return
+	}
+
+	cookie, err := r.Cookie(stateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(stateHash(q.Get("state")))) != 1 {
+		logging.FromContext(r.Context()).WarnContext(r.Context(), "oidc login failed",
+			"reason", "state was not issued to this browser")
+		writeProblem(w, r, services.ErrOIDCLoginFailed)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: stateCookie, Path: "/auth/oidc", MaxAge: -1, HttpOnly: true})
+
+	user, redirectURL, err := h.oidcService.CompleteLogin(r.Context(), q.Get("state"), q.Get("code"))
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	token, err := h.tokens.Sign(user)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if redirectURL != "" {
+		http.Redirect(w, r, redirectURL+"#token="+url.QueryEscape(token), http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"token": token})
+}
+
+func stateHash(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
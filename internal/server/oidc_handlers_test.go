@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/services"
+	"pwp-remastered/internal/store"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// expiredLogins has no logins, a callback reaching it answers invalid_oidc_state
+type expiredLogins struct {
+	store.OIDCStore
+}
+
+func (expiredLogins) ConsumeLogin(ctx context.Context, state string) (*domain.OIDCLogin, error) {
+	return nil, store.ErrOIDCLoginNotFound
+}
+
+func TestOIDCCallbackState(t *testing.T) {
+	r := chi.NewRouter()
+	NewOIDCHandlers(services.NewOIDCService(expiredLogins{}, nil), testTokens).RegisterRoutes(r)
+
+	tests := []struct {
+		name     string
+		cookie   *http.Cookie
+		wantCode string
+	}{
+		{"no cookie", nil, "oidc_login_failed"},
+		{"other login's cookie", &http.Cookie{Name: stateCookie, Value: stateHash("other-state")}, "oidc_login_failed"},
+		{"state in the cookie", &http.Cookie{Name: stateCookie, Value: "sent-state"}, "oidc_login_failed"},
+		{"cookie of the state", &http.Cookie{Name: stateCookie, Value: stateHash("sent-state")}, "invalid_oidc_state"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?state=sent-state&code=code", nil)
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			var problem struct {
+				Code string `json:"code"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != http.StatusUnauthorized || problem.Code != tt.wantCode {
+				t.Errorf("status = %d, code = %q; want 401 and %q", w.Code, problem.Code, tt.wantCode)
+			}
+		})
+	}
+}
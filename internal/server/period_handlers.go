@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/services"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type PeriodHandlers struct {
+	periodService *services.PeriodService
+}
+
+// NewPeriodHandlers creates a new period handlers
+func NewPeriodHandlers(periodService *services.PeriodService) *PeriodHandlers {
+	return &PeriodHandlers{
+		periodService: periodService,
+	}
+}
+
+// RegisterRoutes registers the accounting period routes. Accountants are not
+// marked in the token, so the service checks who may change periods.
+func (h *PeriodHandlers) RegisterRoutes(r chi.Router) {
+	r.Route("/periods", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", h.ListPeriods)
+		r.Post("/", h.CreatePeriod)
+		r.Get("/{id}", h.GetPeriod)
+		r.Post("/{id}/close", h.ClosePeriod)
+		r.Post("/{id}/reopen", h.ReopenPeriod)
+	})
+}
+
+func (h *PeriodHandlers) ListPeriods(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	periods, err := h.periodService.ListPeriods(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domain.AccountingPeriodList{Periods: periods})
+}
+
+// GetPeriod returns a period with the history of who created, closed and reopened it
+func (h *PeriodHandlers) GetPeriod(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	period, err := h.periodService.GetPeriod(r.Context(), &caller, id)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(period)
+}
+
+func (h *PeriodHandlers) CreatePeriod(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	var period domain.AccountingPeriod
+	if err := json.NewDecoder(r.Body).Decode(&period); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+
+	if err := h.periodService.CreatePeriod(r.Context(), &caller, &period); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(period)
+}
+
+func (h *PeriodHandlers) ClosePeriod(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.periodService.ClosePeriod)
+}
+
+func (h *PeriodHandlers) ReopenPeriod(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.periodService.ReopenPeriod)
+}
+
+func (h *PeriodHandlers) changeStatus(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, caller *domain.User, id int) (*domain.AccountingPeriod, error)) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	period, err := change(r.Context(), &caller, id)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(period)
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/logging"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Errors raised by the handlers themselves, before a service is involved
+var (
+	errUnauthorized = domain.NewUnauthorizedError("unauthorized", "authentication required")
+	errInvalidToken = domain.NewUnauthorizedError("invalid_token", "token is missing, expired or invalid")
+	errAdminOnly    = domain.NewForbiddenError("admin_required", "this action requires an administrator")
+	errInvalidBody  = domain.NewValidationError("invalid_body", "request body is not valid")
+	errInvalidID    = domain.NewValidationError("invalid_id", "path identifier must be an integer")
+	errInternal     = &domain.Error{Kind: domain.KindInternal, Code: "internal_error", Message: "an unexpected error occurred"}
+)
+
+// problem is an RFC 7807 problem details body extended with a stable code
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	// Extensions are the domain error's details, encoded as members of the body
+	Extensions map[string]any `json:"-"`
+}
+
+func (p problem) MarshalJSON() ([]byte, error) {
+	type members problem
+	body, err := json.Marshal(members(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return body, err
+	}
+	extensions, err := json.Marshal(p.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	body = append(body[:len(body)-1], ',')
+	return append(body, extensions[1:]...), nil
+}
+
+var kindStatus = map[domain.ErrorKind]int{
+	domain.KindValidation:           http.StatusBadRequest,
+	domain.KindUnauthorized:         http.StatusUnauthorized,
+	domain.KindForbidden:            http.StatusForbidden,
+	domain.KindNotFound:             http.StatusNotFound,
+	domain.KindConflict:             http.StatusConflict,
+	domain.KindTooLarge:             http.StatusRequestEntityTooLarge,
+	domain.KindUnsupportedMediaType: http.StatusUnsupportedMediaType,
+}
+
+// writeProblem answers with the problem+json body for err. Only the domain
+// error's own localized message is sent, the context it was wrapped with is
+// logged, so decoder, provider, driver and SQL messages never reach the client.
+func writeProblem(w http.ResponseWriter, r *http.Request, err error) {
+	domainErr := clientError(r, err)
+	status, ok := kindStatus[domainErr.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	body := problem{
+		Type:     "urn:pwp:error:" + domainErr.Code,
+		Title:    localizedMessage(domainErr, acceptLanguages(r)),
+		Status:   status,
+		Instance: r.URL.Path,
+		Code:     domainErr.Code,
+
+		Extensions: domainErr.Details,
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// clientError returns the domain error of err that can be shown to the client.
+// Errors that are not domain errors are reported as internal, and the context a
+// domain error was wrapped with is logged.
+func clientError(r *http.Request, err error) *domain.Error {
+	domainErr, ok := domain.AsError(err)
+	if !ok || domainErr.Kind == domain.KindInternal {
+		reportInternalError(r, err)
+		return errInternal
+	}
+	if err.Error() != domainErr.Message {
+		logging.FromContext(r.Context()).InfoContext(r.Context(), "request rejected", "code", domainErr.Code, "error", err)
+	}
+	return domainErr
+}
+
+// reportInternalError logs an error the client is not told about and records
+// it on the request's span
+func reportInternalError(r *http.Request, err error) {
+	trace.SpanFromContext(r.Context()).RecordError(err)
+	logging.FromContext(r.Context()).ErrorContext(r.Context(), "request failed", "error", err)
+}
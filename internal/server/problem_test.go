@@ -0,0 +1,131 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/logging"
+	"pwp-remastered/internal/services"
+	"pwp-remastered/internal/store"
+	"strings"
+	"testing"
+)
+
+func TestWriteProblem(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		language   string
+		wantStatus int
+		wantCode   string
+		wantTitle  string
+		wantLogged string
+	}{
+		{"not found", store.ErrEventNotFound, "", http.StatusNotFound, "event_not_found", "event not found", ""},
+		{"forbidden in turkish", services.ErrForbidden, "tr-TR,tr;q=0.9", http.StatusForbidden, "forbidden", "Bu işlemi yapmaya yetkiniz yok.", ""},
+		{"english preferred", services.ErrForbidden, "en-US,tr;q=0.5", http.StatusForbidden, "forbidden", "caller is not allowed to perform this action", ""},
+		{"wrapped decoder error", fmt.Errorf("%w: invalid character 'x' looking for beginning of value", errInvalidBody), "", http.StatusBadRequest, "invalid_body", "request body is not valid", "looking for beginning of value"},
+		{"wrapped provider error", fmt.Errorf("%w: oauth2: invalid_grant code expired", services.ErrOIDCLoginFailed), "", http.StatusUnauthorized, "oidc_login_failed", "identity provider login failed", "invalid_grant"},
+		{"conflict", store.ErrUserExists, "", http.StatusConflict, "user_exists", "username or email is already taken", ""},
+		{"internal", errors.New(`pq: relation "events" does not exist`), "", http.StatusInternalServerError, "internal_error", "an unexpected error occurred", "does not exist"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			r := httptest.NewRequest(http.MethodGet, "/events/7", nil)
+			r = r.WithContext(logging.NewContext(r.Context(), logging.New(&logs, slog.LevelInfo)))
+			if tt.language != "" {
+				r.Header.Set("Accept-Language", tt.language)
+			}
+			w := httptest.NewRecorder()
+			writeProblem(w, r, tt.err)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			// The cause is logged, never sent
+			if tt.wantLogged != "" {
+				if strings.Contains(w.Body.String(), tt.wantLogged) {
+					t.Errorf("body leaks the cause: %s", w.Body)
+				}
+				if !strings.Contains(logs.String(), tt.wantLogged) {
+					t.Errorf("cause not logged: %s", logs.String())
+				}
+			}
+			var body problem
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.wantCode || body.Title != tt.wantTitle {
+				t.Errorf("body = %+v", body)
+			}
+			if body.Status != tt.wantStatus || body.Instance != "/events/7" || !strings.HasSuffix(body.Type, tt.wantCode) {
+				t.Errorf("body = %+v", body)
+			}
+		})
+	}
+}
+
+func TestWriteProblemDetails(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/events/14", nil)
+	w := httptest.NewRecorder()
+	writeProblem(w, r, store.PeriodClosed(&domain.AccountingPeriod{ID: 3, StartDate: "2024-01-01", EndDate: "2024-01-31"}))
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"code":         "period_closed",
+		"period_id":    float64(3),
+		"period_start": "2024-01-01",
+		"period_end":   "2024-01-31",
+	}
+	for member, value := range want {
+		if body[member] != value {
+			t.Errorf("%s = %v, want %v", member, body[member], value)
+		}
+	}
+}
+
+func TestLocalizeRowErrors(t *testing.T) {
+	var logs bytes.Buffer
+	r := httptest.NewRequest(http.MethodPost, "/events/import", nil)
+	r = r.WithContext(logging.NewContext(r.Context(), logging.New(&logs, slog.LevelInfo)))
+	r.Header.Set("Accept-Language", "tr")
+	rowErrors := []domain.EventImportRowError{
+		{Row: 2, Column: "username", Err: fmt.Errorf("%w: mehmet.demir", store.ErrUserNotFound)},
+		{Row: 3, Err: errors.New(`pq: relation "users" does not exist`)},
+	}
+	localizeRowErrors(r, rowErrors)
+
+	if rowErrors[0].Code != "user_not_found" || rowErrors[0].Title != "Kullanıcı bulunamadı." {
+		t.Errorf("row 2 = %+v", rowErrors[0])
+	}
+	if rowErrors[1].Code != "internal_error" {
+		t.Errorf("row 3 = %+v", rowErrors[1])
+	}
+	body, err := json.Marshal(rowErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cause := range []string{"mehmet.demir", "does not exist"} {
+		if strings.Contains(string(body), cause) {
+			t.Errorf("row errors leak %q: %s", cause, body)
+		}
+		if !strings.Contains(logs.String(), cause) {
+			t.Errorf("%q not logged: %s", cause, logs.String())
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/services"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type RateHandlers struct {
+	eventService *services.EventService
+}
+
+// NewRateHandlers creates a new mileage rate handlers
+func NewRateHandlers(eventService *services.EventService) *RateHandlers {
+	return &RateHandlers{
+		eventService: eventService,
+	}
+}
+
+func (h *RateHandlers) RegisterRoutes(r chi.Router) {
+	r.Route("/mileage-rates", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", h.GetMileageRates)
+		r.With(AdminMiddleware).Post("/", h.CreateMileageRate)
+	})
+}
+
+// GetMileageRates lists every rate version of the caller's tenant
+func (h *RateHandlers) GetMileageRates(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	rates, err := h.eventService.GetMileageRates(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domain.MileageRateList{Rates: rates})
+}
+
+// CreateMileageRate adds a new rate version taking effect on effective_from (YYYY-MM-DD)
+func (h *RateHandlers) CreateMileageRate(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	var req struct {
+		TypeID        *int    `json:"type_id"`
+		RatePerKm     float64 `json:"rate_per_km"`
+		EffectiveFrom string  `json:"effective_from"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+	effectiveFrom, err := time.Parse("2006-01-02", req.EffectiveFrom)
+	if err != nil {
+		writeProblem(w, r, fmt.Errorf("%w: effective_from must be YYYY-MM-DD", errInvalidBody))
+		return
+	}
+
+	rate := domain.MileageRate{
+		TypeID:        req.TypeID,
+		RatePerKm:     req.RatePerKm,
+		EffectiveFrom: effectiveFrom,
+	}
+	if err := h.eventService.CreateMileageRate(r.Context(), &caller, &rate); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rate)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"pwp-remastered/internal/logging"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// requestLogger writes one line per request with its ID, caller, route
+// pattern, status and latency. It runs after middleware.RequestID and puts a
+// logger with the request ID and the caller in the request context, so the
+// lines services and stores write for the request carry them too.
+func requestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			requestID := middleware.GetReqID(r.Context())
+			w.Header().Set(middleware.RequestIDHeader, requestID)
+
+			l := logger.With("request_id", requestID)
+			if caller, err := ExtractUserFromRequest(r); err == nil {
+				l = l.With("user_id", caller.ID, "tenant_id", caller.TenantID)
+			}
+			ctx := logging.NewContext(r.Context(), l)
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			route := ""
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				route = rctx.RoutePattern()
+			}
+			l.LogAttrs(ctx, level, "request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", route),
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/logging"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(testTokens.Authenticate)
+	r.Use(requestLogger(logging.New(&buf, slog.LevelInfo)))
+	r.Get("/events/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).Info("inside")
+		writeProblem(w, r, errors.New("connection refused"))
+	})
+
+	caller := &domain.User{ID: 3, Username: "ayse", TenantID: 2}
+	w := doRequest(t, r, caller, http.MethodGet, "/events/42", "")
+	if w.Header().Get(middleware.RequestIDHeader) == "" {
+		t.Error("response has no request ID header")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d log lines, want inside, request failed and request:\n%s", len(lines), buf.String())
+	}
+	var entries []map[string]interface{}
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line is not JSON: %s", line)
+		}
+		if entry["request_id"] == "" || entry["user_id"] != float64(3) || entry["tenant_id"] != float64(2) {
+			t.Errorf("log line misses request attributes: %s", line)
+		}
+		entries = append(entries, entry)
+	}
+
+	request := entries[2]
+	if request["msg"] != "request" || request["level"] != "ERROR" {
+		t.Errorf("request line = %v, want an error level request line", request)
+	}
+	if request["route"] != "/events/{id}" || request["path"] != "/events/42" || request["status"] != float64(http.StatusInternalServerError) {
+		t.Errorf("request line = %v", request)
+	}
+	if _, ok := request["latency_ms"].(float64); !ok {
+		t.Errorf("request line has no latency: %v", request)
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRequestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	events, _, _ := newEventTestRouter(t)
+	r := chi.NewRouter()
+	r.Use(requestTracing)
+	r.Mount("/", events)
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodGet, "/events/10", nil)
+	req.Header.Set("traceparent", traceparent)
+	token, err := testTokens.Sign(&testOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+
+	spans := exporter.GetSpans()
+	var server, service *tracetest.SpanStub
+	for i := range spans {
+		switch spans[i].SpanKind {
+		case trace.SpanKindServer:
+			server = &spans[i]
+		case trace.SpanKindInternal:
+			if spans[i].Name == "EventService.GetEvent" {
+				service = &spans[i]
+			}
+		}
+	}
+	if server == nil || service == nil {
+		t.Fatalf("spans = %v, want the request and EventService.GetEvent", spans)
+	}
+
+	if server.Name != "GET /events/{id}" {
+		t.Errorf("request span name = %q", server.Name)
+	}
+	if got := server.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %s, want the one of traceparent", got)
+	}
+	if got := server.Parent.SpanID().String(); got != "00f067aa0ba902b7" || !server.Parent.IsRemote() {
+		t.Errorf("parent span = %s, want the remote one of traceparent", got)
+	}
+	if service.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Error("service span is not a child of the request span")
+	}
+	if server.Status.Code == codes.Error {
+		t.Errorf("request span status = %v", server.Status)
+	}
+	var status int64
+	for _, attr := range server.Attributes {
+		if attr.Key == semconv.HTTPResponseStatusCodeKey {
+			status = attr.Value.AsInt64()
+		}
+	}
+	if status != http.StatusOK {
+		t.Errorf("http.response.status_code = %d, want 200", status)
+	}
+}
@@ -2,44 +2,101 @@ package server
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 
+	"pwp-remastered/internal/health"
 	"pwp-remastered/internal/services"
 	"pwp-remastered/internal/store"
 )
 
 func (s *Server) RegisterRoutes() http.Handler {
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.Use(middleware.RequestID)
+	r.Use(requestTracing)
+	r.Use(s.tokens.Authenticate)
+	r.Use(requestLogger(slog.Default()))
+	r.Use(requestMetrics)
 	r.Use(middleware.CleanPath)
 
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins: []string{"https://*", "http://*"},
-		// AllowedOrigins:   []string{"*"},
+		AllowedOrigins:   s.cfg.CORS.AllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Last-Event-ID"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
 	r.Get("/", s.HelloWorldHandler)
-	r.Get("/health", s.healthHandler)
+
+	s.healthHandlers = NewHealthHandlers(health.NewReadiness(2*time.Second,
+		health.Database(s.db),
+		health.Migrations(s.db, s.latestMigration),
+		health.Blob(s.blobs),
+	))
+	s.healthHandlers.RegisterRoutes(r)
 
 	// Initialize and register user handlers
 	userStore := store.NewUserStore(s.db)
 	userService := services.NewUserService(userStore)
-	s.userHandlers = NewUserHandlers(userService)
+	authService := services.NewAuthService(userStore, store.NewLDAPStore(s.db))
+	oidcService := services.NewOIDCService(store.NewOIDCStore(s.db), authService)
+	s.userHandlers = NewUserHandlers(userService, authService, s.tokens)
 	s.userHandlers.RegisterRoutes(r)
+	s.oidcHandlers = NewOIDCHandlers(oidcService, s.tokens)
+	s.oidcHandlers.RegisterRoutes(r)
+
+	teamStore := store.NewTeamStore(s.db)
+	s.teamHandlers = NewTeamHandlers(services.NewTeamService(teamStore, userStore))
+	s.teamHandlers.RegisterRoutes(r)
+	s.scimHandlers = NewScimHandlers(services.NewScimService(store.NewScimTokenStore(s.db), userStore, teamStore))
+	s.scimHandlers.RegisterRoutes(r)
+
+	s.webhookHandlers = NewWebhookHandlers(services.NewWebhookService(store.NewWebhookStore(s.db)))
+	s.webhookHandlers.RegisterRoutes(r)
+
+	settingsStore := store.NewSettingsStore(s.db)
+	settingsService := services.NewSettingsService(settingsStore)
+	s.settingsHandlers = NewSettingsHandlers(settingsService, authService, oidcService)
+	s.settingsHandlers.RegisterRoutes(r)
 
 	eventStore := store.NewEventStore(s.db)
-	eventService := services.NewEventService(eventStore)
-	s.eventHandlers = NewEventHandlers(*eventService, eventStore)
+	rateStore := store.NewRateStore(s.db)
+	locationStore := store.NewLocationStore(s.db)
+	vehicleStore := store.NewVehicleStore(s.db)
+	periodStore := store.NewPeriodStore(s.db)
+	eventPolicy := services.NewEventPolicy(userStore)
+	budgetService := services.NewBudgetService(store.NewBudgetStore(s.db), eventStore, settingsStore, userStore, teamStore, eventPolicy)
+	eventService := services.NewEventService(eventStore, rateStore, locationStore, vehicleStore, periodStore, eventPolicy, s.broker, budgetService)
+	s.eventHandlers = NewEventHandlers(*eventService, settingsService, services.NewEventImportService(eventService, userStore), s.shutdown)
 	s.eventHandlers.RegisterRoutes(r)
+	s.rateHandlers = NewRateHandlers(eventService)
+	s.rateHandlers.RegisterRoutes(r)
+
+	s.periodHandlers = NewPeriodHandlers(services.NewPeriodService(periodStore, userStore))
+	s.periodHandlers.RegisterRoutes(r)
+	s.budgetHandlers = NewBudgetHandlers(budgetService, settingsService)
+	s.budgetHandlers.RegisterRoutes(r)
+
+	locationService := services.NewLocationService(locationStore)
+	s.locationHandlers = NewLocationHandlers(locationService)
+	s.locationHandlers.RegisterRoutes(r)
+
+	vehicleService := services.NewVehicleService(vehicleStore)
+	s.vehicleHandlers = NewVehicleHandlers(vehicleService, settingsService)
+	s.vehicleHandlers.RegisterRoutes(r)
+
+	attachmentLimits := services.DefaultAttachmentLimits
+	attachmentLimits.MaxSize = s.cfg.AttachmentMaxBytes
+	attachmentStore := store.NewAttachmentStore(s.db)
+	attachmentService := services.NewAttachmentService(attachmentStore, eventStore, s.blobs, attachmentLimits, eventPolicy)
+	s.attachmentHandlers = NewAttachmentHandlers(attachmentService)
+	s.attachmentHandlers.RegisterRoutes(r)
 
 	return r
 }
@@ -50,13 +107,9 @@ func (s *Server) HelloWorldHandler(w http.ResponseWriter, r *http.Request) {
 
 	jsonResp, err := json.Marshal(resp)
 	if err != nil {
-		log.Fatalf("error handling JSON marshal. Err: %v", err)
+		writeProblem(w, r, err)
+		return
 	}
 
 	_, _ = w.Write(jsonResp)
 }
-
-func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
-	jsonResp, _ := json.Marshal(s.db.Health())
-	_, _ = w.Write(jsonResp)
-}
@@ -0,0 +1,399 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/logging"
+	"pwp-remastered/internal/scim"
+	"pwp-remastered/internal/services"
+	"pwp-remastered/internal/store"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// scimDefaultCount is the page size of list requests without a count
+const scimDefaultCount = 100
+
+type scimTenantKey struct{}
+
+type ScimHandlers struct {
+	scimService *services.ScimService
+}
+
+// NewScimHandlers creates a new SCIM handlers
+func NewScimHandlers(scimService *services.ScimService) *ScimHandlers {
+	return &ScimHandlers{
+		scimService: scimService,
+	}
+}
+
+// RegisterRoutes registers the token management routes for admins and the
+// SCIM 2.0 endpoints under /scim/v2, which take a SCIM token instead of a JWT
+func (h *ScimHandlers) RegisterRoutes(r chi.Router) {
+	r.Route("/scim", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(AuthMiddleware)
+			r.Use(AdminMiddleware)
+			r.Get("/tokens", h.ListTokens)
+			r.Post("/tokens", h.CreateToken)
+			r.Delete("/tokens/{id}", h.DeleteToken)
+		})
+		r.Route("/v2", func(r chi.Router) {
+			r.Use(h.authenticate)
+			r.Get("/ServiceProviderConfig", h.GetServiceProviderConfig)
+			r.Get("/Users", h.ListUsers)
+			r.Post("/Users", h.CreateUser)
+			r.Get("/Users/{id}", h.GetUser)
+			r.Put("/Users/{id}", h.ReplaceUser)
+			r.Patch("/Users/{id}", h.PatchUser)
+			r.Delete("/Users/{id}", h.DeleteUser)
+			r.Get("/Groups", h.ListGroups)
+			r.Post("/Groups", h.CreateGroup)
+			r.Get("/Groups/{id}", h.GetGroup)
+			r.Put("/Groups/{id}", h.ReplaceGroup)
+			r.Patch("/Groups/{id}", h.PatchGroup)
+			r.Delete("/Groups/{id}", h.DeleteGroup)
+		})
+	})
+}
+
+func (h *ScimHandlers) ListTokens(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	tokens, err := h.scimService.ListTokens(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domain.ScimTokenList{Tokens: tokens})
+}
+
+// CreateToken issues a SCIM token, its value is only part of this response
+func (h *ScimHandlers) CreateToken(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	var token domain.ScimToken
+	if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+
+	if err := h.scimService.CreateToken(r.Context(), &caller, &token); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(token)
+}
+
+func (h *ScimHandlers) DeleteToken(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	if err := h.scimService.DeleteToken(r.Context(), &caller, id); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authenticate resolves the SCIM token of the request to its tenant
+func (h *ScimHandlers) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantID, err := h.scimService.Authenticate(r.Context(), strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if err != nil {
+			writeScimError(w, r, err)
+			return
+		}
+		ctx := logging.With(r.Context(), "tenant_id", tenantID)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, scimTenantKey{}, tenantID)))
+	})
+}
+
+func scimTenant(r *http.Request) int {
+	tenantID, _ := r.Context().Value(scimTenantKey{}).(int)
+	return tenantID
+}
+
+func (h *ScimHandlers) GetServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	writeScim(w, http.StatusOK, scim.Config)
+}
+
+func (h *ScimHandlers) ListUsers(w http.ResponseWriter, r *http.Request) {
+	startIndex, count, err := parseScimPage(r)
+	if err != nil {
+		writeScimError(w, r, err)
+		return
+	}
+
+	list, err := h.scimService.ListUsers(r.Context(), scimTenant(r), r.URL.Query().Get("filter"), startIndex, count)
+	if err != nil {
+		writeScimError(w, r, err)
+		return
+	}
+	for i := range list.Resources.([]scim.User) {
+		user := &list.Resources.([]scim.User)[i]
+		user.Meta.Location = scimLocation(r, "Users", user.ID)
+	}
+	writeScim(w, http.StatusOK, list)
+}
+
+func (h *ScimHandlers) GetUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeScimError(w, r, store.ErrUserNotFound)
+		return
+	}
+
+	user, err := h.scimService.GetUser(r.Context(), scimTenant(r), id)
+	h.writeUser(w, r, http.StatusOK, user, err)
+}
+
+func (h *ScimHandlers) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var in scim.User
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeScimError(w, r, fmt.Errorf("%w: %v", errInvalidBody, err))
+		return
+	}
+
+	user, err := h.scimService.CreateUser(r.Context(), scimTenant(r), &in)
+	h.writeUser(w, r, http.StatusCreated, user, err)
+}
+
+func (h *ScimHandlers) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeScimError(w, r, store.ErrUserNotFound)
+		return
+	}
+
+	var in scim.User
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeScimError(w, r, fmt.Errorf("%w: %v", errInvalidBody, err))
+		return
+	}
+
+	user, err := h.scimService.ReplaceUser(r.Context(), scimTenant(r), id, &in)
+	h.writeUser(w, r, http.StatusOK, user, err)
+}
+
+func (h *ScimHandlers) PatchUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeScimError(w, r, store.ErrUserNotFound)
+		return
+	}
+
+	var patch scim.PatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeScimError(w, r, fmt.Errorf("%w: %v", errInvalidBody, err))
+		return
+	}
+
+	user, err := h.scimService.PatchUser(r.Context(), scimTenant(r), id, patch.Operations)
+	h.writeUser(w, r, http.StatusOK, user, err)
+}
+
+// DeleteUser deactivates the user, see ScimService.DeactivateUser
+func (h *ScimHandlers) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeScimError(w, r, store.ErrUserNotFound)
+		return
+	}
+
+	if err := h.scimService.DeactivateUser(r.Context(), scimTenant(r), id); err != nil {
+		writeScimError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ScimHandlers) writeUser(w http.ResponseWriter, r *http.Request, status int, user *scim.User, err error) {
+	if err != nil {
+		writeScimError(w, r, err)
+		return
+	}
+	user.Meta.Location = scimLocation(r, "Users", user.ID)
+	if status == http.StatusCreated {
+		w.Header().Set("Location", user.Meta.Location)
+	}
+	writeScim(w, status, user)
+}
+
+func (h *ScimHandlers) ListGroups(w http.ResponseWriter, r *http.Request) {
+	startIndex, count, err := parseScimPage(r)
+	if err != nil {
+		writeScimError(w, r, err)
+		return
+	}
+
+	list, err := h.scimService.ListGroups(r.Context(), scimTenant(r), r.URL.Query().Get("filter"), startIndex, count)
+	if err != nil {
+		writeScimError(w, r, err)
+		return
+	}
+	for i := range list.Resources.([]scim.Group) {
+		group := &list.Resources.([]scim.Group)[i]
+		group.Meta.Location = scimLocation(r, "Groups", group.ID)
+	}
+	writeScim(w, http.StatusOK, list)
+}
+
+func (h *ScimHandlers) GetGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeScimError(w, r, store.ErrTeamNotFound)
+		return
+	}
+
+	group, err := h.scimService.GetGroup(r.Context(), scimTenant(r), id)
+	h.writeGroup(w, r, http.StatusOK, group, err)
+}
+
+func (h *ScimHandlers) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var in scim.Group
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeScimError(w, r, fmt.Errorf("%w: %v", errInvalidBody, err))
+		return
+	}
+
+	group, err := h.scimService.CreateGroup(r.Context(), scimTenant(r), &in)
+	h.writeGroup(w, r, http.StatusCreated, group, err)
+}
+
+func (h *ScimHandlers) ReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeScimError(w, r, store.ErrTeamNotFound)
+		return
+	}
+
+	var in scim.Group
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeScimError(w, r, fmt.Errorf("%w: %v", errInvalidBody, err))
+		return
+	}
+
+	group, err := h.scimService.ReplaceGroup(r.Context(), scimTenant(r), id, &in)
+	h.writeGroup(w, r, http.StatusOK, group, err)
+}
+
+func (h *ScimHandlers) PatchGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeScimError(w, r, store.ErrTeamNotFound)
+		return
+	}
+
+	var patch scim.PatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeScimError(w, r, fmt.Errorf("%w: %v", errInvalidBody, err))
+		return
+	}
+
+	group, err := h.scimService.PatchGroup(r.Context(), scimTenant(r), id, patch.Operations)
+	h.writeGroup(w, r, http.StatusOK, group, err)
+}
+
+func (h *ScimHandlers) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeScimError(w, r, store.ErrTeamNotFound)
+		return
+	}
+
+	if err := h.scimService.DeleteGroup(r.Context(), scimTenant(r), id); err != nil {
+		writeScimError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ScimHandlers) writeGroup(w http.ResponseWriter, r *http.Request, status int, group *scim.Group, err error) {
+	if err != nil {
+		writeScimError(w, r, err)
+		return
+	}
+	group.Meta.Location = scimLocation(r, "Groups", group.ID)
+	if status == http.StatusCreated {
+		w.Header().Set("Location", group.Meta.Location)
+	}
+	writeScim(w, status, group)
+}
+
+// parseScimPage reads the 1-based startIndex and the count of a list request
+func parseScimPage(r *http.Request) (int, int, error) {
+	q := r.URL.Query()
+	startIndex, count := 1, scimDefaultCount
+	if v := q.Get("startIndex"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w: startIndex must be an integer", scim.ErrInvalidValue)
+		}
+		startIndex = max(n, 1)
+	}
+	if v := q.Get("count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w: count must be an integer", scim.ErrInvalidValue)
+		}
+		count = max(n, 0)
+	}
+	return startIndex, count, nil
+}
+
+// scimLocation is the absolute URL of a resource, as meta.location requires
+func scimLocation(r *http.Request, resource string, id string) string {
+	return baseURL(r) + "/scim/v2/" + resource + "/" + id
+}
+
+func writeScim(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeScimError answers with a SCIM error body, the SCIM counterpart of writeProblem
+func writeScimError(w http.ResponseWriter, r *http.Request, err error) {
+	domainErr := clientError(r, err)
+	status, ok := kindStatus[domainErr.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	body := scim.Error{
+		Schemas:  []string{scim.SchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scim.ScimType(domainErr.Code),
+		Detail:   domainErr.Message,
+	}
+	writeScim(w, status, body)
+}
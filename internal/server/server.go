@@ -1,39 +1,152 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
-	"strconv"
+	"sync"
 	"time"
 
-	_ "github.com/joho/godotenv/autoload"
-
+	"pwp-remastered/internal/blob"
+	"pwp-remastered/internal/config"
 	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/live"
+	"pwp-remastered/internal/metrics"
+	"pwp-remastered/internal/services"
+	"pwp-remastered/internal/store"
+	"pwp-remastered/internal/webhook"
+	"pwp-remastered/migrations"
 )
 
 type Server struct {
-	port          int
-	db            database.Service
-	userHandlers  *UserHandlers
-	eventHandlers *EventHandlers
+	cfg                config.Config
+	db                 database.Service
+	blobs              blob.BlobStore
+	broker             live.Broker
+	tokens             *TokenSigner
+	latestMigration    uint
+	shutdown           context.Context
+	userHandlers       *UserHandlers
+	eventHandlers      *EventHandlers
+	attachmentHandlers *AttachmentHandlers
+	rateHandlers       *RateHandlers
+	locationHandlers   *LocationHandlers
+	vehicleHandlers    *VehicleHandlers
+	settingsHandlers   *SettingsHandlers
+	teamHandlers       *TeamHandlers
+	webhookHandlers    *WebhookHandlers
+	periodHandlers     *PeriodHandlers
+	budgetHandlers     *BudgetHandlers
+	scimHandlers       *ScimHandlers
+	oidcHandlers       *OIDCHandlers
+	healthHandlers     *HealthHandlers
 }
 
-func NewServer() *http.Server {
-	port, _ := strconv.Atoi(os.Getenv("PORT"))
+// NewServer creates the API server from cfg, which must be valid. The caller
+// owns db and closes it once the server has shut down and stop has returned,
+// stop ends the background work using db and waits for it to finish.
+func NewServer(cfg config.Config, db database.Service) (*http.Server, func(), error) {
+	blobs, err := newBlobStore(cfg.Blob)
+	if err != nil {
+		return nil, nil, fmt.Errorf("blob store: %w", err)
+	}
+	latestMigration, err := migrations.Latest()
+	if err != nil {
+		return nil, nil, fmt.Errorf("migrations: %w", err)
+	}
+	db = metrics.InstrumentDB(db)
+
+	background, cancel := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	// run starts background work that stop waits for
+	run := func(work func(context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			work(background)
+		}()
+	}
+	stop := func() {
+		cancel()
+		workers.Wait()
+	}
+
+	// Event streams never finish by themselves, they end when Shutdown starts
+	// so it can wait for them before the caller closes db
+	shutdown, endStreams := context.WithCancel(context.Background())
+
 	NewServer := &Server{
-		port: port,
-		db:   database.New(),
+		cfg:             cfg,
+		db:              db,
+		blobs:           blobs,
+		broker:          newBroker(db, cfg.LiveBroker, run),
+		tokens:          NewTokenSigner(cfg.Auth),
+		latestMigration: latestMigration,
+		shutdown:        shutdown,
 	}
 
 	// Declare Server config
 	server := &http.Server{
-		Addr:         fmt.Sprintf(":%d", NewServer.port),
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
 		Handler:      NewServer.RegisterRoutes(),
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
 	}
+	server.RegisterOnShutdown(endStreams)
+
+	run(webhook.NewDispatcher(store.NewWebhookStore(db)).Run)
+	run(services.NewBlobSweeper(store.NewAttachmentStore(db), blobs).Run)
 
-	return server
+	// Metrics are served on their own address, away from the public port
+	if cfg.MetricsAddr != "" {
+		metricsServer := &http.Server{
+			Addr:              cfg.MetricsAddr,
+			Handler:           metrics.Handler(),
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("metrics server", "error", err)
+			}
+		}()
+		server.RegisterOnShutdown(func() { metricsServer.Close() })
+	}
+
+	return server, stop, nil
+}
+
+// newBroker picks the live event stream backend: "memory" for a single
+// replica or "postgres" to fan out across replicas with LISTEN/NOTIFY. The
+// configuration only allows these two.
+func newBroker(db database.Service, backend string, run func(func(context.Context))) live.Broker {
+	const backlog = 1000
+	if backend == "postgres" {
+		broker := live.NewPostgresBroker(db, backlog)
+		run(broker.Run)
+		return broker
+	}
+	return live.NewMemoryBroker(backlog)
+}
+
+// newBlobStore picks the attachment storage backend, "local" or "s3"
+func newBlobStore(cfg config.Blob) (blob.BlobStore, error) {
+	switch cfg.Backend {
+	case "local":
+		return blob.NewLocalStore(cfg.LocalDir)
+	case "s3":
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return blob.NewS3Store(ctx, blob.S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			Bucket:    cfg.S3.Bucket,
+			Region:    cfg.S3.Region,
+			UseSSL:    cfg.S3.UseSSL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown blob backend %q", cfg.Backend)
+	}
 }
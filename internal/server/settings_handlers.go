@@ -0,0 +1,188 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type SettingsHandlers struct {
+	settingsService *services.SettingsService
+	authService     *services.AuthService
+	oidcService     *services.OIDCService
+}
+
+// NewSettingsHandlers creates a new tenant settings handlers
+func NewSettingsHandlers(settingsService *services.SettingsService, authService *services.AuthService, oidcService *services.OIDCService) *SettingsHandlers {
+	return &SettingsHandlers{settingsService: settingsService, authService: authService, oidcService: oidcService}
+}
+
+func (h *SettingsHandlers) RegisterRoutes(r chi.Router) {
+	r.Route("/tenant/settings", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", h.GetTenantSettings)
+		r.With(AdminMiddleware).Put("/", h.UpdateTenantSettings)
+		r.Route("/ldap", func(r chi.Router) {
+			r.Use(AdminMiddleware)
+			r.Get("/", h.GetLDAPConfig)
+			r.Put("/", h.UpdateLDAPConfig)
+			r.Delete("/", h.DeleteLDAPConfig)
+		})
+		r.Route("/oidc", func(r chi.Router) {
+			r.Use(AdminMiddleware)
+			r.Get("/", h.GetOIDCConfig)
+			r.Put("/", h.UpdateOIDCConfig)
+			r.Delete("/", h.DeleteOIDCConfig)
+		})
+	})
+}
+
+func (h *SettingsHandlers) GetTenantSettings(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	settings, err := h.settingsService.GetTenantSettings(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+func (h *SettingsHandlers) UpdateTenantSettings(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	var settings domain.TenantSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+
+	if err := h.settingsService.UpdateTenantSettings(r.Context(), &caller, &settings); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+func (h *SettingsHandlers) GetLDAPConfig(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	config, err := h.authService.GetLDAPConfig(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config)
+}
+
+func (h *SettingsHandlers) UpdateLDAPConfig(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	var config domain.LDAPConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+
+	if err := h.authService.UpdateLDAPConfig(r.Context(), &caller, &config); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config)
+}
+
+func (h *SettingsHandlers) DeleteLDAPConfig(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	if err := h.authService.DeleteLDAPConfig(r.Context(), &caller); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SettingsHandlers) GetOIDCConfig(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	config, err := h.oidcService.GetOIDCConfig(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config)
+}
+
+func (h *SettingsHandlers) UpdateOIDCConfig(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	var config domain.OIDCConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+
+	if err := h.oidcService.UpdateOIDCConfig(r.Context(), &caller, &config); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config)
+}
+
+func (h *SettingsHandlers) DeleteOIDCConfig(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	if err := h.oidcService.DeleteOIDCConfig(r.Context(), &caller); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/services"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type TeamHandlers struct {
+	teamService *services.TeamService
+}
+
+// NewTeamHandlers creates a new team handlers
+func NewTeamHandlers(teamService *services.TeamService) *TeamHandlers {
+	return &TeamHandlers{
+		teamService: teamService,
+	}
+}
+
+func (h *TeamHandlers) RegisterRoutes(r chi.Router) {
+	r.Route("/teams", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", h.ListTeams)
+		r.Get("/{id}", h.GetTeam)
+		r.Group(func(r chi.Router) {
+			r.Use(AdminMiddleware)
+			r.Post("/", h.CreateTeam)
+			r.Put("/{id}", h.UpdateTeam)
+			r.Delete("/{id}", h.DeleteTeam)
+			r.Put("/{id}/members/{userID}", h.AddMember)
+			r.Delete("/{id}/members/{userID}", h.RemoveMember)
+		})
+	})
+}
+
+func (h *TeamHandlers) ListTeams(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	teams, err := h.teamService.ListTeams(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domain.TeamList{Teams: teams})
+}
+
+// GetTeam returns a team with its members
+func (h *TeamHandlers) GetTeam(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	team, err := h.teamService.GetTeam(r.Context(), &caller, id)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(team)
+}
+
+func (h *TeamHandlers) CreateTeam(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	var team domain.Team
+	if err := json.NewDecoder(r.Body).Decode(&team); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+
+	if err := h.teamService.CreateTeam(r.Context(), &caller, &team); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(team)
+}
+
+func (h *TeamHandlers) UpdateTeam(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	var team domain.Team
+	if err := json.NewDecoder(r.Body).Decode(&team); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+	team.ID = id
+
+	if err := h.teamService.UpdateTeam(r.Context(), &caller, &team); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(team)
+}
+
+func (h *TeamHandlers) DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	if err := h.teamService.DeleteTeam(r.Context(), &caller, id); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddMember adds a user to a team, adding an existing member again is a no-op
+func (h *TeamHandlers) AddMember(w http.ResponseWriter, r *http.Request) {
+	h.changeMember(w, r, h.teamService.AddMember)
+}
+
+func (h *TeamHandlers) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	h.changeMember(w, r, h.teamService.RemoveMember)
+}
+
+func (h *TeamHandlers) changeMember(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, caller *domain.User, teamID int, userID int) error) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	teamID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	if err := change(r.Context(), &caller, teamID, userID); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,23 +2,27 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"pwp-remastered/internal/domain"
 	"pwp-remastered/internal/services"
+	"pwp-remastered/internal/store"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/matthewhartstonge/argon2"
 )
 
 type UserHandlers struct {
 	userService *services.UserService
+	authService *services.AuthService
+	tokens      *TokenSigner
 }
 
-func NewUserHandlers(userService *services.UserService) *UserHandlers {
+func NewUserHandlers(userService *services.UserService, authService *services.AuthService, tokens *TokenSigner) *UserHandlers {
 	return &UserHandlers{
 		userService: userService,
+		authService: authService,
+		tokens:      tokens,
 	}
 }
 
@@ -29,6 +33,7 @@ func (h *UserHandlers) RegisterRoutes(r chi.Router) {
 		r.Post("/", h.CreateUser)
 		r.Get("/{id}", h.GetUser)
 		r.Get("/me", h.GetSelfUser)
+		r.Get("/me/reports", h.ListReports)
 		r.Get("/all", h.GetAllUsers)
 		r.Put("/{id}", h.UpdateUser)
 		r.Put("/me", h.UpdateSelfUser)
@@ -42,38 +47,25 @@ func (h *UserHandlers) RegisterRoutes(r chi.Router) {
 func (h *UserHandlers) ListUsers(w http.ResponseWriter, r *http.Request) {
 	caller, err := ExtractUserFromRequest(r)
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeProblem(w, r, errUnauthorized)
 		return
 	}
 
-	tokenString := r.Header.Get("Authorization")
-	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-		tokenString = tokenString[7:]
-	}
-	if tokenString != "" {
-		token, err := ParseJWT(tokenString)
-		if err == nil && token.Valid {
-			if claims, ok := token.Claims.(jwt.MapClaims); ok {
-				if idVal, ok := claims["user_id"].(float64); ok {
-					caller.ID = int(idVal)
-				}
-				if isAdmin, ok := claims["is_admin"].(bool); ok {
-					caller.IsAdmin = isAdmin
-				}
-			}
-		}
+	filter, err := parseUserFilter(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
 	}
 
-	users, err := h.userService.ListUsers(&caller)
+	users, err := h.userService.ListUsers(r.Context(), &caller, filter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProblem(w, r, err)
 		return
 	}
 
-	response := map[string][]domain.User{"users": users}
-	jsonResp, err := json.Marshal(response)
+	jsonResp, err := json.Marshal(users)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProblem(w, r, err)
 		return
 	}
 
@@ -85,23 +77,23 @@ func (h *UserHandlers) GetUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		writeProblem(w, r, errInvalidID)
 		return
 	}
 
-	user, err := h.userService.GetUser(id)
+	user, err := h.userService.GetUser(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProblem(w, r, err)
 		return
 	}
 	if user == nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		writeProblem(w, r, store.ErrUserNotFound)
 		return
 	}
 
 	jsonResp, err := json.Marshal(user)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProblem(w, r, err)
 		return
 	}
 
@@ -112,23 +104,23 @@ func (h *UserHandlers) GetUser(w http.ResponseWriter, r *http.Request) {
 func (h *UserHandlers) GetSelfUser(w http.ResponseWriter, r *http.Request) {
 	caller, err := ExtractUserFromRequest(r)
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeProblem(w, r, errUnauthorized)
 		return
 	}
 
-	user, err := h.userService.GetUser(caller.ID)
+	user, err := h.userService.GetUser(r.Context(), caller.ID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProblem(w, r, err)
 		return
 	}
 	if user == nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		writeProblem(w, r, store.ErrUserNotFound)
 		return
 	}
 
 	jsonResp, err := json.Marshal(user)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProblem(w, r, err)
 		return
 	}
 
@@ -139,18 +131,18 @@ func (h *UserHandlers) GetSelfUser(w http.ResponseWriter, r *http.Request) {
 func (h *UserHandlers) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var user domain.User
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, fmt.Errorf("%w: %v", errInvalidBody, err))
 		return
 	}
 
-	if err := h.userService.CreateUser(&user); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.userService.CreateUser(r.Context(), &user); err != nil {
+		writeProblem(w, r, err)
 		return
 	}
 
 	jsonResp, err := json.Marshal(user)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProblem(w, r, err)
 		return
 	}
 
@@ -163,45 +155,31 @@ func (h *UserHandlers) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		writeProblem(w, r, errInvalidID)
 		return
 	}
 
 	var user domain.User
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, fmt.Errorf("%w: %v", errInvalidBody, err))
 		return
 	}
 	user.ID = id
 
-	// Extract caller from JWT (for now, only ID and is_admin fields are extracted)
-	var caller domain.User
-	tokenString := r.Header.Get("Authorization")
-	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-		tokenString = tokenString[7:]
-	}
-	if tokenString != "" {
-		token, err := ParseJWT(tokenString)
-		if err == nil && token.Valid {
-			if claims, ok := token.Claims.(jwt.MapClaims); ok {
-				if idVal, ok := claims["user_id"].(float64); ok {
-					caller.ID = int(idVal)
-				}
-				if isAdmin, ok := claims["is_admin"].(bool); ok {
-					caller.IsAdmin = isAdmin
-				}
-			}
-		}
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
 	}
 
-	if err := h.userService.UpdateUser(&caller, &user); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.userService.UpdateUser(r.Context(), &caller, &user); err != nil {
+		writeProblem(w, r, err)
 		return
 	}
 
 	jsonResp, err := json.Marshal(user)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProblem(w, r, err)
 		return
 	}
 
@@ -214,36 +192,26 @@ func (h *UserHandlers) UpdateSelfUser(w http.ResponseWriter, r *http.Request) {
 	var caller domain.User
 
 	if err := json.NewDecoder(r.Body).Decode(&caller); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, fmt.Errorf("%w: %v", errInvalidBody, err))
 		return
 	}
 
-	tokenString := r.Header.Get("Authorization")
-	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-		tokenString = tokenString[7:]
-	}
-	if tokenString != "" {
-		token, err := ParseJWT(tokenString)
-		if err == nil && token.Valid {
-			if claims, ok := token.Claims.(jwt.MapClaims); ok {
-				if idVal, ok := claims["user_id"].(float64); ok {
-					caller.ID = int(idVal)
-				}
-				if isAdmin, ok := claims["is_admin"].(bool); ok {
-					caller.IsAdmin = isAdmin
-				}
-			}
-		}
+	token, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
 	}
+	caller.ID = token.ID
+	caller.IsAdmin = token.IsAdmin
 
-	if err := h.userService.UpdateSelfUser(&caller); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.userService.UpdateSelfUser(r.Context(), &caller); err != nil {
+		writeProblem(w, r, err)
 		return
 	}
 
 	jsonResp, err := json.Marshal(caller)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProblem(w, r, err)
 		return
 	}
 
@@ -255,12 +223,12 @@ func (h *UserHandlers) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		writeProblem(w, r, errInvalidID)
 		return
 	}
 
-	if err := h.userService.DeleteUser(id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.userService.DeleteUser(r.Context(), id); err != nil {
+		writeProblem(w, r, err)
 		return
 	}
 
@@ -271,57 +239,39 @@ func (h *UserHandlers) ChangeUserStatus(w http.ResponseWriter, r *http.Request)
 	var caller domain.User
 
 	if err := json.NewDecoder(r.Body).Decode(&caller); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, fmt.Errorf("%w: %v", errInvalidBody, err))
 		return
 	}
 
-	tokenString := r.Header.Get("Authorization")
-	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-		tokenString = tokenString[7:]
-	}
-	if tokenString != "" {
-		token, err := ParseJWT(tokenString)
-		if err == nil && token.Valid {
-			if claims, ok := token.Claims.(jwt.MapClaims); ok {
-				if idVal, ok := claims["user_id"].(float64); ok {
-					caller.ID = int(idVal)
-				}
-				if isAdmin, ok := claims["is_admin"].(bool); ok {
-					caller.IsAdmin = isAdmin
-				}
-			}
-		}
+	token, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
 	}
+	caller.ID = token.ID
+	caller.IsAdmin = token.IsAdmin
 
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		writeProblem(w, r, errInvalidID)
 		return
 	}
 
-	if err := h.userService.ChangeUserStatus(&caller, id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.userService.ChangeUserStatus(r.Context(), &caller, id); err != nil {
+		writeProblem(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// AuthMiddleware checks for JWT in Authorization header and enforces authentication
+// AuthMiddleware enforces authentication, it refuses requests that
+// TokenSigner.Authenticate found no valid token on
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenString := r.Header.Get("Authorization")
-		if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-			tokenString = tokenString[7:]
-		}
-		if tokenString == "" {
-			http.Error(w, "Missing token", http.StatusUnauthorized)
-			return
-		}
-		token, err := ParseJWT(tokenString)
-		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		if _, err := ExtractUserFromRequest(r); err != nil {
+			writeProblem(w, r, errInvalidToken)
 			return
 		}
 		next.ServeHTTP(w, r)
@@ -330,56 +280,40 @@ func AuthMiddleware(next http.Handler) http.Handler {
 
 func AdminMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenString := r.Header.Get("Authorization")
-		if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-			tokenString = tokenString[7:]
-		}
-		if tokenString == "" {
-			http.Error(w, "Missing token", http.StatusUnauthorized)
-			return
-		}
-		token, err := ParseJWT(tokenString)
-		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		caller, err := ExtractUserFromRequest(r)
+		if err != nil {
+			writeProblem(w, r, errInvalidToken)
 			return
 		}
-		if !token.Claims.(jwt.MapClaims)["is_admin"].(bool) {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		if !caller.IsAdmin {
+			writeProblem(w, r, errAdminOnly)
 			return
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-// Login returns a JWT token
+// Login returns a JWT token. Usernames are unique per tenant, so tenant_id
+// names the tenant the user signs in to.
 func (h *UserHandlers) Login(w http.ResponseWriter, r *http.Request) {
 	type loginRequest struct {
 		Username string `json:"username"`
 		Password string `json:"password"`
+		TenantID int    `json:"tenant_id"`
 	}
 	var req loginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
-		return
-	}
-	user, err := h.userService.GetUserByUsername(req.Username)
-	if err != nil || user == nil {
-		http.Error(w, "Giriş bilgileri hatalı.", http.StatusUnauthorized)
+		writeProblem(w, r, errInvalidBody)
 		return
 	}
-
-	if user.Status == 0 {
-		http.Error(w, "Kulanıcı hesabı inaktif.", http.StatusForbidden)
-		return
-	}
-	// Use argon2 to verify password
-	if ok, _ := argon2.VerifyEncoded([]byte(req.Password), []byte(user.HashedPassword)); !ok {
-		http.Error(w, "Giriş bilgileri hatalı.", http.StatusUnauthorized)
+	user, err := h.authService.Login(r.Context(), req.TenantID, req.Username, req.Password)
+	if err != nil {
+		writeProblem(w, r, err)
 		return
 	}
-	token, err := GenerateJWT(user.ID, user.Username, user.IsAdmin)
+	token, err := h.tokens.Sign(user)
 	if err != nil {
-		http.Error(w, "Could not generate token", http.StatusInternalServerError)
+		writeProblem(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -404,35 +338,20 @@ func (h *UserHandlers) UpdateSelfPassword(w http.ResponseWriter, r *http.Request
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&passwordRequest); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeProblem(w, r, fmt.Errorf("%w: %v", errInvalidBody, err))
 		return
 	}
 
-	tokenString := r.Header.Get("Authorization")
-	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-		tokenString = tokenString[7:]
-	}
-	if tokenString == "" {
-		http.Error(w, "Missing token", http.StatusUnauthorized)
-		return
-	}
-	token, err := ParseJWT(tokenString)
-	if err != nil || !token.Valid {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
+	token, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errInvalidToken)
 		return
 	}
+	caller.ID = token.ID
+	caller.IsAdmin = token.IsAdmin
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		if idVal, ok := claims["user_id"].(float64); ok {
-			caller.ID = int(idVal)
-		}
-		if isAdmin, ok := claims["is_admin"].(bool); ok {
-			caller.IsAdmin = isAdmin
-		}
-	}
-
-	if err := h.userService.UpdateSelfPassword(&caller, passwordRequest.Password); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.userService.UpdateSelfPassword(r.Context(), &caller, passwordRequest.Password); err != nil {
+		writeProblem(w, r, err)
 		return
 	}
 
@@ -442,28 +361,57 @@ func (h *UserHandlers) UpdateSelfPassword(w http.ResponseWriter, r *http.Request
 func (h *UserHandlers) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	caller, err := ExtractUserFromRequest(r)
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeProblem(w, r, errUnauthorized)
 		return
 	}
 
 	if !caller.IsAdmin {
-		http.Error(w, "Unauthorized", http.StatusForbidden)
+		writeProblem(w, r, errAdminOnly)
 		return
 	}
 
-	users, err := h.userService.GetAllUsers(&caller)
+	filter, err := parseUserFilter(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProblem(w, r, err)
 		return
 	}
 
-	response := map[string][]domain.User{"users": users}
-	jsonResp, err := json.Marshal(response)
+	users, err := h.userService.GetAllUsers(r.Context(), &caller, filter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProblem(w, r, err)
+		return
+	}
+
+	jsonResp, err := json.Marshal(users)
+	if err != nil {
+		writeProblem(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(jsonResp)
 }
+
+// ListReports lists the users reporting to the caller, directly or through the hierarchy
+func (h *UserHandlers) ListReports(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	filter, err := parseUserFilter(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	users, err := h.userService.ListReports(r.Context(), &caller, filter)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(users)
+}
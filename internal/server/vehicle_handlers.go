@@ -0,0 +1,219 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/services"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type VehicleHandlers struct {
+	vehicleService  *services.VehicleService
+	settingsService *services.SettingsService
+}
+
+// NewVehicleHandlers creates a new vehicle handlers
+func NewVehicleHandlers(vehicleService *services.VehicleService, settingsService *services.SettingsService) *VehicleHandlers {
+	return &VehicleHandlers{
+		vehicleService:  vehicleService,
+		settingsService: settingsService,
+	}
+}
+
+func (h *VehicleHandlers) RegisterRoutes(r chi.Router) {
+	r.Route("/vehicles", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", h.ListVehicles)
+		r.Post("/", h.CreateVehicle)
+		r.Get("/{id}", h.GetVehicle)
+		r.Put("/{id}", h.UpdateVehicle)
+		r.Get("/{id}/odometer-readings", h.GetReadings)
+		r.Post("/{id}/odometer-readings", h.RecordReading)
+	})
+	r.With(AuthMiddleware).Get("/reports/road-prices/vehicles", h.GetRoadPriceByVehicle)
+}
+
+func (h *VehicleHandlers) ListVehicles(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	vehicles, err := h.vehicleService.ListVehicles(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domain.VehicleList{Vehicles: vehicles})
+}
+
+func (h *VehicleHandlers) GetVehicle(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	vehicle, err := h.vehicleService.GetVehicle(r.Context(), &caller, id)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(vehicle)
+}
+
+func (h *VehicleHandlers) CreateVehicle(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	vehicle := domain.Vehicle{IsActive: true}
+	if err := json.NewDecoder(r.Body).Decode(&vehicle); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+
+	if err := h.vehicleService.CreateVehicle(r.Context(), &caller, &vehicle); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(vehicle)
+}
+
+func (h *VehicleHandlers) UpdateVehicle(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	var vehicle domain.Vehicle
+	if err := json.NewDecoder(r.Body).Decode(&vehicle); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+	vehicle.ID = id
+
+	if err := h.vehicleService.UpdateVehicle(r.Context(), &caller, &vehicle); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(vehicle)
+}
+
+func (h *VehicleHandlers) GetReadings(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	readings, err := h.vehicleService.GetReadings(r.Context(), &caller, id)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"readings": readings})
+}
+
+func (h *VehicleHandlers) RecordReading(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	var reading domain.OdometerReading
+	if err := json.NewDecoder(r.Body).Decode(&reading); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+	reading.VehicleID = id
+
+	if err := h.vehicleService.RecordReading(r.Context(), &caller, &reading); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reading)
+}
+
+// GetRoadPriceByVehicle reports road price totals per vehicle between startdate and
+// enddate, see parseDateRange for the accepted values
+func (h *VehicleHandlers) GetRoadPriceByVehicle(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	loc, err := h.settingsService.TimeZone(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	q := r.URL.Query()
+	if q.Get("startdate") == "" || q.Get("enddate") == "" {
+		writeProblem(w, r, fmt.Errorf("%w: missing startdate or enddate", errInvalidListParam))
+		return
+	}
+	dateRange, err := parseDateRange(q, loc)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	totals, err := h.vehicleService.GetRoadPriceByVehicle(r.Context(), &caller, dateRange)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domain.VehicleRoadPriceReport{Vehicles: totals})
+}
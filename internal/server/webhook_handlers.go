@@ -0,0 +1,241 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/services"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type WebhookHandlers struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhookHandlers creates a new webhook handlers
+func NewWebhookHandlers(webhookService *services.WebhookService) *WebhookHandlers {
+	return &WebhookHandlers{
+		webhookService: webhookService,
+	}
+}
+
+func (h *WebhookHandlers) RegisterRoutes(r chi.Router) {
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Use(AdminMiddleware)
+		r.Get("/", h.ListSubscriptions)
+		r.Post("/", h.CreateSubscription)
+		r.Get("/deliveries", h.ListDeliveries)
+		r.Get("/deliveries/{id}", h.GetDelivery)
+		r.Post("/deliveries/{id}/redeliver", h.Redeliver)
+		r.Get("/{id}", h.GetSubscription)
+		r.Put("/{id}", h.UpdateSubscription)
+		r.Delete("/{id}", h.DeleteSubscription)
+	})
+}
+
+func (h *WebhookHandlers) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	subscriptions, err := h.webhookService.ListSubscriptions(r.Context(), &caller)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(domain.WebhookSubscriptionList{Subscriptions: subscriptions})
+}
+
+func (h *WebhookHandlers) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	subscription, err := h.webhookService.GetSubscription(r.Context(), &caller, id)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscription)
+}
+
+// CreateSubscription adds a subscription, the response carries the signing secret
+func (h *WebhookHandlers) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	subscription := domain.WebhookSubscription{IsActive: true}
+	if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+
+	if err := h.webhookService.CreateSubscription(r.Context(), &caller, &subscription); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(subscription)
+}
+
+func (h *WebhookHandlers) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	var subscription domain.WebhookSubscription
+	if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil {
+		writeProblem(w, r, errInvalidBody)
+		return
+	}
+	subscription.ID = id
+
+	if err := h.webhookService.UpdateSubscription(r.Context(), &caller, &subscription); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscription)
+}
+
+func (h *WebhookHandlers) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	if err := h.webhookService.DeleteSubscription(r.Context(), &caller, id); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries lists the delivery log, newest first, filtered by the optional
+// subscription_id and status query parameters
+func (h *WebhookHandlers) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	var filter domain.WebhookDeliveryFilter
+	if filter.PageRequest, err = parsePageRequest(q); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+	if v := q.Get("subscription_id"); v != "" {
+		if filter.SubscriptionID, err = strconv.Atoi(v); err != nil {
+			writeProblem(w, r, fmt.Errorf("%w: subscription_id must be an integer", errInvalidListParam))
+			return
+		}
+	}
+	for _, v := range q["status"] {
+		for _, status := range strings.Split(v, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				filter.Statuses = append(filter.Statuses, status)
+			}
+		}
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(r.Context(), &caller, filter)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// GetDelivery returns a delivery with its attempt log
+func (h *WebhookHandlers) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	delivery, err := h.webhookService.GetDelivery(r.Context(), &caller, id)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// Redeliver queues a delivery to be sent again by the dispatcher
+func (h *WebhookHandlers) Redeliver(w http.ResponseWriter, r *http.Request) {
+	caller, err := ExtractUserFromRequest(r)
+	if err != nil {
+		writeProblem(w, r, errUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, errInvalidID)
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(r.Context(), &caller, id)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
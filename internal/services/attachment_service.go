@@ -0,0 +1,206 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"pwp-remastered/internal/blob"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+	"slices"
+)
+
+var (
+	ErrAttachmentTooLarge   = domain.NewTooLargeError("attachment_too_large", "attachment exceeds the maximum allowed size")
+	ErrAttachmentTypeDenied = domain.NewUnsupportedMediaTypeError("attachment_type_denied", "attachment type is not allowed")
+)
+
+// AttachmentLimits restricts what can be uploaded as an event attachment
+type AttachmentLimits struct {
+	MaxSize      int64
+	AllowedTypes []string
+}
+
+// DefaultAttachmentLimits accepts receipts up to 10 MB as PDF or common image formats
+var DefaultAttachmentLimits = AttachmentLimits{
+	MaxSize: 10 << 20,
+	AllowedTypes: []string{
+		"application/pdf",
+		"image/jpeg",
+		"image/png",
+		"image/webp",
+		"image/heic",
+	},
+}
+
+// AttachmentService handles business logic for event attachments
+type AttachmentService struct {
+	store  store.AttachmentStore
+	events store.EventStore
+	blobs  blob.BlobStore
+	limits AttachmentLimits
+	policy *EventPolicy
+}
+
+// NewAttachmentService creates a new attachment service
+func NewAttachmentService(attachmentStore store.AttachmentStore, eventStore store.EventStore, blobs blob.BlobStore, limits AttachmentLimits, policy *EventPolicy) *AttachmentService {
+	return &AttachmentService{
+		store:  attachmentStore,
+		events: eventStore,
+		blobs:  blobs,
+		limits: limits,
+		policy: policy,
+	}
+}
+
+// MaxSize returns the largest accepted attachment in bytes
+func (s *AttachmentService) MaxSize() int64 {
+	return s.limits.MaxSize
+}
+
+// Upload stores the contents of r and records it as an attachment of the
+// event. The type is taken from the contents, never from the client.
+func (s *AttachmentService) Upload(ctx context.Context, caller *domain.User, eventID int, fileName string, size int64, r io.Reader) (*domain.Attachment, error) {
+	ctx, span := tracer.Start(ctx, "AttachmentService.Upload")
+	defer span.End()
+	event, err := s.events.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Authorize(ctx, caller, event, EventUpdate); err != nil {
+		return nil, err
+	}
+	if size > s.limits.MaxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+	contentType := sniffContentType(head)
+	if !slices.Contains(s.limits.AllowedTypes, contentType) {
+		return nil, ErrAttachmentTypeDenied
+	}
+
+	key, err := newStorageKey(eventID)
+	if err != nil {
+		return nil, err
+	}
+	body := io.MultiReader(bytes.NewReader(head), r)
+	if err := s.blobs.Put(ctx, key, body, size, contentType); err != nil {
+		return nil, err
+	}
+
+	attachment := &domain.Attachment{
+		EventID:     eventID,
+		UserID:      caller.ID,
+		FileName:    filepath.Base(fileName),
+		ContentType: contentType,
+		Size:        size,
+		StorageKey:  key,
+	}
+	if err := s.store.CreateAttachment(ctx, attachment); err != nil {
+		_ = s.blobs.Delete(ctx, key)
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// ListAttachments returns the attachments of an event
+func (s *AttachmentService) ListAttachments(ctx context.Context, caller *domain.User, eventID int) ([]domain.Attachment, error) {
+	ctx, span := tracer.Start(ctx, "AttachmentService.ListAttachments")
+	defer span.End()
+	event, err := s.events.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Authorize(ctx, caller, event, EventRead); err != nil {
+		return nil, err
+	}
+	return s.store.ListEventAttachments(ctx, eventID)
+}
+
+// OpenAttachment returns the attachment metadata and a reader for its contents.
+// The caller must close the reader.
+func (s *AttachmentService) OpenAttachment(ctx context.Context, caller *domain.User, eventID int, id int) (*domain.Attachment, io.ReadCloser, error) {
+	ctx, span := tracer.Start(ctx, "AttachmentService.OpenAttachment")
+	defer span.End()
+	event, err := s.events.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.policy.Authorize(ctx, caller, event, EventRead); err != nil {
+		return nil, nil, err
+	}
+
+	attachment, err := s.store.GetAttachment(ctx, eventID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, err := s.blobs.Get(ctx, attachment.StorageKey)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, nil, store.ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, rc, nil
+}
+
+// DeleteAttachment removes an attachment and its stored contents
+func (s *AttachmentService) DeleteAttachment(ctx context.Context, caller *domain.User, eventID int, id int) error {
+	ctx, span := tracer.Start(ctx, "AttachmentService.DeleteAttachment")
+	defer span.End()
+	event, err := s.events.GetEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if err := s.policy.Authorize(ctx, caller, event, EventUpdate); err != nil {
+		return err
+	}
+
+	attachment, err := s.store.GetAttachment(ctx, eventID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.store.DeleteAttachment(ctx, eventID, id); err != nil {
+		return err
+	}
+	return s.blobs.Delete(ctx, attachment.StorageKey)
+}
+
+func newStorageKey(eventID int) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("events/%d/%s", eventID, hex.EncodeToString(buf)), nil
+}
+
+// heicBrands are the ISO base media file brands of HEIC and HEIF images
+var heicBrands = []string{"heic", "heix", "heim", "heis", "hevc", "hevx", "mif1", "msf1"}
+
+// sniffContentType returns the media type of a file from its first bytes.
+// http.DetectContentType knows PDF and the common web image formats, HEIC
+// photos from phones are recognised by their ftyp box. Anything else is
+// application/octet-stream, whatever the client declared.
+func sniffContentType(head []byte) string {
+	contentType := http.DetectContentType(head)
+	if contentType == "application/octet-stream" && len(head) >= 12 && string(head[4:8]) == "ftyp" && slices.Contains(heicBrands, string(head[8:12])) {
+		return "image/heic"
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return contentType
+}
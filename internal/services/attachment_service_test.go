@@ -0,0 +1,27 @@
+package services
+
+import "testing"
+
+func TestSniffContentType(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"pdf", "%PDF-1.7\n%\xe2\xe3\xcf\xd3\n", "application/pdf"},
+		{"png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png"},
+		{"jpeg", "\xff\xd8\xff\xe0\x00\x10JFIF\x00", "image/jpeg"},
+		{"heic", "\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic", "image/heic"},
+		{"heif brand", "\x00\x00\x00\x18ftypmif1\x00\x00\x00\x00mif1heic", "image/heic"},
+		{"other ftyp brand", "\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2", "application/octet-stream"},
+		{"text", "Fiş tutarı: 120 TL", "text/plain"},
+		// An executable is not a receipt, whatever type the client declares
+		{"binary", "MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff", "application/octet-stream"},
+		{"short", "\x00\x01", "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		if got := sniffContentType([]byte(tt.head)); got != tt.want {
+			t.Errorf("%s: sniffContentType() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
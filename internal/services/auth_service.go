@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/metrics"
+	"pwp-remastered/internal/store"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matthewhartstonge/argon2"
+)
+
+var (
+	ErrInvalidCredentials = domain.NewUnauthorizedError("invalid_credentials", "invalid username or password")
+	ErrAccountInactive    = domain.NewForbiddenError("account_inactive", "user account is inactive")
+	ErrTenantRequired     = domain.NewValidationError("tenant_required", "tenant_id is required to sign in")
+	ErrLDAPAccount        = domain.NewForbiddenError("ldap_account_incomplete", "directory entry has no email address")
+	ErrInvalidLDAPConfig  = domain.NewValidationError("invalid_ldap_config", "LDAP configuration needs an ldap:// or ldaps:// url, a base_dn and filters containing %s")
+)
+
+// Identity is what an authenticator knows about a user after a successful
+// login. Nil flags are not managed by the authenticator.
+type Identity struct {
+	Email        string
+	FirstName    string
+	LastName     string
+	IsAdmin      *bool
+	IsAccountant *bool
+}
+
+// Authenticator verifies the password of a login. user is the local account,
+// nil when there is none yet. A nil Identity leaves the account as it is.
+type Authenticator interface {
+	Authenticate(user *domain.User, username string, password string) (*Identity, error)
+}
+
+// LocalAuthenticator checks the argon2 hash stored with the account
+type LocalAuthenticator struct{}
+
+func (LocalAuthenticator) Authenticate(user *domain.User, username string, password string) (*Identity, error) {
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+	if ok, _ := argon2.VerifyEncoded([]byte(password), []byte(user.HashedPassword)); !ok {
+		return nil, ErrInvalidCredentials
+	}
+	return nil, nil
+}
+
+// AuthService signs users in with the authenticator of their tenant: LDAP
+// when the tenant enabled it, the local password otherwise
+type AuthService struct {
+	users store.UserStore
+	ldap  store.LDAPStore
+	dial  LDAPDialer
+}
+
+// NewAuthService creates a new auth service
+func NewAuthService(userStore store.UserStore, ldapStore store.LDAPStore) *AuthService {
+	return &AuthService{users: userStore, ldap: ldapStore, dial: DialLDAP}
+}
+
+// Login authenticates username in the tenant and returns the account, synced
+// with the directory for LDAP tenants. Usernames are unique per tenant, so the
+// tenant is always needed. Users without an account can only sign in to an
+// LDAP tenant, the account is created on first login.
+func (s *AuthService) Login(ctx context.Context, tenantID int, username string, password string) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Login")
+	defer span.End()
+	user, method, err := s.login(ctx, tenantID, username, password)
+	metrics.ObserveLogin(method, err)
+	return user, err
+}
+
+// login authenticates the user and returns the method that decided, local
+// or ldap
+func (s *AuthService) login(ctx context.Context, tenantID int, username string, password string) (*domain.User, string, error) {
+	if tenantID == 0 {
+		return nil, "local", ErrTenantRequired
+	}
+	user, err := s.users.GetUserByUsername(ctx, tenantID, username)
+	if err != nil {
+		return nil, "local", err
+	}
+	if user != nil && user.Status == 0 {
+		return nil, "local", ErrAccountInactive
+	}
+
+	authenticator, err := s.authenticator(ctx, tenantID)
+	if err != nil {
+		return nil, "local", err
+	}
+	method := "local"
+	if _, ok := authenticator.(*LDAPAuthenticator); ok {
+		method = "ldap"
+	}
+	identity, err := authenticator.Authenticate(user, username, password)
+	if err != nil {
+		return nil, method, err
+	}
+	if identity == nil {
+		return user, method, nil
+	}
+	user, err = s.sync(ctx, tenantID, user, username, identity)
+	return user, method, err
+}
+
+func (s *AuthService) authenticator(ctx context.Context, tenantID int) (Authenticator, error) {
+	if tenantID == 0 {
+		return LocalAuthenticator{}, nil
+	}
+	config, err := s.ldap.GetLDAPConfig(ctx, tenantID)
+	if errors.Is(err, store.ErrLDAPConfigNotFound) {
+		return LocalAuthenticator{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !config.Enabled {
+		return LocalAuthenticator{}, nil
+	}
+	return NewLDAPAuthenticator(config, s.dial), nil
+}
+
+// sync copies identity to the account, creating it when user is nil
+func (s *AuthService) sync(ctx context.Context, tenantID int, user *domain.User, username string, identity *Identity) (*domain.User, error) {
+	synced := domain.User{Username: username, TenantID: tenantID, IsUser: true, Status: 1}
+	if user != nil {
+		synced = *user
+	}
+	if identity.Email != "" {
+		synced.Email = identity.Email
+	}
+	if synced.Email == "" {
+		return nil, ErrLDAPAccount
+	}
+	if identity.FirstName != "" || identity.LastName != "" {
+		synced.FirstName, synced.LastName = identity.FirstName, identity.LastName
+	}
+	if identity.IsAdmin != nil {
+		synced.IsAdmin = *identity.IsAdmin
+	}
+	if identity.IsAccountant != nil {
+		synced.IsAccountant = *identity.IsAccountant
+	}
+
+	if user == nil {
+		if err := s.users.CreateUser(ctx, &synced); err != nil {
+			return nil, err
+		}
+		return &synced, nil
+	}
+	if synced == *user {
+		return user, nil
+	}
+	// The directory decides the flags, so the update is made as an admin
+	if err := s.users.UpdateUser(ctx, &domain.User{TenantID: tenantID, IsAdmin: true}, &synced); err != nil {
+		return nil, err
+	}
+	return &synced, nil
+}
+
+// GetLDAPConfig returns the LDAP configuration of the caller's tenant without
+// the bind password, admins only
+func (s *AuthService) GetLDAPConfig(ctx context.Context, caller *domain.User) (*domain.LDAPConfig, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.GetLDAPConfig")
+	defer span.End()
+	if !caller.IsAdmin {
+		return nil, ErrForbidden
+	}
+	config, err := s.ldap.GetLDAPConfig(ctx, caller.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	config.BindPassword = ""
+	return config, nil
+}
+
+// UpdateLDAPConfig replaces the LDAP configuration of the caller's tenant,
+// admins only. Empty attributes and filters get the OpenLDAP defaults, an
+// empty bind password keeps the stored one.
+func (s *AuthService) UpdateLDAPConfig(ctx context.Context, caller *domain.User, config *domain.LDAPConfig) error {
+	ctx, span := tracer.Start(ctx, "AuthService.UpdateLDAPConfig")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	if err := normalizeLDAPConfig(config); err != nil {
+		return err
+	}
+	config.TenantID = caller.TenantID
+	if err := s.ldap.UpdateLDAPConfig(ctx, config); err != nil {
+		return err
+	}
+	config.BindPassword = ""
+	return nil
+}
+
+// DeleteLDAPConfig switches the caller's tenant back to local passwords,
+// admins only
+func (s *AuthService) DeleteLDAPConfig(ctx context.Context, caller *domain.User) error {
+	ctx, span := tracer.Start(ctx, "AuthService.DeleteLDAPConfig")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	return s.ldap.DeleteLDAPConfig(ctx, caller.TenantID)
+}
+
+func normalizeLDAPConfig(config *domain.LDAPConfig) error {
+	defaults := []struct {
+		field *string
+		value string
+	}{
+		{&config.UserFilter, "(uid=%s)"},
+		{&config.EmailAttribute, "mail"},
+		{&config.FirstNameAttribute, "givenName"},
+		{&config.LastNameAttribute, "sn"},
+		{&config.GroupFilter, "(member=%s)"},
+	}
+	for _, d := range defaults {
+		*d.field = strings.TrimSpace(*d.field)
+		if *d.field == "" {
+			*d.field = d.value
+		}
+	}
+
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+		return fmt.Errorf("%w: url must be ldap://host or ldaps://host", ErrInvalidLDAPConfig)
+	}
+	if strings.TrimSpace(config.BaseDN) == "" {
+		return fmt.Errorf("%w: base_dn is required", ErrInvalidLDAPConfig)
+	}
+	for _, dn := range []string{config.BaseDN, config.GroupBaseDN, config.AdminGroup, config.AccountantGroup, config.BindDN} {
+		if dn == "" {
+			continue
+		}
+		if _, err := ldap.ParseDN(dn); err != nil {
+			return fmt.Errorf("%w: %q is not a DN", ErrInvalidLDAPConfig, dn)
+		}
+	}
+	for _, filter := range []string{config.UserFilter, config.GroupFilter} {
+		if !strings.Contains(filter, "%s") {
+			return fmt.Errorf("%w: %q has no %%s", ErrInvalidLDAPConfig, filter)
+		}
+		if _, err := ldap.CompileFilter(ldapFilter(filter, "x")); err != nil {
+			return fmt.Errorf("%w: %q is not an LDAP filter", ErrInvalidLDAPConfig, filter)
+		}
+	}
+	return nil
+}
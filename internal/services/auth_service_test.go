@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/matthewhartstonge/argon2"
+)
+
+// accountStore records the accounts logins create and update
+type accountStore struct {
+	hierarchyStore
+	created []domain.User
+	updated []domain.User
+}
+
+func (s *accountStore) CreateUser(ctx context.Context, user *domain.User) error {
+	user.ID = 100 + len(s.created)
+	s.created = append(s.created, *user)
+	return nil
+}
+
+func (s *accountStore) UpdateUser(ctx context.Context, caller *domain.User, user *domain.User) error {
+	s.updated = append(s.updated, *user)
+	return nil
+}
+
+// ldapConfigs holds the LDAP configuration of tenant 2
+type ldapConfigs struct {
+	store.LDAPStore
+	config domain.LDAPConfig
+}
+
+func (s *ldapConfigs) GetLDAPConfig(ctx context.Context, tenantID int) (*domain.LDAPConfig, error) {
+	if tenantID != s.config.TenantID {
+		return nil, store.ErrLDAPConfigNotFound
+	}
+	config := s.config
+	return &config, nil
+}
+
+// fakeDirectory answers binds with passwords and searches by their filter
+type fakeDirectory struct {
+	ldap.Client
+	passwords map[string]string
+	searches  map[string][]*ldap.Entry
+	binds     []string
+}
+
+func (d *fakeDirectory) SetTimeout(time.Duration) {}
+
+func (d *fakeDirectory) Close() error { return nil }
+
+func (d *fakeDirectory) Bind(dn string, password string) error {
+	d.binds = append(d.binds, dn)
+	if want, ok := d.passwords[dn]; !ok || want != password {
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+	}
+	return nil
+}
+
+func (d *fakeDirectory) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	return &ldap.SearchResult{Entries: d.searches[req.Filter]}, nil
+}
+
+const ayseDN = "uid=ayse,ou=people,dc=example,dc=org"
+
+func newLDAPAuthService(t *testing.T, users map[int]domain.User) (*AuthService, *accountStore, *fakeDirectory) {
+	t.Helper()
+	accounts := &accountStore{hierarchyStore: hierarchyStore{users: users}}
+	directory := &fakeDirectory{
+		passwords: map[string]string{
+			"cn=admin,dc=example,dc=org": "service",
+			ayseDN:                       "secret",
+		},
+		searches: map[string][]*ldap.Entry{
+			"(uid=ayse)": {ldap.NewEntry(ayseDN, map[string][]string{
+				"mail": {"ayse@example.org"}, "givenName": {"Ayşe"}, "sn": {"Yılmaz"},
+			})},
+			"(member=" + ayseDN + ")": {ldap.NewEntry("CN=PWP-Admins, OU=groups, DC=example, DC=org", nil)},
+		},
+	}
+	config := domain.LDAPConfig{
+		TenantID: 2, Enabled: true, URL: "ldap://directory",
+		BindDN: "cn=admin,dc=example,dc=org", BindPassword: "service",
+		BaseDN: "ou=people,dc=example,dc=org", GroupBaseDN: "ou=groups,dc=example,dc=org",
+		AdminGroup: "cn=pwp-admins,ou=groups,dc=example,dc=org", AccountantGroup: "cn=pwp-accountants,ou=groups,dc=example,dc=org",
+	}
+	if err := normalizeLDAPConfig(&config); err != nil {
+		t.Fatal(err)
+	}
+	s := &AuthService{
+		users: accounts,
+		ldap:  &ldapConfigs{config: config},
+		dial:  func(string) (ldap.Client, error) { return directory, nil },
+	}
+	return s, accounts, directory
+}
+
+func TestLocalLogin(t *testing.T) {
+	argon := argon2.DefaultConfig()
+	hash, err := argon.HashEncoded([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := argon.HashEncoded([]byte("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _, _ := newLDAPAuthService(t, map[int]domain.User{
+		1: {ID: 1, TenantID: 1, Username: "ali", HashedPassword: string(hash), Status: 1},
+		2: {ID: 2, TenantID: 1, Username: "eski", HashedPassword: string(hash), Status: 0},
+		3: {ID: 3, TenantID: 3, Username: "ali", HashedPassword: string(other), Status: 1},
+	})
+
+	tests := []struct {
+		name     string
+		tenantID int
+		username string
+		password string
+		want     int
+		err      error
+	}{
+		{"valid password", 1, "ali", "secret", 1, nil},
+		{"same username in another tenant", 3, "ali", "other", 3, nil},
+		{"password of the other tenant's user", 3, "ali", "secret", 0, ErrInvalidCredentials},
+		{"wrong password", 1, "ali", "wrong", 0, ErrInvalidCredentials},
+		{"inactive", 1, "eski", "secret", 0, ErrAccountInactive},
+		{"unknown user", 1, "yok", "secret", 0, ErrInvalidCredentials},
+		{"user of another tenant", 3, "eski", "secret", 0, ErrInvalidCredentials},
+		{"no tenant", 0, "ali", "secret", 0, ErrTenantRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := s.Login(t.Context(), tt.tenantID, tt.username, tt.password)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Login() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && user.ID != tt.want {
+				t.Errorf("Login() user = %d, want %d", user.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestLDAPLoginCreatesUser(t *testing.T) {
+	s, accounts, _ := newLDAPAuthService(t, map[int]domain.User{})
+
+	user, err := s.Login(t.Context(), 2, "ayse", "secret")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if len(accounts.created) != 1 {
+		t.Fatalf("CreateUser() called %d times, want 1", len(accounts.created))
+	}
+	want := domain.User{
+		ID: 100, Username: "ayse", Email: "ayse@example.org", FirstName: "Ayşe", LastName: "Yılmaz",
+		IsAdmin: true, IsUser: true, TenantID: 2, Status: 1,
+	}
+	if *user != want {
+		t.Errorf("Login() user = %+v, want %+v", *user, want)
+	}
+}
+
+func TestLDAPLoginSyncsUser(t *testing.T) {
+	s, accounts, directory := newLDAPAuthService(t, map[int]domain.User{
+		7: {ID: 7, TenantID: 2, Username: "ayse", Email: "old@example.org", IsAccountant: true, Status: 1},
+	})
+
+	if _, err := s.Login(t.Context(), 2, "ayse", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Login() with a wrong password error = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if len(accounts.updated) != 0 {
+		t.Fatalf("UpdateUser() called after a failed login")
+	}
+
+	user, err := s.Login(t.Context(), 2, "ayse", "secret")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if user.ID != 7 || user.Email != "ayse@example.org" || !user.IsAdmin || user.IsAccountant {
+		t.Errorf("Login() user = %+v, want the directory's email and groups", *user)
+	}
+	if len(accounts.updated) != 1 || len(accounts.created) != 0 {
+		t.Errorf("got %d updates and %d creates, want a single update", len(accounts.updated), len(accounts.created))
+	}
+	// Service account, user, service account again for the groups
+	wantBinds := []string{"cn=admin,dc=example,dc=org", ayseDN, "cn=admin,dc=example,dc=org"}
+	if got := directory.binds[len(directory.binds)-3:]; !slices.Equal(got, wantBinds) {
+		t.Errorf("binds = %v, want %v", got, wantBinds)
+	}
+}
+
+func TestLDAPLoginRejects(t *testing.T) {
+	s, accounts, _ := newLDAPAuthService(t, map[int]domain.User{})
+
+	// * would match every entry if it was not escaped
+	for _, username := range []string{"*", "mehmet", ""} {
+		if _, err := s.Login(t.Context(), 2, username, "secret"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Login(%q) error = %v, want %v", username, err, ErrInvalidCredentials)
+		}
+	}
+	if _, err := s.Login(t.Context(), 2, "ayse", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Login() without a password error = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if len(accounts.created) != 0 {
+		t.Errorf("CreateUser() called for a rejected login")
+	}
+}
+
+func TestNormalizeLDAPConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config domain.LDAPConfig
+		valid  bool
+	}{
+		{"defaults", domain.LDAPConfig{URL: "ldaps://ad.example.org:636", BaseDN: "dc=example,dc=org"}, true},
+		{"active directory", domain.LDAPConfig{URL: "ldap://ad", BaseDN: "dc=corp", UserFilter: "(&(objectClass=user)(sAMAccountName=%s))", GroupFilter: "(member:1.2.840.113556.1.4.1941:=%s)"}, true},
+		{"http url", domain.LDAPConfig{URL: "http://ad", BaseDN: "dc=corp"}, false},
+		{"no base dn", domain.LDAPConfig{URL: "ldap://ad"}, false},
+		{"filter without placeholder", domain.LDAPConfig{URL: "ldap://ad", BaseDN: "dc=corp", UserFilter: "(uid=ayse)"}, false},
+		{"broken filter", domain.LDAPConfig{URL: "ldap://ad", BaseDN: "dc=corp", UserFilter: "(uid=%s"}, false},
+		{"broken group", domain.LDAPConfig{URL: "ldap://ad", BaseDN: "dc=corp", AdminGroup: "admins"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := normalizeLDAPConfig(&tt.config)
+			if tt.valid && err != nil {
+				t.Errorf("normalizeLDAPConfig() error = %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidLDAPConfig) {
+				t.Errorf("normalizeLDAPConfig() error = %v, want %v", err, ErrInvalidLDAPConfig)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"pwp-remastered/internal/blob"
+	"pwp-remastered/internal/logging"
+	"pwp-remastered/internal/store"
+	"time"
+)
+
+// BlobSweeper deletes the stored contents of deleted attachments. Attachment
+// rows also go away with their event, the database queues their storage keys
+// and the sweeper works through them.
+type BlobSweeper struct {
+	store     store.AttachmentStore
+	blobs     blob.BlobStore
+	Interval  time.Duration
+	BatchSize int
+}
+
+// NewBlobSweeper creates a sweeper that deletes up to 100 blobs every minute
+func NewBlobSweeper(attachmentStore store.AttachmentStore, blobs blob.BlobStore) *BlobSweeper {
+	return &BlobSweeper{
+		store:     attachmentStore,
+		blobs:     blobs,
+		Interval:  time.Minute,
+		BatchSize: 100,
+	}
+}
+
+// Run sweeps every Interval until ctx is done
+func (s *BlobSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Sweep(ctx); err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "blob sweep failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep deletes one batch of orphaned blobs and returns how many were deleted
+func (s *BlobSweeper) Sweep(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "BlobSweeper.Sweep")
+	defer span.End()
+	keys, err := s.store.OrphanedBlobs(ctx, s.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, key := range keys {
+		// A blob that fails to delete stays queued for the next sweep
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "delete orphaned blob failed", "storage_key", key, "error", err)
+			continue
+		}
+		if err := s.store.ForgetOrphanedBlob(ctx, key); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
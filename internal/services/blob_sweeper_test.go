@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"pwp-remastered/internal/blob"
+	"pwp-remastered/internal/store"
+)
+
+// orphanStore holds the queue the attachments trigger fills
+type orphanStore struct {
+	store.AttachmentStore
+	orphans []string
+}
+
+func (s *orphanStore) OrphanedBlobs(ctx context.Context, limit int) ([]string, error) {
+	return slices.Clone(s.orphans[:min(limit, len(s.orphans))]), nil
+}
+
+func (s *orphanStore) ForgetOrphanedBlob(ctx context.Context, key string) error {
+	for i, orphan := range s.orphans {
+		if orphan == key {
+			s.orphans = append(s.orphans[:i], s.orphans[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// failingDeletes refuses to delete one key
+type failingDeletes struct {
+	blob.BlobStore
+	key string
+}
+
+func (b failingDeletes) Delete(ctx context.Context, key string) error {
+	if key == b.key {
+		return errors.New("unavailable")
+	}
+	return b.BlobStore.Delete(ctx, key)
+}
+
+func TestBlobSweeper(t *testing.T) {
+	ctx := context.Background()
+	local, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"events/1/a", "events/1/b", "events/2/c"} {
+		if err := local.Put(ctx, key, strings.NewReader("receipt"), 7, "application/pdf"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// events/9/gone was already deleted, events/2/c cannot be deleted now
+	orphans := &orphanStore{orphans: []string{"events/1/a", "events/1/b", "events/9/gone", "events/2/c"}}
+	sweeper := NewBlobSweeper(orphans, failingDeletes{local, "events/2/c"})
+	n, err := sweeper.Sweep(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("deleted %d blobs, want 3", n)
+	}
+	if len(orphans.orphans) != 1 || orphans.orphans[0] != "events/2/c" {
+		t.Errorf("orphans = %v, want the failed one kept for the next sweep", orphans.orphans)
+	}
+	if _, err := local.Get(ctx, "events/1/a"); !errors.Is(err, blob.ErrNotFound) {
+		t.Errorf("events/1/a still stored: %v", err)
+	}
+	rc, err := local.Get(ctx, "events/2/c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, rc)
+	rc.Close()
+}
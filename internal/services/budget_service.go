@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/logging"
+	"pwp-remastered/internal/store"
+	"time"
+)
+
+var ErrInvalidBudget = domain.NewValidationError("invalid_budget", "budget needs exactly one of user_id, team_id and type_id in the tenant, a period of month, quarter or year and a positive limit")
+
+// BudgetService manages road cost budgets and raises an alert the first time a
+// budget reaches each of domain.BudgetThresholds in a period
+type BudgetService struct {
+	store    store.BudgetStore
+	events   store.EventStore
+	settings store.SettingsStore
+	users    store.UserStore
+	teams    store.TeamStore
+	policy   *EventPolicy
+	now      func() time.Time
+}
+
+// NewBudgetService creates a new budget service
+func NewBudgetService(budgetStore store.BudgetStore, eventStore store.EventStore, settingsStore store.SettingsStore, userStore store.UserStore, teamStore store.TeamStore, policy *EventPolicy) *BudgetService {
+	return &BudgetService{
+		store:    budgetStore,
+		events:   eventStore,
+		settings: settingsStore,
+		users:    userStore,
+		teams:    teamStore,
+		policy:   policy,
+		now:      time.Now,
+	}
+}
+
+// ListBudgets lists the budgets of the caller's tenant, admins only
+func (s *BudgetService) ListBudgets(ctx context.Context, caller *domain.User) ([]domain.Budget, error) {
+	ctx, span := tracer.Start(ctx, "BudgetService.ListBudgets")
+	defer span.End()
+	if !caller.IsAdmin {
+		return nil, ErrForbidden
+	}
+	return s.store.ListBudgets(ctx, caller.TenantID)
+}
+
+// GetBudget retrieves a budget of the caller's tenant, admins only
+func (s *BudgetService) GetBudget(ctx context.Context, caller *domain.User, id int) (*domain.Budget, error) {
+	ctx, span := tracer.Start(ctx, "BudgetService.GetBudget")
+	defer span.End()
+	if !caller.IsAdmin {
+		return nil, ErrForbidden
+	}
+	return s.store.GetBudget(ctx, caller.TenantID, id)
+}
+
+// CreateBudget adds a budget to the caller's tenant, admins only
+func (s *BudgetService) CreateBudget(ctx context.Context, caller *domain.User, budget *domain.Budget) error {
+	ctx, span := tracer.Start(ctx, "BudgetService.CreateBudget")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	budget.TenantID = caller.TenantID
+	if err := s.validateBudget(ctx, budget); err != nil {
+		return err
+	}
+	return s.store.CreateBudget(ctx, budget)
+}
+
+// UpdateBudget changes a budget of the caller's tenant, admins only
+func (s *BudgetService) UpdateBudget(ctx context.Context, caller *domain.User, budget *domain.Budget) error {
+	ctx, span := tracer.Start(ctx, "BudgetService.UpdateBudget")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	budget.TenantID = caller.TenantID
+	if err := s.validateBudget(ctx, budget); err != nil {
+		return err
+	}
+	return s.store.UpdateBudget(ctx, budget)
+}
+
+// DeleteBudget removes a budget of the caller's tenant, admins only
+func (s *BudgetService) DeleteBudget(ctx context.Context, caller *domain.User, id int) error {
+	ctx, span := tracer.Start(ctx, "BudgetService.DeleteBudget")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	return s.store.DeleteBudget(ctx, caller.TenantID, id)
+}
+
+// GetStatus reports the spend against limit of the budgets the caller may see
+// in the periods containing at, now when at is zero. Admins see every budget of
+// the tenant, others the user budgets of themselves and their reports.
+func (s *BudgetService) GetStatus(ctx context.Context, caller *domain.User, at time.Time) ([]domain.BudgetStatus, error) {
+	ctx, span := tracer.Start(ctx, "BudgetService.GetStatus")
+	defer span.End()
+	if at.IsZero() {
+		at = s.now()
+	}
+	budgets, err := s.store.ListBudgets(ctx, caller.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := s.tenantLocation(ctx, caller.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []domain.BudgetStatus{}
+	for _, budget := range budgets {
+		if !caller.IsAdmin {
+			if budget.UserID == nil || s.policy.AuthorizeUserEvents(ctx, caller, *budget.UserID) != nil {
+				continue
+			}
+		}
+		status, err := s.status(ctx, budget, at.In(loc))
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
+
+// CheckEvent raises the alerts of the budgets covering a created or updated
+// event. The event is already saved, so failures are only logged.
+func (s *BudgetService) CheckEvent(ctx context.Context, tenantID int, event *domain.Event) {
+	ctx, span := tracer.Start(ctx, "BudgetService.CheckEvent")
+	defer span.End()
+	if err := s.checkEvent(ctx, tenantID, event); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "budget check failed", "event_id", event.ID, "error", err)
+	}
+}
+
+func (s *BudgetService) checkEvent(ctx context.Context, tenantID int, event *domain.Event) error {
+	budgets, err := s.store.ListBudgetsFor(ctx, tenantID, event.UserID, event.TypeID)
+	if err != nil || len(budgets) == 0 {
+		return err
+	}
+	loc, err := s.tenantLocation(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	for _, budget := range budgets {
+		status, err := s.status(ctx, budget, event.StartDate.In(loc))
+		if err != nil {
+			return err
+		}
+		for _, threshold := range domain.BudgetThresholds {
+			if threshold > status.Threshold {
+				break
+			}
+			if _, err := s.store.RecordAlert(ctx, domain.BudgetAlert{Threshold: threshold, BudgetStatus: *status}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// status totals the budget's events starting within the period containing at,
+// with the filter the dated event listings use
+func (s *BudgetService) status(ctx context.Context, budget domain.Budget, at time.Time) (*domain.BudgetStatus, error) {
+	from, to := budgetWindow(budget.Period, at)
+	filter := domain.EventFilter{
+		TenantID: budget.TenantID,
+		Range:    domain.DateRange{From: from, To: to, Mode: domain.RangeStartsWithin},
+	}
+	switch {
+	case budget.UserID != nil:
+		filter.UserIDs = []int{*budget.UserID}
+	case budget.TeamID != nil:
+		filter.TeamIDs = []int{*budget.TeamID}
+	case budget.TypeID != nil:
+		filter.TypeIDs = []int{*budget.TypeID}
+	}
+	totals, err := s.events.SumEvents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &domain.BudgetStatus{
+		Budget:      budget,
+		PeriodStart: from,
+		PeriodEnd:   to,
+		Spent:       totals.RoadPrice,
+		EventCount:  totals.EventCount,
+		Ratio:       totals.RoadPrice / budget.Limit,
+	}
+	for _, threshold := range domain.BudgetThresholds {
+		if totals.RoadPrice*100 >= budget.Limit*float64(threshold) {
+			status.Threshold = threshold
+		}
+	}
+	return status, nil
+}
+
+// budgetWindow returns the calendar period containing at, in at's location
+func budgetWindow(period string, at time.Time) (time.Time, time.Time) {
+	year, month, _ := at.Date()
+	switch period {
+	case domain.BudgetYear:
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, at.Location())
+		return start, start.AddDate(1, 0, 0)
+	case domain.BudgetQuarter:
+		start := time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, at.Location())
+		return start, start.AddDate(0, 3, 0)
+	default:
+		start := time.Date(year, month, 1, 0, 0, 0, 0, at.Location())
+		return start, start.AddDate(0, 1, 0)
+	}
+}
+
+func (s *BudgetService) tenantLocation(ctx context.Context, tenantID int) (*time.Location, error) {
+	settings, err := s.settings.GetTenantSettings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return time.LoadLocation(settings.TimeZone)
+}
+
+func (s *BudgetService) validateBudget(ctx context.Context, budget *domain.Budget) error {
+	scopes := 0
+	for _, id := range []*int{budget.UserID, budget.TeamID, budget.TypeID} {
+		if id != nil {
+			scopes++
+		}
+	}
+	if scopes != 1 || budget.Limit <= 0 {
+		return ErrInvalidBudget
+	}
+	switch budget.Period {
+	case domain.BudgetMonth, domain.BudgetQuarter, domain.BudgetYear:
+	default:
+		return ErrInvalidBudget
+	}
+
+	switch {
+	case budget.UserID != nil:
+		user, err := s.users.GetUser(ctx, *budget.UserID)
+		if err == store.ErrUserNotFound || (err == nil && user.TenantID != budget.TenantID) {
+			return ErrInvalidBudget
+		}
+		return err
+	case budget.TeamID != nil:
+		_, err := s.teams.GetTeam(ctx, budget.TenantID, *budget.TeamID)
+		if err == store.ErrTeamNotFound {
+			return ErrInvalidBudget
+		}
+		return err
+	default:
+		_, err := s.events.GetEventType(ctx, budget.TenantID, *budget.TypeID)
+		if err == store.ErrEventTypeNotFound {
+			return ErrInvalidBudget
+		}
+		return err
+	}
+}
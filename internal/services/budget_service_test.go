@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+	"testing"
+	"time"
+)
+
+func TestBudgetWindow(t *testing.T) {
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2025, time.May, 20, 10, 0, 0, 0, istanbul)
+
+	tests := []struct {
+		period   string
+		from, to time.Time
+	}{
+		{domain.BudgetMonth, time.Date(2025, time.May, 1, 0, 0, 0, 0, istanbul), time.Date(2025, time.June, 1, 0, 0, 0, 0, istanbul)},
+		{domain.BudgetQuarter, time.Date(2025, time.April, 1, 0, 0, 0, 0, istanbul), time.Date(2025, time.July, 1, 0, 0, 0, 0, istanbul)},
+		{domain.BudgetYear, time.Date(2025, time.January, 1, 0, 0, 0, 0, istanbul), time.Date(2026, time.January, 1, 0, 0, 0, 0, istanbul)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			from, to := budgetWindow(tt.period, at)
+			if !from.Equal(tt.from) || !to.Equal(tt.to) {
+				t.Errorf("budgetWindow() = %v - %v, want %v - %v", from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+type recordingBudgetStore struct {
+	store.BudgetStore
+	budgets []domain.Budget
+	alerts  []int
+}
+
+func (s *recordingBudgetStore) ListBudgetsFor(ctx context.Context, tenantID int, userID int, typeID int) ([]domain.Budget, error) {
+	return s.budgets, nil
+}
+
+func (s *recordingBudgetStore) RecordAlert(ctx context.Context, alert domain.BudgetAlert) (bool, error) {
+	s.alerts = append(s.alerts, alert.Threshold)
+	return true, nil
+}
+
+type totalsEventStore struct {
+	store.EventStore
+	totals domain.EventTotals
+	filter domain.EventFilter
+}
+
+func (s *totalsEventStore) SumEvents(ctx context.Context, filter domain.EventFilter) (*domain.EventTotals, error) {
+	s.filter = filter
+	return &s.totals, nil
+}
+
+type utcSettingsStore struct {
+	store.SettingsStore
+}
+
+func (s *utcSettingsStore) GetTenantSettings(ctx context.Context, tenantID int) (*domain.TenantSettings, error) {
+	return &domain.TenantSettings{TenantID: tenantID, TimeZone: "UTC"}, nil
+}
+
+func TestCheckEventAlerts(t *testing.T) {
+	userID := 1
+	budget := domain.Budget{ID: 7, TenantID: 1, UserID: &userID, Period: domain.BudgetMonth, Limit: 1000}
+	event := &domain.Event{ID: 3, UserID: 1, TypeID: 2, StartDate: time.Date(2025, time.May, 20, 10, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name  string
+		spent float64
+		want  []int
+	}{
+		{"below", 799.99, nil},
+		{"warning", 800, []int{80}},
+		{"exceeded", 1200, []int{80, 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budgets := &recordingBudgetStore{budgets: []domain.Budget{budget}}
+			events := &totalsEventStore{totals: domain.EventTotals{EventCount: 2, RoadPrice: tt.spent}}
+			s := NewBudgetService(budgets, events, &utcSettingsStore{}, nil, nil, nil)
+
+			s.CheckEvent(t.Context(), 1, event)
+			if len(budgets.alerts) != len(tt.want) {
+				t.Fatalf("alerts = %v, want %v", budgets.alerts, tt.want)
+			}
+			for i := range tt.want {
+				if budgets.alerts[i] != tt.want[i] {
+					t.Fatalf("alerts = %v, want %v", budgets.alerts, tt.want)
+				}
+			}
+			if got := events.filter.UserIDs; len(got) != 1 || got[0] != userID {
+				t.Errorf("filter users = %v, want [%d]", got, userID)
+			}
+			if want := time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC); !events.filter.Range.From.Equal(want) {
+				t.Errorf("filter from = %v, want %v", events.filter.Range.From, want)
+			}
+		})
+	}
+}
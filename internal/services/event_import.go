@@ -0,0 +1,359 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/metrics"
+	"pwp-remastered/internal/store"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidImport      = domain.NewValidationError("invalid_import", "import needs a CSV file with a header row and columns for username, type, start_date and end_date")
+	ErrInvalidImportValue = domain.NewValidationError("invalid_import_value", "value is missing or not valid for its column")
+	ErrImportTooLarge     = domain.NewTooLargeError("import_too_large", "import file exceeds the maximum allowed size")
+)
+
+// Limits of a single import, longer histories are imported in several files
+const (
+	MaxImportRows = 5000
+	MaxImportSize = 10 << 20
+)
+
+// importRequired are the fields every row needs a value for
+var importRequired = []string{domain.ImportUsername, domain.ImportType, domain.ImportStartDate, domain.ImportEndDate}
+
+// Local time layouts accepted in date columns besides RFC 3339, read in the tenant's time zone
+var importTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"02.01.2006",
+}
+
+// EventImportService creates historical events of a tenant from CSV files
+type EventImportService struct {
+	events *EventService
+	users  store.UserStore
+}
+
+// NewEventImportService creates a new event import service
+func NewEventImportService(events *EventService, userStore store.UserStore) *EventImportService {
+	return &EventImportService{
+		events: events,
+		users:  userStore,
+	}
+}
+
+// importRow is a CSV record keyed by import field
+type importRow struct {
+	line   int
+	values map[string]string
+}
+
+// ImportEvents reads events from CSV, resolving usernames of the caller's
+// tenant and event type names or translations to IDs. Dates without an offset
+// are read in loc and road_price is taken as given, no mileage pricing is done.
+// Every row is validated; the events are only saved, in one transaction, when
+// none has an error and dryRun is false. Admins only.
+func (s *EventImportService) ImportEvents(ctx context.Context, caller *domain.User, r io.Reader, mapping domain.EventImportMapping, loc *time.Location, dryRun bool) (*domain.EventImportResult, error) {
+	ctx, span := tracer.Start(ctx, "EventImportService.ImportEvents")
+	defer span.End()
+	if !caller.IsAdmin {
+		return nil, ErrForbidden
+	}
+	rows, columns, err := readImportCSV(r, mapping)
+	if err != nil {
+		return nil, err
+	}
+	types, err := s.events.store.GetEventTypes(ctx, caller.TenantID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.EventImportResult{
+		DryRun: dryRun,
+		Rows:   len(rows),
+		Errors: []domain.EventImportRowError{},
+		Events: []domain.Event{},
+	}
+	users := map[string]*domain.User{}
+	for _, row := range rows {
+		event, rowErrors, err := s.parseRow(ctx, caller, row, columns, types, users, loc)
+		if err != nil {
+			return nil, err
+		}
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, rowErrors...)
+			continue
+		}
+		result.Events = append(result.Events, *event)
+	}
+	if dryRun || len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	if err := s.events.store.ImportEvents(ctx, result.Events); err != nil {
+		return nil, err
+	}
+	result.Imported = len(result.Events)
+	for i := range result.Events {
+		metrics.EventCreated("import", result.Events[i].RoadPrice)
+		s.events.publish(ctx, domain.WebhookEventCreated, caller.TenantID, &result.Events[i])
+		s.events.budgets.CheckEvent(ctx, caller.TenantID, &result.Events[i])
+	}
+	return result, nil
+}
+
+// parseRow builds the event of a row. Problems with the row's values are
+// returned as row errors, err is only set when a lookup failed.
+func (s *EventImportService) parseRow(ctx context.Context, caller *domain.User, row importRow, columns map[string]string, types []domain.EventType, users map[string]*domain.User, loc *time.Location) (*domain.Event, []domain.EventImportRowError, error) {
+	var rowErrors []domain.EventImportRowError
+	fail := func(field string, err error) {
+		rowErrors = append(rowErrors, domain.EventImportRowError{Row: row.line, Column: columns[field], Err: err})
+	}
+	for _, field := range importRequired {
+		if row.values[field] == "" {
+			fail(field, fmt.Errorf("%w: %s is required", ErrInvalidImportValue, field))
+		}
+	}
+
+	event := &domain.Event{
+		Name:   row.values[domain.ImportName],
+		Title:  row.values[domain.ImportTitle],
+		Status: domain.EventPending,
+	}
+	if description := row.values[domain.ImportDescription]; description != "" {
+		event.Description = &description
+	}
+
+	if username := row.values[domain.ImportUsername]; username != "" {
+		user, err := s.lookupUser(ctx, caller.TenantID, username, users)
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			fail(domain.ImportUsername, fmt.Errorf("%w: %s", err, username))
+		case err != nil:
+			return nil, nil, err
+		default:
+			event.UserID = user.ID
+			event.User = &domain.EventUser{ID: user.ID, Username: user.Username, FirstName: user.FirstName, LastName: user.LastName, TenantID: user.TenantID}
+		}
+	}
+
+	if name := row.values[domain.ImportType]; name != "" {
+		eventType := findEventType(types, name)
+		switch {
+		case eventType == nil:
+			fail(domain.ImportType, fmt.Errorf("%w: %s", store.ErrEventTypeNotFound, name))
+		case eventType.IsArchived:
+			fail(domain.ImportType, fmt.Errorf("%w: %s", ErrEventTypeArchived, name))
+		default:
+			event.TypeID = eventType.ID
+			event.Type = eventType
+		}
+	}
+
+	datesValid := true
+	for _, field := range []string{domain.ImportStartDate, domain.ImportEndDate} {
+		value := row.values[field]
+		if value == "" {
+			datesValid = false
+			continue
+		}
+		date, parseErr := parseImportTime(value, loc)
+		if parseErr != nil {
+			fail(field, fmt.Errorf("%w: %s, use YYYY-MM-DD, YYYY-MM-DD HH:MM or RFC 3339", ErrInvalidImportValue, value))
+			datesValid = false
+			continue
+		}
+		if field == domain.ImportStartDate {
+			event.StartDate = date
+		} else {
+			event.EndDate = date
+		}
+	}
+	if datesValid && event.EndDate.Before(event.StartDate) {
+		fail(domain.ImportEndDate, fmt.Errorf("%w: end_date is before start_date", ErrInvalidImportValue))
+		datesValid = false
+	}
+	if datesValid {
+		err := checkPeriodsOpen(ctx, s.events.periods, caller.TenantID, event)
+		if errors.Is(err, store.ErrPeriodClosed) {
+			fail(domain.ImportStartDate, err)
+		} else if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if value := row.values[domain.ImportRoadPrice]; value != "" {
+		// Spreadsheets in Turkish locales write decimal commas
+		if !strings.Contains(value, ".") {
+			value = strings.Replace(value, ",", ".", 1)
+		}
+		price, parseErr := strconv.ParseFloat(value, 64)
+		if parseErr != nil || price < 0 {
+			fail(domain.ImportRoadPrice, fmt.Errorf("%w: %s is not a non-negative amount", ErrInvalidImportValue, row.values[domain.ImportRoadPrice]))
+		} else {
+			event.RoadPrice = roundCents(price)
+		}
+	}
+	return event, rowErrors, nil
+}
+
+// lookupUser finds a user of the tenant by username, caching the result per import
+func (s *EventImportService) lookupUser(ctx context.Context, tenantID int, username string, users map[string]*domain.User) (*domain.User, error) {
+	if user, ok := users[username]; ok {
+		if user == nil {
+			return nil, store.ErrUserNotFound
+		}
+		return user, nil
+	}
+	// The store answers an unknown username with neither a user nor an error
+	user, err := s.users.GetUserByUsername(ctx, tenantID, username)
+	if errors.Is(err, store.ErrUserNotFound) || (err == nil && user == nil) {
+		users[username] = nil
+		return nil, store.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	users[username] = user
+	return user, nil
+}
+
+// findEventType matches name against the default label and the translations
+// of the event types, ignoring case
+func findEventType(types []domain.EventType, name string) *domain.EventType {
+	for i := range types {
+		if strings.EqualFold(types[i].Type, name) {
+			return &types[i]
+		}
+		for _, label := range types[i].Translations {
+			if strings.EqualFold(label, name) {
+				return &types[i]
+			}
+		}
+	}
+	return nil
+}
+
+func parseImportTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range importTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown date format")
+}
+
+// readImportCSV reads the rows of a CSV file and returns them keyed by import
+// field, along with the header of each mapped column. Comma and semicolon
+// separated files are accepted, a leading UTF-8 byte order mark is ignored.
+func readImportCSV(r io.Reader, mapping domain.EventImportMapping) ([]importRow, map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = importDelimiter(data)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("%w: file is empty", ErrInvalidImport)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	positions := map[string]int{}
+	for i, name := range header {
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for field := range mapping {
+		if !isImportField(field) {
+			return nil, nil, fmt.Errorf("%w: unknown field %q in mapping", ErrInvalidImport, field)
+		}
+	}
+
+	indexes := map[string]int{}
+	columns := map[string]string{}
+	for _, field := range domain.ImportFields {
+		column, mapped := mapping[field]
+		if !mapped {
+			column = field
+		}
+		i, ok := positions[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			if mapped {
+				return nil, nil, fmt.Errorf("%w: column %q mapped to %s is not in the header", ErrInvalidImport, column, field)
+			}
+			continue
+		}
+		indexes[field] = i
+		columns[field] = header[i]
+	}
+	for _, field := range importRequired {
+		if _, ok := indexes[field]; !ok {
+			return nil, nil, fmt.Errorf("%w: no column for %s", ErrInvalidImport, field)
+		}
+	}
+
+	rows := []importRow{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		if len(rows) == MaxImportRows {
+			return nil, nil, fmt.Errorf("%w: more than %d rows", ErrInvalidImport, MaxImportRows)
+		}
+		line, _ := reader.FieldPos(0)
+		row := importRow{line: line, values: map[string]string{}}
+		for field, i := range indexes {
+			row.values[field] = strings.TrimSpace(record[i])
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("%w: file has no rows", ErrInvalidImport)
+	}
+	return rows, columns, nil
+}
+
+// importDelimiter picks the separator of the header line, spreadsheets in
+// locales with decimal commas export semicolon separated files
+func importDelimiter(data []byte) rune {
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		return ';'
+	}
+	return ','
+}
+
+func isImportField(field string) bool {
+	for _, known := range domain.ImportFields {
+		if field == known {
+			return true
+		}
+	}
+	return false
+}
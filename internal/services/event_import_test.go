@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/live"
+	"pwp-remastered/internal/store"
+	"strings"
+	"testing"
+	"time"
+)
+
+type importEventStore struct {
+	store.EventStore
+	imported []domain.Event
+}
+
+func (s *importEventStore) GetEventTypes(ctx context.Context, tenantID int, includeArchived bool) ([]domain.EventType, error) {
+	return []domain.EventType{
+		{ID: 1, Type: "Yol", Translations: map[string]string{"en": "Travel"}},
+		{ID: 2, Type: "Otopark", IsArchived: true},
+	}, nil
+}
+
+func (s *importEventStore) ImportEvents(ctx context.Context, events []domain.Event) error {
+	for i := range events {
+		events[i].ID = 100 + i
+	}
+	s.imported = events
+	return nil
+}
+
+// marchClosedStore has March 2024 closed
+type marchClosedStore struct {
+	store.PeriodStore
+}
+
+func (s *marchClosedStore) ClosedPeriodOverlapping(ctx context.Context, tenantID int, start time.Time, end time.Time) (*domain.AccountingPeriod, error) {
+	if start.Before(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)) && !end.Before(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		return &domain.AccountingPeriod{ID: 1, StartDate: "2024-03-01", EndDate: "2024-03-31", Status: domain.PeriodClosed}, nil
+	}
+	return nil, nil
+}
+
+func newImportTestService() (*EventImportService, *importEventStore) {
+	events := &importEventStore{}
+	users := &hierarchyStore{users: map[int]domain.User{
+		1: {ID: 1, Username: "ayse", TenantID: 1},
+		2: {ID: 2, Username: "mehmet", TenantID: 1},
+		3: {ID: 3, Username: "outsider", TenantID: 2},
+	}}
+	budgets := NewBudgetService(&recordingBudgetStore{}, events, &utcSettingsStore{}, users, nil, nil)
+	eventService := NewEventService(events, nil, nil, nil, &marchClosedStore{}, NewEventPolicy(users), live.NewMemoryBroker(10), budgets)
+	return NewEventImportService(eventService, users), events
+}
+
+func TestImportEvents(t *testing.T) {
+	admin := &domain.User{ID: 9, TenantID: 1, IsAdmin: true}
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const valid = "Personel;Tür;Başlık;Başlangıç;Bitiş;Tutar\n" +
+		"ayse;yol;Müşteri ziyareti;2024-05-02 09:00;2024-05-02 17:00;125,50\n" +
+		"mehmet;Travel;Fuar;2024-05-03;2024-05-03;80\n"
+	mapping := domain.EventImportMapping{
+		domain.ImportUsername:  "Personel",
+		domain.ImportType:      "Tür",
+		domain.ImportTitle:     "Başlık",
+		domain.ImportStartDate: "Başlangıç",
+		domain.ImportEndDate:   "Bitiş",
+		domain.ImportRoadPrice: "Tutar",
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		s, events := newImportTestService()
+		result, err := s.ImportEvents(t.Context(), admin, strings.NewReader(valid), mapping, istanbul, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Errors) != 0 || len(result.Events) != 2 || result.Imported != 0 || events.imported != nil {
+			t.Fatalf("result = %+v, want two valid rows and nothing saved", result)
+		}
+		first := result.Events[0]
+		if first.UserID != 1 || first.TypeID != 1 || first.RoadPrice != 125.5 || first.Title != "Müşteri ziyareti" {
+			t.Errorf("first event = %+v", first)
+		}
+		if want := time.Date(2024, 5, 2, 6, 0, 0, 0, time.UTC); !first.StartDate.Equal(want) {
+			t.Errorf("start = %v, want %v", first.StartDate, want)
+		}
+		if result.Events[1].TypeID != 1 {
+			t.Errorf("translated type resolved to %d, want 1", result.Events[1].TypeID)
+		}
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		s, events := newImportTestService()
+		result, err := s.ImportEvents(t.Context(), admin, strings.NewReader(valid), mapping, istanbul, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Imported != 2 || len(events.imported) != 2 || result.Events[0].ID != 100 {
+			t.Fatalf("result = %+v, want both rows saved", result)
+		}
+	})
+
+	t.Run("invalid rows", func(t *testing.T) {
+		s, events := newImportTestService()
+		csv := "username,type,start_date,end_date,road_price\n" +
+			"ayse,Yol,2024-05-02,2024-05-02,10\n" +
+			"outsider,Yol,2024-05-02,2024-05-02,10\n" +
+			"ayse,Otopark,2024-05-02,2024-05-01,-3\n" +
+			"ayse,Yol,2024-03-10,2024-03-10,\n" +
+			",Yok,yarın,2024-05-02,\n"
+		result, err := s.ImportEvents(t.Context(), admin, strings.NewReader(csv), nil, time.UTC, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Imported != 0 || events.imported != nil || len(result.Events) != 1 {
+			t.Fatalf("result = %+v, want nothing saved and one valid row", result)
+		}
+
+		want := []struct {
+			row    int
+			column string
+			err    error
+		}{
+			{3, "username", store.ErrUserNotFound},
+			{4, "type", ErrEventTypeArchived},
+			{4, "end_date", ErrInvalidImportValue},
+			{4, "road_price", ErrInvalidImportValue},
+			{5, "start_date", store.ErrPeriodClosed},
+			{6, "username", ErrInvalidImportValue},
+			{6, "type", store.ErrEventTypeNotFound},
+			{6, "start_date", ErrInvalidImportValue},
+		}
+		if len(result.Errors) != len(want) {
+			t.Fatalf("errors = %+v, want %d", result.Errors, len(want))
+		}
+		for i, w := range want {
+			got := result.Errors[i]
+			if got.Row != w.row || got.Column != w.column || !errors.Is(got.Err, w.err) {
+				t.Errorf("error %d = row %d %s %v, want row %d %s %v", i, got.Row, got.Column, got.Err, w.row, w.column, w.err)
+			}
+		}
+	})
+
+	fileErrors := []struct {
+		name    string
+		csv     string
+		mapping domain.EventImportMapping
+	}{
+		{"empty", "", nil},
+		{"header only", "username,type,start_date,end_date\n", nil},
+		{"missing column", "username,type,start_date\nayse,Yol,2024-05-02\n", nil},
+		{"unknown field", valid, domain.EventImportMapping{"vehicle": "Araç"}},
+		{"mapped column missing", valid, domain.EventImportMapping{domain.ImportUsername: "Kullanıcı"}},
+	}
+	for _, tt := range fileErrors {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newImportTestService()
+			if _, err := s.ImportEvents(t.Context(), admin, strings.NewReader(tt.csv), tt.mapping, time.UTC, true); !errors.Is(err, ErrInvalidImport) {
+				t.Errorf("ImportEvents() = %v, want %v", err, ErrInvalidImport)
+			}
+		})
+	}
+
+	t.Run("not admin", func(t *testing.T) {
+		s, _ := newImportTestService()
+		if _, err := s.ImportEvents(t.Context(), &domain.User{ID: 1, TenantID: 1}, strings.NewReader(valid), mapping, time.UTC, true); !errors.Is(err, ErrForbidden) {
+			t.Errorf("ImportEvents() = %v, want %v", err, ErrForbidden)
+		}
+	})
+}
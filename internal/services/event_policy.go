@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+)
+
+// EventAction is an operation on a single event or its attachments
+type EventAction int
+
+const (
+	EventRead EventAction = iota
+	EventUpdate
+	EventDelete
+	EventApprove
+)
+
+// EventPolicy is the single place that decides who may act on an event:
+//   - the owner may read, update and delete it
+//   - admins of the owner's tenant may read, update and delete it
+//   - managers above the owner in the reporting hierarchy may read, update,
+//     delete and approve it
+//   - nobody approves their own event
+type EventPolicy struct {
+	users store.UserStore
+}
+
+// NewEventPolicy creates a new event policy
+func NewEventPolicy(userStore store.UserStore) *EventPolicy {
+	return &EventPolicy{users: userStore}
+}
+
+// Authorize returns ErrForbidden unless caller may perform action on event
+func (p *EventPolicy) Authorize(ctx context.Context, caller *domain.User, event *domain.Event, action EventAction) error {
+	if event.UserID == caller.ID {
+		if action == EventApprove {
+			return ErrForbidden
+		}
+		return nil
+	}
+	if event.User != nil && isTenantAdmin(caller, event.User.TenantID) {
+		return nil
+	}
+	return p.authorizeManager(ctx, caller, event.UserID)
+}
+
+// AuthorizeUserEvents returns ErrForbidden unless caller may list the events of
+// userID, under the same rules as reading one of them
+func (p *EventPolicy) AuthorizeUserEvents(ctx context.Context, caller *domain.User, userID int) error {
+	if userID == caller.ID {
+		return nil
+	}
+	if caller.IsAdmin {
+		owner, err := p.users.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if isTenantAdmin(caller, owner.TenantID) {
+			return nil
+		}
+	}
+	return p.authorizeManager(ctx, caller, userID)
+}
+
+func (p *EventPolicy) authorizeManager(ctx context.Context, caller *domain.User, userID int) error {
+	isManager, err := p.users.IsManagerOf(ctx, caller.ID, userID)
+	if err != nil {
+		return err
+	}
+	if !isManager {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func isTenantAdmin(caller *domain.User, tenantID int) bool {
+	return caller.IsAdmin && caller.TenantID == tenantID
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"pwp-remastered/internal/domain"
+	"slices"
+	"testing"
+)
+
+func TestEventPolicyAuthorize(t *testing.T) {
+	director, manager := 1, 2
+	users := &hierarchyStore{users: map[int]domain.User{
+		1: {ID: 1, TenantID: 1},
+		2: {ID: 2, TenantID: 1, ManagerID: &director},
+		3: {ID: 3, TenantID: 1, ManagerID: &manager},
+		4: {ID: 4, TenantID: 1},
+		5: {ID: 5, TenantID: 1, IsAdmin: true},
+		6: {ID: 6, TenantID: 2, IsAdmin: true},
+	}}
+	policy := NewEventPolicy(users)
+	event := &domain.Event{ID: 10, UserID: 3, User: &domain.EventUser{ID: 3, TenantID: 1}}
+
+	tests := []struct {
+		name    string
+		caller  int
+		allowed []EventAction
+	}{
+		{"owner", 3, []EventAction{EventRead, EventUpdate, EventDelete}},
+		{"manager", 2, []EventAction{EventRead, EventUpdate, EventDelete, EventApprove}},
+		{"manager's manager", 1, []EventAction{EventRead, EventUpdate, EventDelete, EventApprove}},
+		{"colleague", 4, nil},
+		{"tenant admin", 5, []EventAction{EventRead, EventUpdate, EventDelete, EventApprove}},
+		{"other tenant's admin", 6, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller := users.users[tt.caller]
+			for _, action := range []EventAction{EventRead, EventUpdate, EventDelete, EventApprove} {
+				err := policy.Authorize(context.Background(), &caller, event, action)
+				want := slices.Contains(tt.allowed, action)
+				if (err == nil) != want {
+					t.Errorf("action %d: err = %v, want allowed %t", action, err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+	"strings"
+)
+
+var ErrInvalidSearch = domain.NewValidationError("invalid_search", "search query is required")
+
+// SearchEvents runs a full-text search over the events the caller can see.
+// Admins search their tenant's events, everybody else their own and those of
+// the users reporting to them.
+func (s *EventService) SearchEvents(ctx context.Context, caller *domain.User, filter domain.EventFilter, locales []string) (*domain.EventSearchList, error) {
+	ctx, span := tracer.Start(ctx, "EventService.SearchEvents")
+	defer span.End()
+	if strings.TrimSpace(filter.Query) == "" {
+		return nil, ErrInvalidSearch
+	}
+	if caller.IsAdmin {
+		filter.TenantID = caller.TenantID
+	} else {
+		filter.VisibleTo = caller.ID
+	}
+
+	results, info, err := s.store.SearchEvents(ctx, filter, searchConfig(locales))
+	if err != nil {
+		return nil, err
+	}
+	return &domain.EventSearchList{Results: results, PageInfo: *info}, nil
+}
+
+// searchConfig picks the text search configuration used for highlighting from
+// the caller's preferred locales, Turkish unless English comes first
+func searchConfig(locales []string) string {
+	for _, locale := range locales {
+		base, _, _ := strings.Cut(strings.ToLower(locale), "-")
+		switch base {
+		case "tr":
+			return store.SearchConfigTurkish
+		case "en":
+			return store.SearchConfigEnglish
+		}
+	}
+	return store.SearchConfigTurkish
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+	"slices"
+	"testing"
+)
+
+func TestSearchConfig(t *testing.T) {
+	tests := []struct {
+		locales []string
+		want    string
+	}{
+		{nil, store.SearchConfigTurkish},
+		{[]string{"en-US", "tr"}, store.SearchConfigEnglish},
+		{[]string{"de", "tr-TR", "en"}, store.SearchConfigTurkish},
+		{[]string{"fr", "EN"}, store.SearchConfigEnglish},
+	}
+	for _, tt := range tests {
+		if got := searchConfig(tt.locales); got != tt.want {
+			t.Errorf("searchConfig(%v) = %q, want %q", tt.locales, got, tt.want)
+		}
+	}
+}
+
+// searchStore matches every event the filter's scope lets through
+type searchStore struct {
+	store.EventStore
+	users  *hierarchyStore
+	events []domain.Event
+}
+
+func (s *searchStore) SearchEvents(ctx context.Context, filter domain.EventFilter, config string) ([]domain.EventSearchResult, *domain.PageInfo, error) {
+	results := []domain.EventSearchResult{}
+	for _, event := range s.events {
+		if filter.VisibleTo != 0 && event.UserID != filter.VisibleTo {
+			if ok, _ := s.users.IsManagerOf(ctx, filter.VisibleTo, event.UserID); !ok {
+				continue
+			}
+		}
+		results = append(results, domain.EventSearchResult{Event: event})
+	}
+	return results, &domain.PageInfo{}, nil
+}
+
+func TestSearchIncludesReports(t *testing.T) {
+	ptr := func(v int) *int { return &v }
+	// 1 manages 2, 2 manages 3, 4 reports to nobody
+	users := &hierarchyStore{users: map[int]domain.User{
+		1: {ID: 1, TenantID: 1},
+		2: {ID: 2, TenantID: 1, ManagerID: ptr(1)},
+		3: {ID: 3, TenantID: 1, ManagerID: ptr(2)},
+		4: {ID: 4, TenantID: 1},
+	}}
+	events := &searchStore{users: users, events: []domain.Event{
+		{ID: 10, UserID: 1}, {ID: 11, UserID: 2}, {ID: 12, UserID: 3}, {ID: 13, UserID: 4},
+	}}
+	service := NewEventService(events, nil, nil, nil, nil, nil, nil, nil)
+
+	tests := []struct {
+		caller domain.User
+		want   []int
+	}{
+		{users.users[1], []int{10, 11, 12}},
+		{users.users[2], []int{11, 12}},
+		{users.users[3], []int{12}},
+	}
+	for _, tt := range tests {
+		list, err := service.SearchEvents(context.Background(), &tt.caller, domain.EventFilter{Query: "ziyaret"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int
+		for _, result := range list.Results {
+			got = append(got, result.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("user %d found %v, want %v", tt.caller.ID, got, tt.want)
+		}
+	}
+}
@@ -1,72 +1,378 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/live"
+	"pwp-remastered/internal/logging"
+	"pwp-remastered/internal/metrics"
 	"pwp-remastered/internal/store"
-	"time"
 )
 
+var ErrForbidden = domain.NewForbiddenError("forbidden", "caller is not allowed to perform this action")
+
 // EventService handles business logic for events
 type EventService struct {
-	store store.EventStore
+	store     store.EventStore
+	rates     store.RateStore
+	locations store.LocationStore
+	vehicles  store.VehicleStore
+	periods   store.PeriodStore
+	policy    *EventPolicy
+	broker    live.Broker
+	budgets   *BudgetService
 }
 
 // NewEventService creates a new event service
-func NewEventService(eventStore store.EventStore) *EventService {
+func NewEventService(eventStore store.EventStore, rateStore store.RateStore, locationStore store.LocationStore, vehicleStore store.VehicleStore, periodStore store.PeriodStore, policy *EventPolicy, broker live.Broker, budgets *BudgetService) *EventService {
 	return &EventService{
-		store: eventStore,
+		store:     eventStore,
+		rates:     rateStore,
+		locations: locationStore,
+		vehicles:  vehicleStore,
+		periods:   periodStore,
+		policy:    policy,
+		broker:    broker,
+		budgets:   budgets,
 	}
 }
 
-// GetEvent retrieves an event by ID
-func (s *EventService) GetEvent(id int) (*domain.Event, error) {
-	return s.store.GetEvent(id)
+// GetEvent retrieves an event by ID if caller may read it
+func (s *EventService) GetEvent(ctx context.Context, caller *domain.User, id int) (*domain.Event, error) {
+	ctx, span := tracer.Start(ctx, "EventService.GetEvent")
+	defer span.End()
+	event, err := s.store.GetEvent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Authorize(ctx, caller, event, EventRead); err != nil {
+		return nil, err
+	}
+	return event, nil
 }
 
 // CreateEvent persists a new event
-func (s *EventService) CreateEvent(event *domain.Event, caller *domain.User) error {
-	return s.store.CreateEvent(event, caller)
+func (s *EventService) CreateEvent(ctx context.Context, event *domain.Event, caller *domain.User) error {
+	ctx, span := tracer.Start(ctx, "EventService.CreateEvent")
+	defer span.End()
+	eventType, err := s.store.GetEventType(ctx, caller.TenantID, event.TypeID)
+	if err != nil {
+		return err
+	}
+	if eventType.IsArchived {
+		return ErrEventTypeArchived
+	}
+
+	// The owner is needed before pricing to check who may use the vehicle
+	event.UserID = caller.ID
+	if err := s.priceEvent(ctx, event, caller.TenantID); err != nil {
+		return err
+	}
+	if err := s.store.CreateEvent(ctx, event, caller); err != nil {
+		return err
+	}
+	metrics.EventCreated("api", event.RoadPrice)
+	s.publish(ctx, domain.WebhookEventCreated, caller.TenantID, event)
+	s.budgets.CheckEvent(ctx, caller.TenantID, event)
+	return nil
+}
+
+// UpdateEvent modifies an existing event. The owner is kept, an admin or
+// manager editing someone else's event does not take it over.
+func (s *EventService) UpdateEvent(ctx context.Context, event *domain.Event, caller *domain.User) error {
+	ctx, span := tracer.Start(ctx, "EventService.UpdateEvent")
+	defer span.End()
+	existing, err := s.store.GetEvent(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.policy.Authorize(ctx, caller, existing, EventUpdate); err != nil {
+		return err
+	}
+	event.UserID = existing.UserID
+
+	// An event may keep an archived type but not be moved onto one
+	eventType, err := s.store.GetEventType(ctx, existing.User.TenantID, event.TypeID)
+	if err != nil {
+		return err
+	}
+	if eventType.IsArchived && event.TypeID != existing.TypeID {
+		return ErrEventTypeArchived
+	}
+	if err := s.priceEvent(ctx, event, existing.User.TenantID); err != nil {
+		return err
+	}
+
+	if err := s.store.UpdateEvent(ctx, event, caller); err != nil {
+		return err
+	}
+	s.publish(ctx, domain.WebhookEventUpdated, existing.User.TenantID, event)
+	s.budgets.CheckEvent(ctx, existing.User.TenantID, event)
+	return nil
 }
 
-// UpdateEvent modifies an existing event
-func (s *EventService) UpdateEvent(event *domain.Event, caller *domain.User) error {
-	userID := caller.ID
+// DeleteEvent removes an event by ID if caller may delete it
+func (s *EventService) DeleteEvent(ctx context.Context, caller *domain.User, id int) error {
+	ctx, span := tracer.Start(ctx, "EventService.DeleteEvent")
+	defer span.End()
+	event, err := s.store.GetEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.policy.Authorize(ctx, caller, event, EventDelete); err != nil {
+		return err
+	}
+	if err := s.store.DeleteEvent(ctx, id); err != nil {
+		return err
+	}
+	s.publish(ctx, domain.WebhookEventDeleted, event.User.TenantID, event)
+	return nil
+}
 
-	if event.UserID != userID {
-		return errors.New("Caller is not the owner of the event")
+// ApproveEvent approves someone else's event, see EventPolicy for who may
+func (s *EventService) ApproveEvent(ctx context.Context, caller *domain.User, id int) (*domain.Event, error) {
+	ctx, span := tracer.Start(ctx, "EventService.ApproveEvent")
+	defer span.End()
+	event, err := s.store.GetEvent(ctx, id)
+	if err != nil {
+		return nil, err
 	}
+	if err := s.policy.Authorize(ctx, caller, event, EventApprove); err != nil {
+		return nil, err
+	}
+	if event.Status == domain.EventApproved {
+		return nil, store.ErrEventApproved
+	}
+	approved, err := s.store.ApproveEvent(ctx, id, caller.ID)
+	if err != nil {
+		return nil, err
+	}
+	// The stream only knows created, updated and deleted, an approval updates the status
+	s.publish(ctx, domain.WebhookEventUpdated, event.User.TenantID, approved)
+	return approved, nil
+}
 
-	return s.store.UpdateEvent(event, caller)
+// StreamEvents streams the changes of the events caller may read, under the
+// same rules as listing their owner's events. With a lastEventID the changes
+// after that message are sent first.
+func (s *EventService) StreamEvents(ctx context.Context, caller *domain.User, lastEventID int64) <-chan live.Message {
+	ctx, span := tracer.Start(ctx, "EventService.StreamEvents")
+	defer span.End()
+	in := s.broker.Subscribe(ctx, lastEventID)
+	out := make(chan live.Message)
+	go func() {
+		defer close(out)
+		// The hierarchy rarely changes, so each owner is checked once per stream
+		visible := map[int]bool{}
+		for msg := range in {
+			if msg.TenantID != caller.TenantID {
+				continue
+			}
+			ok, checked := visible[msg.UserID]
+			if !checked {
+				err := s.policy.AuthorizeUserEvents(ctx, caller, msg.UserID)
+				ok = err == nil
+				if ok || errors.Is(err, ErrForbidden) {
+					visible[msg.UserID] = ok
+				}
+			}
+			if !ok {
+				continue
+			}
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
 }
 
-// DeleteEvent removes an event by ID
-func (s *EventService) DeleteEvent(id int) error {
+// publish sends a committed change to the stream, a failure only costs the
+// listeners a notification
+func (s *EventService) publish(ctx context.Context, eventType string, tenantID int, event *domain.Event) {
+	data, err := json.Marshal(event)
+	if err == nil {
+		err = s.broker.Publish(ctx, live.Message{
+			Type:     eventType,
+			TenantID: tenantID,
+			UserID:   event.UserID,
+			ObjectID: event.ID,
+			Data:     data,
+		})
+	}
+	if err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "live publish failed", "type", eventType, "event_id", event.ID, "error", err)
+	}
+}
 
-	return s.store.DeleteEvent(id)
+// GetDatedUserEvents retrieves a page of a user's events within a date range
+func (s *EventService) GetDatedUserEvents(ctx context.Context, caller *domain.User, userID int, filter domain.EventFilter) (*domain.EventList, error) {
+	ctx, span := tracer.Start(ctx, "EventService.GetDatedUserEvents")
+	defer span.End()
+	if err := s.policy.AuthorizeUserEvents(ctx, caller, userID); err != nil {
+		return nil, err
+	}
+	filter.UserIDs = []int{userID}
+	return s.listEvents(ctx, filter)
 }
 
-// GetDatedUserEvents retrieves events for a user within a date range
-func (s *EventService) GetDatedUserEvents(caller *domain.User, userID int, startDate time.Time, endDate time.Time) ([]domain.Event, error) {
+// GetAllDatedEvents retrieves a page of the tenant's events within a date range, admins only
+func (s *EventService) GetAllDatedEvents(ctx context.Context, caller *domain.User, filter domain.EventFilter) (*domain.EventList, error) {
+	ctx, span := tracer.Start(ctx, "EventService.GetAllDatedEvents")
+	defer span.End()
 	if !caller.IsAdmin {
-		return nil, errors.New("Caller is not admin")
-		// return s.store.GetSelfDatedEvents(caller, startDate, endDate)
+		return nil, ErrForbidden
 	}
-	return s.store.GetDatedUserEvents(userID, startDate, endDate)
+	filter.TenantID = caller.TenantID
+	return s.listEvents(ctx, filter)
+}
+
+// GetTeamDatedEvents retrieves a page of the events of everybody reporting to
+// the caller, directly or through the hierarchy, within a date range
+func (s *EventService) GetTeamDatedEvents(ctx context.Context, caller *domain.User, filter domain.EventFilter) (*domain.EventList, error) {
+	ctx, span := tracer.Start(ctx, "EventService.GetTeamDatedEvents")
+	defer span.End()
+	filter.ManagerID = caller.ID
+	return s.listEvents(ctx, filter)
+}
+
+// GetSelfDatedEvents retrieves a page of the caller's own events within a date range
+func (s *EventService) GetSelfDatedEvents(ctx context.Context, caller *domain.User, filter domain.EventFilter) (*domain.EventList, error) {
+	ctx, span := tracer.Start(ctx, "EventService.GetSelfDatedEvents")
+	defer span.End()
+	filter.UserIDs = []int{caller.ID}
+	return s.listEvents(ctx, filter)
 }
 
-// GetAllDatedEvents retrieves all events within a date range
-func (s *EventService) GetAllDatedEvents(caller *domain.User, startDate time.Time, endDate time.Time) ([]domain.Event, error) {
+func (s *EventService) listEvents(ctx context.Context, filter domain.EventFilter) (*domain.EventList, error) {
+	events, info, err := s.store.ListEvents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.EventList{Events: events, PageInfo: *info}, nil
+}
+
+// GetEventTypes lists the shared event types and the caller's tenant's own,
+// with their label resolved for the first matching locale. Archived types are
+// only listed for admins who ask for them.
+func (s *EventService) GetEventTypes(ctx context.Context, caller *domain.User, locales []string, includeArchived bool) ([]domain.EventType, error) {
+	ctx, span := tracer.Start(ctx, "EventService.GetEventTypes")
+	defer span.End()
+	eventTypes, err := s.store.GetEventTypes(ctx, caller.TenantID, includeArchived && caller.IsAdmin)
+	if err != nil {
+		return nil, err
+	}
+	for i := range eventTypes {
+		eventTypes[i].Label = resolveLabel(&eventTypes[i], locales)
+	}
+	return eventTypes, nil
+}
+
+// CreateEventType adds an event type of the caller's tenant at the end of its list
+func (s *EventService) CreateEventType(ctx context.Context, caller *domain.User, eventType *domain.EventType) error {
+	ctx, span := tracer.Start(ctx, "EventService.CreateEventType")
+	defer span.End()
 	if !caller.IsAdmin {
-		return nil, errors.New("Caller is not authorized")
+		return ErrForbidden
 	}
-	return s.store.GetAllDatedEvents(startDate, endDate)
+	if err := validateEventType(eventType); err != nil {
+		return err
+	}
+	eventType.TenantID = &caller.TenantID
+	return s.store.CreateEventType(ctx, eventType)
 }
 
-func (s *EventService) GetSelfDatedEvents(caller *domain.User, startDate time.Time, endDate time.Time) ([]domain.Event, error) {
-	return s.store.GetSelfDatedEvents(caller, startDate, endDate)
+// UpdateEventType replaces an event type's label, color, pricing flag and translations
+func (s *EventService) UpdateEventType(ctx context.Context, caller *domain.User, eventType *domain.EventType) error {
+	ctx, span := tracer.Start(ctx, "EventService.UpdateEventType")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	if err := validateEventType(eventType); err != nil {
+		return err
+	}
+	if err := s.checkOwnEventType(ctx, caller, eventType.ID); err != nil {
+		return err
+	}
+	eventType.TenantID = &caller.TenantID
+	if err := s.store.UpdateEventType(ctx, eventType); err != nil {
+		return err
+	}
+	updated, err := s.store.GetEventType(ctx, caller.TenantID, eventType.ID)
+	if err != nil {
+		return err
+	}
+	*eventType = *updated
+	return nil
 }
 
-func (s *EventService) GetEventTypes() ([]domain.EventType, error) {
-	return s.store.GetEventTypes()
+// SetEventTypeArchived hides or restores an event type. Archived types stay on
+// existing events but cannot be used for new ones.
+func (s *EventService) SetEventTypeArchived(ctx context.Context, caller *domain.User, id int, archived bool) error {
+	ctx, span := tracer.Start(ctx, "EventService.SetEventTypeArchived")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	if err := s.checkOwnEventType(ctx, caller, id); err != nil {
+		return err
+	}
+	return s.store.SetEventTypeArchived(ctx, caller.TenantID, id, archived)
+}
+
+// ReorderEventTypes sets the display order of the caller's tenant's own types
+// to the order of ids, which must list each of them once. Shared types keep
+// their place before them.
+func (s *EventService) ReorderEventTypes(ctx context.Context, caller *domain.User, ids []int) error {
+	ctx, span := tracer.Start(ctx, "EventService.ReorderEventTypes")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	return s.store.ReorderEventTypes(ctx, caller.TenantID, ids)
+}
+
+// checkOwnEventType returns ErrEventTypeShared when id is a shared type, which
+// tenant admins may use but not change
+func (s *EventService) checkOwnEventType(ctx context.Context, caller *domain.User, id int) error {
+	eventType, err := s.store.GetEventType(ctx, caller.TenantID, id)
+	if err != nil {
+		return err
+	}
+	if eventType.TenantID == nil {
+		return ErrEventTypeShared
+	}
+	return nil
+}
+
+// GetMileageRates lists every rate version of the caller's tenant
+func (s *EventService) GetMileageRates(ctx context.Context, caller *domain.User) ([]domain.MileageRate, error) {
+	ctx, span := tracer.Start(ctx, "EventService.GetMileageRates")
+	defer span.End()
+	return s.rates.ListRates(ctx, caller.TenantID)
+}
+
+// CreateMileageRate adds a new rate version for the caller's tenant. Rates are
+// never edited in place, a change is a new version with a later effective date.
+func (s *EventService) CreateMileageRate(ctx context.Context, caller *domain.User, rate *domain.MileageRate) error {
+	ctx, span := tracer.Start(ctx, "EventService.CreateMileageRate")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	if rate.RatePerKm < 0 || rate.EffectiveFrom.IsZero() {
+		return ErrInvalidRate
+	}
+	if rate.TypeID != nil {
+		if _, err := s.store.GetEventType(ctx, caller.TenantID, *rate.TypeID); err != nil {
+			return err
+		}
+	}
+	rate.TenantID = caller.TenantID
+	return s.rates.CreateRate(ctx, rate)
 }
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"pwp-remastered/internal/domain"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrInvalidEventType  = domain.NewValidationError("invalid_event_type", "event type needs a name, hex color such as #1a2b3c and non-empty translations")
+	ErrEventTypeArchived = domain.NewConflictError("event_type_archived", "event type is archived")
+	ErrEventTypeShared   = domain.NewForbiddenError("event_type_shared", "event types shared by every tenant cannot be changed by a tenant admin")
+)
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func validateEventType(eventType *domain.EventType) error {
+	eventType.Type = strings.TrimSpace(eventType.Type)
+	if eventType.Type == "" {
+		return ErrInvalidEventType
+	}
+	if eventType.Color != nil && !hexColor.MatchString(*eventType.Color) {
+		return ErrInvalidEventType
+	}
+
+	translations := make(map[string]string, len(eventType.Translations))
+	for locale, label := range eventType.Translations {
+		locale = strings.ToLower(strings.TrimSpace(locale))
+		label = strings.TrimSpace(label)
+		if locale == "" || label == "" {
+			return ErrInvalidEventType
+		}
+		translations[locale] = label
+	}
+	eventType.Translations = translations
+	return nil
+}
+
+// resolveLabel picks the translation for the first locale that has one. Locales
+// are tried as given and then by their base language, so "en-GB" falls back to "en".
+// Without a match the default Type label is used.
+func resolveLabel(eventType *domain.EventType, locales []string) string {
+	for _, locale := range locales {
+		locale = strings.ToLower(locale)
+		if label, ok := eventType.Translations[locale]; ok {
+			return label
+		}
+		if base, _, found := strings.Cut(locale, "-"); found {
+			if label, ok := eventType.Translations[base]; ok {
+				return label
+			}
+		}
+	}
+	return eventType.Type
+}
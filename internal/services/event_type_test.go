@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+
+	"pwp-remastered/internal/domain"
+)
+
+func TestResolveLabel(t *testing.T) {
+	eventType := &domain.EventType{
+		Type:         "Müşteri ziyareti",
+		Translations: map[string]string{"tr": "Müşteri ziyareti", "en": "Customer visit"},
+	}
+
+	tests := []struct {
+		locales []string
+		want    string
+	}{
+		{locales: []string{"en"}, want: "Customer visit"},
+		{locales: []string{"en-GB", "tr"}, want: "Customer visit"},
+		{locales: []string{"de", "tr-TR"}, want: "Müşteri ziyareti"},
+		{locales: []string{"de"}, want: "Müşteri ziyareti"},
+		{locales: nil, want: "Müşteri ziyareti"},
+	}
+	for _, tt := range tests {
+		if got := resolveLabel(eventType, tt.locales); got != tt.want {
+			t.Errorf("resolveLabel(%v) = %q, want %q", tt.locales, got, tt.want)
+		}
+	}
+}
+
+func TestValidateEventTypeColor(t *testing.T) {
+	for color, valid := range map[string]bool{"#fff": true, "#1A2b3C": true, "red": false, "#12345": false, "1a2b3c": false} {
+		eventType := &domain.EventType{Type: "Trip", Color: &color}
+		if err := validateEventType(eventType); (err == nil) != valid {
+			t.Errorf("validateEventType(color %q) returned %v, expected valid=%v", color, err, valid)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"pwp-remastered/internal/domain"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ldapTimeout bounds connecting to and each request on the directory
+const ldapTimeout = 10 * time.Second
+
+// LDAPDialer opens a connection to the directory at url
+type LDAPDialer func(url string) (ldap.Client, error)
+
+// DialLDAP connects to an ldap:// or ldaps:// url
+func DialLDAP(url string) (ldap.Client, error) {
+	return ldap.DialURL(url, ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout}))
+}
+
+// LDAPAuthenticator binds as the user's entry to check the password. The
+// entry is found with the service account of the configuration, or
+// anonymously when it has none.
+type LDAPAuthenticator struct {
+	config *domain.LDAPConfig
+	dial   LDAPDialer
+}
+
+// NewLDAPAuthenticator creates an authenticator for a tenant's directory
+func NewLDAPAuthenticator(config *domain.LDAPConfig, dial LDAPDialer) *LDAPAuthenticator {
+	return &LDAPAuthenticator{config: config, dial: dial}
+}
+
+func (a *LDAPAuthenticator) Authenticate(user *domain.User, username string, password string) (*Identity, error) {
+	// Servers accept a bind without a password as an anonymous bind
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(ldapTimeout/time.Second), false,
+		ldapFilter(a.config.UserFilter, username),
+		[]string{a.config.EmailAttribute, a.config.FirstNameAttribute, a.config.LastNameAttribute},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("ldap user search: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap user bind: %w", err)
+	}
+
+	identity := &Identity{
+		Email:     entry.GetAttributeValue(a.config.EmailAttribute),
+		FirstName: entry.GetAttributeValue(a.config.FirstNameAttribute),
+		LastName:  entry.GetAttributeValue(a.config.LastNameAttribute),
+	}
+	if err := a.mapGroups(conn, entry.DN, identity); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (a *LDAPAuthenticator) connect() (ldap.Client, error) {
+	conn, err := a.dial(a.config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap dial: %w", err)
+	}
+	conn.SetTimeout(ldapTimeout)
+	if a.config.StartTLS {
+		u, err := url.Parse(a.config.URL)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if err := conn.StartTLS(&tls.Config{ServerName: u.Hostname()}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap start tls: %w", err)
+		}
+	}
+	if err := a.bindService(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (a *LDAPAuthenticator) bindService(conn ldap.Client) error {
+	if a.config.BindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(a.config.BindDN, a.config.BindPassword); err != nil {
+		return fmt.Errorf("ldap service bind: %w", err)
+	}
+	return nil
+}
+
+// mapGroups sets the flags whose group is configured from the groups the
+// entry is a member of
+func (a *LDAPAuthenticator) mapGroups(conn ldap.Client, dn string, identity *Identity) error {
+	if a.config.GroupBaseDN == "" || (a.config.AdminGroup == "" && a.config.AccountantGroup == "") {
+		return nil
+	}
+	// The user may not be allowed to read groups, search as the service account again
+	if err := a.bindService(conn); err != nil {
+		return err
+	}
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.config.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(ldapTimeout/time.Second), false,
+		ldapFilter(a.config.GroupFilter, dn),
+		[]string{"1.1"},
+		nil,
+	))
+	if err != nil {
+		return fmt.Errorf("ldap group search: %w", err)
+	}
+
+	member := func(group string) *bool {
+		if group == "" {
+			return nil
+		}
+		found := false
+		for _, entry := range result.Entries {
+			found = found || sameDN(entry.DN, group)
+		}
+		return &found
+	}
+	identity.IsAdmin = member(a.config.AdminGroup)
+	identity.IsAccountant = member(a.config.AccountantGroup)
+	return nil
+}
+
+// ldapFilter replaces %s in filter with the escaped value
+func ldapFilter(filter string, value string) string {
+	return strings.ReplaceAll(filter, "%s", ldap.EscapeFilter(value))
+}
+
+// sameDN compares DNs the way directories do, ignoring case and spacing
+func sameDN(a string, b string) bool {
+	dnA, errA := ldap.ParseDN(a)
+	dnB, errB := ldap.ParseDN(b)
+	if errA != nil || errB != nil {
+		return strings.EqualFold(a, b)
+	}
+	return dnA.EqualFold(dnB)
+}
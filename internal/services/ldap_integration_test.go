@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"pwp-remastered/internal/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// directoryLDIF seeds the OpenLDAP container: ayse is in pwp-admins, mehmet in
+// no group
+const directoryLDIF = `dn: dc=example,dc=org
+objectClass: dcObject
+objectClass: organization
+dc: example
+o: example
+
+dn: ou=people,dc=example,dc=org
+objectClass: organizationalUnit
+ou: people
+
+dn: ou=groups,dc=example,dc=org
+objectClass: organizationalUnit
+ou: groups
+
+dn: uid=ayse,ou=people,dc=example,dc=org
+objectClass: inetOrgPerson
+uid: ayse
+cn: Ayse Yilmaz
+givenName: Ayse
+sn: Yilmaz
+mail: ayse@example.org
+userPassword: ayse-secret
+
+dn: uid=mehmet,ou=people,dc=example,dc=org
+objectClass: inetOrgPerson
+uid: mehmet
+cn: Mehmet Demir
+givenName: Mehmet
+sn: Demir
+mail: mehmet@example.org
+userPassword: mehmet-secret
+
+dn: cn=pwp-admins,ou=groups,dc=example,dc=org
+objectClass: groupOfNames
+cn: pwp-admins
+member: uid=ayse,ou=people,dc=example,dc=org
+`
+
+// startOpenLDAP runs an OpenLDAP server seeded with directoryLDIF and returns
+// its ldap:// url
+func startOpenLDAP(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("starts an OpenLDAP container")
+	}
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctx := context.Background()
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "bitnami/openldap:2.6",
+			ExposedPorts: []string{"1389/tcp"},
+			Env: map[string]string{
+				"LDAP_ROOT":            "dc=example,dc=org",
+				"LDAP_ADMIN_USERNAME":  "admin",
+				"LDAP_ADMIN_PASSWORD":  "admin-secret",
+				"LDAP_CUSTOM_LDIF_DIR": "/ldifs",
+			},
+			Files: []testcontainers.ContainerFile{{
+				Reader:            strings.NewReader(directoryLDIF),
+				ContainerFilePath: "/ldifs/directory.ldif",
+				FileMode:          0o644,
+			}},
+			WaitingFor: wait.ForAll(
+				wait.ForLog("** Starting slapd **"),
+				wait.ForListeningPort("1389/tcp"),
+			).WithDeadline(time.Minute),
+		},
+		Started: true,
+	})
+	testcontainers.CleanupContainer(t, container)
+	if err != nil {
+		t.Fatalf("could not start openldap container: %v", err)
+	}
+
+	endpoint, err := container.PortEndpoint(ctx, "1389/tcp", "ldap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return endpoint
+}
+
+func TestLDAPAuthenticatorOpenLDAP(t *testing.T) {
+	config := domain.LDAPConfig{
+		URL:          startOpenLDAP(t),
+		BindDN:       "cn=admin,dc=example,dc=org",
+		BindPassword: "admin-secret",
+		BaseDN:       "ou=people,dc=example,dc=org",
+		GroupBaseDN:  "ou=groups,dc=example,dc=org",
+		AdminGroup:   "cn=pwp-admins,ou=groups,dc=example,dc=org",
+	}
+	if err := normalizeLDAPConfig(&config); err != nil {
+		t.Fatal(err)
+	}
+	authenticator := NewLDAPAuthenticator(&config, DialLDAP)
+
+	identity, err := authenticator.Authenticate(nil, "ayse", "ayse-secret")
+	if err != nil {
+		t.Fatalf("Authenticate(ayse) error = %v", err)
+	}
+	if identity.Email != "ayse@example.org" || identity.FirstName != "Ayse" || identity.LastName != "Yilmaz" {
+		t.Errorf("Authenticate(ayse) identity = %+v", identity)
+	}
+	if identity.IsAdmin == nil || !*identity.IsAdmin || identity.IsAccountant != nil {
+		t.Errorf("Authenticate(ayse) flags = %v, %v; want admin and an unmanaged accountant flag", identity.IsAdmin, identity.IsAccountant)
+	}
+
+	identity, err = authenticator.Authenticate(nil, "mehmet", "mehmet-secret")
+	if err != nil {
+		t.Fatalf("Authenticate(mehmet) error = %v", err)
+	}
+	if identity.IsAdmin == nil || *identity.IsAdmin {
+		t.Errorf("Authenticate(mehmet) IsAdmin = %v, want false", identity.IsAdmin)
+	}
+
+	for _, login := range [][2]string{{"ayse", "mehmet-secret"}, {"*", "ayse-secret"}, {"nobody", "ayse-secret"}} {
+		if _, err := authenticator.Authenticate(nil, login[0], login[1]); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Authenticate(%s) error = %v, want %v", login[0], err, ErrInvalidCredentials)
+		}
+	}
+}
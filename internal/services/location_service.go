@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"math"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+	"strings"
+)
+
+var ErrInvalidLocation = domain.NewValidationError("invalid_location", "location needs a name and coordinates within range")
+
+// LocationService handles business logic for the saved locations registry
+type LocationService struct {
+	store store.LocationStore
+}
+
+// NewLocationService creates a new location service
+func NewLocationService(locationStore store.LocationStore) *LocationService {
+	return &LocationService{
+		store: locationStore,
+	}
+}
+
+// GetLocation retrieves a location of the caller's tenant
+func (s *LocationService) GetLocation(ctx context.Context, caller *domain.User, id int) (*domain.Location, error) {
+	ctx, span := tracer.Start(ctx, "LocationService.GetLocation")
+	defer span.End()
+	return s.store.GetLocation(ctx, caller.TenantID, id)
+}
+
+// SearchLocations lists the caller's tenant locations matching query and tag
+func (s *LocationService) SearchLocations(ctx context.Context, caller *domain.User, query string, tag string) ([]domain.Location, error) {
+	ctx, span := tracer.Start(ctx, "LocationService.SearchLocations")
+	defer span.End()
+	return s.store.SearchLocations(ctx, caller.TenantID, strings.TrimSpace(query), strings.TrimSpace(tag))
+}
+
+// CreateLocation saves a new location for the caller's tenant
+func (s *LocationService) CreateLocation(ctx context.Context, caller *domain.User, location *domain.Location) error {
+	ctx, span := tracer.Start(ctx, "LocationService.CreateLocation")
+	defer span.End()
+	if err := validateLocation(location); err != nil {
+		return err
+	}
+	location.TenantID = caller.TenantID
+	return s.store.CreateLocation(ctx, location)
+}
+
+// UpdateLocation modifies a location of the caller's tenant
+func (s *LocationService) UpdateLocation(ctx context.Context, caller *domain.User, location *domain.Location) error {
+	ctx, span := tracer.Start(ctx, "LocationService.UpdateLocation")
+	defer span.End()
+	if err := validateLocation(location); err != nil {
+		return err
+	}
+	location.TenantID = caller.TenantID
+	return s.store.UpdateLocation(ctx, location)
+}
+
+// DeleteLocation removes a location, events referencing it keep their coordinates
+func (s *LocationService) DeleteLocation(ctx context.Context, caller *domain.User, id int) error {
+	ctx, span := tracer.Start(ctx, "LocationService.DeleteLocation")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	return s.store.DeleteLocation(ctx, caller.TenantID, id)
+}
+
+// SetRouteDistance configures the road distance between two locations of the caller's tenant
+func (s *LocationService) SetRouteDistance(ctx context.Context, caller *domain.User, route *domain.LocationRoute) error {
+	ctx, span := tracer.Start(ctx, "LocationService.SetRouteDistance")
+	defer span.End()
+	if route.DistanceKm < 0 || route.FromLocationID == route.ToLocationID {
+		return ErrInvalidLocation
+	}
+	for _, id := range []int{route.FromLocationID, route.ToLocationID} {
+		if _, err := s.store.GetLocation(ctx, caller.TenantID, id); err != nil {
+			return err
+		}
+	}
+	return s.store.SetRouteDistance(ctx, route)
+}
+
+func validateLocation(location *domain.Location) error {
+	location.Name = strings.TrimSpace(location.Name)
+	if location.Name == "" || (location.Latitude == nil) != (location.Longitude == nil) {
+		return ErrInvalidLocation
+	}
+	if location.Latitude != nil && (math.Abs(*location.Latitude) > 90 || math.Abs(*location.Longitude) > 180) {
+		return ErrInvalidLocation
+	}
+
+	tags := []string{}
+	for _, tag := range location.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	location.Tags = tags
+	return nil
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/logging"
+	"pwp-remastered/internal/metrics"
+	"pwp-remastered/internal/store"
+	"pwp-remastered/internal/webhook"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrInvalidOIDCConfig = domain.NewValidationError("invalid_oidc_config", "OpenID Connect configuration needs an https issuer, a client_id and the openid scope")
+	ErrOIDCLoginFailed   = domain.NewUnauthorizedError("oidc_login_failed", "identity provider login failed")
+	ErrOIDCAccount       = domain.NewForbiddenError("oidc_account_incomplete", "ID token has no username claim or verified email")
+)
+
+// oidcTimeout bounds discovery, key and token requests to the provider
+const oidcTimeout = 10 * time.Second
+
+// OIDCService signs users in through their tenant's OpenID Connect provider
+// with the authorization code flow and PKCE. Accounts are matched by the
+// username claim and created on first login, like LDAP accounts. The
+// provider is only reached at public addresses.
+type OIDCService struct {
+	store  store.OIDCStore
+	auth   *AuthService
+	client *http.Client
+
+	mu sync.Mutex
+	// providers caches discovery by issuer, their key sets refresh themselves.
+	// mu guards the map only, discovery runs unlocked.
+	providers map[string]*oidc.Provider
+}
+
+// NewOIDCService creates a new OpenID Connect service
+func NewOIDCService(oidcStore store.OIDCStore, authService *AuthService) *OIDCService {
+	return &OIDCService{
+		store:     oidcStore,
+		auth:      authService,
+		client:    webhook.NewClient(oidcTimeout),
+		providers: map[string]*oidc.Provider{},
+	}
+}
+
+// BeginLogin starts a login to tenantID and returns the provider URL to send
+// the user to and the login's state. The provider redirects back to
+// redirectURI.
+func (s *OIDCService) BeginLogin(ctx context.Context, tenantID int, redirectURI string) (string, string, error) {
+	ctx, span := tracer.Start(ctx, "OIDCService.BeginLogin")
+	defer span.End()
+	config, err := s.enabledConfig(ctx, tenantID)
+	if err != nil {
+		return "", "", err
+	}
+	provider, err := s.provider(ctx, config.Issuer)
+	if err != nil {
+		return "", "", err
+	}
+
+	login := domain.OIDCLogin{
+		State:        randomToken(),
+		TenantID:     tenantID,
+		CodeVerifier: oauth2.GenerateVerifier(),
+		Nonce:        randomToken(),
+		RedirectURI:  redirectURI,
+	}
+	if err := s.store.CreateLogin(ctx, &login); err != nil {
+		return "", "", err
+	}
+	return oauth2Config(config, provider, redirectURI).AuthCodeURL(
+		login.State, oauth2.S256ChallengeOption(login.CodeVerifier), oidc.Nonce(login.Nonce),
+	), login.State, nil
+}
+
+// CompleteLogin handles the provider's callback: it redeems code with the
+// PKCE verifier of state, verifies the ID token and syncs the account. It
+// returns the user and the tenant's redirect URL.
+func (s *OIDCService) CompleteLogin(ctx context.Context, state string, code string) (*domain.User, string, error) {
+	ctx, span := tracer.Start(ctx, "OIDCService.CompleteLogin")
+	defer span.End()
+	user, redirectURL, err := s.completeLogin(ctx, state, code)
+	metrics.ObserveLogin("oidc", err)
+	return user, redirectURL, err
+}
+
+func (s *OIDCService) completeLogin(ctx context.Context, state string, code string) (*domain.User, string, error) {
+	login, err := s.store.ConsumeLogin(ctx, state)
+	if err != nil {
+		return nil, "", err
+	}
+	config, err := s.enabledConfig(ctx, login.TenantID)
+	if err != nil {
+		return nil, "", err
+	}
+	provider, err := s.provider(ctx, config.Issuer)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(oidc.ClientContext(ctx, s.client), oidcTimeout)
+	defer cancel()
+	token, err := oauth2Config(config, provider, login.RedirectURI).Exchange(ctx, code, oauth2.VerifierOption(login.CodeVerifier))
+	if err != nil {
+		return nil, "", loginFailed(ctx, "code exchange", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, "", loginFailed(ctx, "token response has no id_token", nil)
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: config.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, "", loginFailed(ctx, "id_token verification", err)
+	}
+	if idToken.Nonce != login.Nonce {
+		return nil, "", loginFailed(ctx, "nonce does not match", nil)
+	}
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, "", loginFailed(ctx, "id_token claims", err)
+	}
+
+	username, identity := oidcIdentity(config, claims)
+	if username == "" {
+		return nil, "", ErrOIDCAccount
+	}
+	// Usernames are unique per tenant, the provider of one tenant cannot sign in to another
+	user, err := s.auth.users.GetUserByUsername(ctx, login.TenantID, username)
+	if err != nil {
+		return nil, "", err
+	}
+	if user != nil && user.Status == 0 {
+		return nil, "", ErrAccountInactive
+	}
+	user, err = s.auth.sync(ctx, login.TenantID, user, username, identity)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, config.RedirectURL, nil
+}
+
+// loginFailed logs why a login failed and returns ErrOIDCLoginFailed, the
+// provider's answer is not shown to the client
+func loginFailed(ctx context.Context, reason string, err error) error {
+	logging.FromContext(ctx).WarnContext(ctx, "oidc login failed", "reason", reason, "error", err)
+	return ErrOIDCLoginFailed
+}
+
+func (s *OIDCService) enabledConfig(ctx context.Context, tenantID int) (*domain.OIDCConfig, error) {
+	config, err := s.store.GetOIDCConfig(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !config.Enabled {
+		return nil, store.ErrOIDCConfigNotFound
+	}
+	return config, nil
+}
+
+// provider returns the cached discovery of issuer. A slow provider does not
+// hold up the logins of other tenants, logins racing on a cold cache may
+// discover twice and the first result is kept.
+func (s *OIDCService) provider(ctx context.Context, issuer string) (*oidc.Provider, error) {
+	s.mu.Lock()
+	provider, ok := s.providers[issuer]
+	s.mu.Unlock()
+	if ok {
+		return provider, nil
+	}
+
+	ctx, cancel := context.WithTimeout(oidc.ClientContext(ctx, s.client), oidcTimeout)
+	defer cancel()
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, loginFailed(ctx, "discovery", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached, ok := s.providers[issuer]; ok {
+		return cached, nil
+	}
+	s.providers[issuer] = provider
+	return provider, nil
+}
+
+func oauth2Config(config *domain.OIDCConfig, provider *oidc.Provider, redirectURI string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  redirectURI,
+		Scopes:       strings.Fields(config.Scopes),
+	}
+}
+
+// oidcIdentity reads the username and the identity from the ID token claims.
+// The username falls back to the email when the provider has verified it,
+// otherwise anyone who can set that address at the provider would take over
+// the account.
+func oidcIdentity(config *domain.OIDCConfig, claims map[string]interface{}) (string, *Identity) {
+	text := func(name string) string {
+		value, _ := claimValue(claims, name).(string)
+		return value
+	}
+	identity := &Identity{
+		Email:     text(config.EmailClaim),
+		FirstName: text(config.FirstNameClaim),
+		LastName:  text(config.LastNameClaim),
+	}
+
+	var roles []string
+	switch value := claimValue(claims, config.RolesClaim).(type) {
+	case string:
+		roles = []string{value}
+	case []interface{}:
+		for _, role := range value {
+			if role, ok := role.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+	}
+	hasRole := func(role string) *bool {
+		if role == "" {
+			return nil
+		}
+		found := slices.Contains(roles, role)
+		return &found
+	}
+	identity.IsAdmin = hasRole(config.AdminRole)
+	identity.IsAccountant = hasRole(config.AccountantRole)
+
+	username := text(config.UsernameClaim)
+	if verified, _ := claims["email_verified"].(bool); username == "" && verified {
+		username = identity.Email
+	}
+	return username, identity
+}
+
+// claimValue looks a claim up by name, dots reach into nested objects as in
+// Keycloak's realm_access.roles
+func claimValue(claims map[string]interface{}, name string) interface{} {
+	if value, ok := claims[name]; ok {
+		return value
+	}
+	head, rest, ok := strings.Cut(name, ".")
+	if !ok {
+		return nil
+	}
+	nested, _ := claims[head].(map[string]interface{})
+	return claimValue(nested, rest)
+}
+
+// randomToken returns 32 random bytes, URL-safe base64 encoded in 43 characters
+func randomToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// GetOIDCConfig returns the OpenID Connect configuration of the caller's
+// tenant without the client secret, admins only
+func (s *OIDCService) GetOIDCConfig(ctx context.Context, caller *domain.User) (*domain.OIDCConfig, error) {
+	ctx, span := tracer.Start(ctx, "OIDCService.GetOIDCConfig")
+	defer span.End()
+	if !caller.IsAdmin {
+		return nil, ErrForbidden
+	}
+	config, err := s.store.GetOIDCConfig(ctx, caller.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	config.ClientSecret = ""
+	return config, nil
+}
+
+// UpdateOIDCConfig replaces the OpenID Connect configuration of the caller's
+// tenant, admins only. Empty claims and scopes get the standard names, an
+// empty client secret keeps the stored one.
+func (s *OIDCService) UpdateOIDCConfig(ctx context.Context, caller *domain.User, config *domain.OIDCConfig) error {
+	ctx, span := tracer.Start(ctx, "OIDCService.UpdateOIDCConfig")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	if err := normalizeOIDCConfig(config); err != nil {
+		return err
+	}
+	config.TenantID = caller.TenantID
+	if err := s.store.UpdateOIDCConfig(ctx, config); err != nil {
+		return err
+	}
+	config.ClientSecret = ""
+	return nil
+}
+
+// DeleteOIDCConfig turns single sign-on off for the caller's tenant, admins only
+func (s *OIDCService) DeleteOIDCConfig(ctx context.Context, caller *domain.User) error {
+	ctx, span := tracer.Start(ctx, "OIDCService.DeleteOIDCConfig")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	return s.store.DeleteOIDCConfig(ctx, caller.TenantID)
+}
+
+func normalizeOIDCConfig(config *domain.OIDCConfig) error {
+	defaults := []struct {
+		field *string
+		value string
+	}{
+		{&config.Scopes, "openid email profile"},
+		{&config.UsernameClaim, "preferred_username"},
+		{&config.EmailClaim, "email"},
+		{&config.FirstNameClaim, "given_name"},
+		{&config.LastNameClaim, "family_name"},
+		{&config.RolesClaim, "groups"},
+	}
+	for _, d := range defaults {
+		*d.field = strings.TrimSpace(*d.field)
+		if *d.field == "" {
+			*d.field = d.value
+		}
+	}
+
+	config.Issuer = strings.TrimSpace(config.Issuer)
+	issuer, err := url.Parse(config.Issuer)
+	if err != nil || issuer.Scheme != "https" || issuer.Hostname() == "" {
+		return fmt.Errorf("%w: issuer must be an https URL", ErrInvalidOIDCConfig)
+	}
+	if !webhook.PublicHost(issuer.Hostname()) {
+		return fmt.Errorf("%w: issuer must be a public host", ErrInvalidOIDCConfig)
+	}
+	if strings.TrimSpace(config.ClientID) == "" {
+		return fmt.Errorf("%w: client_id is required", ErrInvalidOIDCConfig)
+	}
+	if !slices.Contains(strings.Fields(config.Scopes), oidc.ScopeOpenID) {
+		return fmt.Errorf("%w: scopes must include openid", ErrInvalidOIDCConfig)
+	}
+	if config.RedirectURL != "" {
+		redirect, err := url.Parse(config.RedirectURL)
+		if err != nil || (redirect.Scheme != "https" && redirect.Scheme != "http") || redirect.Host == "" || redirect.Fragment != "" {
+			return fmt.Errorf("%w: redirect_url must be an absolute URL without a fragment", ErrInvalidOIDCConfig)
+		}
+	}
+	return nil
+}
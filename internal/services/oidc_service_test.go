@@ -0,0 +1,372 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/logging"
+	"pwp-remastered/internal/store"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIdP is an OpenID Connect provider serving discovery, its key set and a
+// token endpoint that checks the PKCE verifier
+type mockIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockCode
+}
+
+// mockCode is an issued authorization code and the ID token it redeems for
+type mockCode struct {
+	challenge   string
+	redirectURI string
+	claims      jwt.MapClaims
+	key         *rsa.PrivateKey
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key, codes: map[string]mockCode{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/authorize",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+			"code_challenge_methods_supported":      []string{"S256"},
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "use": "sig", "alg": "RS256", "kid": "test",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", idp.token)
+	idp.server = httptest.NewTLSServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// authorize stands in for the user signing in at the provider: it checks the
+// authorization request and returns the code the callback receives
+func (idp *mockIdP) authorize(t *testing.T, authURL string, claims jwt.MapClaims) (state string, code string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("authorization request without an S256 challenge: %s", authURL)
+	}
+	if q.Get("response_type") != "code" || q.Get("client_id") != "pwp" || q.Get("nonce") == "" {
+		t.Fatalf("unexpected authorization request: %s", authURL)
+	}
+
+	token := jwt.MapClaims{
+		"iss": idp.server.URL, "aud": "pwp", "sub": "1234", "nonce": q.Get("nonce"),
+		"iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		token[name] = value
+	}
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	code = randomToken()
+	idp.codes[code] = mockCode{challenge: q.Get("code_challenge"), redirectURI: q.Get("redirect_uri"), claims: token, key: idp.key}
+	return q.Get("state"), code
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	idp.mu.Lock()
+	code, ok := idp.codes[r.Form.Get("code")]
+	delete(idp.codes, r.Form.Get("code"))
+	idp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge || r.Form.Get("redirect_uri") != code.redirectURI {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	signed := jwt.NewWithClaims(jwt.SigningMethodRS256, code.claims)
+	signed.Header["kid"] = "test"
+	idToken, err := signed.SignedString(code.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access", "token_type": "Bearer", "expires_in": 3600, "id_token": idToken,
+	})
+}
+
+// memoryOIDCStore keeps configurations and logins in memory
+type memoryOIDCStore struct {
+	configs map[int]domain.OIDCConfig
+	logins  map[string]domain.OIDCLogin
+}
+
+func (s *memoryOIDCStore) GetOIDCConfig(ctx context.Context, tenantID int) (*domain.OIDCConfig, error) {
+	config, ok := s.configs[tenantID]
+	if !ok {
+		return nil, store.ErrOIDCConfigNotFound
+	}
+	return &config, nil
+}
+
+func (s *memoryOIDCStore) UpdateOIDCConfig(ctx context.Context, config *domain.OIDCConfig) error {
+	s.configs[config.TenantID] = *config
+	return nil
+}
+
+func (s *memoryOIDCStore) DeleteOIDCConfig(ctx context.Context, tenantID int) error {
+	delete(s.configs, tenantID)
+	return nil
+}
+
+func (s *memoryOIDCStore) CreateLogin(ctx context.Context, login *domain.OIDCLogin) error {
+	s.logins[login.State] = *login
+	return nil
+}
+
+func (s *memoryOIDCStore) ConsumeLogin(ctx context.Context, state string) (*domain.OIDCLogin, error) {
+	login, ok := s.logins[state]
+	if !ok {
+		return nil, store.ErrOIDCLoginNotFound
+	}
+	delete(s.logins, state)
+	return &login, nil
+}
+
+const oidcCallback = "https://pwp.example.org/auth/oidc/callback"
+
+func newOIDCTestService(t *testing.T, users map[int]domain.User) (*OIDCService, *mockIdP, *accountStore, *memoryOIDCStore) {
+	t.Helper()
+	idp := newMockIdP(t)
+	config := domain.OIDCConfig{
+		TenantID: 3, Enabled: true, Issuer: "https://sso.example.org", ClientID: "pwp", ClientSecret: "secret",
+		AdminRole: "pwp-admins", AccountantRole: "pwp-accountants", RedirectURL: "https://app.example.org/sso",
+	}
+	if err := normalizeOIDCConfig(&config); err != nil {
+		t.Fatal(err)
+	}
+	// The mock provider listens on loopback, which the configuration and the
+	// service's client refuse
+	config.Issuer = idp.server.URL
+	oidcStore := &memoryOIDCStore{configs: map[int]domain.OIDCConfig{3: config}, logins: map[string]domain.OIDCLogin{}}
+	accounts := &accountStore{hierarchyStore: hierarchyStore{users: users}}
+
+	s := NewOIDCService(oidcStore, &AuthService{users: accounts})
+	s.client = idp.server.Client()
+	return s, idp, accounts, oidcStore
+}
+
+func TestOIDCLogin(t *testing.T) {
+	// zeynep of tenant 1 is someone else, tenant 3 gets its own account
+	s, idp, accounts, _ := newOIDCTestService(t, map[int]domain.User{
+		1: {ID: 1, TenantID: 1, Username: "zeynep", Email: "zeynep@example.com", Status: 1},
+	})
+
+	authURL, _, err := s.BeginLogin(t.Context(), 3, oidcCallback)
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	state, code := idp.authorize(t, authURL, jwt.MapClaims{
+		"preferred_username": "zeynep", "email": "zeynep@example.org",
+		"given_name": "Zeynep", "family_name": "Kaya", "groups": []string{"staff", "pwp-accountants"},
+	})
+
+	user, redirectURL, err := s.CompleteLogin(t.Context(), state, code)
+	if err != nil {
+		t.Fatalf("CompleteLogin() error = %v", err)
+	}
+	want := domain.User{
+		ID: 100, Username: "zeynep", Email: "zeynep@example.org", FirstName: "Zeynep", LastName: "Kaya",
+		IsAccountant: true, IsUser: true, TenantID: 3, Status: 1,
+	}
+	if *user != want || len(accounts.created) != 1 {
+		t.Errorf("CompleteLogin() user = %+v, want %+v created", *user, want)
+	}
+	if redirectURL != "https://app.example.org/sso" {
+		t.Errorf("CompleteLogin() redirect = %q", redirectURL)
+	}
+
+	if _, _, err := s.CompleteLogin(t.Context(), state, code); !errors.Is(err, store.ErrOIDCLoginNotFound) {
+		t.Errorf("CompleteLogin() with a used state error = %v, want %v", err, store.ErrOIDCLoginNotFound)
+	}
+}
+
+func TestOIDCLoginRejects(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.MapClaims{"preferred_username": "zeynep", "email": "zeynep@example.org"}
+
+	tests := []struct {
+		name string
+		// tamper changes the login or the issued code before the callback
+		tamper func(idp *mockIdP, logins *memoryOIDCStore, state string, code string)
+		claims jwt.MapClaims
+		err    error
+	}{
+		{
+			name: "wrong verifier",
+			tamper: func(idp *mockIdP, logins *memoryOIDCStore, state string, code string) {
+				login := logins.logins[state]
+				login.CodeVerifier = "guessed-verifier-guessed-verifier-guessed-verifier"
+				logins.logins[state] = login
+			},
+			claims: claims,
+			err:    ErrOIDCLoginFailed,
+		},
+		{
+			name: "foreign signing key",
+			tamper: func(idp *mockIdP, logins *memoryOIDCStore, state string, code string) {
+				issued := idp.codes[code]
+				issued.key = otherKey
+				idp.codes[code] = issued
+			},
+			claims: claims,
+			err:    ErrOIDCLoginFailed,
+		},
+		{name: "other audience", claims: jwt.MapClaims{"aud": "someone-else", "preferred_username": "zeynep"}, err: ErrOIDCLoginFailed},
+		{name: "replayed nonce", claims: jwt.MapClaims{"nonce": "old", "preferred_username": "zeynep"}, err: ErrOIDCLoginFailed},
+		{name: "expired token", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix(), "preferred_username": "zeynep"}, err: ErrOIDCLoginFailed},
+		{name: "no username", claims: jwt.MapClaims{"name": "Zeynep"}, err: ErrOIDCAccount},
+		{name: "unverified email", claims: jwt.MapClaims{"email": "eski@example.org", "email_verified": false}, err: ErrOIDCAccount},
+		{name: "inactive user", claims: jwt.MapClaims{"preferred_username": "eski"}, err: ErrAccountInactive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, idp, accounts, logins := newOIDCTestService(t, map[int]domain.User{
+				2: {ID: 2, TenantID: 3, Username: "eski", Status: 0},
+			})
+			authURL, _, err := s.BeginLogin(t.Context(), 3, oidcCallback)
+			if err != nil {
+				t.Fatalf("BeginLogin() error = %v", err)
+			}
+			state, code := idp.authorize(t, authURL, tt.claims)
+			if tt.tamper != nil {
+				tt.tamper(idp, logins, state, code)
+			}
+
+			var logs bytes.Buffer
+			ctx := logging.NewContext(t.Context(), logging.New(&logs, slog.LevelInfo))
+			_, _, err = s.CompleteLogin(ctx, state, code)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("CompleteLogin() error = %v, want %v", err, tt.err)
+			}
+			// The provider's answer is logged, not returned
+			if tt.err == ErrOIDCLoginFailed && (err != ErrOIDCLoginFailed || !strings.Contains(logs.String(), "oidc login failed")) {
+				t.Errorf("CompleteLogin() error = %q, logged %q", err, logs.String())
+			}
+			if len(accounts.created)+len(accounts.updated) != 0 {
+				t.Errorf("account written for a rejected login")
+			}
+		})
+	}
+}
+
+func TestOIDCDisabledTenant(t *testing.T) {
+	s, _, _, oidcStore := newOIDCTestService(t, map[int]domain.User{})
+	config := oidcStore.configs[3]
+	config.Enabled = false
+	oidcStore.configs[3] = config
+
+	for _, tenantID := range []int{3, 4} {
+		if _, _, err := s.BeginLogin(t.Context(), tenantID, oidcCallback); !errors.Is(err, store.ErrOIDCConfigNotFound) {
+			t.Errorf("BeginLogin(%d) error = %v, want %v", tenantID, err, store.ErrOIDCConfigNotFound)
+		}
+	}
+}
+
+func TestOIDCIdentity(t *testing.T) {
+	config := domain.OIDCConfig{Issuer: "https://sso.example.org/realms/pwp", ClientID: "pwp", AdminRole: "admin", RolesClaim: "realm_access.roles"}
+	if err := normalizeOIDCConfig(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	username, identity := oidcIdentity(&config, map[string]interface{}{
+		"email":          "deniz@example.org",
+		"email_verified": true,
+		"realm_access":   map[string]interface{}{"roles": []interface{}{"admin", "offline_access"}},
+	})
+	if username != "deniz@example.org" {
+		t.Errorf("username = %q, want the email", username)
+	}
+	if identity.IsAdmin == nil || !*identity.IsAdmin || identity.IsAccountant != nil {
+		t.Errorf("flags = %v, %v; want admin and an unmanaged accountant flag", identity.IsAdmin, identity.IsAccountant)
+	}
+
+	for _, verified := range []interface{}{false, "true", nil} {
+		username, _ := oidcIdentity(&config, map[string]interface{}{"email": "deniz@example.org", "email_verified": verified})
+		if username != "" {
+			t.Errorf("email_verified %v: username = %q, want none", verified, username)
+		}
+	}
+}
+
+func TestOIDCIssuer(t *testing.T) {
+	tests := []struct {
+		issuer string
+		valid  bool
+	}{
+		{"https://sso.example.org/realms/pwp", true},
+		{"http://sso.example.org", false},
+		{"https://localhost:8443", false},
+		{"https://127.0.0.1", false},
+		{"https://169.254.169.254/latest", false},
+		{"https://[fd00::1]", false},
+	}
+	for _, tt := range tests {
+		config := domain.OIDCConfig{Issuer: tt.issuer, ClientID: "pwp"}
+		if err := normalizeOIDCConfig(&config); (err == nil) != tt.valid {
+			t.Errorf("normalizeOIDCConfig(%q) error = %v, want valid %t", tt.issuer, err, tt.valid)
+		}
+	}
+
+	// Names resolving to internal addresses are refused when discovery connects
+	idp := newMockIdP(t)
+	s := NewOIDCService(&memoryOIDCStore{}, &AuthService{})
+	if _, err := s.provider(t.Context(), idp.server.URL); !errors.Is(err, ErrOIDCLoginFailed) {
+		t.Errorf("provider() of a loopback issuer error = %v, want %v", err, ErrOIDCLoginFailed)
+	}
+	if len(s.providers) != 0 {
+		t.Errorf("loopback issuer cached")
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+	"time"
+)
+
+var (
+	ErrInvalidPeriod = domain.NewValidationError("invalid_period", "period needs a start_date and end_date formatted as YYYY-MM-DD, start_date not after end_date")
+)
+
+// PeriodService manages accounting periods. Everybody in the tenant may see
+// them, accountants and admins create, close and reopen them.
+type PeriodService struct {
+	store store.PeriodStore
+	users store.UserStore
+}
+
+// NewPeriodService creates a new period service
+func NewPeriodService(periodStore store.PeriodStore, userStore store.UserStore) *PeriodService {
+	return &PeriodService{store: periodStore, users: userStore}
+}
+
+// ListPeriods lists the periods of the caller's tenant, latest first
+func (s *PeriodService) ListPeriods(ctx context.Context, caller *domain.User) ([]domain.AccountingPeriod, error) {
+	ctx, span := tracer.Start(ctx, "PeriodService.ListPeriods")
+	defer span.End()
+	return s.store.ListPeriods(ctx, caller.TenantID)
+}
+
+// GetPeriod retrieves a period of the caller's tenant with its history
+func (s *PeriodService) GetPeriod(ctx context.Context, caller *domain.User, id int) (*domain.AccountingPeriod, error) {
+	ctx, span := tracer.Start(ctx, "PeriodService.GetPeriod")
+	defer span.End()
+	return s.store.GetPeriod(ctx, caller.TenantID, id)
+}
+
+// CreatePeriod adds an open period to the caller's tenant
+func (s *PeriodService) CreatePeriod(ctx context.Context, caller *domain.User, period *domain.AccountingPeriod) error {
+	ctx, span := tracer.Start(ctx, "PeriodService.CreatePeriod")
+	defer span.End()
+	if err := s.authorizeAccountant(ctx, caller); err != nil {
+		return err
+	}
+	start, err := time.Parse(time.DateOnly, period.StartDate)
+	if err != nil {
+		return ErrInvalidPeriod
+	}
+	end, err := time.Parse(time.DateOnly, period.EndDate)
+	if err != nil || end.Before(start) {
+		return ErrInvalidPeriod
+	}
+	period.TenantID = caller.TenantID
+	return s.store.CreatePeriod(ctx, period, caller.ID)
+}
+
+// ClosePeriod locks the events of a period and records who closed it
+func (s *PeriodService) ClosePeriod(ctx context.Context, caller *domain.User, id int) (*domain.AccountingPeriod, error) {
+	ctx, span := tracer.Start(ctx, "PeriodService.ClosePeriod")
+	defer span.End()
+	if err := s.authorizeAccountant(ctx, caller); err != nil {
+		return nil, err
+	}
+	if err := s.store.ClosePeriod(ctx, caller.TenantID, id, caller.ID); err != nil {
+		return nil, err
+	}
+	return s.store.GetPeriod(ctx, caller.TenantID, id)
+}
+
+// ReopenPeriod unlocks the events of a closed period, the history keeps the closure
+func (s *PeriodService) ReopenPeriod(ctx context.Context, caller *domain.User, id int) (*domain.AccountingPeriod, error) {
+	ctx, span := tracer.Start(ctx, "PeriodService.ReopenPeriod")
+	defer span.End()
+	if err := s.authorizeAccountant(ctx, caller); err != nil {
+		return nil, err
+	}
+	if err := s.store.ReopenPeriod(ctx, caller.TenantID, id, caller.ID); err != nil {
+		return nil, err
+	}
+	return s.store.GetPeriod(ctx, caller.TenantID, id)
+}
+
+// authorizeAccountant lets admins through and checks the accountant flag in the
+// database, it is not part of the token
+func (s *PeriodService) authorizeAccountant(ctx context.Context, caller *domain.User) error {
+	if caller.IsAdmin {
+		return nil
+	}
+	user, err := s.users.GetUser(ctx, caller.ID)
+	if err != nil {
+		return err
+	}
+	if !user.IsAccountant || user.TenantID != caller.TenantID {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// checkPeriodsOpen returns store.ErrPeriodClosed when an event falls in a
+// closed period of the tenant. It takes no lock, the event store checks again
+// when writing.
+func checkPeriodsOpen(ctx context.Context, periods store.PeriodStore, tenantID int, events ...*domain.Event) error {
+	for _, event := range events {
+		period, err := periods.ClosedPeriodOverlapping(ctx, tenantID, event.StartDate, event.EndDate)
+		if err != nil {
+			return err
+		}
+		if period != nil {
+			return store.PeriodClosed(period)
+		}
+	}
+	return nil
+}
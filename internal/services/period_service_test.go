@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+	"testing"
+)
+
+type recordingPeriodStore struct {
+	store.PeriodStore
+	created *domain.AccountingPeriod
+}
+
+func (s *recordingPeriodStore) CreatePeriod(ctx context.Context, period *domain.AccountingPeriod, userID int) error {
+	s.created = period
+	return nil
+}
+
+func TestCreatePeriod(t *testing.T) {
+	users := &hierarchyStore{users: map[int]domain.User{
+		1: {ID: 1, TenantID: 1},
+		2: {ID: 2, TenantID: 1, IsAccountant: true},
+		3: {ID: 3, TenantID: 1, IsAdmin: true},
+	}}
+	january := domain.AccountingPeriod{StartDate: "2025-01-01", EndDate: "2025-01-31"}
+
+	tests := []struct {
+		name    string
+		caller  domain.User
+		period  domain.AccountingPeriod
+		wantErr error
+	}{
+		{"accountant", domain.User{ID: 2, TenantID: 1}, january, nil},
+		{"admin", domain.User{ID: 3, TenantID: 1, IsAdmin: true}, january, nil},
+		{"single day", domain.User{ID: 2, TenantID: 1}, domain.AccountingPeriod{StartDate: "2025-01-01", EndDate: "2025-01-01"}, nil},
+		{"user", domain.User{ID: 1, TenantID: 1}, january, ErrForbidden},
+		{"accountant of another tenant", domain.User{ID: 2, TenantID: 2}, january, ErrForbidden},
+		{"end before start", domain.User{ID: 2, TenantID: 1}, domain.AccountingPeriod{StartDate: "2025-01-31", EndDate: "2025-01-01"}, ErrInvalidPeriod},
+		{"timestamp", domain.User{ID: 2, TenantID: 1}, domain.AccountingPeriod{StartDate: "2025-01-01T00:00:00Z", EndDate: "2025-01-31"}, ErrInvalidPeriod},
+		{"missing end", domain.User{ID: 2, TenantID: 1}, domain.AccountingPeriod{StartDate: "2025-01-01"}, ErrInvalidPeriod},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			periods := &recordingPeriodStore{}
+			s := NewPeriodService(periods, users)
+			period := tt.period
+			err := s.CreatePeriod(t.Context(), &tt.caller, &period)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreatePeriod() = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && periods.created.TenantID != tt.caller.TenantID {
+				t.Errorf("tenant = %d, want the caller's %d", periods.created.TenantID, tt.caller.TenantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+)
+
+const earthRadiusKm = 6371.0
+
+var (
+	ErrInvalidTrip = domain.NewValidationError("invalid_trip", "trip needs both origin and destination coordinates within range, or a non-negative distance")
+	ErrInvalidRate = domain.NewValidationError("invalid_rate", "mileage rate needs a non-negative rate_per_km and an effective_from date")
+	ErrOdometer    = domain.NewValidationError("invalid_odometer", "odometer readings must not go below the vehicle's last known reading and must end after they start")
+	ErrVehicleUse  = domain.NewValidationError("vehicle_not_usable", "vehicle is inactive or belongs to another employee")
+)
+
+// greatCircleKm returns the haversine distance between two coordinates in km
+func greatCircleKm(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// tripDistanceKm returns the distance to price the event with. An explicit
+// distance wins over the great-circle distance between the coordinates.
+// ok is false when the event carries no trip information at all.
+func tripDistanceKm(event *domain.Event) (distance float64, ok bool, err error) {
+	if event.DistanceKm != nil {
+		if *event.DistanceKm < 0 {
+			return 0, false, ErrInvalidTrip
+		}
+		return *event.DistanceKm, true, nil
+	}
+
+	coords := []*float64{event.OriginLat, event.OriginLng, event.DestinationLat, event.DestinationLng}
+	present := 0
+	for _, c := range coords {
+		if c != nil {
+			present++
+		}
+	}
+	if present == 0 {
+		return 0, false, nil
+	}
+	if present != len(coords) ||
+		math.Abs(*event.OriginLat) > 90 || math.Abs(*event.DestinationLat) > 90 ||
+		math.Abs(*event.OriginLng) > 180 || math.Abs(*event.DestinationLng) > 180 {
+		return 0, false, ErrInvalidTrip
+	}
+	return greatCircleKm(*event.OriginLat, *event.OriginLng, *event.DestinationLat, *event.DestinationLng), true, nil
+}
+
+// priceEvent derives RoadPrice from the trip distance and the mileage rate in
+// force on the event's start date. Events without trip information, of a type
+// that is not pricable, or of a tenant without rates keep the typed price.
+func (s *EventService) priceEvent(ctx context.Context, event *domain.Event, tenantID int) error {
+	if err := s.resolveLocations(ctx, event, tenantID); err != nil {
+		return err
+	}
+	vehicle, err := s.resolveVehicle(ctx, event, tenantID)
+	if err != nil {
+		return err
+	}
+
+	distance, ok, err := tripDistanceKm(event)
+	if err != nil || !ok {
+		return err
+	}
+	distance = roundCents(distance)
+	event.DistanceKm = &distance
+
+	eventType, err := s.store.GetEventType(ctx, tenantID, event.TypeID)
+	if err != nil {
+		return err
+	}
+	if !eventType.IsPricable {
+		return nil
+	}
+
+	if vehicle != nil && vehicle.RatePerKm != nil {
+		event.RoadPrice = roundCents(distance * *vehicle.RatePerKm)
+		return nil
+	}
+
+	rate, err := s.rates.GetEffectiveRate(ctx, tenantID, event.TypeID, event.StartDate)
+	if errors.Is(err, store.ErrRateNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	event.RoadPrice = roundCents(distance * rate.RatePerKm)
+	return nil
+}
+
+// resolveLocations checks that the referenced saved locations belong to the
+// tenant, fills in missing coordinates from them and applies a configured
+// route distance between the two when the event has no explicit distance.
+func (s *EventService) resolveLocations(ctx context.Context, event *domain.Event, tenantID int) error {
+	if event.OriginLocationID != nil {
+		origin, err := s.locations.GetLocation(ctx, tenantID, *event.OriginLocationID)
+		if err != nil {
+			return err
+		}
+		event.OriginLocation = origin
+		if event.OriginLat == nil && event.OriginLng == nil {
+			event.OriginLat, event.OriginLng = origin.Latitude, origin.Longitude
+		}
+	}
+	if event.DestinationLocationID != nil {
+		destination, err := s.locations.GetLocation(ctx, tenantID, *event.DestinationLocationID)
+		if err != nil {
+			return err
+		}
+		event.DestinationLocation = destination
+		if event.DestinationLat == nil && event.DestinationLng == nil {
+			event.DestinationLat, event.DestinationLng = destination.Latitude, destination.Longitude
+		}
+	}
+
+	if event.OriginLocationID != nil && event.DestinationLocationID != nil && event.DistanceKm == nil {
+		distance, err := s.locations.GetRouteDistance(ctx, *event.OriginLocationID, *event.DestinationLocationID)
+		if errors.Is(err, store.ErrRouteNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		event.DistanceKm = &distance
+	}
+	return nil
+}
+
+// resolveVehicle checks that the event's vehicle may be used by the event owner
+// and that the odometer readings continue from the vehicle's last known reading.
+// Odometer readings give the trip distance when no explicit distance is set.
+func (s *EventService) resolveVehicle(ctx context.Context, event *domain.Event, tenantID int) (*domain.Vehicle, error) {
+	if event.VehicleID == nil {
+		if event.OdometerStart != nil || event.OdometerEnd != nil {
+			return nil, ErrOdometer
+		}
+		return nil, nil
+	}
+
+	vehicle, err := s.vehicles.GetVehicle(ctx, tenantID, *event.VehicleID)
+	if err != nil {
+		return nil, err
+	}
+	if !vehicle.IsActive || (vehicle.OwnerUserID != nil && *vehicle.OwnerUserID != event.UserID) {
+		return nil, ErrVehicleUse
+	}
+
+	if event.OdometerStart != nil {
+		last, err := s.vehicles.LastOdometerBefore(ctx, vehicle.ID, event.StartDate, event.ID)
+		if err != nil {
+			return nil, err
+		}
+		if *event.OdometerStart < last {
+			return nil, ErrOdometer
+		}
+	}
+	if event.OdometerStart != nil && event.OdometerEnd != nil {
+		if *event.OdometerEnd < *event.OdometerStart {
+			return nil, ErrOdometer
+		}
+		if event.DistanceKm == nil {
+			distance := float64(*event.OdometerEnd - *event.OdometerStart)
+			event.DistanceKm = &distance
+		}
+	}
+	return vehicle, nil
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"pwp-remastered/internal/domain"
+)
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+func TestGreatCircleKm(t *testing.T) {
+	// Ankara Kızılay to Istanbul Taksim is roughly 350 km as the crow flies
+	got := greatCircleKm(39.9208, 32.8541, 41.0370, 28.9850)
+	if math.Abs(got-351) > 5 {
+		t.Fatalf("expected about 351 km, got %.1f", got)
+	}
+
+	if got := greatCircleKm(39.9, 32.8, 39.9, 32.8); got != 0 {
+		t.Fatalf("expected 0 km for identical points, got %f", got)
+	}
+}
+
+func TestTripDistanceKm(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   domain.Event
+		want    float64
+		ok      bool
+		wantErr bool
+	}{
+		{name: "no trip information", event: domain.Event{}},
+		{name: "explicit distance", event: domain.Event{DistanceKm: ptr(42.5)}, want: 42.5, ok: true},
+		{name: "explicit distance wins over coordinates", event: domain.Event{
+			DistanceKm: ptr(10), OriginLat: ptr(39.9), OriginLng: ptr(32.8), DestinationLat: ptr(41.0), DestinationLng: ptr(28.9),
+		}, want: 10, ok: true},
+		{name: "negative distance", event: domain.Event{DistanceKm: ptr(-1)}, wantErr: true},
+		{name: "partial coordinates", event: domain.Event{OriginLat: ptr(39.9), OriginLng: ptr(32.8)}, wantErr: true},
+		{name: "latitude out of range", event: domain.Event{
+			OriginLat: ptr(91), OriginLng: ptr(32.8), DestinationLat: ptr(41.0), DestinationLng: ptr(28.9),
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := tripDistanceKm(&tt.event)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if ok != tt.ok || got != tt.want {
+				t.Fatalf("expected (%v, %v), got (%v, %v)", tt.want, tt.ok, got, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,513 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/scim"
+	"pwp-remastered/internal/store"
+	"strconv"
+	"strings"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+var (
+	ErrScimUnauthorized = domain.NewUnauthorizedError("invalid_scim_token", "SCIM token is missing or unknown")
+	ErrInvalidScimToken = domain.NewValidationError("invalid_scim_token_name", "SCIM token needs a name")
+)
+
+// ScimService provisions the users and teams of a tenant for SCIM clients.
+// SCIM users are users, groups are teams. The client is identified by a
+// tenant-scoped token, it cannot change the admin and accountant flags.
+type ScimService struct {
+	tokens      store.ScimTokenStore
+	users       store.UserStore
+	teams       store.TeamStore
+	userService *UserService
+}
+
+// NewScimService creates a new SCIM service
+func NewScimService(tokenStore store.ScimTokenStore, userStore store.UserStore, teamStore store.TeamStore) *ScimService {
+	return &ScimService{
+		tokens:      tokenStore,
+		users:       userStore,
+		teams:       teamStore,
+		userService: NewUserService(userStore),
+	}
+}
+
+// ListTokens lists the SCIM tokens of the caller's tenant, admins only
+func (s *ScimService) ListTokens(ctx context.Context, caller *domain.User) ([]domain.ScimToken, error) {
+	ctx, span := tracer.Start(ctx, "ScimService.ListTokens")
+	defer span.End()
+	if !caller.IsAdmin {
+		return nil, ErrForbidden
+	}
+	return s.tokens.ListTokens(ctx, caller.TenantID)
+}
+
+// CreateToken issues a SCIM token for the caller's tenant, admins only. The
+// token value is only available on the returned token.
+func (s *ScimService) CreateToken(ctx context.Context, caller *domain.User, token *domain.ScimToken) error {
+	ctx, span := tracer.Start(ctx, "ScimService.CreateToken")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	token.Name = strings.TrimSpace(token.Name)
+	if token.Name == "" {
+		return ErrInvalidScimToken
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	value := "scim_" + hex.EncodeToString(buf)
+	token.TenantID = caller.TenantID
+	if err := s.tokens.CreateToken(ctx, token, hashScimToken(value)); err != nil {
+		return err
+	}
+	token.Token = value
+	return nil
+}
+
+// DeleteToken revokes a SCIM token of the caller's tenant, admins only
+func (s *ScimService) DeleteToken(ctx context.Context, caller *domain.User, id int) error {
+	ctx, span := tracer.Start(ctx, "ScimService.DeleteToken")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	return s.tokens.DeleteToken(ctx, caller.TenantID, id)
+}
+
+// Authenticate returns the tenant of a SCIM token
+func (s *ScimService) Authenticate(ctx context.Context, value string) (int, error) {
+	ctx, span := tracer.Start(ctx, "ScimService.Authenticate")
+	defer span.End()
+	if value == "" {
+		return 0, ErrScimUnauthorized
+	}
+	token, err := s.tokens.UseToken(ctx, hashScimToken(value))
+	if errors.Is(err, store.ErrScimTokenNotFound) {
+		return 0, ErrScimUnauthorized
+	}
+	if err != nil {
+		return 0, err
+	}
+	return token.TenantID, nil
+}
+
+func hashScimToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// scimCaller stands in for the SCIM client in store calls that take a caller.
+// It is not an admin, so the store keeps the flags SCIM does not manage.
+func scimCaller(tenantID int) *domain.User {
+	return &domain.User{TenantID: tenantID}
+}
+
+// ListUsers returns the users of the tenant matching filter, startIndex is
+// 1-based. Filters may compare id, userName, emails and active.
+func (s *ScimService) ListUsers(ctx context.Context, tenantID int, filter string, startIndex int, count int) (*scim.ListResponse, error) {
+	ctx, span := tracer.Start(ctx, "ScimService.ListUsers")
+	defer span.End()
+	comparisons, err := scim.ParseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	userFilter := domain.UserFilter{
+		PageRequest: domain.PageRequest{Sort: "id", Limit: min(max(count, 1), scim.MaxResults), WithTotal: true},
+		TenantID:    tenantID,
+		Offset:      startIndex - 1,
+	}
+	for _, c := range comparisons {
+		switch c.Attribute {
+		case "id":
+			id, err := strconv.Atoi(c.Value)
+			if err != nil {
+				id = 0
+			}
+			userFilter.IDs = append(userFilter.IDs, id)
+		case "username":
+			userFilter.Username = c.Value
+		case "emails", "emails.value":
+			userFilter.Email = c.Value
+		case "active":
+			status := 0
+			if c.Value == "true" {
+				status = 1
+			}
+			userFilter.Statuses = []int{status}
+		default:
+			return nil, fmt.Errorf("%w: %s cannot be filtered", scim.ErrInvalidFilter, c.Attribute)
+		}
+	}
+
+	users, info, err := s.users.ListUsers(ctx, userFilter)
+	if err != nil {
+		return nil, err
+	}
+	resources := []scim.User{}
+	if count > 0 {
+		for i := range users {
+			resources = append(resources, *toScimUser(&users[i]))
+		}
+	}
+	return listResponse(*info.Total, startIndex, resources, len(resources)), nil
+}
+
+// GetUser returns a user of the tenant
+func (s *ScimService) GetUser(ctx context.Context, tenantID int, id int) (*scim.User, error) {
+	ctx, span := tracer.Start(ctx, "ScimService.GetUser")
+	defer span.End()
+	user, err := s.tenantUser(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toScimUser(user), nil
+}
+
+// CreateUser adds an active user to the tenant unless in is inactive
+func (s *ScimService) CreateUser(ctx context.Context, tenantID int, in *scim.User) (*scim.User, error) {
+	ctx, span := tracer.Start(ctx, "ScimService.CreateUser")
+	defer span.End()
+	user := domain.User{TenantID: tenantID, IsUser: true, Status: 1}
+	if err := applyScimUser(&user, in); err != nil {
+		return nil, err
+	}
+	if err := s.userService.CreateUser(ctx, &user); err != nil {
+		return nil, err
+	}
+	return toScimUser(&user), nil
+}
+
+// ReplaceUser replaces the attributes of a user SCIM manages. The user store
+// announces a deactivation to the webhooks in the same transaction.
+func (s *ScimService) ReplaceUser(ctx context.Context, tenantID int, id int, in *scim.User) (*scim.User, error) {
+	ctx, span := tracer.Start(ctx, "ScimService.ReplaceUser")
+	defer span.End()
+	existing, err := s.tenantUser(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.replaceUser(ctx, existing, in)
+}
+
+// PatchUser applies PATCH operations to a user
+func (s *ScimService) PatchUser(ctx context.Context, tenantID int, id int, ops []scim.PatchOperation) (*scim.User, error) {
+	ctx, span := tracer.Start(ctx, "ScimService.PatchUser")
+	defer span.End()
+	existing, err := s.tenantUser(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	in := toScimUser(existing)
+	if err := scim.ApplyUserPatch(in, ops); err != nil {
+		return nil, err
+	}
+	return s.replaceUser(ctx, existing, in)
+}
+
+// DeactivateUser answers a DELETE. Users own events and stay in the tenant's
+// history, so they are deactivated rather than deleted.
+func (s *ScimService) DeactivateUser(ctx context.Context, tenantID int, id int) error {
+	ctx, span := tracer.Start(ctx, "ScimService.DeactivateUser")
+	defer span.End()
+	user, err := s.tenantUser(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	return s.users.SetUserStatus(ctx, scimCaller(tenantID), user.ID, 0)
+}
+
+func (s *ScimService) replaceUser(ctx context.Context, existing *domain.User, in *scim.User) (*scim.User, error) {
+	user := *existing
+	if err := applyScimUser(&user, in); err != nil {
+		return nil, err
+	}
+	if err := s.userService.validateManager(ctx, &user); err != nil {
+		return nil, err
+	}
+
+	if err := s.users.UpdateUser(ctx, scimCaller(user.TenantID), &user); err != nil {
+		return nil, err
+	}
+	return toScimUser(&user), nil
+}
+
+func (s *ScimService) tenantUser(ctx context.Context, tenantID int, id int) (*domain.User, error) {
+	user, err := s.users.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.TenantID != tenantID {
+		return nil, store.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// applyScimUser copies the attributes of in onto user. A missing manager
+// extension keeps the manager, an extension without a manager clears it.
+func applyScimUser(user *domain.User, in *scim.User) error {
+	user.Username = strings.TrimSpace(in.UserName)
+	if user.Username == "" {
+		return fmt.Errorf("%w: userName is required", scim.ErrInvalidValue)
+	}
+	user.Email = strings.TrimSpace(in.PrimaryEmail())
+	if user.Email == "" {
+		return fmt.Errorf("%w: an email is required", scim.ErrInvalidValue)
+	}
+	user.FirstName, user.LastName = "", ""
+	if in.Name != nil {
+		user.FirstName, user.LastName = in.Name.GivenName, in.Name.FamilyName
+	}
+	if in.Active != nil {
+		user.Status = 0
+		if *in.Active {
+			user.Status = 1
+		}
+	}
+
+	if in.Enterprise != nil {
+		user.ManagerID = nil
+		if in.Enterprise.Manager != nil {
+			managerID, err := strconv.Atoi(in.Enterprise.Manager.Value)
+			if err != nil {
+				return ErrInvalidManager
+			}
+			user.ManagerID = &managerID
+		}
+	}
+
+	if in.Password != "" {
+		argon := argon2.DefaultConfig()
+		hashedPassword, err := argon.HashEncoded([]byte(in.Password))
+		if err != nil {
+			return err
+		}
+		user.HashedPassword = string(hashedPassword)
+	}
+	return nil
+}
+
+func toScimUser(user *domain.User) *scim.User {
+	active := user.Status == 1
+	out := &scim.User{
+		Schemas:  []string{scim.SchemaUser, scim.SchemaEnterpriseUser},
+		ID:       strconv.Itoa(user.ID),
+		UserName: user.Username,
+		Name: &scim.Name{
+			GivenName:  user.FirstName,
+			FamilyName: user.LastName,
+			Formatted:  strings.TrimSpace(user.FirstName + " " + user.LastName),
+		},
+		Active: &active,
+		Meta:   &scim.Meta{ResourceType: "User"},
+	}
+	if user.Email != "" {
+		out.Emails = []scim.Email{{Value: user.Email, Type: "work", Primary: true}}
+	}
+	if user.ManagerID != nil {
+		out.Enterprise = &scim.EnterpriseUser{Manager: &scim.Reference{Value: strconv.Itoa(*user.ManagerID)}}
+	}
+	return out
+}
+
+// ListGroups returns the teams of the tenant matching filter, startIndex is
+// 1-based. Filters may compare id and displayName.
+func (s *ScimService) ListGroups(ctx context.Context, tenantID int, filter string, startIndex int, count int) (*scim.ListResponse, error) {
+	ctx, span := tracer.Start(ctx, "ScimService.ListGroups")
+	defer span.End()
+	comparisons, err := scim.ParseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	teams, err := s.teams.ListTeams(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := []domain.Team{}
+	for _, team := range teams {
+		matches := true
+		for _, c := range comparisons {
+			switch c.Attribute {
+			case "id":
+				matches = matches && strconv.Itoa(team.ID) == c.Value
+			case "displayname":
+				matches = matches && strings.EqualFold(team.Name, c.Value)
+			default:
+				return nil, fmt.Errorf("%w: %s cannot be filtered", scim.ErrInvalidFilter, c.Attribute)
+			}
+		}
+		if matches {
+			matching = append(matching, team)
+		}
+	}
+
+	resources := []scim.Group{}
+	from := min(startIndex-1, len(matching))
+	for _, team := range matching[from:min(from+min(count, scim.MaxResults), len(matching))] {
+		group, err := s.toScimGroup(ctx, &team)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, *group)
+	}
+	return listResponse(len(matching), startIndex, resources, len(resources)), nil
+}
+
+// GetGroup returns a team of the tenant with its members
+func (s *ScimService) GetGroup(ctx context.Context, tenantID int, id int) (*scim.Group, error) {
+	ctx, span := tracer.Start(ctx, "ScimService.GetGroup")
+	defer span.End()
+	team, err := s.teams.GetTeam(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toScimGroup(ctx, team)
+}
+
+// CreateGroup adds a team with the given members to the tenant
+func (s *ScimService) CreateGroup(ctx context.Context, tenantID int, in *scim.Group) (*scim.Group, error) {
+	ctx, span := tracer.Start(ctx, "ScimService.CreateGroup")
+	defer span.End()
+	team := domain.Team{TenantID: tenantID, Name: in.DisplayName}
+	if err := validateTeam(&team); err != nil {
+		return nil, err
+	}
+	if err := s.teams.CreateTeam(ctx, &team); err != nil {
+		return nil, err
+	}
+	if err := s.syncMembers(ctx, &team, in.Members); err != nil {
+		return nil, err
+	}
+	return s.toScimGroup(ctx, &team)
+}
+
+// ReplaceGroup renames a team and replaces its members
+func (s *ScimService) ReplaceGroup(ctx context.Context, tenantID int, id int, in *scim.Group) (*scim.Group, error) {
+	ctx, span := tracer.Start(ctx, "ScimService.ReplaceGroup")
+	defer span.End()
+	team, err := s.teams.GetTeam(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.replaceGroup(ctx, team, in)
+}
+
+// PatchGroup applies PATCH operations to a team, typically member changes
+func (s *ScimService) PatchGroup(ctx context.Context, tenantID int, id int, ops []scim.PatchOperation) (*scim.Group, error) {
+	ctx, span := tracer.Start(ctx, "ScimService.PatchGroup")
+	defer span.End()
+	team, err := s.teams.GetTeam(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	in, err := s.toScimGroup(ctx, team)
+	if err != nil {
+		return nil, err
+	}
+	if err := scim.ApplyGroupPatch(in, ops); err != nil {
+		return nil, err
+	}
+	return s.replaceGroup(ctx, team, in)
+}
+
+// DeleteGroup removes a team, its members stay users of the tenant
+func (s *ScimService) DeleteGroup(ctx context.Context, tenantID int, id int) error {
+	ctx, span := tracer.Start(ctx, "ScimService.DeleteGroup")
+	defer span.End()
+	return s.teams.DeleteTeam(ctx, tenantID, id)
+}
+
+func (s *ScimService) replaceGroup(ctx context.Context, team *domain.Team, in *scim.Group) (*scim.Group, error) {
+	if in.DisplayName != team.Name {
+		team.Name = in.DisplayName
+		if err := validateTeam(team); err != nil {
+			return nil, err
+		}
+		if err := s.teams.UpdateTeam(ctx, team); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.syncMembers(ctx, team, in.Members); err != nil {
+		return nil, err
+	}
+	return s.toScimGroup(ctx, team)
+}
+
+// syncMembers makes members the members of team, they must be users of the team's tenant
+func (s *ScimService) syncMembers(ctx context.Context, team *domain.Team, members []scim.Reference) error {
+	want := map[int]bool{}
+	for _, member := range members {
+		notMember := fmt.Errorf("%w: member %q is not a user of the tenant", scim.ErrInvalidValue, member.Value)
+		id, err := strconv.Atoi(member.Value)
+		if err != nil {
+			return notMember
+		}
+		if _, err := s.tenantUser(ctx, team.TenantID, id); errors.Is(err, store.ErrUserNotFound) {
+			return notMember
+		} else if err != nil {
+			return err
+		}
+		want[id] = true
+	}
+
+	current, err := s.teams.ListMembers(ctx, team.ID)
+	if err != nil {
+		return err
+	}
+	for _, member := range current {
+		if want[member.UserID] {
+			delete(want, member.UserID)
+			continue
+		}
+		if err := s.teams.RemoveMember(ctx, team.ID, member.UserID); err != nil {
+			return err
+		}
+	}
+	for userID := range want {
+		if err := s.teams.AddMember(ctx, team.ID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ScimService) toScimGroup(ctx context.Context, team *domain.Team) (*scim.Group, error) {
+	members, err := s.teams.ListMembers(ctx, team.ID)
+	if err != nil {
+		return nil, err
+	}
+	group := &scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          strconv.Itoa(team.ID),
+		DisplayName: team.Name,
+		Members:     []scim.Reference{},
+		Meta:        &scim.Meta{ResourceType: "Group", Created: &team.CreatedAt, LastModified: &team.UpdatedAt},
+	}
+	for _, member := range members {
+		group.Members = append(group.Members, scim.Reference{Value: strconv.Itoa(member.UserID), Display: member.Username})
+	}
+	return group, nil
+}
+
+func listResponse(total int, startIndex int, resources interface{}, n int) *scim.ListResponse {
+	return &scim.ListResponse{
+		Schemas:      []string{scim.SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: n,
+		Resources:    resources,
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/scim"
+	"pwp-remastered/internal/store"
+	"testing"
+)
+
+// provisionedStore records the writes SCIM makes to a hierarchyStore
+type provisionedStore struct {
+	hierarchyStore
+	updated []domain.User
+	// statuses are the ids SetUserStatus was called with and their new status
+	statuses map[int]int
+}
+
+func (s *provisionedStore) UpdateUser(ctx context.Context, caller *domain.User, user *domain.User) error {
+	s.updated = append(s.updated, *user)
+	return nil
+}
+
+func (s *provisionedStore) SetUserStatus(ctx context.Context, caller *domain.User, id int, status int) error {
+	s.statuses[id] = status
+	return nil
+}
+
+func newProvisionedStore() *provisionedStore {
+	return &provisionedStore{hierarchyStore: hierarchyStore{users: map[int]domain.User{
+		1: {ID: 1, TenantID: 1, Username: "ayse", Email: "ayse@example.com", Status: 1},
+		2: {ID: 2, TenantID: 2, Username: "mehmet", Email: "mehmet@example.com", Status: 1},
+		3: {ID: 3, TenantID: 1, Username: "eski", Email: "eski@example.com", Status: 0},
+	}}, statuses: map[int]int{}}
+}
+
+func TestScimPatchUserDeactivates(t *testing.T) {
+	users := newProvisionedStore()
+	s := NewScimService(nil, users, nil)
+
+	ops := []scim.PatchOperation{{Op: "Replace", Value: json.RawMessage(`{"active": "False", "name.givenName": "Ayşe"}`)}}
+	user, err := s.PatchUser(t.Context(), 1, 1, ops)
+	if err != nil {
+		t.Fatalf("PatchUser() error = %v", err)
+	}
+	if user.Active == nil || *user.Active {
+		t.Errorf("PatchUser() active = %v, want false", user.Active)
+	}
+	// The name and the status are written together, in one transaction
+	if len(users.updated) != 1 || users.updated[0].Status != 0 || users.updated[0].FirstName != "Ayşe" {
+		t.Errorf("UpdateUser() got %+v, want the new name and status 0", users.updated)
+	}
+	if len(users.statuses) != 0 {
+		t.Errorf("SetUserStatus() got %v, want no calls", users.statuses)
+	}
+}
+
+func TestScimTenantIsolation(t *testing.T) {
+	users := newProvisionedStore()
+	s := NewScimService(nil, users, nil)
+
+	if _, err := s.GetUser(t.Context(), 1, 2); !errors.Is(err, store.ErrUserNotFound) {
+		t.Errorf("GetUser() of another tenant error = %v, want %v", err, store.ErrUserNotFound)
+	}
+	if err := s.DeactivateUser(t.Context(), 1, 2); !errors.Is(err, store.ErrUserNotFound) {
+		t.Errorf("DeactivateUser() of another tenant error = %v, want %v", err, store.ErrUserNotFound)
+	}
+	if len(users.statuses) != 0 {
+		t.Errorf("SetUserStatus() got %v, want no calls", users.statuses)
+	}
+}
+
+func TestScimDeactivateUser(t *testing.T) {
+	users := newProvisionedStore()
+	s := NewScimService(nil, users, nil)
+
+	for _, id := range []int{1, 3} {
+		if err := s.DeactivateUser(t.Context(), 1, id); err != nil {
+			t.Fatalf("DeactivateUser(%d) error = %v", id, err)
+		}
+	}
+	// 3 is already inactive and stays so, the status is set rather than toggled
+	if want := map[int]int{1: 0, 3: 0}; !maps.Equal(users.statuses, want) {
+		t.Errorf("SetUserStatus() got %v, want %v", users.statuses, want)
+	}
+}
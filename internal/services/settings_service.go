@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+	"time"
+)
+
+var ErrInvalidTimeZone = domain.NewValidationError("invalid_time_zone", "invalid time zone, use an IANA name such as Europe/Istanbul")
+
+// SettingsService handles tenant settings and the caller's time zone
+type SettingsService struct {
+	store store.SettingsStore
+}
+
+// NewSettingsService creates a new settings service
+func NewSettingsService(settingsStore store.SettingsStore) *SettingsService {
+	return &SettingsService{store: settingsStore}
+}
+
+// GetTenantSettings returns the settings of the caller's tenant
+func (s *SettingsService) GetTenantSettings(ctx context.Context, caller *domain.User) (*domain.TenantSettings, error) {
+	ctx, span := tracer.Start(ctx, "SettingsService.GetTenantSettings")
+	defer span.End()
+	return s.store.GetTenantSettings(ctx, caller.TenantID)
+}
+
+// UpdateTenantSettings replaces the settings of the caller's tenant, admins only
+func (s *SettingsService) UpdateTenantSettings(ctx context.Context, caller *domain.User, settings *domain.TenantSettings) error {
+	ctx, span := tracer.Start(ctx, "SettingsService.UpdateTenantSettings")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	if _, err := loadTimeZone(settings.TimeZone); err != nil {
+		return err
+	}
+	settings.TenantID = caller.TenantID
+	return s.store.UpdateTenantSettings(ctx, settings)
+}
+
+// TimeZone returns the location calendar days are interpreted in for caller
+func (s *SettingsService) TimeZone(ctx context.Context, caller *domain.User) (*time.Location, error) {
+	ctx, span := tracer.Start(ctx, "SettingsService.TimeZone")
+	defer span.End()
+	name, err := s.store.EffectiveTimeZone(ctx, caller.ID)
+	if err != nil {
+		return nil, err
+	}
+	return loadTimeZone(name)
+}
+
+// loadTimeZone accepts IANA names only, "Local" would depend on the server
+func loadTimeZone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, ErrInvalidTimeZone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimeZone
+	}
+	return loc, nil
+}
+
+// normalizeUserTimeZone clears an empty time zone and rejects unknown ones
+func normalizeUserTimeZone(user *domain.User) error {
+	if user.TimeZone == nil {
+		return nil
+	}
+	if *user.TimeZone == "" {
+		user.TimeZone = nil
+		return nil
+	}
+	_, err := loadTimeZone(*user.TimeZone)
+	return err
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+	"strings"
+)
+
+var ErrInvalidTeam = domain.NewValidationError("invalid_team", "team needs a name")
+
+// TeamService handles business logic for teams and their members
+type TeamService struct {
+	store store.TeamStore
+	users store.UserStore
+}
+
+// NewTeamService creates a new team service
+func NewTeamService(teamStore store.TeamStore, userStore store.UserStore) *TeamService {
+	return &TeamService{
+		store: teamStore,
+		users: userStore,
+	}
+}
+
+// ListTeams lists the teams of the caller's tenant
+func (s *TeamService) ListTeams(ctx context.Context, caller *domain.User) ([]domain.Team, error) {
+	ctx, span := tracer.Start(ctx, "TeamService.ListTeams")
+	defer span.End()
+	return s.store.ListTeams(ctx, caller.TenantID)
+}
+
+// GetTeam retrieves a team of the caller's tenant with its members
+func (s *TeamService) GetTeam(ctx context.Context, caller *domain.User, id int) (*domain.Team, error) {
+	ctx, span := tracer.Start(ctx, "TeamService.GetTeam")
+	defer span.End()
+	team, err := s.store.GetTeam(ctx, caller.TenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if team.Members, err = s.store.ListMembers(ctx, team.ID); err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// CreateTeam adds a team to the caller's tenant, admins only
+func (s *TeamService) CreateTeam(ctx context.Context, caller *domain.User, team *domain.Team) error {
+	ctx, span := tracer.Start(ctx, "TeamService.CreateTeam")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	if err := validateTeam(team); err != nil {
+		return err
+	}
+	team.TenantID = caller.TenantID
+	return s.store.CreateTeam(ctx, team)
+}
+
+// UpdateTeam renames a team of the caller's tenant, admins only
+func (s *TeamService) UpdateTeam(ctx context.Context, caller *domain.User, team *domain.Team) error {
+	ctx, span := tracer.Start(ctx, "TeamService.UpdateTeam")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	if err := validateTeam(team); err != nil {
+		return err
+	}
+	team.TenantID = caller.TenantID
+	return s.store.UpdateTeam(ctx, team)
+}
+
+// DeleteTeam removes a team and its memberships, admins only
+func (s *TeamService) DeleteTeam(ctx context.Context, caller *domain.User, id int) error {
+	ctx, span := tracer.Start(ctx, "TeamService.DeleteTeam")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	return s.store.DeleteTeam(ctx, caller.TenantID, id)
+}
+
+// AddMember adds a user of the caller's tenant to a team, admins only
+func (s *TeamService) AddMember(ctx context.Context, caller *domain.User, teamID int, userID int) error {
+	ctx, span := tracer.Start(ctx, "TeamService.AddMember")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	if _, err := s.store.GetTeam(ctx, caller.TenantID, teamID); err != nil {
+		return err
+	}
+	user, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.TenantID != caller.TenantID {
+		return store.ErrUserNotFound
+	}
+	return s.store.AddMember(ctx, teamID, userID)
+}
+
+// RemoveMember removes a user from a team, admins only
+func (s *TeamService) RemoveMember(ctx context.Context, caller *domain.User, teamID int, userID int) error {
+	ctx, span := tracer.Start(ctx, "TeamService.RemoveMember")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	if _, err := s.store.GetTeam(ctx, caller.TenantID, teamID); err != nil {
+		return err
+	}
+	return s.store.RemoveMember(ctx, teamID, userID)
+}
+
+func validateTeam(team *domain.Team) error {
+	team.Name = strings.TrimSpace(team.Name)
+	if team.Name == "" {
+		return ErrInvalidTeam
+	}
+	return nil
+}
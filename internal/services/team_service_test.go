@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+	"slices"
+	"testing"
+)
+
+// memberStore keeps teams of several tenants and their members in memory
+type memberStore struct {
+	store.TeamStore
+	teams   map[int]domain.Team
+	members map[int][]int
+}
+
+func (s *memberStore) GetTeam(ctx context.Context, tenantID int, id int) (*domain.Team, error) {
+	team, ok := s.teams[id]
+	if !ok || team.TenantID != tenantID {
+		return nil, store.ErrTeamNotFound
+	}
+	return &team, nil
+}
+
+func (s *memberStore) ListMembers(ctx context.Context, teamID int) ([]domain.TeamMember, error) {
+	members := []domain.TeamMember{}
+	for _, userID := range s.members[teamID] {
+		members = append(members, domain.TeamMember{UserID: userID})
+	}
+	return members, nil
+}
+
+func (s *memberStore) AddMember(ctx context.Context, teamID int, userID int) error {
+	if !slices.Contains(s.members[teamID], userID) {
+		s.members[teamID] = append(s.members[teamID], userID)
+	}
+	return nil
+}
+
+func (s *memberStore) RemoveMember(ctx context.Context, teamID int, userID int) error {
+	i := slices.Index(s.members[teamID], userID)
+	if i < 0 {
+		return store.ErrTeamMemberNotFound
+	}
+	s.members[teamID] = slices.Delete(s.members[teamID], i, i+1)
+	return nil
+}
+
+func TestTeamMembership(t *testing.T) {
+	admin := domain.User{ID: 1, TenantID: 1, IsAdmin: true}
+	otherAdmin := domain.User{ID: 5, TenantID: 2, IsAdmin: true}
+	users := &hierarchyStore{users: map[int]domain.User{
+		1: admin,
+		2: {ID: 2, TenantID: 1},
+		3: {ID: 3, TenantID: 1},
+		5: otherAdmin,
+		6: {ID: 6, TenantID: 2},
+	}}
+
+	tests := []struct {
+		name    string
+		caller  domain.User
+		add     bool
+		teamID  int
+		userID  int
+		wantErr error
+		want    []int
+	}{
+		{"admin adds user", admin, true, 10, 3, nil, []int{2, 3}},
+		{"adding twice keeps one membership", admin, true, 10, 2, nil, []int{2}},
+		{"user cannot add", users.users[2], true, 10, 3, ErrForbidden, []int{2}},
+		{"other tenant's user", admin, true, 10, 6, store.ErrUserNotFound, []int{2}},
+		{"missing user", admin, true, 10, 404, store.ErrUserNotFound, []int{2}},
+		{"other tenant's team", otherAdmin, true, 10, 6, store.ErrTeamNotFound, []int{2}},
+		{"admin removes member", admin, false, 10, 2, nil, nil},
+		{"remove non-member", admin, false, 10, 3, store.ErrTeamMemberNotFound, []int{2}},
+		{"other tenant's admin cannot remove", otherAdmin, false, 10, 2, store.ErrTeamNotFound, []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			teams := &memberStore{
+				teams: map[int]domain.Team{
+					10: {ID: 10, TenantID: 1, Name: "Satış"},
+					20: {ID: 20, TenantID: 2, Name: "Satış"},
+				},
+				members: map[int][]int{10: {2}},
+			}
+			service := NewTeamService(teams, users)
+
+			var err error
+			if tt.add {
+				err = service.AddMember(context.Background(), &tt.caller, tt.teamID, tt.userID)
+			} else {
+				err = service.RemoveMember(context.Background(), &tt.caller, tt.teamID, tt.userID)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			team, err := service.GetTeam(context.Background(), &admin, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for _, member := range team.Members {
+				got = append(got, member.UserID)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("members = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
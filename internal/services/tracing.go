@@ -0,0 +1,11 @@
+package services
+
+import (
+	"pwp-remastered/internal/metrics"
+
+	"go.opentelemetry.io/otel"
+)
+
+// tracer starts the span of every exported service method, the statements run
+// within it are timed by the span's name
+var tracer = metrics.Tracer(otel.Tracer("pwp-remastered/internal/services"))
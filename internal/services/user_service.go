@@ -1,14 +1,15 @@
 package services
 
 import (
-	"errors"
-	"fmt"
+	"context"
 	"pwp-remastered/internal/domain"
 	"pwp-remastered/internal/store"
 
 	"github.com/matthewhartstonge/argon2"
 )
 
+var ErrInvalidManager = domain.NewValidationError("invalid_manager", "manager must be another user of the same tenant who does not report to the user")
+
 // UserService handles business logic for users
 type UserService struct {
 	store store.UserStore
@@ -22,97 +23,183 @@ func NewUserService(userStore store.UserStore) *UserService {
 }
 
 // GetUser retrieves a user by ID
-func (s *UserService) GetUser(id int) (*domain.User, error) {
-	return s.store.GetUser(id)
+func (s *UserService) GetUser(ctx context.Context, id int) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUser")
+	defer span.End()
+	return s.store.GetUser(ctx, id)
 }
 
-func (s *UserService) GetUserMe(caller *domain.User) (*domain.User, error) {
-	return s.store.GetUser(caller.ID)
+func (s *UserService) GetUserMe(ctx context.Context, caller *domain.User) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUserMe")
+	defer span.End()
+	return s.store.GetUser(ctx, caller.ID)
 }
 
-// GetUserByUsername retrieves a user by username
-func (s *UserService) GetUserByUsername(username string) (*domain.User, error) {
-	return s.store.GetUserByUsername(username)
+// GetUserByUsername retrieves a user of the tenant by username
+func (s *UserService) GetUserByUsername(ctx context.Context, tenantID int, username string) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUserByUsername")
+	defer span.End()
+	return s.store.GetUserByUsername(ctx, tenantID, username)
 }
 
 // CreateUser creates a new user
-func (s *UserService) CreateUser(user *domain.User) error {
-	return s.store.CreateUser(user)
+func (s *UserService) CreateUser(ctx context.Context, user *domain.User) error {
+	ctx, span := tracer.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+	if err := normalizeUserTimeZone(user); err != nil {
+		return err
+	}
+	if err := s.validateManager(ctx, user); err != nil {
+		return err
+	}
+	return s.store.CreateUser(ctx, user)
 }
 
 // UpdateUser updates an existing user
-func (s *UserService) UpdateUser(caller *domain.User, user *domain.User) error {
+func (s *UserService) UpdateUser(ctx context.Context, caller *domain.User, user *domain.User) error {
+	ctx, span := tracer.Start(ctx, "UserService.UpdateUser")
+	defer span.End()
 	argon := argon2.DefaultConfig()
 
-	// fmt.Println("caller:", caller)
-	// if caller.IsAdmin == false || caller.ID == user.ID {
-	// 	return errors.New("Unauthorized")
-	// }
+	if err := normalizeUserTimeZone(user); err != nil {
+		return err
+	}
 
-	existingUser, err := s.store.GetUser(user.ID)
-	if err != nil {
-		fmt.Println("Error retrieving user:", err)
+	if _, err := s.store.GetUser(ctx, user.ID); err != nil {
 		return err
 	}
-	if existingUser == nil {
-		fmt.Println("User not found")
+	if err := s.validateManager(ctx, user); err != nil {
 		return err
-	} else {
-		hashedPassword, err := argon.HashEncoded([]byte(user.HashedPassword))
-		if err != nil {
-			return err
-		}
-		user.HashedPassword = string(hashedPassword)
-		// Check if the user exists
-
-		return s.store.UpdateUser(caller, user)
 	}
+	hashedPassword, err := argon.HashEncoded([]byte(user.HashedPassword))
+	if err != nil {
+		return err
+	}
+	user.HashedPassword = string(hashedPassword)
+	return s.store.UpdateUser(ctx, caller, user)
+}
 
+// validateManager checks that the user's manager, if any, is someone else in
+// the same tenant and that the hierarchy stays free of cycles
+func (s *UserService) validateManager(ctx context.Context, user *domain.User) error {
+	if user.ManagerID == nil {
+		return nil
+	}
+	if *user.ManagerID == user.ID {
+		return ErrInvalidManager
+	}
+	manager, err := s.store.GetUser(ctx, *user.ManagerID)
+	if err == store.ErrUserNotFound {
+		return ErrInvalidManager
+	}
+	if err != nil {
+		return err
+	}
+	if manager.TenantID != user.TenantID {
+		return ErrInvalidManager
+	}
+	if user.ID == 0 {
+		return nil
+	}
+	reportsToUser, err := s.store.IsManagerOf(ctx, user.ID, manager.ID)
+	if err != nil {
+		return err
+	}
+	if reportsToUser {
+		return ErrInvalidManager
+	}
+	return nil
 }
 
 // DeleteUser removes a user by ID
-func (s *UserService) DeleteUser(id int) error {
-	return s.store.DeleteUser(id)
+func (s *UserService) DeleteUser(ctx context.Context, id int) error {
+	ctx, span := tracer.Start(ctx, "UserService.DeleteUser")
+	defer span.End()
+	return s.store.DeleteUser(ctx, id)
 }
 
-// ListUsers retrieves all users
-func (s *UserService) ListUsers(caller *domain.User) ([]domain.User, error) {
+// ListUsers retrieves a page of the active users of the caller's tenant,
+// non-admins only see themselves
+func (s *UserService) ListUsers(ctx context.Context, caller *domain.User, filter domain.UserFilter) (*domain.UserList, error) {
+	ctx, span := tracer.Start(ctx, "UserService.ListUsers")
+	defer span.End()
 	if !caller.IsAdmin {
-		selfUser, err := s.store.GetUser(caller.ID)
+		selfUser, err := s.store.GetUser(ctx, caller.ID)
 		if err != nil {
 			return nil, err
 		}
-		return []domain.User{*selfUser}, nil
+		return &domain.UserList{Users: []domain.User{*selfUser}}, nil
+	}
+	filter.TenantID = caller.TenantID
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = []int{1}
+	}
+	users, info, err := s.store.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
-	return s.store.ListUsers()
+	return &domain.UserList{Users: users, PageInfo: *info}, nil
 }
 
-func (s *UserService) ChangeUserStatus(caller *domain.User, id int) error {
+func (s *UserService) ChangeUserStatus(ctx context.Context, caller *domain.User, id int) error {
+	ctx, span := tracer.Start(ctx, "UserService.ChangeUserStatus")
+	defer span.End()
 	if caller.IsAdmin == false {
-		return errors.New("Unauthorized")
+		return ErrForbidden
 	}
-	return s.store.ChangeUserStatus(caller, id)
+	return s.store.ChangeUserStatus(ctx, caller, id)
 }
 
-func (s *UserService) UpdateSelfUser(caller *domain.User) error {
+func (s *UserService) UpdateSelfUser(ctx context.Context, caller *domain.User) error {
+	ctx, span := tracer.Start(ctx, "UserService.UpdateSelfUser")
+	defer span.End()
 	argon := argon2.DefaultConfig()
 
+	if err := normalizeUserTimeZone(caller); err != nil {
+		return err
+	}
+
 	hashedPassword, err := argon.HashEncoded([]byte(caller.HashedPassword))
 	if err != nil {
 		return err
 	}
 	caller.HashedPassword = string(hashedPassword)
 
-	return s.store.UpdateSelfUser(caller)
+	return s.store.UpdateSelfUser(ctx, caller)
 }
 
-func (s *UserService) UpdateSelfPassword(caller *domain.User, password string) error {
-	return s.store.UpdateSelfPassword(caller, password)
+func (s *UserService) UpdateSelfPassword(ctx context.Context, caller *domain.User, password string) error {
+	ctx, span := tracer.Start(ctx, "UserService.UpdateSelfPassword")
+	defer span.End()
+	return s.store.UpdateSelfPassword(ctx, caller, password)
 }
 
-func (s *UserService) GetAllUsers(caller *domain.User) ([]domain.User, error) {
+// GetAllUsers retrieves a page of the caller's tenant users regardless of their status
+func (s *UserService) GetAllUsers(ctx context.Context, caller *domain.User, filter domain.UserFilter) (*domain.UserList, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetAllUsers")
+	defer span.End()
 	if !caller.IsAdmin {
-		return nil, errors.New("Unauthorized")
+		return nil, ErrForbidden
+	}
+	filter.TenantID = caller.TenantID
+	users, info, err := s.store.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.UserList{Users: users, PageInfo: *info}, nil
+}
+
+// ListReports retrieves a page of the active users reporting to the caller at any depth
+func (s *UserService) ListReports(ctx context.Context, caller *domain.User, filter domain.UserFilter) (*domain.UserList, error) {
+	ctx, span := tracer.Start(ctx, "UserService.ListReports")
+	defer span.End()
+	filter.ManagerID = caller.ID
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = []int{1}
+	}
+	users, info, err := s.store.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
-	return s.store.GetAllUsers()
+	return &domain.UserList{Users: users, PageInfo: *info}, nil
 }
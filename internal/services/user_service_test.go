@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+	"slices"
+	"testing"
+)
+
+// hierarchyStore is a user store holding a reporting hierarchy
+type hierarchyStore struct {
+	store.UserStore
+	users map[int]domain.User
+}
+
+func (s *hierarchyStore) GetUser(ctx context.Context, id int) (*domain.User, error) {
+	user, ok := s.users[id]
+	if !ok {
+		return nil, store.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (s *hierarchyStore) GetUserByUsername(ctx context.Context, tenantID int, username string) (*domain.User, error) {
+	for _, user := range s.users {
+		if user.TenantID == tenantID && user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *hierarchyStore) IsManagerOf(ctx context.Context, managerID int, userID int) (bool, error) {
+	for seen := map[int]bool{}; !seen[userID]; {
+		seen[userID] = true
+		user, ok := s.users[userID]
+		if !ok || user.ManagerID == nil {
+			return false, nil
+		}
+		if *user.ManagerID == managerID {
+			return true, nil
+		}
+		userID = *user.ManagerID
+	}
+	return false, nil
+}
+
+func (s *hierarchyStore) ListUsers(ctx context.Context, filter domain.UserFilter) ([]domain.User, *domain.PageInfo, error) {
+	users := []domain.User{}
+	for _, user := range s.users {
+		if filter.TenantID != 0 && user.TenantID != filter.TenantID {
+			continue
+		}
+		if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, user.Status) {
+			continue
+		}
+		users = append(users, user)
+	}
+	return users, &domain.PageInfo{}, nil
+}
+
+func TestAdminListsOnlyOwnTenant(t *testing.T) {
+	// 1 and 2 are in tenant A, 3 and 4 in tenant B; 2 and 4 are inactive
+	s := &UserService{store: &hierarchyStore{users: map[int]domain.User{
+		1: {ID: 1, TenantID: 1, Status: 1, IsAdmin: true},
+		2: {ID: 2, TenantID: 1, Status: 0},
+		3: {ID: 3, TenantID: 2, Status: 1},
+		4: {ID: 4, TenantID: 2, Status: 0},
+	}}}
+	admin := &domain.User{ID: 1, TenantID: 1, IsAdmin: true}
+
+	active, err := s.ListUsers(context.Background(), admin, domain.UserFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := s.GetAllUsers(context.Background(), admin, domain.UserFilter{TenantID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active.Users) != 1 || active.Users[0].ID != 1 {
+		t.Errorf("ListUsers = %v, want only the active user of tenant A", active.Users)
+	}
+	if len(all.Users) != 2 {
+		t.Errorf("GetAllUsers = %v, want the two users of tenant A", all.Users)
+	}
+	for _, user := range append(active.Users, all.Users...) {
+		if user.TenantID != admin.TenantID {
+			t.Errorf("admin of tenant A sees user %d of tenant %d", user.ID, user.TenantID)
+		}
+	}
+}
+
+func TestValidateManager(t *testing.T) {
+	ptr := func(v int) *int { return &v }
+	// 1 manages 2, 2 manages 3, 4 is in another tenant
+	s := &UserService{store: &hierarchyStore{users: map[int]domain.User{
+		1: {ID: 1, TenantID: 1},
+		2: {ID: 2, TenantID: 1, ManagerID: ptr(1)},
+		3: {ID: 3, TenantID: 1, ManagerID: ptr(2)},
+		4: {ID: 4, TenantID: 2},
+	}}}
+
+	tests := []struct {
+		name    string
+		user    domain.User
+		wantErr error
+	}{
+		{"no manager", domain.User{ID: 1, TenantID: 1}, nil},
+		{"new user", domain.User{TenantID: 1, ManagerID: ptr(3)}, nil},
+		{"move within tenant", domain.User{ID: 3, TenantID: 1, ManagerID: ptr(1)}, nil},
+		{"self", domain.User{ID: 2, TenantID: 1, ManagerID: ptr(2)}, ErrInvalidManager},
+		{"other tenant", domain.User{ID: 2, TenantID: 1, ManagerID: ptr(4)}, ErrInvalidManager},
+		{"unknown manager", domain.User{ID: 2, TenantID: 1, ManagerID: ptr(9)}, ErrInvalidManager},
+		{"direct cycle", domain.User{ID: 1, TenantID: 1, ManagerID: ptr(2)}, ErrInvalidManager},
+		{"indirect cycle", domain.User{ID: 1, TenantID: 1, ManagerID: ptr(3)}, ErrInvalidManager},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.validateManager(t.Context(), &tt.user); !errors.Is(err, tt.wantErr) {
+				t.Errorf("validateManager() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrInvalidVehicle = domain.NewValidationError("invalid_vehicle", "vehicle needs a plate, a known fuel type and non-negative rate and odometer")
+
+var fuelTypes = []string{"petrol", "diesel", "lpg", "hybrid", "electric"}
+
+// VehicleService handles business logic for the vehicle registry
+type VehicleService struct {
+	store store.VehicleStore
+}
+
+// NewVehicleService creates a new vehicle service
+func NewVehicleService(vehicleStore store.VehicleStore) *VehicleService {
+	return &VehicleService{
+		store: vehicleStore,
+	}
+}
+
+// GetVehicle retrieves a vehicle of the caller's tenant
+func (s *VehicleService) GetVehicle(ctx context.Context, caller *domain.User, id int) (*domain.Vehicle, error) {
+	ctx, span := tracer.Start(ctx, "VehicleService.GetVehicle")
+	defer span.End()
+	return s.store.GetVehicle(ctx, caller.TenantID, id)
+}
+
+// ListVehicles lists the vehicles of the caller's tenant
+func (s *VehicleService) ListVehicles(ctx context.Context, caller *domain.User) ([]domain.Vehicle, error) {
+	ctx, span := tracer.Start(ctx, "VehicleService.ListVehicles")
+	defer span.End()
+	return s.store.ListVehicles(ctx, caller.TenantID)
+}
+
+// CreateVehicle registers a new vehicle for the caller's tenant
+func (s *VehicleService) CreateVehicle(ctx context.Context, caller *domain.User, vehicle *domain.Vehicle) error {
+	ctx, span := tracer.Start(ctx, "VehicleService.CreateVehicle")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	if err := validateVehicle(vehicle); err != nil {
+		return err
+	}
+	vehicle.TenantID = caller.TenantID
+	return s.store.CreateVehicle(ctx, vehicle)
+}
+
+// UpdateVehicle modifies a vehicle, deactivating keeps its history for reports
+func (s *VehicleService) UpdateVehicle(ctx context.Context, caller *domain.User, vehicle *domain.Vehicle) error {
+	ctx, span := tracer.Start(ctx, "VehicleService.UpdateVehicle")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	if err := validateVehicle(vehicle); err != nil {
+		return err
+	}
+	vehicle.TenantID = caller.TenantID
+	if err := s.store.UpdateVehicle(ctx, vehicle); err != nil {
+		return err
+	}
+	updated, err := s.store.GetVehicle(ctx, caller.TenantID, vehicle.ID)
+	if err != nil {
+		return err
+	}
+	*vehicle = *updated
+	return nil
+}
+
+// GetReadings returns the odometer history of a vehicle
+func (s *VehicleService) GetReadings(ctx context.Context, caller *domain.User, vehicleID int) ([]domain.OdometerReading, error) {
+	ctx, span := tracer.Start(ctx, "VehicleService.GetReadings")
+	defer span.End()
+	if _, err := s.store.GetVehicle(ctx, caller.TenantID, vehicleID); err != nil {
+		return nil, err
+	}
+	return s.store.ListReadings(ctx, vehicleID)
+}
+
+// RecordReading adds a manual odometer reading, e.g. from a service visit
+func (s *VehicleService) RecordReading(ctx context.Context, caller *domain.User, reading *domain.OdometerReading) error {
+	ctx, span := tracer.Start(ctx, "VehicleService.RecordReading")
+	defer span.End()
+	vehicle, err := s.store.GetVehicle(ctx, caller.TenantID, reading.VehicleID)
+	if err != nil {
+		return err
+	}
+	if !caller.IsAdmin && vehicle.OwnerUserID != nil && *vehicle.OwnerUserID != caller.ID {
+		return ErrForbidden
+	}
+	if reading.RecordedAt.IsZero() {
+		reading.RecordedAt = time.Now()
+	}
+	last, err := s.store.LastOdometerBefore(ctx, vehicle.ID, reading.RecordedAt, 0)
+	if err != nil {
+		return err
+	}
+	if reading.ReadingKm < last {
+		return ErrOdometer
+	}
+	reading.EventID = nil
+	reading.UserID = caller.ID
+	return s.store.RecordReading(ctx, reading)
+}
+
+// GetRoadPriceByVehicle breaks the tenant's road prices in a date range down per vehicle
+func (s *VehicleService) GetRoadPriceByVehicle(ctx context.Context, caller *domain.User, dateRange domain.DateRange) ([]domain.VehicleRoadPrice, error) {
+	ctx, span := tracer.Start(ctx, "VehicleService.GetRoadPriceByVehicle")
+	defer span.End()
+	if !caller.IsAdmin {
+		return nil, ErrForbidden
+	}
+	return s.store.GetRoadPriceByVehicle(ctx, caller.TenantID, dateRange)
+}
+
+func validateVehicle(vehicle *domain.Vehicle) error {
+	vehicle.Plate = strings.ToUpper(strings.Join(strings.Fields(vehicle.Plate), " "))
+	if vehicle.Plate == "" || !slices.Contains(fuelTypes, vehicle.FuelType) || vehicle.InitialOdometerKm < 0 {
+		return ErrInvalidVehicle
+	}
+	if vehicle.RatePerKm != nil && *vehicle.RatePerKm < 0 {
+		return ErrInvalidVehicle
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+	"pwp-remastered/internal/webhook"
+	"slices"
+)
+
+var ErrInvalidWebhook = domain.NewValidationError("invalid_webhook", "webhook needs an absolute https URL to a public host and at least one known event type")
+
+// WebhookService manages a tenant's webhook subscriptions and delivery log, admins only
+type WebhookService struct {
+	store store.WebhookStore
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(webhookStore store.WebhookStore) *WebhookService {
+	return &WebhookService{store: webhookStore}
+}
+
+// ListSubscriptions lists the subscriptions of the caller's tenant
+func (s *WebhookService) ListSubscriptions(ctx context.Context, caller *domain.User) ([]domain.WebhookSubscription, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.ListSubscriptions")
+	defer span.End()
+	if !caller.IsAdmin {
+		return nil, ErrForbidden
+	}
+	return s.store.ListSubscriptions(ctx, caller.TenantID)
+}
+
+// GetSubscription retrieves a subscription of the caller's tenant, without its secret
+func (s *WebhookService) GetSubscription(ctx context.Context, caller *domain.User, id int) (*domain.WebhookSubscription, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetSubscription")
+	defer span.End()
+	if !caller.IsAdmin {
+		return nil, ErrForbidden
+	}
+	return s.store.GetSubscription(ctx, caller.TenantID, id)
+}
+
+// CreateSubscription adds a subscription with a new signing secret. The secret
+// is only returned here, receivers have to store it.
+func (s *WebhookService) CreateSubscription(ctx context.Context, caller *domain.User, subscription *domain.WebhookSubscription) error {
+	ctx, span := tracer.Start(ctx, "WebhookService.CreateSubscription")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	if err := validateSubscription(subscription); err != nil {
+		return err
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return err
+	}
+	subscription.TenantID = caller.TenantID
+	subscription.Secret = secret
+	return s.store.CreateSubscription(ctx, subscription)
+}
+
+// UpdateSubscription changes the URL, event types and active flag, the secret is kept
+func (s *WebhookService) UpdateSubscription(ctx context.Context, caller *domain.User, subscription *domain.WebhookSubscription) error {
+	ctx, span := tracer.Start(ctx, "WebhookService.UpdateSubscription")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	if err := validateSubscription(subscription); err != nil {
+		return err
+	}
+	subscription.TenantID = caller.TenantID
+	subscription.Secret = ""
+	return s.store.UpdateSubscription(ctx, subscription)
+}
+
+// DeleteSubscription removes a subscription with its delivery log
+func (s *WebhookService) DeleteSubscription(ctx context.Context, caller *domain.User, id int) error {
+	ctx, span := tracer.Start(ctx, "WebhookService.DeleteSubscription")
+	defer span.End()
+	if !caller.IsAdmin {
+		return ErrForbidden
+	}
+	return s.store.DeleteSubscription(ctx, caller.TenantID, id)
+}
+
+// ListDeliveries retrieves a page of the delivery log of the caller's tenant
+func (s *WebhookService) ListDeliveries(ctx context.Context, caller *domain.User, filter domain.WebhookDeliveryFilter) (*domain.WebhookDeliveryList, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.ListDeliveries")
+	defer span.End()
+	if !caller.IsAdmin {
+		return nil, ErrForbidden
+	}
+	deliveries, info, err := s.store.ListDeliveries(ctx, caller.TenantID, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.WebhookDeliveryList{Deliveries: deliveries, PageInfo: *info}, nil
+}
+
+// GetDelivery retrieves a delivery with every attempt made
+func (s *WebhookService) GetDelivery(ctx context.Context, caller *domain.User, id int) (*domain.WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetDelivery")
+	defer span.End()
+	if !caller.IsAdmin {
+		return nil, ErrForbidden
+	}
+	return s.store.GetDelivery(ctx, caller.TenantID, id)
+}
+
+// Redeliver sends a delivery again, whatever its state, with a fresh set of retries
+func (s *WebhookService) Redeliver(ctx context.Context, caller *domain.User, id int) (*domain.WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.Redeliver")
+	defer span.End()
+	if !caller.IsAdmin {
+		return nil, ErrForbidden
+	}
+	if err := s.store.Redeliver(ctx, caller.TenantID, id); err != nil {
+		return nil, err
+	}
+	return s.store.GetDelivery(ctx, caller.TenantID, id)
+}
+
+func validateSubscription(subscription *domain.WebhookSubscription) error {
+	u, err := url.Parse(subscription.URL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return ErrInvalidWebhook
+	}
+	if !webhook.PublicHost(u.Hostname()) {
+		return ErrInvalidWebhook
+	}
+	if len(subscription.EventTypes) == 0 {
+		return ErrInvalidWebhook
+	}
+	for _, eventType := range subscription.EventTypes {
+		if !slices.Contains(domain.WebhookEventTypes, eventType) {
+			return ErrInvalidWebhook
+		}
+	}
+	slices.Sort(subscription.EventTypes)
+	subscription.EventTypes = slices.Compact(subscription.EventTypes)
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"errors"
+	"pwp-remastered/internal/domain"
+	"testing"
+)
+
+func TestValidateSubscription(t *testing.T) {
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{"https://hooks.example.com/pwp", nil},
+		{"https://93.184.216.34:8443/pwp", nil},
+		{"http://hooks.example.com/pwp", ErrInvalidWebhook},
+		{"https:///pwp", ErrInvalidWebhook},
+		{"/pwp", ErrInvalidWebhook},
+		{"https://localhost/pwp", ErrInvalidWebhook},
+		{"https://api.LOCALHOST./pwp", ErrInvalidWebhook},
+		{"https://127.0.0.1/pwp", ErrInvalidWebhook},
+		{"https://10.0.0.5/pwp", ErrInvalidWebhook},
+		{"https://169.254.169.254/latest/meta-data", ErrInvalidWebhook},
+		{"https://[::1]:8443/pwp", ErrInvalidWebhook},
+	}
+	for _, tt := range tests {
+		subscription := &domain.WebhookSubscription{URL: tt.url, EventTypes: []string{domain.WebhookEventCreated}}
+		if err := validateSubscription(subscription); !errors.Is(err, tt.want) {
+			t.Errorf("validateSubscription(%s) = %v, want %v", tt.url, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/domain"
+)
+
+var ErrAttachmentNotFound = domain.NewNotFoundError("attachment_not_found", "attachment not found")
+
+// AttachmentStore handles event attachment metadata. The contents live in a blob.BlobStore.
+type AttachmentStore interface {
+	CreateAttachment(context.Context, *domain.Attachment) error
+	GetAttachment(ctx context.Context, eventID int, id int) (*domain.Attachment, error)
+	ListEventAttachments(ctx context.Context, eventID int) ([]domain.Attachment, error)
+	DeleteAttachment(ctx context.Context, eventID int, id int) error
+	// OrphanedBlobs returns up to limit storage keys of deleted attachments
+	// whose contents may still be stored
+	OrphanedBlobs(ctx context.Context, limit int) ([]string, error)
+	// ForgetOrphanedBlob drops key from the orphans once its contents are deleted
+	ForgetOrphanedBlob(ctx context.Context, key string) error
+}
+
+type attachmentDBStore struct {
+	db database.Service
+}
+
+func NewAttachmentStore(db database.Service) AttachmentStore {
+	return &attachmentDBStore{db: db}
+}
+
+// CreateAttachment records an attachment unless its event falls in a closed
+// period, the receipts of a closed period are final as well
+func (s *attachmentDBStore) CreateAttachment(ctx context.Context, attachment *domain.Attachment) error {
+	query := `
+		INSERT INTO event_attachments (event_id, user_id, file_name, content_type, size, storage_key)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	return s.db.Transact(ctx, func(tx database.Querier) error {
+		if err := lockEventPeriods(ctx, tx, attachment.EventID); err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx,
+			query,
+			attachment.EventID, attachment.UserID, attachment.FileName,
+			attachment.ContentType, attachment.Size, attachment.StorageKey,
+		).Scan(&attachment.ID, &attachment.CreatedAt)
+	})
+}
+
+func (s *attachmentDBStore) GetAttachment(ctx context.Context, eventID int, id int) (*domain.Attachment, error) {
+	var attachment domain.Attachment
+	query := `
+		SELECT id, event_id, user_id, file_name, content_type, size, storage_key, created_at
+		FROM event_attachments
+		WHERE event_id = $1 AND id = $2`
+
+	err := s.db.QueryRowContext(ctx, query, eventID, id).Scan(
+		&attachment.ID, &attachment.EventID, &attachment.UserID, &attachment.FileName,
+		&attachment.ContentType, &attachment.Size, &attachment.StorageKey, &attachment.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (s *attachmentDBStore) ListEventAttachments(ctx context.Context, eventID int) ([]domain.Attachment, error) {
+	query := `
+		SELECT id, event_id, user_id, file_name, content_type, size, storage_key, created_at
+		FROM event_attachments
+		WHERE event_id = $1
+		ORDER BY created_at`
+
+	rows, err := s.db.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []domain.Attachment{}
+	for rows.Next() {
+		var attachment domain.Attachment
+		err := rows.Scan(
+			&attachment.ID, &attachment.EventID, &attachment.UserID, &attachment.FileName,
+			&attachment.ContentType, &attachment.Size, &attachment.StorageKey, &attachment.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// DeleteAttachment removes an attachment unless its event falls in a closed period
+func (s *attachmentDBStore) DeleteAttachment(ctx context.Context, eventID int, id int) error {
+	query := `DELETE FROM event_attachments WHERE event_id = $1 AND id = $2`
+	return s.db.Transact(ctx, func(tx database.Querier) error {
+		if err := lockEventPeriods(ctx, tx, eventID); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, query, eventID, id)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrAttachmentNotFound
+		}
+		return nil
+	})
+}
+
+func (s *attachmentDBStore) OrphanedBlobs(ctx context.Context, limit int) ([]string, error) {
+	query := `SELECT storage_key FROM orphaned_blobs ORDER BY queued_at LIMIT $1`
+	rows, err := s.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *attachmentDBStore) ForgetOrphanedBlob(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM orphaned_blobs WHERE storage_key = $1`, key)
+	return err
+}
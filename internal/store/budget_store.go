@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/domain"
+)
+
+var ErrBudgetNotFound = domain.NewNotFoundError("budget_not_found", "budget not found")
+
+// BudgetStore handles the budgets of a tenant and the alerts already raised
+type BudgetStore interface {
+	GetBudget(ctx context.Context, tenantID int, id int) (*domain.Budget, error)
+	ListBudgets(ctx context.Context, tenantID int) ([]domain.Budget, error)
+	CreateBudget(context.Context, *domain.Budget) error
+	UpdateBudget(context.Context, *domain.Budget) error
+	DeleteBudget(ctx context.Context, tenantID int, id int) error
+	// ListBudgetsFor returns the budgets covering an event of userID with typeID:
+	// the user's own, those of the user's teams and those of the event type
+	ListBudgetsFor(ctx context.Context, tenantID int, userID int, typeID int) ([]domain.Budget, error)
+	// RecordAlert queues a budget.threshold_crossed notification unless one was
+	// already sent for the threshold in the status' period, and reports whether it did
+	RecordAlert(ctx context.Context, alert domain.BudgetAlert) (bool, error)
+}
+
+type budgetDBStore struct {
+	db database.Service
+}
+
+func NewBudgetStore(db database.Service) BudgetStore {
+	return &budgetDBStore{db: db}
+}
+
+const budgetSelect = `
+		SELECT id, tenant_id, user_id, team_id, type_id, period, amount, created_at, updated_at
+		FROM budgets`
+
+func scanBudget(row rowScanner) (*domain.Budget, error) {
+	var budget domain.Budget
+	err := row.Scan(
+		&budget.ID, &budget.TenantID, &budget.UserID, &budget.TeamID, &budget.TypeID,
+		&budget.Period, &budget.Limit, &budget.CreatedAt, &budget.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+func scanBudgets(rows *sql.Rows, err error) ([]domain.Budget, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	budgets := []domain.Budget{}
+	for rows.Next() {
+		budget, err := scanBudget(rows)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, *budget)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return budgets, nil
+}
+
+func (s *budgetDBStore) GetBudget(ctx context.Context, tenantID int, id int) (*domain.Budget, error) {
+	budget, err := scanBudget(s.db.QueryRowContext(ctx, budgetSelect+`
+		WHERE tenant_id = $1 AND id = $2`, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrBudgetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (s *budgetDBStore) ListBudgets(ctx context.Context, tenantID int) ([]domain.Budget, error) {
+	return scanBudgets(s.db.QueryContext(ctx, budgetSelect+`
+		WHERE tenant_id = $1
+		ORDER BY id`, tenantID))
+}
+
+func (s *budgetDBStore) CreateBudget(ctx context.Context, budget *domain.Budget) error {
+	return s.db.QueryRowContext(ctx, `
+		INSERT INTO budgets (tenant_id, user_id, team_id, type_id, period, amount)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at`,
+		budget.TenantID, budget.UserID, budget.TeamID, budget.TypeID, budget.Period, budget.Limit,
+	).Scan(&budget.ID, &budget.CreatedAt, &budget.UpdatedAt)
+}
+
+func (s *budgetDBStore) UpdateBudget(ctx context.Context, budget *domain.Budget) error {
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE budgets
+		SET user_id = $3, team_id = $4, type_id = $5, period = $6, amount = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE tenant_id = $1 AND id = $2
+		RETURNING created_at, updated_at`,
+		budget.TenantID, budget.ID, budget.UserID, budget.TeamID, budget.TypeID, budget.Period, budget.Limit,
+	).Scan(&budget.CreatedAt, &budget.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrBudgetNotFound
+	}
+	return err
+}
+
+func (s *budgetDBStore) DeleteBudget(ctx context.Context, tenantID int, id int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM budgets WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrBudgetNotFound
+	}
+	return nil
+}
+
+func (s *budgetDBStore) ListBudgetsFor(ctx context.Context, tenantID int, userID int, typeID int) ([]domain.Budget, error) {
+	return scanBudgets(s.db.QueryContext(ctx, budgetSelect+`
+		WHERE tenant_id = $1
+		  AND (user_id = $2
+		       OR type_id = $3
+		       OR team_id IN (SELECT team_id FROM team_members WHERE user_id = $2))
+		ORDER BY id`, tenantID, userID, typeID))
+}
+
+func (s *budgetDBStore) RecordAlert(ctx context.Context, alert domain.BudgetAlert) (bool, error) {
+	var sent bool
+	err := s.db.Transact(ctx, func(tx database.Querier) error {
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO budget_alerts (budget_id, period_start, threshold, spent)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT DO NOTHING`,
+			alert.Budget.ID, alert.PeriodStart, alert.Threshold, alert.Spent)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil || n == 0 {
+			return err
+		}
+		sent = true
+		return enqueueWebhook(ctx, tx, alert.Budget.TenantID, domain.WebhookBudgetThreshold, alert)
+	})
+	return sent, err
+}
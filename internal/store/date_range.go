@@ -0,0 +1,33 @@
+package store
+
+import (
+	"pwp-remastered/internal/domain"
+)
+
+// whereDateRange adds the conditions of r on the events aliased e
+func whereDateRange(b *queryBuilder, r domain.DateRange) {
+	switch r.Mode {
+	case domain.RangeContained:
+		if !r.From.IsZero() {
+			b.where("e.start_date >= ?", r.From)
+		}
+		if !r.To.IsZero() {
+			b.where("e.end_date <= ?", r.To)
+		}
+	case domain.RangeStartsWithin:
+		if !r.From.IsZero() {
+			b.where("e.start_date >= ?", r.From)
+		}
+		if !r.To.IsZero() {
+			b.where("e.start_date < ?", r.To)
+		}
+	default:
+		if !r.To.IsZero() {
+			b.where("e.start_date < ?", r.To)
+		}
+		// Zero length events starting on the lower bound count as overlapping
+		if !r.From.IsZero() {
+			b.where("(e.end_date > ? OR e.start_date >= ?)", r.From, r.From)
+		}
+	}
+}
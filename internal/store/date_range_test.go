@@ -0,0 +1,34 @@
+package store
+
+import (
+	"pwp-remastered/internal/domain"
+	"testing"
+	"time"
+)
+
+func TestWhereDateRange(t *testing.T) {
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	tests := []struct {
+		mode domain.DateRangeMode
+		want string
+	}{
+		{domain.RangeOverlap, "\n\t\tWHERE e.start_date < $1\n\t\t  AND (e.end_date > $2 OR e.start_date >= $3)"},
+		{domain.RangeContained, "\n\t\tWHERE e.start_date >= $1\n\t\t  AND e.end_date <= $2"},
+		{domain.RangeStartsWithin, "\n\t\tWHERE e.start_date >= $1\n\t\t  AND e.start_date < $2"},
+	}
+	for _, tt := range tests {
+		b := &queryBuilder{}
+		whereDateRange(b, domain.DateRange{From: from, To: to, Mode: tt.mode})
+		if got := b.whereClause(); got != tt.want {
+			t.Errorf("%s: whereClause() = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+
+	b := &queryBuilder{}
+	whereDateRange(b, domain.DateRange{})
+	if got := b.whereClause(); got != "" {
+		t.Errorf("open range: whereClause() = %q, want none", got)
+	}
+}
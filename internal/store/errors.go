@@ -0,0 +1,26 @@
+package store
+
+import (
+	"errors"
+	"pwp-remastered/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrUserNotFound   = domain.NewNotFoundError("user_not_found", "user not found")
+	ErrUserExists     = domain.NewConflictError("user_exists", "username or email is already taken")
+	ErrLocationExists = domain.NewConflictError("location_exists", "a location with this name already exists")
+	ErrVehicleExists  = domain.NewConflictError("vehicle_exists", "a vehicle with this plate already exists")
+	ErrRateExists     = domain.NewConflictError("rate_exists", "a mileage rate for this type and date already exists")
+	ErrTeamExists     = domain.NewConflictError("team_exists", "a team with this name already exists")
+)
+
+// uniqueViolation replaces a Postgres unique constraint violation with conflict
+func uniqueViolation(err error, conflict error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return conflict
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"pwp-remastered/internal/domain"
+	"strconv"
+)
+
+// Text search configurations an event search can highlight with
+const (
+	SearchConfigTurkish = "turkish"
+	SearchConfigEnglish = "english"
+)
+
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2"
+
+// SearchEvents matches filter.Query against the search_vector of events with
+// both the Turkish and English configurations, best match first. config picks
+// the parser used for the highlighted snippets.
+func (s *eventDBStore) SearchEvents(ctx context.Context, filter domain.EventFilter, config string) ([]domain.EventSearchResult, *domain.PageInfo, error) {
+	if config != SearchConfigTurkish && config != SearchConfigEnglish {
+		config = SearchConfigTurkish
+	}
+
+	// The text query is matched here instead of eventFilter's ILIKE
+	text := filter.Query
+	filter.Query = ""
+
+	// conditions binds the tsquery first so rank and highlights can reuse it
+	conditions := func() (*queryBuilder, string) {
+		b := &queryBuilder{}
+		tsquery := fmt.Sprintf("(websearch_to_tsquery('turkish', %s) || websearch_to_tsquery('english', %[1]s))", b.arg(text))
+		b.where("e.search_vector @@ " + tsquery)
+		return eventFilter(b, filter), tsquery
+	}
+
+	info := &domain.PageInfo{}
+	if filter.WithTotal {
+		b, _ := conditions()
+		var total int
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM events e`+b.whereClause(), b.args...).Scan(&total); err != nil {
+			return nil, nil, err
+		}
+		info.Total = &total
+	}
+
+	b, tsquery := conditions()
+	rank := fmt.Sprintf("ts_rank(e.search_vector, %s)", tsquery)
+	fields := map[string]sortField[domain.EventSearchResult]{
+		"rank": {rank, "real", func(r *domain.EventSearchResult) string {
+			return strconv.FormatFloat(float64(r.Rank), 'g', -1, 32)
+		}},
+	}
+	p, err := newPage(b, fields, "-rank", "e.id", filter.Sort, filter.Cursor, filter.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg := b.arg(config) + "::regconfig"
+	opts := b.arg(headlineOptions)
+	query := `
+		SELECT` + eventColumns + `,
+			` + rank + `,
+			ts_headline(` + cfg + `, e.title, ` + tsquery + `, ` + opts + `),
+			ts_headline(` + cfg + `, e.description, ` + tsquery + `, ` + opts + `)` +
+		eventJoins + b.whereClause() + p.orderLimit()
+
+	rows, err := s.db.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var results []domain.EventSearchResult
+	for rows.Next() {
+		var result domain.EventSearchResult
+		var description sql.NullString
+		event, err := scanEvent(rows, &result.Rank, &result.TitleHighlight, &description)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.Event = *event
+		result.DescriptionHighlight = description.String
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	results, info.NextCursor = p.trim(results, func(r *domain.EventSearchResult) int { return r.ID })
+	return results, info, nil
+}
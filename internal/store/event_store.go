@@ -1,25 +1,54 @@
 package store
 
 import (
+	"context"
 	"database/sql"
-	"errors"
+	"encoding/json"
 	"pwp-remastered/internal/database"
 	"pwp-remastered/internal/domain"
+	"slices"
+	"strconv"
 
 	"time"
 )
 
+var (
+	ErrEventNotFound     = domain.NewNotFoundError("event_not_found", "event not found")
+	ErrEventTypeNotFound = domain.NewNotFoundError("event_type_not_found", "event type not found")
+	ErrEventApproved     = domain.NewConflictError("event_already_approved", "event is already approved")
+	// ErrInvalidEventTypeOrder rejects an order that misses, repeats or adds a type
+	ErrInvalidEventTypeOrder = domain.NewValidationError("invalid_event_type_order", "ids must list each of the tenant's event types exactly once")
+)
+
 // EventStore handles event data operations
 type EventStore interface {
-	GetEvent(int) (*domain.Event, error)
-	CreateEvent(*domain.Event, *domain.User) error
-	UpdateEvent(*domain.Event, *domain.User) error
-	DeleteEvent(int) error
-	GetDatedUserEvents(int, time.Time, time.Time) ([]domain.Event, error)
-	GetAllDatedEvents(time.Time, time.Time) ([]domain.Event, error)
-	GetSelfDatedEvents(*domain.User, time.Time, time.Time) ([]domain.Event, error)
-	GetEventType(int) (*domain.EventType, error)
-	GetEventTypes() ([]domain.EventType, error)
+	GetEvent(context.Context, int) (*domain.Event, error)
+	// CreateEvent, ImportEvents, UpdateEvent and DeleteEvent return
+	// ErrPeriodClosed when an event falls in a closed accounting period
+	CreateEvent(context.Context, *domain.Event, *domain.User) error
+	// ImportEvents creates events for their UserID in a single transaction,
+	// either all of them are saved or none is
+	ImportEvents(context.Context, []domain.Event) error
+	UpdateEvent(context.Context, *domain.Event, *domain.User) error
+	DeleteEvent(context.Context, int) error
+	// ApproveEvent marks a pending event approved by approverID
+	ApproveEvent(ctx context.Context, id int, approverID int) (*domain.Event, error)
+	ListEvents(context.Context, domain.EventFilter) ([]domain.Event, *domain.PageInfo, error)
+	// SumEvents totals the events ListEvents returns for the same filter, ignoring paging
+	SumEvents(context.Context, domain.EventFilter) (*domain.EventTotals, error)
+	SearchEvents(ctx context.Context, filter domain.EventFilter, config string) ([]domain.EventSearchResult, *domain.PageInfo, error)
+	// GetEventType and GetEventTypes see the shared types and the tenant's own
+	GetEventType(ctx context.Context, tenantID int, id int) (*domain.EventType, error)
+	GetEventTypes(ctx context.Context, tenantID int, includeArchived bool) ([]domain.EventType, error)
+	// CreateEventType adds a type owned by its TenantID
+	CreateEventType(context.Context, *domain.EventType) error
+	// UpdateEventType, SetEventTypeArchived and ReorderEventTypes only change
+	// the tenant's own types, shared types are not found
+	UpdateEventType(context.Context, *domain.EventType) error
+	SetEventTypeArchived(ctx context.Context, tenantID int, id int, archived bool) error
+	// ReorderEventTypes orders the tenant's own types, ids must hold each of
+	// them exactly once
+	ReorderEventTypes(ctx context.Context, tenantID int, ids []int) error
 }
 
 // Example implementation using database layer
@@ -31,252 +60,548 @@ func NewEventStore(db database.Service) EventStore {
 	return &eventDBStore{db: db}
 }
 
-func (s *eventDBStore) GetEvent(id int) (*domain.Event, error) {
-	var event domain.Event
-	var user domain.EventUser
-	var eventType domain.EventType
+// eventSelect is the shared projection for event reads, use scanEvent on its rows
+const eventSelect = `
+		SELECT` + eventColumns + eventJoins
 
-	query := `
-		SELECT 
-			e.id, e.type_id, e.user_id, e.name, e.title, e.description, 
+const eventColumns = `
+			e.id, e.type_id, e.user_id, e.name, e.title, e.description,
 			e.start_date, e.end_date, e.road_price,
-			u.id, u.username, u.first_name, u.last_name,
-			et.id,et.type, et.language, et.color, et.is_pricable
+			e.origin_lat, e.origin_lng, e.destination_lat, e.destination_lng, e.distance_km,
+			e.vehicle_id, e.odometer_start, e.odometer_end,
+			e.status, e.approved_by, e.approved_at,
+			ol.id, ol.name, ol.address, ol.latitude, ol.longitude, COALESCE(array_to_json(ol.tags)::text, '[]'),
+			dl.id, dl.name, dl.address, dl.latitude, dl.longitude, COALESCE(array_to_json(dl.tags)::text, '[]'),
+			u.id, u.username, u.first_name, u.last_name, COALESCE(u.tenant_id, 0),
+			et.id, et.tenant_id, et.type, et.color, et.is_pricable, et.is_archived, et.sort_order`
+
+const eventJoins = `
 		FROM events e
 		LEFT JOIN users u ON e.user_id = u.id
 		LEFT JOIN event_types et ON e.type_id = et.id
-		WHERE e.id = $1`
+		LEFT JOIN locations ol ON e.origin_location_id = ol.id
+		LEFT JOIN locations dl ON e.destination_location_id = dl.id`
 
-	err := s.db.QueryRow(query, id).Scan(
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanEvent reads an eventSelect row, extra receives columns selected after eventColumns
+func scanEvent(row rowScanner, extra ...interface{}) (*domain.Event, error) {
+	var event domain.Event
+	var user domain.EventUser
+	var eventType domain.EventType
+	var origin, destination eventLocation
+
+	dest := []interface{}{
 		&event.ID, &event.TypeID, &event.UserID, &event.Name, &event.Title, &event.Description,
 		&event.StartDate, &event.EndDate, &event.RoadPrice,
-		&user.ID, &user.Username, &user.FirstName, &user.LastName,
-		&eventType.ID, &eventType.Type, &eventType.Language, &eventType.Color, &eventType.IsPricable,
-	)
-	if err != nil {
+		&event.OriginLat, &event.OriginLng, &event.DestinationLat, &event.DestinationLng, &event.DistanceKm,
+		&event.VehicleID, &event.OdometerStart, &event.OdometerEnd,
+		&event.Status, &event.ApprovedBy, &event.ApprovedAt,
+		&origin.id, &origin.name, &origin.location.Address, &origin.location.Latitude, &origin.location.Longitude, &origin.tags,
+		&destination.id, &destination.name, &destination.location.Address, &destination.location.Latitude, &destination.location.Longitude, &destination.tags,
+		&user.ID, &user.Username, &user.FirstName, &user.LastName, &user.TenantID,
+		&eventType.ID, &eventType.TenantID, &eventType.Type, &eventType.Color, &eventType.IsPricable, &eventType.IsArchived, &eventType.SortOrder,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
 		return nil, err
 	}
 
+	var err error
 	event.User = &user
 	event.Type = &eventType
+	if event.OriginLocation, err = origin.resolve(); err != nil {
+		return nil, err
+	}
+	if event.DestinationLocation, err = destination.resolve(); err != nil {
+		return nil, err
+	}
+	if event.OriginLocation != nil {
+		event.OriginLocationID = &event.OriginLocation.ID
+	}
+	if event.DestinationLocation != nil {
+		event.DestinationLocationID = &event.DestinationLocation.ID
+	}
 	return &event, nil
 }
 
-func (s *eventDBStore) CreateEvent(event *domain.Event, caller *domain.User) error {
-	userID := caller.ID
-	typeID := event.TypeID
-
-	EventType, err := s.GetEventType(typeID)
+// eventLocation collects the nullable columns of a LEFT JOINed location
+type eventLocation struct {
+	id       sql.NullInt64
+	name     sql.NullString
+	tags     string
+	location domain.Location
+}
 
-	if err != nil {
-		return err
+func (l *eventLocation) resolve() (*domain.Location, error) {
+	if !l.id.Valid {
+		return nil, nil
 	}
+	l.location.ID = int(l.id.Int64)
+	l.location.Name = l.name.String
+	if err := json.Unmarshal([]byte(l.tags), &l.location.Tags); err != nil {
+		return nil, err
+	}
+	return &l.location, nil
+}
 
-	event.Type = EventType
-	event.UserID = userID
+func scanEvents(rows *sql.Rows) ([]domain.Event, error) {
+	defer rows.Close()
 
-	query := `
-		INSERT INTO events (type_id, user_id, name, title, description, start_date, end_date, road_price)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id`
+	var events []domain.Event
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
 
-	err = s.db.QueryRow(query, typeID, userID, event.Name, event.Title, event.Description, event.StartDate, event.EndDate, event.RoadPrice).Scan(&event.ID)
+func (s *eventDBStore) GetEvent(ctx context.Context, id int) (*domain.Event, error) {
+	return getEvent(ctx, s.db, id)
+}
+
+// getEvent reads an event with q, so writes can return it from their transaction
+func getEvent(ctx context.Context, q database.Querier, id int) (*domain.Event, error) {
+	event, err := scanEvent(q.QueryRowContext(ctx, eventSelect+`
+		WHERE e.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrEventNotFound
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return event, nil
+}
 
-	userQuery := `
-		SELECT u.id, u.username, u.first_name, u.last_name
-		FROM users u
-		WHERE u.id = $1`
-	var user domain.EventUser
-	err = s.db.QueryRow(userQuery, userID).Scan(&user.ID, &user.Username, &user.FirstName, &user.LastName)
+// lockEvent locks the event's row until tx ends and reads it, so its dates
+// cannot change between checking and writing it
+func lockEvent(ctx context.Context, tx database.Querier, id int) (*domain.Event, error) {
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM events WHERE id = $1 FOR UPDATE`, id); err != nil {
+		return nil, err
+	}
+	return getEvent(ctx, tx, id)
+}
+
+// lockEventPeriods locks the event and the periods it falls in, see lockOpenPeriods
+func lockEventPeriods(ctx context.Context, tx database.Querier, eventID int) error {
+	event, err := lockEvent(ctx, tx, eventID)
 	if err != nil {
 		return err
 	}
-	event.User = &user
+	return lockOpenPeriods(ctx, tx, event.UserID, event.StartDate, event.EndDate)
+}
 
-	return nil
+// CreateEvent inserts the event owned by caller and fills it in as stored
+func (s *eventDBStore) CreateEvent(ctx context.Context, event *domain.Event, caller *domain.User) error {
+	event.UserID = caller.ID
+	return s.db.Transact(ctx, func(tx database.Querier) error {
+		return insertEvent(ctx, tx, event)
+	})
 }
 
-func (s *eventDBStore) UpdateEvent(event *domain.Event, caller *domain.User) error {
-	//TODO: type cannot be manually changed add it to query and remove user_id
+func (s *eventDBStore) ImportEvents(ctx context.Context, events []domain.Event) error {
+	return s.db.Transact(ctx, func(tx database.Querier) error {
+		for i := range events {
+			if err := insertEvent(ctx, tx, &events[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
 
+// insertEvent saves a new event, fills it in as stored, and records its
+// odometer reading and queues its event.created webhook in the same transaction.
+// It returns ErrPeriodClosed when the event falls in a closed period.
+func insertEvent(ctx context.Context, tx database.Querier, event *domain.Event) error {
+	if err := lockOpenPeriods(ctx, tx, event.UserID, event.StartDate, event.EndDate); err != nil {
+		return err
+	}
 	query := `
-		UPDATE events
-		SET type_id = $1, user_id = $2, name = $3, title = $4, description = $5, start_date = $6, end_date = $7, road_price = $8
-		WHERE id = $9
+		INSERT INTO events (type_id, user_id, name, title, description, start_date, end_date, road_price,
+		                    origin_lat, origin_lng, destination_lat, destination_lng, distance_km,
+		                    origin_location_id, destination_location_id, vehicle_id, odometer_start, odometer_end)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		RETURNING id`
 
-	_, err := s.db.Exec(query, event.TypeID, event.UserID, event.Name, event.Title, event.Description, event.StartDate, event.EndDate, event.RoadPrice, event.ID)
+	err := tx.QueryRowContext(ctx, query, event.TypeID, event.UserID, event.Name, event.Title, event.Description, event.StartDate, event.EndDate, event.RoadPrice,
+		event.OriginLat, event.OriginLng, event.DestinationLat, event.DestinationLng, event.DistanceKm,
+		event.OriginLocationID, event.DestinationLocationID, event.VehicleID, event.OdometerStart, event.OdometerEnd).Scan(&event.ID)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func (s *eventDBStore) DeleteEvent(id int) error {
-	query := `
-		DELETE FROM events
-		WHERE id = $1`
-
-	_, err := s.db.Exec(query, id)
+	created, err := getEvent(ctx, tx, event.ID)
 	if err != nil {
 		return err
 	}
-	return nil
+	*event = *created
+	if err := syncEventReading(ctx, tx, created); err != nil {
+		return err
+	}
+	return enqueueWebhook(ctx, tx, created.User.TenantID, domain.WebhookEventCreated, created)
 }
 
-func (s *eventDBStore) GetDatedUserEvents(id int, startdate time.Time, enddate time.Time) ([]domain.Event, error) {
-	var events []domain.Event
+// UpdateEvent replaces the event and fills it in as stored. The approval is
+// cleared, a changed event has to be approved again. Neither the stored nor
+// the new dates may fall in a closed period.
+func (s *eventDBStore) UpdateEvent(ctx context.Context, event *domain.Event, caller *domain.User) error {
+	//TODO: type cannot be manually changed add it to query and remove user_id
 
 	query := `
-		SELECT
-			e.id, e.type_id, e.user_id, e.name, e.title, e.description,
-			e.start_date, e.end_date, e.road_price,
-			u.id, u.username, u.first_name, u.last_name,
-			et.id,et.type, et.language, et.color, et.is_pricable
-		FROM events e
-		LEFT JOIN users u ON e.user_id = u.id
-		LEFT JOIN event_types et ON e.type_id = et.id
-		WHERE e.user_id = $1 AND e.start_date >= $2 AND e.end_date <= $3
-		ORDER BY e.start_date`
-
-	rows, err := s.db.Query(query, id, startdate, enddate)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var event domain.Event
-		var user domain.EventUser
-		var eventType domain.EventType
-
-		err := rows.Scan(
-			&event.ID, &event.TypeID, &event.UserID, &event.Name, &event.Title, &event.Description,
-			&event.StartDate, &event.EndDate, &event.RoadPrice,
-			&user.ID, &user.Username, &user.FirstName, &user.LastName,
-			&eventType.ID, &eventType.Type, &eventType.Language, &eventType.Color, &eventType.IsPricable,
-		)
+		UPDATE events
+		SET type_id = $1, user_id = $2, name = $3, title = $4, description = $5, start_date = $6, end_date = $7, road_price = $8,
+		    origin_lat = $9, origin_lng = $10, destination_lat = $11, destination_lng = $12, distance_km = $13,
+		    origin_location_id = $14, destination_location_id = $15,
+		    vehicle_id = $16, odometer_start = $17, odometer_end = $18,
+		    status = 'pending', approved_by = NULL, approved_at = NULL
+		WHERE id = $19`
+
+	return s.db.Transact(ctx, func(tx database.Querier) error {
+		// Moving an event out of a closed period changes that period as much as editing it
+		existing, err := lockEvent(ctx, tx, event.ID)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := lockOpenPeriods(ctx, tx, existing.UserID, existing.StartDate, existing.EndDate); err != nil {
+			return err
+		}
+		if err := lockOpenPeriods(ctx, tx, event.UserID, event.StartDate, event.EndDate); err != nil {
+			return err
 		}
 
-		event.User = &user
-		event.Type = &eventType
-		events = append(events, event)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return events, nil
+		result, err := tx.ExecContext(ctx, query, event.TypeID, event.UserID, event.Name, event.Title, event.Description, event.StartDate, event.EndDate, event.RoadPrice,
+			event.OriginLat, event.OriginLng, event.DestinationLat, event.DestinationLng, event.DistanceKm,
+			event.OriginLocationID, event.DestinationLocationID, event.VehicleID, event.OdometerStart, event.OdometerEnd, event.ID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrEventNotFound
+		}
 
+		updated, err := getEvent(ctx, tx, event.ID)
+		if err != nil {
+			return err
+		}
+		*event = *updated
+		if err := syncEventReading(ctx, tx, updated); err != nil {
+			return err
+		}
+		return enqueueWebhook(ctx, tx, updated.User.TenantID, domain.WebhookEventUpdated, updated)
+	})
 }
 
-func (s *eventDBStore) GetAllDatedEvents(startdate time.Time, enddate time.Time) ([]domain.Event, error) {
-	var events []domain.Event
+// DeleteEvent removes the event unless it falls in a closed period
+func (s *eventDBStore) DeleteEvent(ctx context.Context, id int) error {
+	return s.db.Transact(ctx, func(tx database.Querier) error {
+		event, err := lockEvent(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := lockOpenPeriods(ctx, tx, event.UserID, event.StartDate, event.EndDate); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM events WHERE id = $1`, id); err != nil {
+			return err
+		}
+		return enqueueWebhook(ctx, tx, event.User.TenantID, domain.WebhookEventDeleted, event)
+	})
+}
 
+// ApproveEvent approves the event unless it falls in a closed period, whose
+// events are final until it is reopened
+func (s *eventDBStore) ApproveEvent(ctx context.Context, id int, approverID int) (*domain.Event, error) {
 	query := `
-		SELECT
-			e.id, e.type_id, e.user_id, e.name, e.title, e.description,
-			e.start_date, e.end_date, e.road_price,
-			u.id, u.username, u.first_name, u.last_name,
-			et.id,et.type, et.language, et.color, et.is_pricable
-		FROM events e
-		LEFT JOIN users u ON e.user_id = u.id
-		LEFT JOIN event_types et ON e.type_id = et.id
-		WHERE e.start_date >= $1 AND e.end_date <= $2
-		ORDER BY e.start_date`
+		UPDATE events
+		SET status = 'approved', approved_by = $1, approved_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND status <> 'approved'`
 
-	rows, err := s.db.Query(query, startdate, enddate)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+	var approved *domain.Event
+	err := s.db.Transact(ctx, func(tx database.Querier) error {
+		if err := lockEventPeriods(ctx, tx, id); err != nil {
+			return err
+		}
 
-	for rows.Next() {
-		var event domain.Event
-		var user domain.EventUser
-		var eventType domain.EventType
-
-		err := rows.Scan(
-			&event.ID, &event.TypeID, &event.UserID, &event.Name, &event.Title, &event.Description,
-			&event.StartDate, &event.EndDate, &event.RoadPrice,
-			&user.ID, &user.Username, &user.FirstName, &user.LastName,
-			&eventType.ID, &eventType.Type, &eventType.Language, &eventType.Color, &eventType.IsPricable,
-		)
+		result, err := tx.ExecContext(ctx, query, approverID, id)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
 		}
 
-		event.User = &user
-		event.Type = &eventType
-		events = append(events, event)
-	}
-	if err := rows.Err(); err != nil {
+		if approved, err = getEvent(ctx, tx, id); err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrEventApproved
+		}
+		return enqueueWebhook(ctx, tx, approved.User.TenantID, domain.WebhookEventApproved, approved)
+	})
+	if err != nil {
 		return nil, err
 	}
-	return events, nil
+	return approved, nil
+}
 
+// eventSortFields are the sort options accepted by ListEvents
+var eventSortFields = map[string]sortField[domain.Event]{
+	"start_date": {"e.start_date", "timestamptz", func(e *domain.Event) string { return e.StartDate.Format(time.RFC3339Nano) }},
+	"end_date":   {"e.end_date", "timestamptz", func(e *domain.Event) string { return e.EndDate.Format(time.RFC3339Nano) }},
+	"road_price": {"e.road_price", "numeric", func(e *domain.Event) string { return strconv.FormatFloat(e.RoadPrice, 'f', -1, 64) }},
+	"title":      {"e.title", "text", func(e *domain.Event) string { return e.Title }},
 }
 
-func (s *eventDBStore) GetSelfDatedEvents(caller *domain.User, startdate time.Time, enddate time.Time) ([]domain.Event, error) {
+// eventFilter adds the conditions of filter to b, without the cursor
+func eventFilter(b *queryBuilder, filter domain.EventFilter) *queryBuilder {
+	if filter.TenantID != 0 {
+		b.where("e.user_id IN (SELECT id FROM users WHERE tenant_id = ?)", filter.TenantID)
+	}
+	whereDateRange(b, filter.Range)
+	if len(filter.UserIDs) > 0 {
+		b.where("e.user_id = ANY(?)", filter.UserIDs)
+	}
+	if filter.ManagerID != 0 {
+		b.where("e.user_id IN "+reportsSubquery, filter.ManagerID)
+	}
+	if filter.VisibleTo != 0 {
+		b.where("(e.user_id = ? OR e.user_id IN "+reportsSubquery+")", filter.VisibleTo, filter.VisibleTo)
+	}
+	if len(filter.TeamIDs) > 0 {
+		b.where("e.user_id IN "+teamMembersSubquery, filter.TeamIDs)
+	}
+	if len(filter.TypeIDs) > 0 {
+		b.where("e.type_id = ANY(?)", filter.TypeIDs)
+	}
+	if filter.MinPrice != nil {
+		b.where("e.road_price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		b.where("e.road_price <= ?", *filter.MaxPrice)
+	}
+	if filter.Query != "" {
+		pattern := "%" + escapeLike(filter.Query) + "%"
+		b.where("(e.name ILIKE ? OR e.title ILIKE ? OR e.description ILIKE ?)", pattern, pattern, pattern)
+	}
+	return b
+}
+
+func (s *eventDBStore) ListEvents(ctx context.Context, filter domain.EventFilter) ([]domain.Event, *domain.PageInfo, error) {
+	info := &domain.PageInfo{}
+	if filter.WithTotal {
+		b := eventFilter(&queryBuilder{}, filter)
+		var total int
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM events e`+b.whereClause(), b.args...).Scan(&total); err != nil {
+			return nil, nil, err
+		}
+		info.Total = &total
+	}
 
-	events, err := s.GetDatedUserEvents(caller.ID, startdate, enddate)
+	b := eventFilter(&queryBuilder{}, filter)
+	p, err := newPage(b, eventSortFields, "start_date", "e.id", filter.Sort, filter.Cursor, filter.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, eventSelect+b.whereClause()+p.orderLimit(), b.args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	events, info.NextCursor = p.trim(events, func(e *domain.Event) int { return e.ID })
+	return events, info, nil
+}
+
+func (s *eventDBStore) SumEvents(ctx context.Context, filter domain.EventFilter) (*domain.EventTotals, error) {
+	b := eventFilter(&queryBuilder{}, filter)
+	var totals domain.EventTotals
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(e.road_price), 0) FROM events e`+b.whereClause(), b.args...).
+		Scan(&totals.EventCount, &totals.RoadPrice)
 	if err != nil {
 		return nil, err
 	}
-	return events, nil
+	return &totals, nil
 }
 
-func (s *eventDBStore) GetEventType(id int) (*domain.EventType, error) {
+const eventTypeSelect = `
+		SELECT et.id, et.tenant_id, et.type, et.color, et.is_pricable, et.is_archived, et.sort_order,
+		       COALESCE((SELECT json_object_agg(t.locale, t.label) FROM event_type_translations t WHERE t.type_id = et.id), '{}')::text
+		FROM event_types et`
+
+func scanEventType(row rowScanner) (*domain.EventType, error) {
 	var eventType domain.EventType
-	query := `
-		SELECT id, type, language, color, is_pricable
-		FROM event_types
-		WHERE id = $1`
+	var translations string
 
-	err := s.db.QueryRow(query, id).Scan(
-		&eventType.ID, &eventType.Type, &eventType.Language, &eventType.Color, &eventType.IsPricable,
+	err := row.Scan(
+		&eventType.ID, &eventType.TenantID, &eventType.Type, &eventType.Color, &eventType.IsPricable,
+		&eventType.IsArchived, &eventType.SortOrder, &translations,
 	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(translations), &eventType.Translations); err != nil {
+		return nil, err
+	}
+	return &eventType, nil
+}
 
+func (s *eventDBStore) GetEventType(ctx context.Context, tenantID int, id int) (*domain.EventType, error) {
+	eventType, err := scanEventType(s.db.QueryRowContext(ctx, eventTypeSelect+`
+		WHERE et.id = $1 AND (et.tenant_id IS NULL OR et.tenant_id = $2)`, id, tenantID))
 	if err == sql.ErrNoRows {
-		var ErrEventTypeNotFound = errors.New("event type not found")
 		return nil, ErrEventTypeNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
-	return &eventType, nil
+	return eventType, nil
 }
 
-func (s *eventDBStore) GetEventTypes() ([]domain.EventType, error) {
-	var eventTypes []domain.EventType
-	query := `
-		SELECT id, type, language, color, is_pricable
-		FROM event_types`
-
-	rows, err := s.db.Query(query)
+// GetEventTypes lists the shared types first, then the tenant's own
+func (s *eventDBStore) GetEventTypes(ctx context.Context, tenantID int, includeArchived bool) ([]domain.EventType, error) {
+	rows, err := s.db.QueryContext(ctx, eventTypeSelect+`
+		WHERE (et.tenant_id IS NULL OR et.tenant_id = $1)
+		  AND ($2 OR NOT et.is_archived)
+		ORDER BY et.tenant_id NULLS FIRST, et.sort_order, et.id`, tenantID, includeArchived)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	var eventTypes []domain.EventType
 	for rows.Next() {
-		var eventType domain.EventType
-		err := rows.Scan(
-			&eventType.ID, &eventType.Type, &eventType.Language, &eventType.Color, &eventType.IsPricable,
-		)
+		eventType, err := scanEventType(rows)
 		if err != nil {
 			return nil, err
 		}
-		eventTypes = append(eventTypes, eventType)
+		eventTypes = append(eventTypes, *eventType)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return eventTypes, nil
+}
 
+// translationsJSON encodes translations for json_each_text, nil becomes an empty object
+func translationsJSON(translations map[string]string) (string, error) {
+	if translations == nil {
+		translations = map[string]string{}
+	}
+	b, err := json.Marshal(translations)
+	return string(b), err
+}
+
+func (s *eventDBStore) CreateEventType(ctx context.Context, eventType *domain.EventType) error {
+	translations, err := translationsJSON(eventType.Translations)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH created AS (
+			INSERT INTO event_types (tenant_id, type, color, is_pricable, sort_order)
+			VALUES ($5, $1, $2, $3, COALESCE((SELECT MAX(sort_order) + 1 FROM event_types WHERE tenant_id = $5), 0))
+			RETURNING id, sort_order
+		), translated AS (
+			INSERT INTO event_type_translations (type_id, locale, label)
+			SELECT created.id, t.key, t.value FROM created, json_each_text($4::json) t
+		)
+		SELECT id, sort_order FROM created`
+
+	return s.db.QueryRowContext(ctx, query, eventType.Type, eventType.Color, eventType.IsPricable, translations, eventType.TenantID).Scan(&eventType.ID, &eventType.SortOrder)
+}
+
+func (s *eventDBStore) UpdateEventType(ctx context.Context, eventType *domain.EventType) error {
+	translations, err := translationsJSON(eventType.Translations)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		WITH updated AS (
+			UPDATE event_types
+			SET type = $1, color = $2, is_pricable = $3
+			WHERE id = $4 AND tenant_id = $6
+			RETURNING id
+		), removed AS (
+			DELETE FROM event_type_translations
+			WHERE type_id IN (SELECT id FROM updated)
+			  AND locale NOT IN (SELECT key FROM json_each_text($5::json))
+		), upserted AS (
+			INSERT INTO event_type_translations (type_id, locale, label)
+			SELECT updated.id, t.key, t.value FROM updated, json_each_text($5::json) t
+			ON CONFLICT (type_id, locale) DO UPDATE SET label = EXCLUDED.label
+		)
+		SELECT id FROM updated`
+
+	err = s.db.QueryRowContext(ctx, query, eventType.Type, eventType.Color, eventType.IsPricable, eventType.ID, translations, eventType.TenantID).Scan(&eventType.ID)
+	if err == sql.ErrNoRows {
+		return ErrEventTypeNotFound
+	}
+	return err
+}
+
+func (s *eventDBStore) SetEventTypeArchived(ctx context.Context, tenantID int, id int, archived bool) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE event_types SET is_archived = $1 WHERE id = $2 AND tenant_id = $3`, archived, id, tenantID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrEventTypeNotFound
+	}
+	return nil
+}
+
+func (s *eventDBStore) ReorderEventTypes(ctx context.Context, tenantID int, ids []int) error {
+	return s.db.Transact(ctx, func(tx database.Querier) error {
+		// Locking the tenant's types keeps a type created meanwhile out of the order
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id FROM event_types WHERE tenant_id = $1 ORDER BY id FOR UPDATE`, tenantID)
+		if err != nil {
+			return err
+		}
+		var existing []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			existing = append(existing, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if !slices.Equal(slices.Sorted(slices.Values(ids)), existing) {
+			return ErrInvalidEventTypeOrder
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE event_types et
+			SET sort_order = o.position
+			FROM unnest($1::int[]) WITH ORDINALITY AS o(id, position)
+			WHERE et.id = o.id AND et.tenant_id = $2`, ids, tenantID)
+		return err
+	})
 }
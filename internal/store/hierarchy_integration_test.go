@@ -0,0 +1,207 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"pwp-remastered/internal/config"
+	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/domain"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+// startPostgres runs a PostgreSQL server with every migration applied
+func startPostgres(t *testing.T) database.Service {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("starts a PostgreSQL container")
+	}
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctx := context.Background()
+	container, err := postgres.Run(ctx, "postgres:16-alpine",
+		postgres.WithDatabase("pwp"),
+		postgres.WithUsername("pwp"),
+		postgres.WithPassword("pwp"),
+		postgres.BasicWaitStrategies(),
+	)
+	testcontainers.CleanupContainer(t, container)
+	if err != nil {
+		t.Fatalf("could not start postgres container: %v", err)
+	}
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := container.MappedPort(ctx, "5432/tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := database.New(config.Database{
+		Host:             host,
+		Port:             port.Int(),
+		Name:             "pwp",
+		User:             "pwp",
+		Password:         "pwp",
+		Schema:           "public",
+		SSLMode:          "disable",
+		MaxOpenConns:     4,
+		MaxIdleConns:     4,
+		StatementTimeout: 5 * time.Second,
+		ApplicationName:  "pwp-test",
+	})
+	t.Cleanup(func() { db.Close() })
+
+	// Glob sorts the names, so the migrations run in version order
+	names, err := filepath.Glob("../../migrations/*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		migration, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.ExecContext(ctx, string(migration)); err != nil {
+			t.Fatalf("%s: %v", filepath.Base(name), err)
+		}
+	}
+	return db
+}
+
+// hierarchySeed builds two tenants. In tenant 1 the director (1) manages the
+// manager (2) who manages the report (3), the colleague (4) has no manager,
+// and 7 and 8 manage each other. In tenant 2 user 6 names the manager of
+// tenant 1 as theirs and manages 9. Team 10 of tenant 1 holds 2 and 4, every
+// user has one event with the user's id times ten.
+const hierarchySeed = `
+	INSERT INTO users (id, username, hashed_password, email, tenant_id) VALUES
+		(1, 'director', '', 'director@example.org', 1),
+		(2, 'manager', '', 'manager@example.org', 1),
+		(3, 'report', '', 'report@example.org', 1),
+		(4, 'colleague', '', 'colleague@example.org', 1),
+		(6, 'outsider', '', 'outsider@example.org', 2),
+		(7, 'first', '', 'first@example.org', 1),
+		(8, 'second', '', 'second@example.org', 1),
+		(9, 'outsider-report', '', 'outsider-report@example.org', 2);
+
+	UPDATE users u SET manager_id = m.manager_id
+	FROM (VALUES (2, 1), (3, 2), (6, 2), (7, 8), (8, 7), (9, 6)) AS m(id, manager_id)
+	WHERE u.id = m.id;
+
+	INSERT INTO teams (id, tenant_id, name) VALUES (10, 1, 'Satış');
+	INSERT INTO team_members (team_id, user_id) VALUES (10, 2), (10, 4);
+
+	INSERT INTO event_types (id, type) VALUES (1, 'Yol');
+	INSERT INTO events (id, type_id, user_id, name, title, start_date, end_date)
+	SELECT id * 10, 1, id, 'Ziyaret', 'Ziyaret', '2025-01-02T09:00:00Z', '2025-01-02T10:00:00Z'
+	FROM users;`
+
+func TestReportingHierarchy(t *testing.T) {
+	db := startPostgres(t)
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, hierarchySeed); err != nil {
+		t.Fatal(err)
+	}
+	users := NewUserStore(db)
+	events := NewEventStore(db)
+
+	t.Run("IsManagerOf", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			managerID int
+			userID    int
+			want      bool
+		}{
+			{"direct report", 2, 3, true},
+			{"report of a report", 1, 3, true},
+			{"own manager", 3, 2, false},
+			{"colleague", 4, 3, false},
+			{"manager in another tenant", 2, 6, false},
+			{"report of a manager in another tenant", 2, 9, false},
+			{"manager inside the other tenant", 6, 9, true},
+			{"cycle", 7, 8, true},
+			{"outside a cycle", 7, 3, false},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := users.IsManagerOf(ctx, tt.managerID, tt.userID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Errorf("IsManagerOf(%d, %d) = %t, want %t", tt.managerID, tt.userID, got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("ListUsers", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			filter domain.UserFilter
+			want   []int
+		}{
+			{"reports at any depth", domain.UserFilter{ManagerID: 1}, []int{2, 3}},
+			{"reports within the tenant", domain.UserFilter{ManagerID: 2}, []int{3}},
+			{"cycle", domain.UserFilter{ManagerID: 7}, []int{7, 8}},
+			{"team members", domain.UserFilter{TeamIDs: []int{10}}, []int{2, 4}},
+			{"reports in the team", domain.UserFilter{ManagerID: 1, TeamIDs: []int{10}}, []int{2}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				list, _, err := users.ListUsers(ctx, tt.filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []int
+				for _, user := range list {
+					got = append(got, user.ID)
+				}
+				slices.Sort(got)
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("users = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	})
+
+	// GetTeamDatedEvents lists with the caller as ManagerID and the team_id
+	// parameter as TeamIDs
+	t.Run("ListEvents", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			filter domain.EventFilter
+			want   []int
+		}{
+			{"reports at any depth", domain.EventFilter{ManagerID: 1}, []int{20, 30}},
+			{"reports within the tenant", domain.EventFilter{ManagerID: 2}, []int{30}},
+			{"manager inside the other tenant", domain.EventFilter{ManagerID: 6}, []int{90}},
+			{"cycle", domain.EventFilter{ManagerID: 8}, []int{70, 80}},
+			{"reports in the team", domain.EventFilter{ManagerID: 1, TeamIDs: []int{10}}, []int{20}},
+			{"no reports", domain.EventFilter{ManagerID: 4}, nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				list, _, err := events.ListEvents(ctx, tt.filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []int
+				for _, event := range list {
+					got = append(got, event.ID)
+				}
+				slices.Sort(got)
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("events = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	})
+}
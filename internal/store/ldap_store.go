@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/domain"
+)
+
+var ErrLDAPConfigNotFound = domain.NewNotFoundError("ldap_config_not_found", "tenant has no LDAP configuration")
+
+// LDAPStore handles the LDAP configuration of tenants
+type LDAPStore interface {
+	GetLDAPConfig(ctx context.Context, tenantID int) (*domain.LDAPConfig, error)
+	// UpdateLDAPConfig keeps the stored bind password when config has none
+	UpdateLDAPConfig(ctx context.Context, config *domain.LDAPConfig) error
+	DeleteLDAPConfig(ctx context.Context, tenantID int) error
+}
+
+type ldapDBStore struct {
+	db database.Service
+}
+
+// NewLDAPStore creates a new LDAPStore instance
+func NewLDAPStore(db database.Service) LDAPStore {
+	return &ldapDBStore{db: db}
+}
+
+func (s *ldapDBStore) GetLDAPConfig(ctx context.Context, tenantID int) (*domain.LDAPConfig, error) {
+	config := domain.LDAPConfig{TenantID: tenantID}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT enabled, url, start_tls, bind_dn, bind_password, base_dn, user_filter,
+		       email_attribute, first_name_attribute, last_name_attribute,
+		       group_base_dn, group_filter, admin_group, accountant_group, updated_at
+		FROM tenant_ldap
+		WHERE tenant_id = $1`,
+		tenantID,
+	).Scan(
+		&config.Enabled, &config.URL, &config.StartTLS, &config.BindDN, &config.BindPassword,
+		&config.BaseDN, &config.UserFilter, &config.EmailAttribute, &config.FirstNameAttribute,
+		&config.LastNameAttribute, &config.GroupBaseDN, &config.GroupFilter, &config.AdminGroup,
+		&config.AccountantGroup, &config.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrLDAPConfigNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (s *ldapDBStore) UpdateLDAPConfig(ctx context.Context, config *domain.LDAPConfig) error {
+	return s.db.QueryRowContext(ctx, `
+		INSERT INTO tenant_ldap (
+		    tenant_id, enabled, url, start_tls, bind_dn, bind_password, base_dn, user_filter,
+		    email_attribute, first_name_attribute, last_name_attribute,
+		    group_base_dn, group_filter, admin_group, accountant_group, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, CURRENT_TIMESTAMP)
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled, url = EXCLUDED.url, start_tls = EXCLUDED.start_tls,
+		    bind_dn = EXCLUDED.bind_dn,
+		    bind_password = CASE WHEN EXCLUDED.bind_password = '' THEN tenant_ldap.bind_password
+		                         ELSE EXCLUDED.bind_password END,
+		    base_dn = EXCLUDED.base_dn, user_filter = EXCLUDED.user_filter,
+		    email_attribute = EXCLUDED.email_attribute,
+		    first_name_attribute = EXCLUDED.first_name_attribute,
+		    last_name_attribute = EXCLUDED.last_name_attribute,
+		    group_base_dn = EXCLUDED.group_base_dn, group_filter = EXCLUDED.group_filter,
+		    admin_group = EXCLUDED.admin_group, accountant_group = EXCLUDED.accountant_group,
+		    updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`,
+		config.TenantID, config.Enabled, config.URL, config.StartTLS, config.BindDN, config.BindPassword,
+		config.BaseDN, config.UserFilter, config.EmailAttribute, config.FirstNameAttribute,
+		config.LastNameAttribute, config.GroupBaseDN, config.GroupFilter, config.AdminGroup,
+		config.AccountantGroup,
+	).Scan(&config.UpdatedAt)
+}
+
+func (s *ldapDBStore) DeleteLDAPConfig(ctx context.Context, tenantID int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM tenant_ldap WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLDAPConfigNotFound
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/domain"
+)
+
+var (
+	ErrLocationNotFound = domain.NewNotFoundError("location_not_found", "location not found")
+	ErrRouteNotFound    = domain.NewNotFoundError("route_not_found", "route not found")
+)
+
+// LocationStore handles the per-tenant saved locations registry
+type LocationStore interface {
+	GetLocation(ctx context.Context, tenantID int, id int) (*domain.Location, error)
+	// SearchLocations matches query against name and address, and filters by tag when given
+	SearchLocations(ctx context.Context, tenantID int, query string, tag string) ([]domain.Location, error)
+	CreateLocation(context.Context, *domain.Location) error
+	UpdateLocation(context.Context, *domain.Location) error
+	DeleteLocation(ctx context.Context, tenantID int, id int) error
+	GetRouteDistance(ctx context.Context, fromID int, toID int) (float64, error)
+	SetRouteDistance(context.Context, *domain.LocationRoute) error
+}
+
+type locationDBStore struct {
+	db database.Service
+}
+
+func NewLocationStore(db database.Service) LocationStore {
+	return &locationDBStore{db: db}
+}
+
+const locationSelect = `
+		SELECT id, tenant_id, name, address, latitude, longitude,
+		       COALESCE(array_to_json(tags)::text, '[]'), created_at, updated_at
+		FROM locations`
+
+func scanLocation(row rowScanner) (*domain.Location, error) {
+	var location domain.Location
+	var tags string
+
+	err := row.Scan(
+		&location.ID, &location.TenantID, &location.Name, &location.Address,
+		&location.Latitude, &location.Longitude, &tags, &location.CreatedAt, &location.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(tags), &location.Tags); err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+func (s *locationDBStore) GetLocation(ctx context.Context, tenantID int, id int) (*domain.Location, error) {
+	location, err := scanLocation(s.db.QueryRowContext(ctx, locationSelect+`
+		WHERE tenant_id = $1 AND id = $2`, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrLocationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return location, nil
+}
+
+func (s *locationDBStore) SearchLocations(ctx context.Context, tenantID int, query string, tag string) ([]domain.Location, error) {
+	rows, err := s.db.QueryContext(ctx, locationSelect+`
+		WHERE tenant_id = $1
+		  AND ($2 = '' OR name ILIKE '%' || $2 || '%' OR address ILIKE '%' || $2 || '%')
+		  AND ($3 = '' OR $3 = ANY(tags))
+		ORDER BY name`, tenantID, query, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := []domain.Location{}
+	for rows.Next() {
+		location, err := scanLocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, *location)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+func (s *locationDBStore) CreateLocation(ctx context.Context, location *domain.Location) error {
+	query := `
+		INSERT INTO locations (tenant_id, name, address, latitude, longitude, tags)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at`
+
+	err := s.db.QueryRowContext(ctx,
+		query,
+		location.TenantID, location.Name, location.Address,
+		location.Latitude, location.Longitude, location.Tags,
+	).Scan(&location.ID, &location.CreatedAt, &location.UpdatedAt)
+	return uniqueViolation(err, ErrLocationExists)
+}
+
+func (s *locationDBStore) UpdateLocation(ctx context.Context, location *domain.Location) error {
+	query := `
+		UPDATE locations
+		SET name = $1, address = $2, latitude = $3, longitude = $4, tags = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE tenant_id = $6 AND id = $7
+		RETURNING created_at, updated_at`
+
+	err := s.db.QueryRowContext(ctx,
+		query,
+		location.Name, location.Address, location.Latitude, location.Longitude, location.Tags,
+		location.TenantID, location.ID,
+	).Scan(&location.CreatedAt, &location.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrLocationNotFound
+	}
+	return uniqueViolation(err, ErrLocationExists)
+}
+
+func (s *locationDBStore) DeleteLocation(ctx context.Context, tenantID int, id int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM locations WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrLocationNotFound
+	}
+	return nil
+}
+
+func (s *locationDBStore) GetRouteDistance(ctx context.Context, fromID int, toID int) (float64, error) {
+	var distance float64
+	query := `
+		SELECT distance_km
+		FROM location_routes
+		WHERE (from_location_id = $1 AND to_location_id = $2)
+		   OR (from_location_id = $2 AND to_location_id = $1)
+		LIMIT 1`
+
+	err := s.db.QueryRowContext(ctx, query, fromID, toID).Scan(&distance)
+	if err == sql.ErrNoRows {
+		return 0, ErrRouteNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return distance, nil
+}
+
+func (s *locationDBStore) SetRouteDistance(ctx context.Context, route *domain.LocationRoute) error {
+	query := `
+		INSERT INTO location_routes (from_location_id, to_location_id, distance_km)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (from_location_id, to_location_id) DO UPDATE SET distance_km = EXCLUDED.distance_km`
+
+	_, err := s.db.ExecContext(ctx, query, route.FromLocationID, route.ToLocationID, route.DistanceKm)
+	return err
+}
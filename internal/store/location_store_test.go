@@ -0,0 +1,28 @@
+package store
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+)
+
+func TestEventLocationResolve(t *testing.T) {
+	// No location joined
+	none := eventLocation{tags: "[]"}
+	if location, err := none.resolve(); err != nil || location != nil {
+		t.Fatalf("resolve() = %+v, %v, want nil", location, err)
+	}
+
+	joined := eventLocation{
+		id:   sql.NullInt64{Int64: 4, Valid: true},
+		name: sql.NullString{String: "Merkez Ofis", Valid: true},
+		tags: `["ofis","İstanbul"]`,
+	}
+	location, err := joined.resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.ID != 4 || location.Name != "Merkez Ofis" || !slices.Equal(location.Tags, []string{"ofis", "İstanbul"}) {
+		t.Errorf("resolve() = %+v", location)
+	}
+}
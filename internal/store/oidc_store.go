@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/domain"
+	"time"
+)
+
+var (
+	ErrOIDCConfigNotFound = domain.NewNotFoundError("oidc_config_not_found", "tenant has no OpenID Connect configuration")
+	ErrOIDCLoginNotFound  = domain.NewUnauthorizedError("invalid_oidc_state", "login request is unknown or expired, start the login again")
+)
+
+// OIDCLoginTTL is how long a login may take at the identity provider
+const OIDCLoginTTL = 10 * time.Minute
+
+// OIDCStore handles the OpenID Connect configuration of tenants and the
+// pending authorization requests
+type OIDCStore interface {
+	GetOIDCConfig(ctx context.Context, tenantID int) (*domain.OIDCConfig, error)
+	// UpdateOIDCConfig keeps the stored client secret when config has none
+	UpdateOIDCConfig(ctx context.Context, config *domain.OIDCConfig) error
+	DeleteOIDCConfig(ctx context.Context, tenantID int) error
+	// CreateLogin stores a login and drops the expired ones
+	CreateLogin(ctx context.Context, login *domain.OIDCLogin) error
+	// ConsumeLogin removes and returns the login with state, a state can only
+	// be used once
+	ConsumeLogin(ctx context.Context, state string) (*domain.OIDCLogin, error)
+}
+
+type oidcDBStore struct {
+	db database.Service
+}
+
+// NewOIDCStore creates a new OIDCStore instance
+func NewOIDCStore(db database.Service) OIDCStore {
+	return &oidcDBStore{db: db}
+}
+
+func (s *oidcDBStore) GetOIDCConfig(ctx context.Context, tenantID int) (*domain.OIDCConfig, error) {
+	config := domain.OIDCConfig{TenantID: tenantID}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT enabled, issuer, client_id, client_secret, scopes, username_claim, email_claim,
+		       first_name_claim, last_name_claim, roles_claim, admin_role, accountant_role,
+		       redirect_url, updated_at
+		FROM tenant_oidc
+		WHERE tenant_id = $1`,
+		tenantID,
+	).Scan(
+		&config.Enabled, &config.Issuer, &config.ClientID, &config.ClientSecret, &config.Scopes,
+		&config.UsernameClaim, &config.EmailClaim, &config.FirstNameClaim, &config.LastNameClaim,
+		&config.RolesClaim, &config.AdminRole, &config.AccountantRole, &config.RedirectURL,
+		&config.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrOIDCConfigNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (s *oidcDBStore) UpdateOIDCConfig(ctx context.Context, config *domain.OIDCConfig) error {
+	return s.db.QueryRowContext(ctx, `
+		INSERT INTO tenant_oidc (
+		    tenant_id, enabled, issuer, client_id, client_secret, scopes, username_claim,
+		    email_claim, first_name_claim, last_name_claim, roles_claim, admin_role,
+		    accountant_role, redirect_url, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, CURRENT_TIMESTAMP)
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled, issuer = EXCLUDED.issuer, client_id = EXCLUDED.client_id,
+		    client_secret = CASE WHEN EXCLUDED.client_secret = '' THEN tenant_oidc.client_secret
+		                         ELSE EXCLUDED.client_secret END,
+		    scopes = EXCLUDED.scopes, username_claim = EXCLUDED.username_claim,
+		    email_claim = EXCLUDED.email_claim, first_name_claim = EXCLUDED.first_name_claim,
+		    last_name_claim = EXCLUDED.last_name_claim, roles_claim = EXCLUDED.roles_claim,
+		    admin_role = EXCLUDED.admin_role, accountant_role = EXCLUDED.accountant_role,
+		    redirect_url = EXCLUDED.redirect_url, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`,
+		config.TenantID, config.Enabled, config.Issuer, config.ClientID, config.ClientSecret,
+		config.Scopes, config.UsernameClaim, config.EmailClaim, config.FirstNameClaim,
+		config.LastNameClaim, config.RolesClaim, config.AdminRole, config.AccountantRole,
+		config.RedirectURL,
+	).Scan(&config.UpdatedAt)
+}
+
+func (s *oidcDBStore) DeleteOIDCConfig(ctx context.Context, tenantID int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM tenant_oidc WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrOIDCConfigNotFound
+	}
+	return nil
+}
+
+func (s *oidcDBStore) CreateLogin(ctx context.Context, login *domain.OIDCLogin) error {
+	return s.db.Transact(ctx, func(tx database.Querier) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM oidc_logins WHERE created_at < $1`, time.Now().Add(-OIDCLoginTTL)); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO oidc_logins (state, tenant_id, code_verifier, nonce, redirect_uri)
+			VALUES ($1, $2, $3, $4, $5)`,
+			login.State, login.TenantID, login.CodeVerifier, login.Nonce, login.RedirectURI,
+		)
+		return err
+	})
+}
+
+func (s *oidcDBStore) ConsumeLogin(ctx context.Context, state string) (*domain.OIDCLogin, error) {
+	login := domain.OIDCLogin{State: state}
+	err := s.db.QueryRowContext(ctx, `
+		DELETE FROM oidc_logins
+		WHERE state = $1 AND created_at >= $2
+		RETURNING tenant_id, code_verifier, nonce, redirect_uri`,
+		state, time.Now().Add(-OIDCLoginTTL),
+	).Scan(&login.TenantID, &login.CodeVerifier, &login.Nonce, &login.RedirectURI)
+	if err == sql.ErrNoRows {
+		return nil, ErrOIDCLoginNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"pwp-remastered/internal/domain"
+	"strings"
+)
+
+var (
+	ErrInvalidCursor = domain.NewValidationError("invalid_cursor", "invalid pagination cursor")
+	ErrInvalidSort   = domain.NewValidationError("invalid_sort", "invalid sort field")
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// queryBuilder collects WHERE conditions and their arguments. Conditions use
+// "?" for values, which are renumbered to positional parameters so user input
+// never ends up in the SQL text.
+type queryBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (b *queryBuilder) where(condition string, args ...interface{}) {
+	var sb strings.Builder
+	for _, arg := range args {
+		i := strings.IndexByte(condition, '?')
+		sb.WriteString(condition[:i])
+		sb.WriteString(b.arg(arg))
+		condition = condition[i+1:]
+	}
+	sb.WriteString(condition)
+	b.conditions = append(b.conditions, sb.String())
+}
+
+// arg binds v and returns its placeholder, for values used outside WHERE
+func (b *queryBuilder) arg(v interface{}) string {
+	b.args = append(b.args, v)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+func (b *queryBuilder) whereClause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+	return "\n\t\tWHERE " + strings.Join(b.conditions, "\n\t\t  AND ")
+}
+
+// escapeLike escapes the LIKE wildcards in a user supplied search term
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// sortField describes a sortable column. key renders the value of the last row
+// on a page into the cursor, cast turns it back into the column type.
+type sortField[T any] struct {
+	column string
+	cast   string
+	key    func(*T) string
+}
+
+type cursor struct {
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func encodeCursor(value string, id int) string {
+	b, _ := json.Marshal(cursor{Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// page is the resolved sort and keyset position of a list query
+type page[T any] struct {
+	field      sortField[T]
+	descending bool
+	limit      int
+	idColumn   string
+}
+
+// newPage resolves sort against fields and applies the cursor as a keyset
+// condition on (sort column, id) to b.
+func newPage[T any](b *queryBuilder, fields map[string]sortField[T], defaultSort string, idColumn string, sort string, after string, limit int) (*page[T], error) {
+	if sort == "" {
+		sort = defaultSort
+	}
+	descending := strings.HasPrefix(sort, "-")
+	field, ok := fields[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return nil, ErrInvalidSort
+	}
+
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	limit = min(limit, MaxPageSize)
+
+	p := &page[T]{field: field, descending: descending, limit: limit, idColumn: idColumn}
+	if after != "" {
+		c, err := decodeCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		op := ">"
+		if descending {
+			op = "<"
+		}
+		b.where(fmt.Sprintf("(%s, %s) %s (?::%s, ?)", field.column, idColumn, op, field.cast), c.Value, c.ID)
+	}
+	return p, nil
+}
+
+// orderLimit returns the ORDER BY and LIMIT clause. One extra row is fetched
+// to find out whether there is a next page.
+func (p *page[T]) orderLimit() string {
+	dir := "ASC"
+	if p.descending {
+		dir = "DESC"
+	}
+	return fmt.Sprintf("\n\t\tORDER BY %s %s, %s %s\n\t\tLIMIT %d", p.field.column, dir, p.idColumn, dir, p.limit+1)
+}
+
+// trim drops the extra row and returns the cursor of the following page
+func (p *page[T]) trim(items []T, id func(*T) int) ([]T, string) {
+	if len(items) <= p.limit {
+		return items, ""
+	}
+	items = items[:p.limit]
+	last := &items[len(items)-1]
+	return items, encodeCursor(p.field.key(last), id(last))
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"errors"
+	"pwp-remastered/internal/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryBuilderNumbersParameters(t *testing.T) {
+	b := &queryBuilder{}
+	b.where("a = ?", 1)
+	b.where("(b ILIKE ? OR c ILIKE ?)", "%x%", "%x%")
+
+	want := "\n\t\tWHERE a = $1\n\t\t  AND (b ILIKE $2 OR c ILIKE $3)"
+	if got := b.whereClause(); got != want {
+		t.Fatalf("whereClause() = %q, want %q", got, want)
+	}
+	if len(b.args) != 3 {
+		t.Fatalf("got %d args, want 3", len(b.args))
+	}
+}
+
+func TestEventPageKeyset(t *testing.T) {
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	filter := domain.EventFilter{Range: domain.DateRange{From: start, To: start.AddDate(0, 1, 0), Mode: domain.RangeStartsWithin}}
+	filter.Sort = "-start_date"
+	filter.Limit = 2
+
+	b := eventFilter(&queryBuilder{}, filter)
+	p, err := newPage(b, eventSortFields, "start_date", "e.id", filter.Sort, "", filter.Limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(p.orderLimit(), "ORDER BY e.start_date DESC, e.id DESC") || !strings.HasSuffix(p.orderLimit(), "LIMIT 3") {
+		t.Fatalf("unexpected order clause %q", p.orderLimit())
+	}
+
+	events := []domain.Event{{ID: 3, StartDate: start}, {ID: 2, StartDate: start}, {ID: 1, StartDate: start}}
+	events, next := p.trim(events, func(e *domain.Event) int { return e.ID })
+	if len(events) != 2 || next == "" {
+		t.Fatalf("trim returned %d events and cursor %q", len(events), next)
+	}
+
+	b = eventFilter(&queryBuilder{}, filter)
+	if _, err := newPage(b, eventSortFields, "start_date", "e.id", filter.Sort, next, filter.Limit); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.whereClause(), "(e.start_date, e.id) < ($3::timestamptz, $4)") {
+		t.Fatalf("cursor condition missing from %q", b.whereClause())
+	}
+	if b.args[2] != start.Format(time.RFC3339Nano) || b.args[3] != 2 {
+		t.Fatalf("unexpected cursor args %v", b.args[2:])
+	}
+}
+
+func TestPageRejectsBadInput(t *testing.T) {
+	if _, err := newPage(&queryBuilder{}, eventSortFields, "start_date", "e.id", "hashed_password", "", 0); !errors.Is(err, ErrInvalidSort) {
+		t.Fatalf("sort: got %v, want ErrInvalidSort", err)
+	}
+	if _, err := newPage(&queryBuilder{}, eventSortFields, "start_date", "e.id", "", "not a cursor", 0); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("cursor: got %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	if got := escapeLike(`50%_off\`); got != `50\%\_off\\` {
+		t.Fatalf("escapeLike() = %q", got)
+	}
+}
@@ -0,0 +1,259 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/domain"
+	"time"
+)
+
+var (
+	ErrPeriodNotFound      = domain.NewNotFoundError("period_not_found", "accounting period not found")
+	ErrPeriodOverlaps      = domain.NewConflictError("period_overlaps", "the period overlaps another accounting period")
+	ErrPeriodAlreadyClosed = domain.NewConflictError("period_already_closed", "the accounting period is already closed")
+	ErrPeriodNotClosed     = domain.NewConflictError("period_not_closed", "the accounting period is not closed")
+	ErrPeriodClosed        = domain.NewConflictError("period_closed", "the event falls in a closed accounting period")
+)
+
+// PeriodClosed returns ErrPeriodClosed naming the closed period
+func PeriodClosed(period *domain.AccountingPeriod) error {
+	return ErrPeriodClosed.WithDetails(map[string]any{
+		"period_id":    period.ID,
+		"period_start": period.StartDate,
+		"period_end":   period.EndDate,
+	})
+}
+
+// PeriodStore handles the accounting periods of a tenant and their history
+type PeriodStore interface {
+	// GetPeriod returns a period with its history
+	GetPeriod(ctx context.Context, tenantID int, id int) (*domain.AccountingPeriod, error)
+	ListPeriods(ctx context.Context, tenantID int) ([]domain.AccountingPeriod, error)
+	// CreatePeriod adds an open period, it must not overlap the tenant's other periods
+	CreatePeriod(ctx context.Context, period *domain.AccountingPeriod, userID int) error
+	ClosePeriod(ctx context.Context, tenantID int, id int, userID int) error
+	ReopenPeriod(ctx context.Context, tenantID int, id int, userID int) error
+	// ClosedPeriodOverlapping returns a closed period of the tenant containing any
+	// day from start to end in the tenant's time zone, or nil
+	ClosedPeriodOverlapping(ctx context.Context, tenantID int, start time.Time, end time.Time) (*domain.AccountingPeriod, error)
+}
+
+type periodDBStore struct {
+	db database.Service
+}
+
+func NewPeriodStore(db database.Service) PeriodStore {
+	return &periodDBStore{db: db}
+}
+
+const periodSelect = `
+		SELECT p.id, p.tenant_id, p.start_date::text, p.end_date::text, p.status, p.closed_by, p.closed_at, p.created_at
+		FROM accounting_periods p`
+
+func scanPeriod(row rowScanner) (*domain.AccountingPeriod, error) {
+	var period domain.AccountingPeriod
+	err := row.Scan(
+		&period.ID, &period.TenantID, &period.StartDate, &period.EndDate,
+		&period.Status, &period.ClosedBy, &period.ClosedAt, &period.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &period, nil
+}
+
+func (s *periodDBStore) GetPeriod(ctx context.Context, tenantID int, id int) (*domain.AccountingPeriod, error) {
+	period, err := scanPeriod(s.db.QueryRowContext(ctx, periodSelect+`
+		WHERE p.tenant_id = $1 AND p.id = $2`, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrPeriodNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT l.action, l.user_id, u.username, l.created_at
+		FROM accounting_period_log l
+		LEFT JOIN users u ON u.id = l.user_id
+		WHERE l.period_id = $1
+		ORDER BY l.created_at, l.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	period.History = []domain.PeriodHistoryItem{}
+	for rows.Next() {
+		var item domain.PeriodHistoryItem
+		if err := rows.Scan(&item.Action, &item.UserID, &item.Username, &item.At); err != nil {
+			return nil, err
+		}
+		period.History = append(period.History, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return period, nil
+}
+
+func (s *periodDBStore) ListPeriods(ctx context.Context, tenantID int) ([]domain.AccountingPeriod, error) {
+	rows, err := s.db.QueryContext(ctx, periodSelect+`
+		WHERE p.tenant_id = $1
+		ORDER BY p.start_date DESC`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	periods := []domain.AccountingPeriod{}
+	for rows.Next() {
+		period, err := scanPeriod(rows)
+		if err != nil {
+			return nil, err
+		}
+		periods = append(periods, *period)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return periods, nil
+}
+
+func (s *periodDBStore) CreatePeriod(ctx context.Context, period *domain.AccountingPeriod, userID int) error {
+	return s.db.Transact(ctx, func(tx database.Querier) error {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO accounting_periods (tenant_id, start_date, end_date)
+			SELECT $1, $2::date, $3::date
+			WHERE NOT EXISTS (
+				SELECT 1 FROM accounting_periods
+				WHERE tenant_id = $1 AND start_date <= $3::date AND end_date >= $2::date
+			)
+			RETURNING id, status, created_at`,
+			period.TenantID, period.StartDate, period.EndDate,
+		).Scan(&period.ID, &period.Status, &period.CreatedAt)
+		if err == sql.ErrNoRows {
+			return ErrPeriodOverlaps
+		}
+		if err != nil {
+			return err
+		}
+		return logPeriodAction(ctx, tx, period.ID, domain.PeriodActionCreated, userID)
+	})
+}
+
+func (s *periodDBStore) ClosePeriod(ctx context.Context, tenantID int, id int, userID int) error {
+	return s.db.Transact(ctx, func(tx database.Querier) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE accounting_periods
+			SET status = 'closed', closed_by = $3, closed_at = CURRENT_TIMESTAMP
+			WHERE tenant_id = $1 AND id = $2 AND status = 'open'`,
+			tenantID, id, userID)
+		if err != nil {
+			return err
+		}
+		if err := s.checkTransition(ctx, tx, result, tenantID, id, ErrPeriodAlreadyClosed); err != nil {
+			return err
+		}
+		return logPeriodAction(ctx, tx, id, domain.PeriodActionClosed, userID)
+	})
+}
+
+func (s *periodDBStore) ReopenPeriod(ctx context.Context, tenantID int, id int, userID int) error {
+	return s.db.Transact(ctx, func(tx database.Querier) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE accounting_periods
+			SET status = 'open', closed_by = NULL, closed_at = NULL
+			WHERE tenant_id = $1 AND id = $2 AND status = 'closed'`,
+			tenantID, id)
+		if err != nil {
+			return err
+		}
+		if err := s.checkTransition(ctx, tx, result, tenantID, id, ErrPeriodNotClosed); err != nil {
+			return err
+		}
+		return logPeriodAction(ctx, tx, id, domain.PeriodActionReopened, userID)
+	})
+}
+
+// checkTransition tells a missing period from one already in the target state
+// when a status update matched no row
+func (s *periodDBStore) checkTransition(ctx context.Context, tx database.Querier, result sql.Result, tenantID int, id int, wrongState error) error {
+	n, err := result.RowsAffected()
+	if err != nil || n > 0 {
+		return err
+	}
+	var exists bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0 FROM accounting_periods WHERE tenant_id = $1 AND id = $2`,
+		tenantID, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrPeriodNotFound
+	}
+	return wrongState
+}
+
+func logPeriodAction(ctx context.Context, tx database.Querier, periodID int, action string, userID int) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO accounting_period_log (period_id, action, user_id)
+		VALUES ($1, $2, $3)`, periodID, action, userID)
+	return err
+}
+
+func (s *periodDBStore) ClosedPeriodOverlapping(ctx context.Context, tenantID int, start time.Time, end time.Time) (*domain.AccountingPeriod, error) {
+	if end.Before(start) {
+		end = start
+	}
+	period, err := scanPeriod(s.db.QueryRowContext(ctx, periodSelect+`
+		LEFT JOIN tenant_settings ts ON ts.tenant_id = p.tenant_id
+		WHERE p.tenant_id = $1 AND p.status = 'closed'
+		  AND p.start_date <= ($3::timestamptz AT TIME ZONE COALESCE(ts.time_zone, $4))::date
+		  AND p.end_date >= ($2::timestamptz AT TIME ZONE COALESCE(ts.time_zone, $4))::date
+		ORDER BY p.start_date
+		LIMIT 1`,
+		tenantID, start, end, DefaultTimeZone))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return period, nil
+}
+
+// lockOpenPeriods locks the periods of the user's tenant containing any day
+// from start to end and returns ErrPeriodClosed when one of them is closed.
+// The share lock is held until tx ends, so a period cannot be closed while an
+// event falling in it is being written.
+func lockOpenPeriods(ctx context.Context, tx database.Querier, userID int, start time.Time, end time.Time) error {
+	if end.Before(start) {
+		end = start
+	}
+	rows, err := tx.QueryContext(ctx, periodSelect+`
+		JOIN users u ON u.tenant_id = p.tenant_id
+		LEFT JOIN tenant_settings ts ON ts.tenant_id = p.tenant_id
+		WHERE u.id = $1
+		  AND p.start_date <= ($3::timestamptz AT TIME ZONE COALESCE(ts.time_zone, $4))::date
+		  AND p.end_date >= ($2::timestamptz AT TIME ZONE COALESCE(ts.time_zone, $4))::date
+		FOR SHARE OF p`,
+		userID, start, end, DefaultTimeZone)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		period, err := scanPeriod(rows)
+		if err != nil {
+			return err
+		}
+		if period.Status == domain.PeriodClosed {
+			return PeriodClosed(period)
+		}
+	}
+	return rows.Err()
+}
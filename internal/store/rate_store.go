@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/domain"
+	"time"
+)
+
+var ErrRateNotFound = domain.NewNotFoundError("rate_not_found", "mileage rate not found")
+
+// RateStore handles the versioned per-km mileage rate tables
+type RateStore interface {
+	// GetEffectiveRate returns the rate in force on the given day, preferring a
+	// rate for the event type over the tenant default.
+	GetEffectiveRate(ctx context.Context, tenantID int, typeID int, on time.Time) (*domain.MileageRate, error)
+	ListRates(ctx context.Context, tenantID int) ([]domain.MileageRate, error)
+	CreateRate(context.Context, *domain.MileageRate) error
+}
+
+type rateDBStore struct {
+	db database.Service
+}
+
+func NewRateStore(db database.Service) RateStore {
+	return &rateDBStore{db: db}
+}
+
+func (s *rateDBStore) GetEffectiveRate(ctx context.Context, tenantID int, typeID int, on time.Time) (*domain.MileageRate, error) {
+	var rate domain.MileageRate
+	query := `
+		SELECT id, tenant_id, type_id, rate_per_km, effective_from, created_at
+		FROM mileage_rates
+		WHERE tenant_id = $1
+		  AND (type_id = $2 OR type_id IS NULL)
+		  AND effective_from <= $3::date
+		ORDER BY type_id IS NULL, effective_from DESC
+		LIMIT 1`
+
+	err := s.db.QueryRowContext(ctx, query, tenantID, typeID, on).Scan(
+		&rate.ID, &rate.TenantID, &rate.TypeID, &rate.RatePerKm, &rate.EffectiveFrom, &rate.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrRateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (s *rateDBStore) ListRates(ctx context.Context, tenantID int) ([]domain.MileageRate, error) {
+	query := `
+		SELECT id, tenant_id, type_id, rate_per_km, effective_from, created_at
+		FROM mileage_rates
+		WHERE tenant_id = $1
+		ORDER BY type_id NULLS FIRST, effective_from DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []domain.MileageRate{}
+	for rows.Next() {
+		var rate domain.MileageRate
+		err := rows.Scan(
+			&rate.ID, &rate.TenantID, &rate.TypeID, &rate.RatePerKm, &rate.EffectiveFrom, &rate.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (s *rateDBStore) CreateRate(ctx context.Context, rate *domain.MileageRate) error {
+	query := `
+		INSERT INTO mileage_rates (tenant_id, type_id, rate_per_km, effective_from)
+		VALUES ($1, $2, $3, $4::date)
+		RETURNING id, created_at`
+
+	err := s.db.QueryRowContext(ctx, query, rate.TenantID, rate.TypeID, rate.RatePerKm, rate.EffectiveFrom).Scan(&rate.ID, &rate.CreatedAt)
+	return uniqueViolation(err, ErrRateExists)
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/domain"
+)
+
+var ErrScimTokenNotFound = domain.NewNotFoundError("scim_token_not_found", "SCIM token not found")
+
+// ScimTokenStore handles the tokens of SCIM provisioning clients. Tokens are
+// looked up by the hex SHA-256 hash of their value.
+type ScimTokenStore interface {
+	ListTokens(ctx context.Context, tenantID int) ([]domain.ScimToken, error)
+	CreateToken(ctx context.Context, token *domain.ScimToken, hash string) error
+	DeleteToken(ctx context.Context, tenantID int, id int) error
+	// UseToken returns the token with hash and records that it was used
+	UseToken(ctx context.Context, hash string) (*domain.ScimToken, error)
+}
+
+type scimTokenDBStore struct {
+	db database.Service
+}
+
+func NewScimTokenStore(db database.Service) ScimTokenStore {
+	return &scimTokenDBStore{db: db}
+}
+
+func scanScimToken(row rowScanner) (*domain.ScimToken, error) {
+	var token domain.ScimToken
+	err := row.Scan(&token.ID, &token.TenantID, &token.Name, &token.CreatedAt, &token.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (s *scimTokenDBStore) ListTokens(ctx context.Context, tenantID int) ([]domain.ScimToken, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, tenant_id, name, created_at, last_used_at
+		FROM scim_tokens
+		WHERE tenant_id = $1
+		ORDER BY id`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []domain.ScimToken{}
+	for rows.Next() {
+		token, err := scanScimToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (s *scimTokenDBStore) CreateToken(ctx context.Context, token *domain.ScimToken, hash string) error {
+	return s.db.QueryRowContext(ctx, `
+		INSERT INTO scim_tokens (tenant_id, name, token_hash)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`,
+		token.TenantID, token.Name, hash,
+	).Scan(&token.ID, &token.CreatedAt)
+}
+
+func (s *scimTokenDBStore) DeleteToken(ctx context.Context, tenantID int, id int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM scim_tokens WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrScimTokenNotFound
+	}
+	return nil
+}
+
+func (s *scimTokenDBStore) UseToken(ctx context.Context, hash string) (*domain.ScimToken, error) {
+	token, err := scanScimToken(s.db.QueryRowContext(ctx, `
+		UPDATE scim_tokens
+		SET last_used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1
+		RETURNING id, tenant_id, name, created_at, last_used_at`, hash))
+	if err == sql.ErrNoRows {
+		return nil, ErrScimTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/domain"
+)
+
+// DefaultTimeZone applies when neither the user nor the tenant has one
+const DefaultTimeZone = "UTC"
+
+// SettingsStore handles tenant settings and time zone lookups
+type SettingsStore interface {
+	GetTenantSettings(ctx context.Context, tenantID int) (*domain.TenantSettings, error)
+	UpdateTenantSettings(ctx context.Context, settings *domain.TenantSettings) error
+	EffectiveTimeZone(ctx context.Context, userID int) (string, error)
+}
+
+type settingsDBStore struct {
+	db database.Service
+}
+
+// NewSettingsStore creates a new SettingsStore instance
+func NewSettingsStore(db database.Service) SettingsStore {
+	return &settingsDBStore{db: db}
+}
+
+// GetTenantSettings returns the defaults for tenants that never saved settings
+func (s *settingsDBStore) GetTenantSettings(ctx context.Context, tenantID int) (*domain.TenantSettings, error) {
+	settings := domain.TenantSettings{TenantID: tenantID, TimeZone: DefaultTimeZone}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT time_zone, updated_at FROM tenant_settings WHERE tenant_id = $1`,
+		tenantID,
+	).Scan(&settings.TimeZone, &settings.UpdatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (s *settingsDBStore) UpdateTenantSettings(ctx context.Context, settings *domain.TenantSettings) error {
+	return s.db.QueryRowContext(ctx, `
+		INSERT INTO tenant_settings (tenant_id, time_zone, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET time_zone = EXCLUDED.time_zone, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`,
+		settings.TenantID, settings.TimeZone,
+	).Scan(&settings.UpdatedAt)
+}
+
+// EffectiveTimeZone resolves the user's time zone, then the tenant's, then UTC
+func (s *settingsDBStore) EffectiveTimeZone(ctx context.Context, userID int) (string, error) {
+	var timeZone string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(u.time_zone, ts.time_zone, $2)
+		FROM users u
+		LEFT JOIN tenant_settings ts ON ts.tenant_id = u.tenant_id
+		WHERE u.id = $1`,
+		userID, DefaultTimeZone,
+	).Scan(&timeZone)
+	if err == sql.ErrNoRows {
+		return DefaultTimeZone, nil
+	}
+	return timeZone, err
+}
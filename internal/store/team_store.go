@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/domain"
+)
+
+var (
+	ErrTeamNotFound       = domain.NewNotFoundError("team_not_found", "team not found")
+	ErrTeamMemberNotFound = domain.NewNotFoundError("team_member_not_found", "user is not a member of the team")
+)
+
+// TeamStore handles the teams of a tenant and their members
+type TeamStore interface {
+	GetTeam(ctx context.Context, tenantID int, id int) (*domain.Team, error)
+	ListTeams(ctx context.Context, tenantID int) ([]domain.Team, error)
+	CreateTeam(context.Context, *domain.Team) error
+	UpdateTeam(context.Context, *domain.Team) error
+	DeleteTeam(ctx context.Context, tenantID int, id int) error
+	ListMembers(ctx context.Context, teamID int) ([]domain.TeamMember, error)
+	// AddMember is a no-op when the user already belongs to the team
+	AddMember(ctx context.Context, teamID int, userID int) error
+	RemoveMember(ctx context.Context, teamID int, userID int) error
+}
+
+type teamDBStore struct {
+	db database.Service
+}
+
+func NewTeamStore(db database.Service) TeamStore {
+	return &teamDBStore{db: db}
+}
+
+const teamSelect = `
+		SELECT id, tenant_id, name, description, created_at, updated_at
+		FROM teams`
+
+func scanTeam(row rowScanner) (*domain.Team, error) {
+	var team domain.Team
+	err := row.Scan(&team.ID, &team.TenantID, &team.Name, &team.Description, &team.CreatedAt, &team.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+func (s *teamDBStore) GetTeam(ctx context.Context, tenantID int, id int) (*domain.Team, error) {
+	team, err := scanTeam(s.db.QueryRowContext(ctx, teamSelect+`
+		WHERE tenant_id = $1 AND id = $2`, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrTeamNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+func (s *teamDBStore) ListTeams(ctx context.Context, tenantID int) ([]domain.Team, error) {
+	rows, err := s.db.QueryContext(ctx, teamSelect+`
+		WHERE tenant_id = $1
+		ORDER BY name`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := []domain.Team{}
+	for rows.Next() {
+		team, err := scanTeam(rows)
+		if err != nil {
+			return nil, err
+		}
+		teams = append(teams, *team)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+func (s *teamDBStore) CreateTeam(ctx context.Context, team *domain.Team) error {
+	query := `
+		INSERT INTO teams (tenant_id, name, description)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at`
+
+	err := s.db.QueryRowContext(ctx, query, team.TenantID, team.Name, team.Description).
+		Scan(&team.ID, &team.CreatedAt, &team.UpdatedAt)
+	return uniqueViolation(err, ErrTeamExists)
+}
+
+func (s *teamDBStore) UpdateTeam(ctx context.Context, team *domain.Team) error {
+	query := `
+		UPDATE teams
+		SET name = $1, description = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE tenant_id = $3 AND id = $4
+		RETURNING created_at, updated_at`
+
+	err := s.db.QueryRowContext(ctx, query, team.Name, team.Description, team.TenantID, team.ID).
+		Scan(&team.CreatedAt, &team.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrTeamNotFound
+	}
+	return uniqueViolation(err, ErrTeamExists)
+}
+
+func (s *teamDBStore) DeleteTeam(ctx context.Context, tenantID int, id int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM teams WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrTeamNotFound
+	}
+	return nil
+}
+
+func (s *teamDBStore) ListMembers(ctx context.Context, teamID int) ([]domain.TeamMember, error) {
+	query := `
+		SELECT u.id, u.username, u.first_name, u.last_name, u.manager_id, tm.added_at
+		FROM team_members tm
+		JOIN users u ON tm.user_id = u.id
+		WHERE tm.team_id = $1
+		ORDER BY u.first_name, u.last_name, u.id`
+
+	rows, err := s.db.QueryContext(ctx, query, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []domain.TeamMember{}
+	for rows.Next() {
+		var member domain.TeamMember
+		err := rows.Scan(&member.UserID, &member.Username, &member.FirstName, &member.LastName, &member.ManagerID, &member.AddedAt)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (s *teamDBStore) AddMember(ctx context.Context, teamID int, userID int) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO team_members (team_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (team_id, user_id) DO NOTHING`, teamID, userID)
+	return err
+}
+
+func (s *teamDBStore) RemoveMember(ctx context.Context, teamID int, userID int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM team_members WHERE team_id = $1 AND user_id = $2`, teamID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrTeamMemberNotFound
+	}
+	return nil
+}
@@ -1,28 +1,57 @@
 package store
 
 import (
+	"context"
 	"database/sql"
-	"errors"
+	"fmt"
 	"pwp-remastered/internal/database"
 	"pwp-remastered/internal/domain"
+	"strconv"
 
 	"github.com/matthewhartstonge/argon2"
 )
 
 // UserStore defines the interface for user data operations
 type UserStore interface {
-	GetUser(id int) (*domain.User, error)
-	GetUserByUsername(username string) (*domain.User, error)
-	CreateUser(user *domain.User) error
-	UpdateUser(caller *domain.User, user *domain.User) error
-	UpdateSelfUser(caller *domain.User) error
-	DeleteUser(id int) error
-	ListUsers() ([]domain.User, error)
-	ChangeUserStatus(caller *domain.User, id int) error
-	UpdateSelfPassword(caller *domain.User, password string) error
-	GetAllUsers() ([]domain.User, error)
+	GetUser(ctx context.Context, id int) (*domain.User, error)
+	// GetUserByUsername looks a username up in a tenant, usernames are unique
+	// per tenant. A missing user is neither a user nor an error.
+	GetUserByUsername(ctx context.Context, tenantID int, username string) (*domain.User, error)
+	CreateUser(ctx context.Context, user *domain.User) error
+	UpdateUser(ctx context.Context, caller *domain.User, user *domain.User) error
+	UpdateSelfUser(ctx context.Context, caller *domain.User) error
+	DeleteUser(ctx context.Context, id int) error
+	ListUsers(ctx context.Context, filter domain.UserFilter) ([]domain.User, *domain.PageInfo, error)
+	ChangeUserStatus(ctx context.Context, caller *domain.User, id int) error
+	// SetUserStatus sets the status of a user, setting the current one again
+	// changes nothing
+	SetUserStatus(ctx context.Context, caller *domain.User, id int, status int) error
+	UpdateSelfPassword(ctx context.Context, caller *domain.User, password string) error
+	// IsManagerOf reports whether userID reports to managerID, directly or
+	// through the hierarchy
+	IsManagerOf(ctx context.Context, managerID int, userID int) (bool, error)
 }
 
+// reportsSubquery selects the ids of everybody reporting to the manager given
+// as its "?" argument at any depth. Managers outside the user's tenant are
+// ignored, UNION stops the walk should the hierarchy contain a cycle.
+const reportsSubquery = `(
+			WITH RECURSIVE reports AS (
+				SELECT r.id, r.tenant_id
+				FROM users r
+				JOIN users m ON m.id = r.manager_id AND m.tenant_id = r.tenant_id
+				WHERE m.id = ?
+				UNION
+				SELECT r.id, r.tenant_id
+				FROM users r
+				JOIN reports ON r.manager_id = reports.id AND r.tenant_id = reports.tenant_id
+			)
+			SELECT id FROM reports
+		)`
+
+// teamMembersSubquery selects the ids of the members of the teams given as its "?" argument
+const teamMembersSubquery = `(SELECT user_id FROM team_members WHERE team_id = ANY(?))`
+
 type userDBStore struct {
 	db database.Service
 }
@@ -32,21 +61,20 @@ func NewUserStore(db database.Service) UserStore {
 	return &userDBStore{db: db}
 }
 
-func (s *userDBStore) GetUser(id int) (*domain.User, error) {
+func (s *userDBStore) GetUser(ctx context.Context, id int) (*domain.User, error) {
 	var user domain.User
 	query := `
 		SELECT id, username, hashed_password, email, first_name, last_name, 
-		       is_admin, is_user, tenant_id, status
+		       is_admin, is_accountant, is_user, tenant_id, status, time_zone, manager_id
 		FROM users WHERE id = $1`
 
-	err := s.db.QueryRow(query, id).Scan(
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
 		&user.ID, &user.Username, &user.HashedPassword, &user.Email,
-		&user.FirstName, &user.LastName, &user.IsAdmin, &user.IsUser,
-		&user.TenantID, &user.Status,
+		&user.FirstName, &user.LastName, &user.IsAdmin, &user.IsAccountant, &user.IsUser,
+		&user.TenantID, &user.Status, &user.TimeZone, &user.ManagerID,
 	)
 
 	if err == sql.ErrNoRows {
-		var ErrUserNotFound = errors.New("user not found")
 		return nil, ErrUserNotFound
 	}
 	if err != nil {
@@ -55,17 +83,17 @@ func (s *userDBStore) GetUser(id int) (*domain.User, error) {
 	return &user, nil
 }
 
-func (s *userDBStore) GetUserByUsername(username string) (*domain.User, error) {
+func (s *userDBStore) GetUserByUsername(ctx context.Context, tenantID int, username string) (*domain.User, error) {
 	var user domain.User
 	query := `
 		SELECT id, username, hashed_password, email, first_name, last_name, 
-		       is_admin, is_user, tenant_id, status
-		FROM users WHERE username = $1`
+		       is_admin, is_accountant, is_user, tenant_id, status, time_zone, manager_id
+		FROM users WHERE tenant_id = $1 AND username = $2`
 
-	err := s.db.QueryRow(query, username).Scan(
+	err := s.db.QueryRowContext(ctx, query, tenantID, username).Scan(
 		&user.ID, &user.Username, &user.HashedPassword, &user.Email,
-		&user.FirstName, &user.LastName, &user.IsAdmin, &user.IsUser,
-		&user.TenantID, &user.Status,
+		&user.FirstName, &user.LastName, &user.IsAdmin, &user.IsAccountant, &user.IsUser,
+		&user.TenantID, &user.Status, &user.TimeZone, &user.ManagerID,
 	)
 
 	if err == sql.ErrNoRows {
@@ -77,67 +105,75 @@ func (s *userDBStore) GetUserByUsername(username string) (*domain.User, error) {
 	return &user, nil
 }
 
-func (s *userDBStore) CreateUser(user *domain.User) error {
+func (s *userDBStore) CreateUser(ctx context.Context, user *domain.User) error {
 	query := `
 		INSERT INTO users (username, hashed_password, email, first_name, last_name, 
-		                  is_admin, is_user, tenant_id, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		                  is_admin, is_accountant, is_user, tenant_id, status, time_zone, manager_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id`
 
-	err := s.db.QueryRow(
+	err := s.db.QueryRowContext(ctx,
 		query,
 		user.Username, user.HashedPassword, user.Email,
-		user.FirstName, user.LastName, user.IsAdmin,
-		user.IsUser, user.TenantID, user.Status,
+		user.FirstName, user.LastName, user.IsAdmin, user.IsAccountant,
+		user.IsUser, user.TenantID, user.Status, user.TimeZone, user.ManagerID,
 	).Scan(&user.ID)
 
-	return err
+	return uniqueViolation(err, ErrUserExists)
 }
 
-func (s *userDBStore) UpdateUser(caller *domain.User, user *domain.User) error {
-	// Admin değilse, is_admin alanını değiştirmesin
-	if !caller.IsAdmin {
-		var currentIsAdmin bool
-		err := s.db.QueryRow("SELECT is_admin FROM users WHERE id = $1", user.ID).Scan(&currentIsAdmin)
-		if err != nil {
-			return err
-		}
-		user.IsAdmin = currentIsAdmin
-	}
-
+// UpdateUser replaces a user, a deactivation is announced to the tenant's
+// webhooks in the same transaction
+func (s *userDBStore) UpdateUser(ctx context.Context, caller *domain.User, user *domain.User) error {
 	query := `
 		UPDATE users 
 		SET username = $1, hashed_password = $2, email = $3,
-		    first_name = $4, last_name = $5, is_admin = $6,
-		    is_user = $7, tenant_id = $8, status = $9
-		WHERE id = $10`
-
-	result, err := s.db.Exec(
-		query,
-		user.Username, user.HashedPassword, user.Email,
-		user.FirstName, user.LastName, user.IsAdmin,
-		user.IsUser, user.TenantID, user.Status,
-		user.ID,
-	)
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
-	}
+		    first_name = $4, last_name = $5, is_admin = $6, is_accountant = $7,
+		    is_user = $8, tenant_id = $9, status = $10, time_zone = $11, manager_id = $12
+		WHERE id = $13
+		RETURNING id, username, first_name, last_name, COALESCE(tenant_id, 0)`
+
+	return s.db.Transact(ctx, func(tx database.Querier) error {
+		var currentIsAdmin, currentIsAccountant bool
+		var currentStatus int
+		err := tx.QueryRowContext(ctx, "SELECT is_admin, is_accountant, status FROM users WHERE id = $1 FOR UPDATE", user.ID).Scan(&currentIsAdmin, &currentIsAccountant, &currentStatus)
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		// Admin değilse, is_admin ve is_accountant alanlarını değiştirmesin
+		if !caller.IsAdmin {
+			user.IsAdmin = currentIsAdmin
+			user.IsAccountant = currentIsAccountant
+		}
 
-	return nil
+		var updated domain.EventUser
+		err = tx.QueryRowContext(ctx,
+			query,
+			user.Username, user.HashedPassword, user.Email,
+			user.FirstName, user.LastName, user.IsAdmin, user.IsAccountant,
+			user.IsUser, user.TenantID, user.Status, user.TimeZone, user.ManagerID,
+			user.ID,
+		).Scan(&updated.ID, &updated.Username, &updated.FirstName, &updated.LastName, &updated.TenantID)
+		if err != nil {
+			return uniqueViolation(err, ErrUserExists)
+		}
+		if currentStatus == 0 || user.Status != 0 {
+			return nil
+		}
+		return enqueueWebhook(ctx, tx, updated.TenantID, domain.WebhookUserDeactivated, updated)
+	})
 }
 
-func (s *userDBStore) UpdateSelfUser(caller *domain.User) error {
+func (s *userDBStore) UpdateSelfUser(ctx context.Context, caller *domain.User) error {
 	if !caller.IsAdmin {
 		var currentIsAdmin bool
-		err := s.db.QueryRow("SELECT is_admin FROM users WHERE id = $1", caller.ID).Scan(&currentIsAdmin)
+		err := s.db.QueryRowContext(ctx, "SELECT is_admin FROM users WHERE id = $1", caller.ID).Scan(&currentIsAdmin)
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
 		if err != nil {
 			return err
 		}
@@ -148,18 +184,18 @@ func (s *userDBStore) UpdateSelfUser(caller *domain.User) error {
 		UPDATE users 
 		SET username = $1, hashed_password = $2, email = $3,
 		    first_name = $4, last_name = $5, is_admin = $6,
-		    is_user = $7, tenant_id = $8, status = $9
-		WHERE id = $10`
+		    is_user = $7, tenant_id = $8, status = $9, time_zone = $10
+		WHERE id = $11`
 
-	result, err := s.db.Exec(
+	result, err := s.db.ExecContext(ctx,
 		query,
 		caller.Username, caller.HashedPassword, caller.Email,
 		caller.FirstName, caller.LastName, caller.IsAdmin,
-		caller.IsUser, caller.TenantID, caller.Status,
+		caller.IsUser, caller.TenantID, caller.Status, caller.TimeZone,
 		caller.ID,
 	)
 	if err != nil {
-		return err
+		return uniqueViolation(err, ErrUserExists)
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -167,15 +203,15 @@ func (s *userDBStore) UpdateSelfUser(caller *domain.User) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return ErrUserNotFound
 	}
 
 	return nil
 }
 
-func (s *userDBStore) DeleteUser(id int) error {
+func (s *userDBStore) DeleteUser(ctx context.Context, id int) error {
 	query := `DELETE FROM users WHERE id = $1`
-	result, err := s.db.Exec(query, id)
+	result, err := s.db.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -185,22 +221,79 @@ func (s *userDBStore) DeleteUser(id int) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return ErrUserNotFound
 	}
 
 	return nil
 }
 
-func (s *userDBStore) ListUsers() ([]domain.User, error) {
+// userSortFields are the sort options accepted by ListUsers
+var userSortFields = map[string]sortField[domain.User]{
+	"id":         {"id", "int", func(u *domain.User) string { return strconv.Itoa(u.ID) }},
+	"username":   {"username", "text", func(u *domain.User) string { return u.Username }},
+	"email":      {"email", "text", func(u *domain.User) string { return u.Email }},
+	"first_name": {"first_name", "text", func(u *domain.User) string { return u.FirstName }},
+	"last_name":  {"last_name", "text", func(u *domain.User) string { return u.LastName }},
+}
+
+// userFilter turns filter into WHERE conditions, without the cursor
+func userFilter(filter domain.UserFilter) *queryBuilder {
+	b := &queryBuilder{}
+	if filter.TenantID != 0 {
+		b.where("tenant_id = ?", filter.TenantID)
+	}
+	if len(filter.IDs) > 0 {
+		b.where("id = ANY(?)", filter.IDs)
+	}
+	if len(filter.Statuses) > 0 {
+		b.where("status = ANY(?)", filter.Statuses)
+	}
+	if filter.ManagerID != 0 {
+		b.where("id IN "+reportsSubquery, filter.ManagerID)
+	}
+	if len(filter.TeamIDs) > 0 {
+		b.where("id IN "+teamMembersSubquery, filter.TeamIDs)
+	}
+	if filter.Query != "" {
+		pattern := "%" + escapeLike(filter.Query) + "%"
+		b.where("(username ILIKE ? OR email ILIKE ? OR first_name ILIKE ? OR last_name ILIKE ?)", pattern, pattern, pattern, pattern)
+	}
+	if filter.Username != "" {
+		b.where("LOWER(username) = LOWER(?)", filter.Username)
+	}
+	if filter.Email != "" {
+		b.where("LOWER(email) = LOWER(?)", filter.Email)
+	}
+	return b
+}
+
+func (s *userDBStore) ListUsers(ctx context.Context, filter domain.UserFilter) ([]domain.User, *domain.PageInfo, error) {
+	info := &domain.PageInfo{}
+	if filter.WithTotal {
+		b := userFilter(filter)
+		var total int
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`+b.whereClause(), b.args...).Scan(&total); err != nil {
+			return nil, nil, err
+		}
+		info.Total = &total
+	}
+
+	b := userFilter(filter)
+	p, err := newPage(b, userSortFields, "id", "id", filter.Sort, filter.Cursor, filter.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
 	query := `
 		SELECT id, username, hashed_password, email, first_name, last_name, 
-		       is_admin, is_user, tenant_id, status
-		FROM users
-		WHERE status != 0`
+		       is_admin, is_accountant, is_user, tenant_id, status, time_zone, manager_id
+		FROM users` + b.whereClause() + p.orderLimit()
+	if filter.Offset > 0 && filter.Cursor == "" {
+		query += fmt.Sprintf("\n\t\tOFFSET %d", filter.Offset)
+	}
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.QueryContext(ctx, query, b.args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
@@ -209,44 +302,83 @@ func (s *userDBStore) ListUsers() ([]domain.User, error) {
 		var user domain.User
 		err := rows.Scan(
 			&user.ID, &user.Username, &user.HashedPassword, &user.Email,
-			&user.FirstName, &user.LastName, &user.IsAdmin, &user.IsUser,
-			&user.TenantID, &user.Status,
+			&user.FirstName, &user.LastName, &user.IsAdmin, &user.IsAccountant, &user.IsUser,
+			&user.TenantID, &user.Status, &user.TimeZone, &user.ManagerID,
 		)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		users = append(users, user)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return users, nil
+	users, info.NextCursor = p.trim(users, func(u *domain.User) int { return u.ID })
+	return users, info, nil
 }
 
-func (s *userDBStore) ChangeUserStatus(caller *domain.User, id int) error {
+// ChangeUserStatus toggles a user between active and inactive, deactivations
+// are announced to the tenant's webhooks
+func (s *userDBStore) ChangeUserStatus(ctx context.Context, caller *domain.User, id int) error {
 	// if !caller.IsAdmin {
 	// 	return errors.New("Unauthorized")
 	// }
 
-	query := `UPDATE users SET status = 1 - status WHERE id = $1`
-	result, err := s.db.Exec(query, id)
-	if err != nil {
-		return err
-	}
+	query := `
+		UPDATE users SET status = 1 - status WHERE id = $1
+		RETURNING id, username, first_name, last_name, COALESCE(tenant_id, 0), status`
+
+	return s.db.Transact(ctx, func(tx database.Querier) error {
+		var user domain.EventUser
+		var status int
+		err := tx.QueryRowContext(ctx, query, id).Scan(&user.ID, &user.Username, &user.FirstName, &user.LastName, &user.TenantID, &status)
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if status != 0 {
+			return nil
+		}
+		return enqueueWebhook(ctx, tx, user.TenantID, domain.WebhookUserDeactivated, user)
+	})
+}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
-	}
-	return nil
+// SetUserStatus sets a user active or inactive, deactivations are announced
+// to the tenant's webhooks
+func (s *userDBStore) SetUserStatus(ctx context.Context, caller *domain.User, id int, status int) error {
+	query := `
+		UPDATE users SET status = $2 WHERE id = $1 AND status <> $2
+		RETURNING id, username, first_name, last_name, COALESCE(tenant_id, 0)`
+
+	return s.db.Transact(ctx, func(tx database.Querier) error {
+		var user domain.EventUser
+		err := tx.QueryRowContext(ctx, query, id, status).Scan(&user.ID, &user.Username, &user.FirstName, &user.LastName, &user.TenantID)
+		if err == sql.ErrNoRows {
+			// Nothing changed, either the user is missing or already has the status
+			var exists bool
+			if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, id).Scan(&exists); err != nil {
+				return err
+			}
+			if !exists {
+				return ErrUserNotFound
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if status != 0 {
+			return nil
+		}
+		return enqueueWebhook(ctx, tx, user.TenantID, domain.WebhookUserDeactivated, user)
+	})
 }
 
-func (s *userDBStore) UpdateSelfPassword(caller *domain.User, password string) error {
+func (s *userDBStore) UpdateSelfPassword(ctx context.Context, caller *domain.User, password string) error {
 	argon := argon2.DefaultConfig()
 
 	hashedPassword, err := argon.HashEncoded([]byte(password))
@@ -255,7 +387,7 @@ func (s *userDBStore) UpdateSelfPassword(caller *domain.User, password string) e
 	}
 
 	query := `UPDATE users SET hashed_password = $1 WHERE id = $2`
-	result, err := s.db.Exec(query, hashedPassword, caller.ID)
+	result, err := s.db.ExecContext(ctx, query, hashedPassword, caller.ID)
 	if err != nil {
 		return err
 	}
@@ -265,40 +397,17 @@ func (s *userDBStore) UpdateSelfPassword(caller *domain.User, password string) e
 		return err
 	}
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return ErrUserNotFound
 	}
 	return nil
 }
 
-func (s *userDBStore) GetAllUsers() ([]domain.User, error) {
-	query := `
-		SELECT id, username, hashed_password, email, first_name, last_name, 
-		       is_admin, is_user, tenant_id, status
-		FROM users`
-
-	rows, err := s.db.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var users []domain.User
-	for rows.Next() {
-		var user domain.User
-		err := rows.Scan(
-			&user.ID, &user.Username, &user.HashedPassword, &user.Email,
-			&user.FirstName, &user.LastName, &user.IsAdmin, &user.IsUser,
-			&user.TenantID, &user.Status,
-		)
-		if err != nil {
-			return nil, err
-		}
-		users = append(users, user)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
+func (s *userDBStore) IsManagerOf(ctx context.Context, managerID int, userID int) (bool, error) {
+	b := &queryBuilder{}
+	b.where("u.id = ?", userID)
+	b.where("u.id IN "+reportsSubquery, managerID)
 
-	return users, nil
+	var isManager bool
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) > 0 FROM users u`+b.whereClause(), b.args...).Scan(&isManager)
+	return isManager, err
 }
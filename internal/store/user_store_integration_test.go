@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"pwp-remastered/internal/domain"
+	"testing"
+)
+
+func TestUserStatusWebhooks(t *testing.T) {
+	db := startPostgres(t)
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO users (id, username, hashed_password, email, tenant_id, status)
+		VALUES (1, 'ayse', '', 'ayse@example.org', 1, 1)`); err != nil {
+		t.Fatal(err)
+	}
+	users := NewUserStore(db)
+	caller := &domain.User{TenantID: 1, IsAdmin: true}
+
+	deactivations := func() int {
+		t.Helper()
+		var n int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM webhook_outbox WHERE event_type = $1`, domain.WebhookUserDeactivated).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	steps := []struct {
+		name   string
+		status int
+		want   int
+	}{
+		{"deactivate", 0, 1},
+		{"deactivate again", 0, 1},
+		{"activate", 1, 1},
+	}
+	for _, step := range steps {
+		if err := users.SetUserStatus(ctx, caller, 1, step.status); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := deactivations(); got != step.want {
+			t.Errorf("%s: %d deactivations queued, want %d", step.name, got, step.want)
+		}
+	}
+	if err := users.SetUserStatus(ctx, caller, 404, 0); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("missing user: err = %v, want %v", err, ErrUserNotFound)
+	}
+
+	// Replacing the user with status 0 deactivates it in the same transaction
+	user, err := users.GetUser(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.FirstName = "Ayşe"
+	user.Status = 0
+	if err := users.UpdateUser(ctx, caller, user); err != nil {
+		t.Fatal(err)
+	}
+	if got := deactivations(); got != 2 {
+		t.Errorf("update: %d deactivations queued, want 2", got)
+	}
+	if err := users.UpdateUser(ctx, caller, user); err != nil {
+		t.Fatal(err)
+	}
+	if got := deactivations(); got != 2 {
+		t.Errorf("update of an inactive user: %d deactivations queued, want 2", got)
+	}
+}
+
+func TestUsernamesPerTenant(t *testing.T) {
+	db := startPostgres(t)
+	ctx := context.Background()
+	users := NewUserStore(db)
+
+	for _, user := range []*domain.User{
+		{Username: "ali", Email: "ali@example.org", TenantID: 1, IsUser: true, Status: 1},
+		{Username: "ali", Email: "ali@example.com", TenantID: 2, IsUser: true, Status: 1},
+	} {
+		if err := users.CreateUser(ctx, user); err != nil {
+			t.Fatalf("tenant %d: %v", user.TenantID, err)
+		}
+	}
+	duplicate := &domain.User{Username: "ali", Email: "ali@example.net", TenantID: 1, IsUser: true, Status: 1}
+	if err := users.CreateUser(ctx, duplicate); !errors.Is(err, ErrUserExists) {
+		t.Errorf("same username in the same tenant: err = %v, want %v", err, ErrUserExists)
+	}
+
+	for _, tenantID := range []int{1, 2} {
+		user, err := users.GetUserByUsername(ctx, tenantID, "ali")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user == nil || user.TenantID != tenantID {
+			t.Errorf("GetUserByUsername(%d) = %+v", tenantID, user)
+		}
+	}
+	if user, err := users.GetUserByUsername(ctx, 3, "ali"); user != nil || err != nil {
+		t.Errorf("GetUserByUsername(3) = %+v, %v, want neither", user, err)
+	}
+}
@@ -0,0 +1,238 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/domain"
+	"time"
+)
+
+var ErrVehicleNotFound = domain.NewNotFoundError("vehicle_not_found", "vehicle not found")
+
+// VehicleStore handles the vehicle registry and odometer history
+type VehicleStore interface {
+	GetVehicle(ctx context.Context, tenantID int, id int) (*domain.Vehicle, error)
+	ListVehicles(ctx context.Context, tenantID int) ([]domain.Vehicle, error)
+	CreateVehicle(context.Context, *domain.Vehicle) error
+	UpdateVehicle(context.Context, *domain.Vehicle) error
+	// LastOdometerBefore returns the highest known reading recorded at or before
+	// the given time, ignoring the reading taken from excludeEventID.
+	LastOdometerBefore(ctx context.Context, vehicleID int, before time.Time, excludeEventID int) (int, error)
+	// RecordReading stores a manual reading, readings of events are written
+	// with the event by the EventStore
+	RecordReading(context.Context, *domain.OdometerReading) error
+	ListReadings(ctx context.Context, vehicleID int) ([]domain.OdometerReading, error)
+	GetRoadPriceByVehicle(ctx context.Context, tenantID int, r domain.DateRange) ([]domain.VehicleRoadPrice, error)
+}
+
+type vehicleDBStore struct {
+	db database.Service
+}
+
+func NewVehicleStore(db database.Service) VehicleStore {
+	return &vehicleDBStore{db: db}
+}
+
+const vehicleSelect = `
+		SELECT v.id, v.tenant_id, v.plate, v.owner_user_id, v.fuel_type, v.rate_per_km,
+		       v.initial_odometer_km,
+		       GREATEST(v.initial_odometer_km, COALESCE((SELECT MAX(r.reading_km) FROM vehicle_odometer_readings r WHERE r.vehicle_id = v.id), 0)),
+		       v.is_active, v.created_at, v.updated_at
+		FROM vehicles v`
+
+func scanVehicle(row rowScanner) (*domain.Vehicle, error) {
+	var vehicle domain.Vehicle
+	err := row.Scan(
+		&vehicle.ID, &vehicle.TenantID, &vehicle.Plate, &vehicle.OwnerUserID, &vehicle.FuelType, &vehicle.RatePerKm,
+		&vehicle.InitialOdometerKm, &vehicle.LastOdometerKm,
+		&vehicle.IsActive, &vehicle.CreatedAt, &vehicle.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+func (s *vehicleDBStore) GetVehicle(ctx context.Context, tenantID int, id int) (*domain.Vehicle, error) {
+	vehicle, err := scanVehicle(s.db.QueryRowContext(ctx, vehicleSelect+`
+		WHERE v.tenant_id = $1 AND v.id = $2`, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrVehicleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return vehicle, nil
+}
+
+func (s *vehicleDBStore) ListVehicles(ctx context.Context, tenantID int) ([]domain.Vehicle, error) {
+	rows, err := s.db.QueryContext(ctx, vehicleSelect+`
+		WHERE v.tenant_id = $1
+		ORDER BY v.plate`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vehicles := []domain.Vehicle{}
+	for rows.Next() {
+		vehicle, err := scanVehicle(rows)
+		if err != nil {
+			return nil, err
+		}
+		vehicles = append(vehicles, *vehicle)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return vehicles, nil
+}
+
+func (s *vehicleDBStore) CreateVehicle(ctx context.Context, vehicle *domain.Vehicle) error {
+	query := `
+		INSERT INTO vehicles (tenant_id, plate, owner_user_id, fuel_type, rate_per_km, initial_odometer_km, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`
+
+	err := s.db.QueryRowContext(ctx,
+		query,
+		vehicle.TenantID, vehicle.Plate, vehicle.OwnerUserID, vehicle.FuelType,
+		vehicle.RatePerKm, vehicle.InitialOdometerKm, vehicle.IsActive,
+	).Scan(&vehicle.ID, &vehicle.CreatedAt, &vehicle.UpdatedAt)
+	vehicle.LastOdometerKm = vehicle.InitialOdometerKm
+	return uniqueViolation(err, ErrVehicleExists)
+}
+
+func (s *vehicleDBStore) UpdateVehicle(ctx context.Context, vehicle *domain.Vehicle) error {
+	query := `
+		UPDATE vehicles
+		SET plate = $1, owner_user_id = $2, fuel_type = $3, rate_per_km = $4,
+		    initial_odometer_km = $5, is_active = $6, updated_at = CURRENT_TIMESTAMP
+		WHERE tenant_id = $7 AND id = $8`
+
+	result, err := s.db.ExecContext(ctx,
+		query,
+		vehicle.Plate, vehicle.OwnerUserID, vehicle.FuelType, vehicle.RatePerKm,
+		vehicle.InitialOdometerKm, vehicle.IsActive,
+		vehicle.TenantID, vehicle.ID,
+	)
+	if err != nil {
+		return uniqueViolation(err, ErrVehicleExists)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrVehicleNotFound
+	}
+	return nil
+}
+
+func (s *vehicleDBStore) LastOdometerBefore(ctx context.Context, vehicleID int, before time.Time, excludeEventID int) (int, error) {
+	var reading int
+	query := `
+		SELECT GREATEST(v.initial_odometer_km, COALESCE(MAX(r.reading_km), 0))
+		FROM vehicles v
+		LEFT JOIN vehicle_odometer_readings r
+		       ON r.vehicle_id = v.id
+		      AND r.recorded_at <= $2
+		      AND r.event_id IS DISTINCT FROM $3
+		WHERE v.id = $1
+		GROUP BY v.initial_odometer_km`
+
+	err := s.db.QueryRowContext(ctx, query, vehicleID, before, excludeEventID).Scan(&reading)
+	if err == sql.ErrNoRows {
+		return 0, ErrVehicleNotFound
+	}
+	return reading, err
+}
+
+func (s *vehicleDBStore) RecordReading(ctx context.Context, reading *domain.OdometerReading) error {
+	query := `
+		INSERT INTO vehicle_odometer_readings (vehicle_id, user_id, reading_km, recorded_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`
+	return s.db.QueryRowContext(ctx, query, reading.VehicleID, reading.UserID, reading.ReadingKm, reading.RecordedAt).Scan(&reading.ID)
+}
+
+// syncEventReading keeps the vehicle history in step with the odometer_end of
+// an event as stored. It runs in the transaction that writes the event.
+func syncEventReading(ctx context.Context, tx database.Querier, event *domain.Event) error {
+	if event.VehicleID == nil || event.OdometerEnd == nil {
+		_, err := tx.ExecContext(ctx, `DELETE FROM vehicle_odometer_readings WHERE event_id = $1`, event.ID)
+		return err
+	}
+
+	query := `
+		INSERT INTO vehicle_odometer_readings (vehicle_id, event_id, user_id, reading_km, recorded_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (event_id) DO UPDATE
+		SET vehicle_id = EXCLUDED.vehicle_id, user_id = EXCLUDED.user_id,
+		    reading_km = EXCLUDED.reading_km, recorded_at = EXCLUDED.recorded_at`
+	_, err := tx.ExecContext(ctx, query, *event.VehicleID, event.ID, event.UserID, *event.OdometerEnd, event.EndDate)
+	return err
+}
+
+func (s *vehicleDBStore) ListReadings(ctx context.Context, vehicleID int) ([]domain.OdometerReading, error) {
+	query := `
+		SELECT id, vehicle_id, event_id, user_id, reading_km, recorded_at
+		FROM vehicle_odometer_readings
+		WHERE vehicle_id = $1
+		ORDER BY recorded_at DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	readings := []domain.OdometerReading{}
+	for rows.Next() {
+		var reading domain.OdometerReading
+		err := rows.Scan(&reading.ID, &reading.VehicleID, &reading.EventID, &reading.UserID, &reading.ReadingKm, &reading.RecordedAt)
+		if err != nil {
+			return nil, err
+		}
+		readings = append(readings, reading)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return readings, nil
+}
+
+func (s *vehicleDBStore) GetRoadPriceByVehicle(ctx context.Context, tenantID int, r domain.DateRange) ([]domain.VehicleRoadPrice, error) {
+	b := &queryBuilder{}
+	b.where("u.tenant_id = ?", tenantID)
+	whereDateRange(b, r)
+	query := `
+		SELECT v.id, v.plate, COUNT(e.id), COALESCE(SUM(e.distance_km), 0), COALESCE(SUM(e.road_price), 0)
+		FROM events e
+		JOIN users u ON e.user_id = u.id
+		LEFT JOIN vehicles v ON e.vehicle_id = v.id` + b.whereClause() + `
+		GROUP BY v.id, v.plate
+		ORDER BY v.plate NULLS LAST`
+
+	rows, err := s.db.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := []domain.VehicleRoadPrice{}
+	for rows.Next() {
+		var total domain.VehicleRoadPrice
+		err := rows.Scan(&total.VehicleID, &total.Plate, &total.EventCount, &total.DistanceKm, &total.RoadPrice)
+		if err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
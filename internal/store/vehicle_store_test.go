@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+// execRecorder records the statements run in a transaction
+type execRecorder struct {
+	database.Querier
+	queries []string
+	args    [][]interface{}
+}
+
+func (q *execRecorder) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	q.queries = append(q.queries, query)
+	q.args = append(q.args, args)
+	return nil, nil
+}
+
+func TestSyncEventReading(t *testing.T) {
+	vehicleID, odometer := 3, 12500
+	end := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	tx := &execRecorder{}
+	event := &domain.Event{ID: 7, UserID: 1, EndDate: end, VehicleID: &vehicleID, OdometerEnd: &odometer}
+	if err := syncEventReading(context.Background(), tx, event); err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.queries) != 1 || !strings.Contains(tx.queries[0], "ON CONFLICT (event_id) DO UPDATE") {
+		t.Fatalf("queries = %v, want an upsert of the event's reading", tx.queries)
+	}
+	if args := tx.args[0]; args[0] != 3 || args[1] != 7 || args[3] != 12500 || args[4] != end {
+		t.Errorf("args = %v", args)
+	}
+
+	// Dropping the vehicle drops the reading
+	tx = &execRecorder{}
+	event.VehicleID = nil
+	if err := syncEventReading(context.Background(), tx, event); err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.queries) != 1 || !strings.HasPrefix(tx.queries[0], "DELETE FROM vehicle_odometer_readings") {
+		t.Fatalf("queries = %v, want the reading deleted", tx.queries)
+	}
+}
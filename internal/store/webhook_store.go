@@ -0,0 +1,389 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"pwp-remastered/internal/database"
+	"pwp-remastered/internal/domain"
+	"time"
+)
+
+var (
+	ErrSubscriptionNotFound = domain.NewNotFoundError("webhook_subscription_not_found", "webhook subscription not found")
+	ErrDeliveryNotFound     = domain.NewNotFoundError("webhook_delivery_not_found", "webhook delivery not found")
+)
+
+// WebhookStore handles webhook subscriptions, the outbox and the delivery log
+type WebhookStore interface {
+	GetSubscription(ctx context.Context, tenantID int, id int) (*domain.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context, tenantID int) ([]domain.WebhookSubscription, error)
+	CreateSubscription(context.Context, *domain.WebhookSubscription) error
+	UpdateSubscription(context.Context, *domain.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, tenantID int, id int) error
+
+	ListDeliveries(ctx context.Context, tenantID int, filter domain.WebhookDeliveryFilter) ([]domain.WebhookDelivery, *domain.PageInfo, error)
+	// GetDelivery returns a delivery with its attempt log
+	GetDelivery(ctx context.Context, tenantID int, id int) (*domain.WebhookDelivery, error)
+	// Redeliver queues a delivery again with a fresh set of attempts
+	Redeliver(ctx context.Context, tenantID int, id int) error
+
+	// FanOut turns up to limit undispatched outbox rows into deliveries for
+	// the matching active subscriptions and returns how many rows it took
+	FanOut(ctx context.Context, limit int) (int, error)
+	// ClaimDue returns up to limit due deliveries and moves their next attempt
+	// lease into the future, so concurrent dispatchers skip them meanwhile
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookJob, error)
+	// RecordAttempt logs an attempt and moves the delivery to status. A pending
+	// delivery is retried at next.
+	RecordAttempt(ctx context.Context, deliveryID int, attempt domain.WebhookAttempt, status string, next time.Time) error
+}
+
+type webhookDBStore struct {
+	db database.Service
+}
+
+func NewWebhookStore(db database.Service) WebhookStore {
+	return &webhookDBStore{db: db}
+}
+
+// enqueueWebhook writes a change to the outbox. It must run in the transaction
+// of the change, so a rolled back change never reaches a subscriber.
+func enqueueWebhook(ctx context.Context, tx database.Querier, tenantID int, eventType string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO webhook_outbox (tenant_id, event_type, data)
+		VALUES ($1, $2, $3)`, tenantID, eventType, string(b))
+	return err
+}
+
+const subscriptionSelect = `
+		SELECT id, tenant_id, url, COALESCE(array_to_json(event_types)::text, '[]'), is_active, created_at, updated_at
+		FROM webhook_subscriptions`
+
+func scanSubscription(row rowScanner) (*domain.WebhookSubscription, error) {
+	var subscription domain.WebhookSubscription
+	var eventTypes string
+
+	err := row.Scan(
+		&subscription.ID, &subscription.TenantID, &subscription.URL, &eventTypes,
+		&subscription.IsActive, &subscription.CreatedAt, &subscription.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(eventTypes), &subscription.EventTypes); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (s *webhookDBStore) GetSubscription(ctx context.Context, tenantID int, id int) (*domain.WebhookSubscription, error) {
+	subscription, err := scanSubscription(s.db.QueryRowContext(ctx, subscriptionSelect+`
+		WHERE tenant_id = $1 AND id = $2`, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (s *webhookDBStore) ListSubscriptions(ctx context.Context, tenantID int) ([]domain.WebhookSubscription, error) {
+	rows, err := s.db.QueryContext(ctx, subscriptionSelect+`
+		WHERE tenant_id = $1
+		ORDER BY id`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []domain.WebhookSubscription{}
+	for rows.Next() {
+		subscription, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, *subscription)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (s *webhookDBStore) CreateSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	query := `
+		INSERT INTO webhook_subscriptions (tenant_id, url, secret, event_types, is_active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+
+	return s.db.QueryRowContext(ctx,
+		query,
+		subscription.TenantID, subscription.URL, subscription.Secret,
+		subscription.EventTypes, subscription.IsActive,
+	).Scan(&subscription.ID, &subscription.CreatedAt, &subscription.UpdatedAt)
+}
+
+func (s *webhookDBStore) UpdateSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	query := `
+		UPDATE webhook_subscriptions
+		SET url = $1, event_types = $2, is_active = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE tenant_id = $4 AND id = $5
+		RETURNING created_at, updated_at`
+
+	err := s.db.QueryRowContext(ctx,
+		query,
+		subscription.URL, subscription.EventTypes, subscription.IsActive,
+		subscription.TenantID, subscription.ID,
+	).Scan(&subscription.CreatedAt, &subscription.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrSubscriptionNotFound
+	}
+	return err
+}
+
+func (s *webhookDBStore) DeleteSubscription(ctx context.Context, tenantID int, id int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+const deliverySelect = `
+		SELECT d.id, d.subscription_id, d.status, d.attempts, d.next_attempt_at, d.last_attempt_at, d.created_at,
+		       o.id, o.event_type, o.tenant_id, o.occurred_at, o.data::text
+		FROM webhook_deliveries d
+		JOIN webhook_outbox o ON d.outbox_id = o.id
+		JOIN webhook_subscriptions s ON d.subscription_id = s.id`
+
+// scanDelivery reads a deliverySelect row, extra receives columns selected after it
+func scanDelivery(row rowScanner, extra ...interface{}) (*domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	var nextAttemptAt time.Time
+	var data string
+
+	dest := []interface{}{
+		&delivery.ID, &delivery.SubscriptionID, &delivery.Status, &delivery.Attempts,
+		&nextAttemptAt, &delivery.LastAttemptAt, &delivery.CreatedAt,
+		&delivery.Payload.ID, &delivery.Payload.Type, &delivery.Payload.TenantID,
+		&delivery.Payload.OccurredAt, &data,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	delivery.Payload.Data = json.RawMessage(data)
+	if delivery.Status == domain.DeliveryPending {
+		delivery.NextAttemptAt = &nextAttemptAt
+	}
+	return &delivery, nil
+}
+
+// deliverySortFields are the sort options accepted by ListDeliveries
+var deliverySortFields = map[string]sortField[domain.WebhookDelivery]{
+	"created_at": {"d.created_at", "timestamptz", func(d *domain.WebhookDelivery) string { return d.CreatedAt.Format(time.RFC3339Nano) }},
+}
+
+func (s *webhookDBStore) ListDeliveries(ctx context.Context, tenantID int, filter domain.WebhookDeliveryFilter) ([]domain.WebhookDelivery, *domain.PageInfo, error) {
+	b := &queryBuilder{}
+	b.where("s.tenant_id = ?", tenantID)
+	if filter.SubscriptionID != 0 {
+		b.where("d.subscription_id = ?", filter.SubscriptionID)
+	}
+	if len(filter.Statuses) > 0 {
+		b.where("d.status = ANY(?)", filter.Statuses)
+	}
+
+	info := &domain.PageInfo{}
+	if filter.WithTotal {
+		var total int
+		query := `SELECT COUNT(*) FROM webhook_deliveries d JOIN webhook_subscriptions s ON d.subscription_id = s.id`
+		if err := s.db.QueryRowContext(ctx, query+b.whereClause(), b.args...).Scan(&total); err != nil {
+			return nil, nil, err
+		}
+		info.Total = &total
+	}
+
+	p, err := newPage(b, deliverySortFields, "-created_at", "d.id", filter.Sort, filter.Cursor, filter.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, deliverySelect+b.whereClause()+p.orderLimit(), b.args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	deliveries, info.NextCursor = p.trim(deliveries, func(d *domain.WebhookDelivery) int { return d.ID })
+	return deliveries, info, nil
+}
+
+func (s *webhookDBStore) GetDelivery(ctx context.Context, tenantID int, id int) (*domain.WebhookDelivery, error) {
+	delivery, err := scanDelivery(s.db.QueryRowContext(ctx, deliverySelect+`
+		WHERE s.tenant_id = $1 AND d.id = $2`, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, attempted_at, response_status, error, duration_ms
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = $1
+		ORDER BY attempted_at, id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	delivery.AttemptLog = []domain.WebhookAttempt{}
+	for rows.Next() {
+		var attempt domain.WebhookAttempt
+		if err := rows.Scan(&attempt.ID, &attempt.AttemptedAt, &attempt.ResponseStatus, &attempt.Error, &attempt.DurationMs); err != nil {
+			return nil, err
+		}
+		delivery.AttemptLog = append(delivery.AttemptLog, attempt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (s *webhookDBStore) Redeliver(ctx context.Context, tenantID int, id int) error {
+	query := `
+		UPDATE webhook_deliveries d
+		SET status = 'pending', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP
+		FROM webhook_subscriptions s
+		WHERE d.subscription_id = s.id AND s.tenant_id = $1 AND d.id = $2`
+
+	result, err := s.db.ExecContext(ctx, query, tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func (s *webhookDBStore) FanOut(ctx context.Context, limit int) (int, error) {
+	query := `
+		WITH batch AS (
+			SELECT id, tenant_id, event_type
+			FROM webhook_outbox
+			WHERE dispatched_at IS NULL
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), fanned AS (
+			INSERT INTO webhook_deliveries (subscription_id, outbox_id)
+			SELECT s.id, batch.id
+			FROM batch
+			JOIN webhook_subscriptions s
+			  ON s.tenant_id = batch.tenant_id AND s.is_active AND batch.event_type = ANY(s.event_types)
+			ON CONFLICT (subscription_id, outbox_id) DO NOTHING
+		)
+		UPDATE webhook_outbox o
+		SET dispatched_at = CURRENT_TIMESTAMP
+		FROM batch
+		WHERE o.id = batch.id`
+
+	result, err := s.db.ExecContext(ctx, query, limit)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	return int(rowsAffected), err
+}
+
+func (s *webhookDBStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookJob, error) {
+	query := `
+		WITH due AS (
+			SELECT d.id
+			FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON d.subscription_id = s.id AND s.is_active
+			WHERE d.status = 'pending' AND d.next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 millisecond'
+		FROM due, webhook_outbox o, webhook_subscriptions s
+		WHERE d.id = due.id AND d.outbox_id = o.id AND d.subscription_id = s.id
+		RETURNING d.id, d.subscription_id, d.status, d.attempts, d.next_attempt_at, d.last_attempt_at, d.created_at,
+		          o.id, o.event_type, o.tenant_id, o.occurred_at, o.data::text,
+		          s.url, s.secret`
+
+	rows, err := s.db.QueryContext(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []domain.WebhookJob
+	for rows.Next() {
+		var job domain.WebhookJob
+		delivery, err := scanDelivery(rows, &job.URL, &job.Secret)
+		if err != nil {
+			return nil, err
+		}
+		job.Delivery = *delivery
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (s *webhookDBStore) RecordAttempt(ctx context.Context, deliveryID int, attempt domain.WebhookAttempt, status string, next time.Time) error {
+	return s.db.Transact(ctx, func(tx database.Querier) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO webhook_delivery_attempts (delivery_id, attempted_at, response_status, error, duration_ms)
+			VALUES ($1, $2, $3, $4, $5)`,
+			deliveryID, attempt.AttemptedAt, attempt.ResponseStatus, attempt.Error, attempt.DurationMs)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = $1, attempts = attempts + 1, last_attempt_at = $2, next_attempt_at = $3
+			WHERE id = $4`, status, attempt.AttemptedAt, next, deliveryID)
+		return err
+	})
+}
@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenAddress = errors.New("webhook: address is not public")
+
+// sharedAddressSpace is the carrier-grade NAT range, it is not routed on the internet
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// PublicAddress reports whether ip may receive deliveries. Loopback, private,
+// link-local, multicast and unspecified addresses are refused so a subscription
+// cannot reach the services next to the server.
+func PublicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// PublicHost reports whether the host of a URL may be public. Names are
+// checked by the client when it connects, localhost and internal addresses
+// given directly are refused here already.
+func PublicHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !PublicAddress(ip) {
+		return false
+	}
+	return true
+}
+
+// checkAddress is a net.Dialer Control function, it runs after the host name
+// is resolved so a name pointing at an internal address is refused as well
+func checkAddress(network string, address string, c syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !PublicAddress(addrPort.Addr()) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// NewClient returns a client that only connects to public addresses, it posts
+// the deliveries and fetches the OpenID Connect discovery documents. It
+// connects directly, without the environment's proxy, so every address is
+// checked.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: checkAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
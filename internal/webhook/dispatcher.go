@@ -0,0 +1,221 @@
+// Package webhook sends the outbox to the tenants' webhook subscriptions
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/logging"
+	"pwp-remastered/internal/metrics"
+	"pwp-remastered/internal/store"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-PWP-Event"
+	HeaderDelivery  = "X-PWP-Delivery"
+	HeaderSignature = "X-PWP-Signature"
+)
+
+var ErrInvalidSignature = errors.New("webhook: invalid signature")
+
+// Sign returns the signature header for body sent at t, in the form
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">"
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + signature(secret, ts, body)
+}
+
+// Verify checks a signature header made by Sign and rejects it when it is
+// older than tolerance, receivers can use it to authenticate deliveries
+func Verify(secret string, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			sig = value
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return ErrInvalidSignature
+	}
+	if now.Sub(time.Unix(unix, 0)).Abs() > tolerance {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, ts, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func signature(secret string, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the delay before retrying after the given failed attempt,
+// doubling from base and capped at max
+func Backoff(attempt int, base time.Duration, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	return min(delay, max)
+}
+
+// Dispatcher polls the outbox, fans it out into deliveries and posts the due
+// ones. Several dispatchers may run against the same database.
+type Dispatcher struct {
+	store  store.WebhookStore
+	client *http.Client
+	now    func() time.Time
+
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// NewDispatcher creates a dispatcher that retries a delivery up to 8 times,
+// from 30 seconds up to 6 hours apart. It only posts to public addresses.
+func NewDispatcher(webhookStore store.WebhookStore) *Dispatcher {
+	return &Dispatcher{
+		store:       webhookStore,
+		client:      NewClient(10 * time.Second),
+		now:         time.Now,
+		Interval:    5 * time.Second,
+		BatchSize:   50,
+		MaxAttempts: 8,
+		BaseDelay:   30 * time.Second,
+		MaxDelay:    6 * time.Hour,
+	}
+}
+
+// Run dispatches every Interval until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+	for {
+		if err := d.Tick(ctx); err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "webhook dispatch failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick fans out the pending outbox and sends the deliveries that are due
+func (d *Dispatcher) Tick(ctx context.Context) error {
+	ctx = metrics.WithMethod(ctx, "Dispatcher.Tick")
+	for {
+		n, err := d.store.FanOut(ctx, d.BatchSize)
+		if err != nil {
+			return err
+		}
+		if n < d.BatchSize {
+			break
+		}
+	}
+
+	// The lease outlasts the client timeout, so a delivery in flight is not
+	// picked up again by another dispatcher
+	jobs, err := d.store.ClaimDue(ctx, d.BatchSize, 2*d.client.Timeout)
+	if err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliver(ctx, job)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, job domain.WebhookJob) {
+	attempt, ok := d.post(ctx, job)
+	status, next := d.schedule(job.Delivery.Attempts+1, ok, attempt.AttemptedAt)
+	// A delivery sent during shutdown is still recorded, or it would be sent again
+	if err := d.store.RecordAttempt(context.WithoutCancel(ctx), job.Delivery.ID, attempt, status, next); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "webhook record attempt failed", "delivery_id", job.Delivery.ID, "error", err)
+	}
+}
+
+// post sends the payload once and reports whether the receiver accepted it
+func (d *Dispatcher) post(ctx context.Context, job domain.WebhookJob) (domain.WebhookAttempt, bool) {
+	start := d.now()
+	attempt := domain.WebhookAttempt{AttemptedAt: start}
+	fail := func(err error) (domain.WebhookAttempt, bool) {
+		msg := err.Error()
+		attempt.Error = &msg
+		attempt.DurationMs = int(d.now().Sub(start).Milliseconds())
+		return attempt, false
+	}
+
+	body, err := json.Marshal(job.Delivery.Payload)
+	if err != nil {
+		return fail(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(body))
+	if err != nil {
+		return fail(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "pwp-webhooks/1")
+	req.Header.Set(HeaderEvent, job.Delivery.Payload.Type)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(job.Delivery.ID))
+	req.Header.Set(HeaderSignature, Sign(job.Secret, start, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	status := resp.StatusCode
+	attempt.ResponseStatus = &status
+	if status < 200 || status > 299 {
+		return fail(fmt.Errorf("unexpected response status %d", status))
+	}
+	attempt.DurationMs = int(d.now().Sub(start).Milliseconds())
+	return attempt, true
+}
+
+// schedule returns the state of a delivery after its attempt-th attempt
+func (d *Dispatcher) schedule(attempt int, ok bool, at time.Time) (string, time.Time) {
+	switch {
+	case ok:
+		return domain.DeliverySucceeded, at
+	case attempt >= d.MaxAttempts:
+		return domain.DeliveryFailed, at
+	default:
+		return domain.DeliveryPending, at.Add(Backoff(attempt, d.BaseDelay, d.MaxDelay))
+	}
+}
@@ -0,0 +1,228 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"pwp-remastered/internal/domain"
+	"pwp-remastered/internal/store"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":1}`)
+	header := Sign("secret", now, body)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		now     time.Time
+		wantErr bool
+	}{
+		{"valid", "secret", header, body, now, false},
+		{"within tolerance", "secret", header, body, now.Add(4 * time.Minute), false},
+		{"wrong secret", "other", header, body, now, true},
+		{"tampered body", "secret", header, []byte(`{"id":2}`), now, true},
+		{"expired", "secret", header, body, now.Add(10 * time.Minute), true},
+		{"missing signature", "secret", "t=1700000000", body, now, true},
+		{"garbage", "secret", "nonsense", body, now, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.body, 5*time.Minute, tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{8, 64 * time.Minute},
+		{20, 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempt, 30*time.Second, 2*time.Hour); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+type recordedAttempt struct {
+	attempt domain.WebhookAttempt
+	status  string
+	next    time.Time
+}
+
+type fakeWebhookStore struct {
+	store.WebhookStore
+	jobs     []domain.WebhookJob
+	mu       sync.Mutex
+	attempts map[int]recordedAttempt
+}
+
+func (s *fakeWebhookStore) FanOut(ctx context.Context, limit int) (int, error) { return 0, nil }
+
+func (s *fakeWebhookStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookJob, error) {
+	jobs := s.jobs
+	s.jobs = nil
+	return jobs, nil
+}
+
+func (s *fakeWebhookStore) RecordAttempt(ctx context.Context, deliveryID int, attempt domain.WebhookAttempt, status string, next time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts[deliveryID] = recordedAttempt{attempt, status, next}
+	return nil
+}
+
+func TestDispatcherTick(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	received := map[string]http.Header{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify("secret", r.Header.Get(HeaderSignature), body, time.Minute, now); err != nil {
+			t.Errorf("delivery %s: %v", r.Header.Get(HeaderDelivery), err)
+		}
+		mu.Lock()
+		received[r.Header.Get(HeaderDelivery)] = r.Header
+		mu.Unlock()
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	job := func(id int, path string, attempts int) domain.WebhookJob {
+		return domain.WebhookJob{
+			Delivery: domain.WebhookDelivery{
+				ID:       id,
+				Attempts: attempts,
+				Payload:  domain.WebhookPayload{ID: 1, Type: domain.WebhookEventCreated, Data: json.RawMessage(`{}`)},
+			},
+			URL:    srv.URL + path,
+			Secret: "secret",
+		}
+	}
+	webhooks := &fakeWebhookStore{
+		jobs: []domain.WebhookJob{
+			job(1, "/ok", 0),
+			job(2, "/fail", 2),
+			job(3, "/fail", 7),
+		},
+		attempts: map[int]recordedAttempt{},
+	}
+	d := NewDispatcher(webhooks)
+	d.now = func() time.Time { return now }
+	// The test server listens on loopback, which the default client refuses
+	d.client = &http.Client{Timeout: 10 * time.Second}
+
+	if err := d.Tick(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id         int
+		wantStatus string
+		wantNext   time.Time
+		wantCode   int
+	}{
+		{1, domain.DeliverySucceeded, now, http.StatusOK},
+		{2, domain.DeliveryPending, now.Add(2 * time.Minute), http.StatusInternalServerError},
+		{3, domain.DeliveryFailed, now, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		got, ok := webhooks.attempts[tt.id]
+		if !ok {
+			t.Errorf("delivery %d: no attempt recorded", tt.id)
+			continue
+		}
+		if got.status != tt.wantStatus || !got.next.Equal(tt.wantNext) {
+			t.Errorf("delivery %d = %s at %v, want %s at %v", tt.id, got.status, got.next, tt.wantStatus, tt.wantNext)
+		}
+		if got.attempt.ResponseStatus == nil || *got.attempt.ResponseStatus != tt.wantCode {
+			t.Errorf("delivery %d: response status = %v, want %d", tt.id, got.attempt.ResponseStatus, tt.wantCode)
+		}
+		if (got.attempt.Error != nil) != (tt.wantStatus != domain.DeliverySucceeded) {
+			t.Errorf("delivery %d: error = %v", tt.id, got.attempt.Error)
+		}
+	}
+	if h := received["1"]; h.Get(HeaderEvent) != domain.WebhookEventCreated {
+		t.Errorf("%s = %q, want %q", HeaderEvent, h.Get(HeaderEvent), domain.WebhookEventCreated)
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"100.64.0.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := PublicAddress(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("PublicAddress(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestDispatcherRefusesInternalAddresses(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	// localhost resolves to loopback, the check runs on the resolved address
+	u, _ := url.Parse(srv.URL)
+	webhooks := &fakeWebhookStore{
+		jobs: []domain.WebhookJob{{
+			Delivery: domain.WebhookDelivery{ID: 1, Payload: domain.WebhookPayload{ID: 1, Type: domain.WebhookEventCreated}},
+			URL:      "http://localhost:" + u.Port() + "/hook",
+			Secret:   "secret",
+		}},
+		attempts: map[int]recordedAttempt{},
+	}
+	if err := NewDispatcher(webhooks).Tick(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got := webhooks.attempts[1]
+	if got.status != domain.DeliveryPending || got.attempt.Error == nil || !strings.Contains(*got.attempt.Error, ErrForbiddenAddress.Error()) {
+		t.Errorf("attempt = %+v, status %s, want it refused", got.attempt, got.status)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("receiver got %d requests, want none", hits.Load())
+	}
+}
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS event_types (
+    id SERIAL PRIMARY KEY,
+    type VARCHAR(255) NOT NULL,
+    language VARCHAR(16) NOT NULL,
+    color VARCHAR(32),
+    is_pricable BOOLEAN DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS events (
+    id SERIAL PRIMARY KEY,
+    type_id INTEGER NOT NULL REFERENCES event_types(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    name VARCHAR(255) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    start_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    road_price NUMERIC(12, 2) DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS events_user_id_start_date_idx ON events (user_id, start_date);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS mileage_rates;
+
+ALTER TABLE events
+    DROP COLUMN IF EXISTS origin_lat,
+    DROP COLUMN IF EXISTS origin_lng,
+    DROP COLUMN IF EXISTS destination_lat,
+    DROP COLUMN IF EXISTS destination_lng,
+    DROP COLUMN IF EXISTS distance_km;
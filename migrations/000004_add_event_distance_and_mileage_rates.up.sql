@@ -0,0 +1,19 @@
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS origin_lat DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS origin_lng DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS destination_lat DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS destination_lng DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS distance_km NUMERIC(10, 2);
+
+CREATE TABLE IF NOT EXISTS mileage_rates (
+    id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    type_id INTEGER REFERENCES event_types(id),
+    rate_per_km NUMERIC(10, 4) NOT NULL CHECK (rate_per_km >= 0),
+    effective_from DATE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One version per tenant, type and effective date; a NULL type is the tenant default
+CREATE UNIQUE INDEX IF NOT EXISTS mileage_rates_version_idx
+    ON mileage_rates (tenant_id, COALESCE(type_id, 0), effective_from);
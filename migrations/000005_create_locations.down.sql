@@ -0,0 +1,6 @@
+ALTER TABLE events
+    DROP COLUMN IF EXISTS origin_location_id,
+    DROP COLUMN IF EXISTS destination_location_id;
+
+DROP TABLE IF EXISTS location_routes;
+DROP TABLE IF EXISTS locations;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS locations (
+    id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    address TEXT,
+    latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS locations_tags_idx ON locations USING GIN (tags);
+
+-- Configured road distances between two saved locations, used instead of the
+-- great-circle distance when pricing trips. Routes are looked up in both directions.
+CREATE TABLE IF NOT EXISTS location_routes (
+    from_location_id INTEGER NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    to_location_id INTEGER NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    distance_km NUMERIC(10, 2) NOT NULL CHECK (distance_km >= 0),
+    PRIMARY KEY (from_location_id, to_location_id)
+);
+
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS origin_location_id INTEGER REFERENCES locations(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS destination_location_id INTEGER REFERENCES locations(id) ON DELETE SET NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE events
+    DROP COLUMN IF EXISTS vehicle_id,
+    DROP COLUMN IF EXISTS odometer_start,
+    DROP COLUMN IF EXISTS odometer_end;
+
+DROP TABLE IF EXISTS vehicle_odometer_readings;
+DROP TABLE IF EXISTS vehicles;
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS vehicles (
+    id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    plate VARCHAR(32) NOT NULL,
+    -- NULL owner means a pool car anyone in the tenant may use
+    owner_user_id INTEGER REFERENCES users(id),
+    fuel_type VARCHAR(16) NOT NULL CHECK (fuel_type IN ('petrol', 'diesel', 'lpg', 'hybrid', 'electric')),
+    -- NULL rate falls back to the tenant mileage rates
+    rate_per_km NUMERIC(10, 4) CHECK (rate_per_km >= 0),
+    initial_odometer_km INTEGER NOT NULL DEFAULT 0 CHECK (initial_odometer_km >= 0),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, plate)
+);
+
+CREATE TABLE IF NOT EXISTS vehicle_odometer_readings (
+    id SERIAL PRIMARY KEY,
+    vehicle_id INTEGER NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    -- Readings taken from an event's odometer_end carry the event, manual readings do not
+    event_id INTEGER UNIQUE REFERENCES events(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    reading_km INTEGER NOT NULL CHECK (reading_km >= 0),
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS vehicle_odometer_readings_vehicle_idx
+    ON vehicle_odometer_readings (vehicle_id, recorded_at);
+
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS vehicle_id INTEGER REFERENCES vehicles(id),
+    ADD COLUMN IF NOT EXISTS odometer_start INTEGER CHECK (odometer_start >= 0),
+    ADD COLUMN IF NOT EXISTS odometer_end INTEGER CHECK (odometer_end >= 0);
@@ -0,0 +1,20 @@
+ALTER TABLE event_types
+    DROP CONSTRAINT IF EXISTS event_types_color_hex,
+    DROP COLUMN IF EXISTS is_archived,
+    DROP COLUMN IF EXISTS sort_order,
+    ADD COLUMN IF NOT EXISTS language VARCHAR(16);
+
+-- Restore one language per type, preferring Turkish as the original data did
+UPDATE event_types et
+SET language = t.locale, type = t.label
+FROM (
+    SELECT DISTINCT ON (type_id) type_id, locale, label
+    FROM event_type_translations
+    ORDER BY type_id, locale <> 'tr', locale
+) t
+WHERE t.type_id = et.id;
+
+UPDATE event_types SET language = 'tr' WHERE language IS NULL;
+ALTER TABLE event_types ALTER COLUMN language SET NOT NULL;
+
+DROP TABLE IF EXISTS event_type_translations;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS event_type_translations (
+    type_id INTEGER NOT NULL REFERENCES event_types(id) ON DELETE CASCADE,
+    locale VARCHAR(16) NOT NULL,
+    label VARCHAR(255) NOT NULL,
+    PRIMARY KEY (type_id, locale)
+);
+
+-- Every existing type was written in a single language, keep it as its first translation
+INSERT INTO event_type_translations (type_id, locale, label)
+SELECT id, lower(language), type FROM event_types
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE event_types
+    DROP COLUMN IF EXISTS language,
+    ADD COLUMN IF NOT EXISTS is_archived BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS sort_order INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE event_types
+    ADD CONSTRAINT event_types_color_hex CHECK (color IS NULL OR color ~ '^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$') NOT VALID;
@@ -0,0 +1,13 @@
+-- Full-text search over event titles and descriptions. Both the Turkish and the
+-- English stemmers are applied so a query matches in either language.
+ALTER TABLE events
+    ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('turkish', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('turkish', coalesce(name, '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(name, '')), 'B') ||
+        setweight(to_tsvector('turkish', coalesce(description, '')), 'C') ||
+        setweight(to_tsvector('english', coalesce(description, '')), 'C')
+    ) STORED;
+
+CREATE INDEX idx_events_search_vector ON events USING GIN (search_vector);
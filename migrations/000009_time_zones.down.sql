@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS tenant_settings;
+ALTER TABLE users DROP COLUMN IF EXISTS time_zone;
@@ -0,0 +1,9 @@
+-- Dated queries interpret calendar days in the caller's time zone: the user's
+-- own setting, then the tenant default, then UTC.
+ALTER TABLE users ADD COLUMN time_zone TEXT;
+
+CREATE TABLE IF NOT EXISTS tenant_settings (
+    tenant_id INTEGER PRIMARY KEY,
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_users_manager_id;
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_manager_not_self,
+    DROP COLUMN IF EXISTS manager_id;
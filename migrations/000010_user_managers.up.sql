@@ -0,0 +1,6 @@
+-- Direct manager of a user, managers may read their reports' events
+ALTER TABLE users
+    ADD COLUMN manager_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    ADD CONSTRAINT users_manager_not_self CHECK (manager_id <> id);
+
+CREATE INDEX IF NOT EXISTS idx_users_manager_id ON users(manager_id);
@@ -0,0 +1 @@
+DROP SEQUENCE IF EXISTS live_message_id_seq;
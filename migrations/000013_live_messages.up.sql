@@ -0,0 +1,2 @@
+-- IDs of the live event stream, shared by the replicas so clients can resume on any of them
+CREATE SEQUENCE IF NOT EXISTS live_message_id_seq;
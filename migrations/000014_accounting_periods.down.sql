@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS accounting_period_log;
+DROP TABLE IF EXISTS accounting_periods;
+
+ALTER TABLE users DROP COLUMN IF EXISTS is_accountant;
@@ -0,0 +1,29 @@
+-- Accountants close and reopen accounting periods next to the admins
+ALTER TABLE users ADD COLUMN is_accountant BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Inclusive date ranges in the tenant's time zone. Events overlapping a closed
+-- period can no longer be created, changed or deleted.
+CREATE TABLE IF NOT EXISTS accounting_periods (
+    id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'closed')),
+    closed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (start_date <= end_date)
+);
+
+CREATE INDEX IF NOT EXISTS accounting_periods_tenant_id_start_date_idx ON accounting_periods (tenant_id, start_date);
+
+-- Who created, closed and reopened each period, and when
+CREATE TABLE IF NOT EXISTS accounting_period_log (
+    id SERIAL PRIMARY KEY,
+    period_id INTEGER NOT NULL REFERENCES accounting_periods(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('created', 'closed', 'reopened')),
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS accounting_period_log_period_id_idx ON accounting_period_log (period_id);
@@ -0,0 +1,25 @@
+-- Road cost limits of a user, a team or an event type per month, quarter or year
+CREATE TABLE IF NOT EXISTS budgets (
+    id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    team_id INTEGER REFERENCES teams(id) ON DELETE CASCADE,
+    type_id INTEGER REFERENCES event_types(id) ON DELETE CASCADE,
+    period VARCHAR(20) NOT NULL CHECK (period IN ('month', 'quarter', 'year')),
+    amount NUMERIC(12, 2) NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (num_nonnulls(user_id, team_id, type_id) = 1)
+);
+
+CREATE INDEX IF NOT EXISTS budgets_tenant_id_idx ON budgets (tenant_id);
+
+-- Thresholds already announced, at most once per budget, period and threshold
+CREATE TABLE IF NOT EXISTS budget_alerts (
+    budget_id INTEGER NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+    period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    threshold INTEGER NOT NULL,
+    spent NUMERIC(12, 2) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (budget_id, period_start, threshold)
+);
@@ -0,0 +1,11 @@
+-- Bearer tokens of the tenants' SCIM provisioning clients, only a SHA-256 hash is kept
+CREATE TABLE IF NOT EXISTS scim_tokens (
+    id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS scim_tokens_tenant_id_idx ON scim_tokens (tenant_id);
@@ -0,0 +1,21 @@
+-- Tenants with an enabled LDAP configuration sign users in with an LDAP bind
+-- instead of the local password hash. %s in the filters is replaced with the
+-- escaped username and the user's DN.
+CREATE TABLE IF NOT EXISTS tenant_ldap (
+    tenant_id INTEGER PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    url TEXT NOT NULL,
+    start_tls BOOLEAN NOT NULL DEFAULT FALSE,
+    bind_dn TEXT NOT NULL DEFAULT '',
+    bind_password TEXT NOT NULL DEFAULT '',
+    base_dn TEXT NOT NULL,
+    user_filter TEXT NOT NULL DEFAULT '(uid=%s)',
+    email_attribute TEXT NOT NULL DEFAULT 'mail',
+    first_name_attribute TEXT NOT NULL DEFAULT 'givenName',
+    last_name_attribute TEXT NOT NULL DEFAULT 'sn',
+    group_base_dn TEXT NOT NULL DEFAULT '',
+    group_filter TEXT NOT NULL DEFAULT '(member=%s)',
+    admin_group TEXT NOT NULL DEFAULT '',
+    accountant_group TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,33 @@
+-- OpenID Connect single sign-on per tenant. Claims name the ID token claims
+-- copied to the user; members of admin_role and accountant_role in
+-- roles_claim get the matching flags.
+CREATE TABLE IF NOT EXISTS tenant_oidc (
+    tenant_id INTEGER PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    issuer TEXT NOT NULL,
+    client_id TEXT NOT NULL,
+    client_secret TEXT NOT NULL DEFAULT '',
+    scopes TEXT NOT NULL DEFAULT 'openid email profile',
+    username_claim TEXT NOT NULL DEFAULT 'preferred_username',
+    email_claim TEXT NOT NULL DEFAULT 'email',
+    first_name_claim TEXT NOT NULL DEFAULT 'given_name',
+    last_name_claim TEXT NOT NULL DEFAULT 'family_name',
+    roles_claim TEXT NOT NULL DEFAULT 'groups',
+    admin_role TEXT NOT NULL DEFAULT '',
+    accountant_role TEXT NOT NULL DEFAULT '',
+    redirect_url TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Authorization requests waiting for the identity provider's callback, keyed
+-- by the state parameter. They hold the PKCE verifier and the nonce.
+CREATE TABLE IF NOT EXISTS oidc_logins (
+    state CHAR(43) PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    code_verifier TEXT NOT NULL,
+    nonce TEXT NOT NULL,
+    redirect_uri TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS oidc_logins_created_at_idx ON oidc_logins (created_at);
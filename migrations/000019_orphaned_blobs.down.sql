@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS event_attachments_orphaned_blob ON event_attachments;
+DROP FUNCTION IF EXISTS queue_orphaned_blob();
+DROP TABLE IF EXISTS orphaned_blobs;
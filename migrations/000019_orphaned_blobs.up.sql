@@ -0,0 +1,19 @@
+-- Attachment rows are also removed by ON DELETE CASCADE when their event is
+-- deleted, so the storage key of every deleted row is queued here and the
+-- blob sweeper removes the contents from blob storage.
+CREATE TABLE IF NOT EXISTS orphaned_blobs (
+    storage_key VARCHAR(512) PRIMARY KEY,
+    queued_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE OR REPLACE FUNCTION queue_orphaned_blob() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO orphaned_blobs (storage_key) VALUES (OLD.storage_key)
+    ON CONFLICT (storage_key) DO NOTHING;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER event_attachments_orphaned_blob
+    AFTER DELETE ON event_attachments
+    FOR EACH ROW EXECUTE FUNCTION queue_orphaned_blob();
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS event_types_tenant_id_idx;
+ALTER TABLE event_types DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,6 @@
+-- Types without a tenant are shared by every tenant and only changed here,
+-- tenant admins manage the types they create
+ALTER TABLE event_types
+    ADD COLUMN IF NOT EXISTS tenant_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS event_types_tenant_id_idx ON event_types (tenant_id);
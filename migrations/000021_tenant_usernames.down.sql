@@ -0,0 +1,4 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_tenant_id_username_key;
+
+ALTER TABLE users
+    ADD CONSTRAINT users_username_key UNIQUE (username);
@@ -0,0 +1,5 @@
+-- Usernames are unique per tenant, logins name the tenant they sign in to
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_username_key;
+
+ALTER TABLE users
+    ADD CONSTRAINT users_tenant_id_username_key UNIQUE (tenant_id, username);
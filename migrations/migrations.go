@@ -0,0 +1,32 @@
+// Package migrations embeds the SQL migrations, which are applied with
+// golang-migrate, so the API can tell whether its database schema is current.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Latest returns the version of the newest migration, the number its file
+// names start with
+func Latest() (uint, error) {
+	names, err := fs.Glob(files, "*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+	var latest uint
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		latest = max(latest, uint(version))
+	}
+	return latest, nil
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+func TestLatest(t *testing.T) {
+	latest, err := Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, _ := fs.Glob(files, fmt.Sprintf("%06d_*.up.sql", latest))
+	if len(names) != 1 {
+		t.Fatalf("Latest() = %d, no migration file has that version", latest)
+	}
+	newer, _ := fs.Glob(files, fmt.Sprintf("%06d_*.up.sql", latest+1))
+	if len(newer) != 0 {
+		t.Fatalf("Latest() = %d, but %v exists", latest, newer)
+	}
+}